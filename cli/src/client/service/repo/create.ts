@@ -1,13 +1,14 @@
 import client from '../../index';
 import { APISuccess } from '../../../models/response';
-import { Repo } from '../../../models/repo';
+import { ScanEvent, ScanJob } from '../../../models/repo';
 
-const createRepository = async (url: string): Promise<APISuccess<Repo>> => {
+const createRepository = async (url: string, ref?: string): Promise<APISuccess<ScanJob>> => {
   try {
-    const body: APISuccess<Repo> = await client
+    const body: APISuccess<ScanJob> = await client
       .post('api/repository', {
         json: {
           url: url,
+          ref: ref,
         },
       })
       .json();
@@ -17,4 +18,50 @@ const createRepository = async (url: string): Promise<APISuccess<Repo>> => {
   }
 };
 
-export { createRepository };
+const findScanJob = async (jobID: string): Promise<APISuccess<ScanJob>> => {
+  try {
+    const body: APISuccess<ScanJob> = await client.get(`api/repository/jobs/${jobID}`).json();
+    return body;
+  } catch (error) {
+    return error;
+  }
+};
+
+const cancelScanJob = async (jobID: string): Promise<APISuccess<ScanJob>> => {
+  try {
+    const body: APISuccess<ScanJob> = await client.delete(`api/repository/jobs/${jobID}`).json();
+    return body;
+  } catch (error) {
+    return error;
+  }
+};
+
+// Reads server-sent events of scan job until stream is closed
+const watchScanJob = (jobID: string, onEvent: (event: ScanEvent) => void): Promise<void> =>
+  new Promise((resolve, reject) => {
+    let buffer = '';
+
+    client
+      .stream(`api/repository/jobs/${jobID}/events`)
+      .on('data', (chunk: Buffer) => {
+        buffer += chunk.toString();
+
+        const messages = buffer.split('\n\n');
+        buffer = messages.pop() || '';
+
+        messages.forEach((message) => {
+          const data = message
+            .split('\n')
+            .filter((line) => line.startsWith('data:'))
+            .map((line) => line.slice('data:'.length).trim())
+            .join('\n');
+          if (data) {
+            onEvent(JSON.parse(data));
+          }
+        });
+      })
+      .on('end', resolve)
+      .on('error', reject);
+  });
+
+export { createRepository, findScanJob, cancelScanJob, watchScanJob };
@@ -0,0 +1,36 @@
+import client from '../../index';
+import { APISuccess } from '../../../models/response';
+import { PullRequest, Repo } from '../../../models/repo';
+
+const createPullRequest = async (repoID: string): Promise<APISuccess<PullRequest>> => {
+  try {
+    const body: APISuccess<PullRequest> = await client
+      .post('api/repository/pull-request', {
+        json: {
+          id: repoID,
+        },
+      })
+      .json();
+    return body;
+  } catch (error) {
+    return error;
+  }
+};
+
+const updateRepositoryToken = async (repoID: string, token: string): Promise<APISuccess<Repo>> => {
+  try {
+    const body: APISuccess<Repo> = await client
+      .put('api/repository/token', {
+        json: {
+          id: repoID,
+          token,
+        },
+      })
+      .json();
+    return body;
+  } catch (error) {
+    return error;
+  }
+};
+
+export { createPullRequest, updateRepositoryToken };
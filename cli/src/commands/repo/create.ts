@@ -1,17 +1,61 @@
 import { prompt } from 'inquirer';
 import { program } from 'commander';
 import ora from 'ora';
-import { createRepository } from '../../client/service/repo/create';
+import { cancelScanJob, createRepository, findScanJob, watchScanJob } from '../../client/service/repo/create';
 import { createSubscriber } from '../../client/service/subscriber/create';
 import { getDefaultUserEmail } from '../../utils/config/auth';
+import { ScanJob } from '../../models/repo';
 
 const spinner = ora('Creating');
 
+const progressText = ({ status, progress }: ScanJob): string => {
+  if (status === 'queued') {
+    return 'Waiting for scan';
+  }
+  const { manifestsFound, packagesFound, packagesResolved, failures } = progress;
+  return `Scanning: ${manifestsFound} package files, ${packagesResolved}/${packagesFound} packages resolved, ${failures.length} failed`;
+};
+
+// Watches scan events until job is finished, Ctrl+C cancels scan
+const waitForScan = async (job: ScanJob): Promise<ScanJob> => {
+  const onInterrupt = () => {
+    spinner.start('Cancelling');
+    cancelScanJob(job.id);
+  };
+  process.once('SIGINT', onInterrupt);
+
+  let last = job;
+  try {
+    await watchScanJob(job.id, (event) => {
+      last = event.job;
+      if (event.type === 'manifests' && event.manifests) {
+        spinner.info(`Found ${event.manifests.length} package files`);
+        spinner.start();
+      }
+      if (event.type === 'failure' && event.failure) {
+        spinner.warn(`${event.failure.path} • ${event.failure.name} - ${event.failure.message}`);
+        spinner.start();
+      }
+      spinner.text = progressText(event.job);
+    });
+  } finally {
+    process.removeListener('SIGINT', onInterrupt);
+  }
+
+  // Stream can be closed before job is finished, status is read once more
+  if (!['completed', 'failed', 'cancelled'].includes(last.status)) {
+    const { data } = await findScanJob(job.id);
+    return data || last;
+  }
+  return last;
+};
+
 const createRepositoryCommand = () =>
   program
     .command('create <url>')
     .description('Creates new repository')
-    .action((url) =>
+    .option('-r, --ref <ref>', 'Branch, tag or commit to track')
+    .action((url, { ref }) =>
       prompt([
         {
           type: 'confirm',
@@ -22,16 +66,33 @@ const createRepositoryCommand = () =>
         spinner.start('Creating');
 
         try {
-          const body = await createRepository(url);
-          const { data, message } = body;
+          const body = await createRepository(url, ref);
+          if (!body.data) {
+            throw new Error(body.message);
+          }
 
-          spinner.succeed(message);
+          const job = await waitForScan(body.data);
+
+          if (job.status === 'cancelled') {
+            spinner.warn('Scan is cancelled');
+            return;
+          }
+          if (job.status === 'failed') {
+            throw new Error(job.error);
+          }
+
+          const { failures } = job.progress;
+          if (failures.length > 0) {
+            spinner.warn(`Repository is created, ${failures.length} packages could not be resolved`);
+          } else {
+            spinner.succeed('You successfully create an git repository.');
+          }
 
           if (addYourself) {
             spinner.start('Adding...');
 
             const email = getDefaultUserEmail();
-            const addBody = await createSubscriber(data.id!, email!);
+            const addBody = await createSubscriber(job.repoID!, email!);
 
             spinner.succeed(addBody.message);
           }
@@ -2,10 +2,12 @@ import createRepo from './create';
 import deleteRepo from './delete';
 import listRepo from './find';
 import updateRepo from './update';
+import pullRequest from './pullRequest';
 
 export const repository = () => {
   createRepo();
   updateRepo();
+  pullRequest();
   listRepo();
   deleteRepo();
 };
@@ -0,0 +1,58 @@
+import { prompt } from 'inquirer';
+import { program } from 'commander';
+import chalk from 'chalk';
+import ora from 'ora';
+import { findAllRepositories } from '../../client/service/repo/findAll';
+import { createPullRequest, updateRepositoryToken } from '../../client/service/repo/pullRequest';
+
+const spinner = ora();
+
+const pullRequestCommand = () =>
+  program
+    .command('pr')
+    .description('Opens pull request that updates outdated packages')
+    .option('-t, --token <token>', 'access token of provider, it is saved for next pull requests')
+    .action(async (command) => {
+      try {
+        const reposBody = await findAllRepositories();
+        const { data } = reposBody;
+
+        prompt([
+          {
+            type: 'list',
+            message: 'Please select repository that you want to update',
+            name: 'repoName',
+            choices: data.map((repo) => repo.name),
+          },
+        ]).then(async ({ repoName }) => {
+          try {
+            const repo = data.find((repo) => repo.name === repoName);
+            if (!repo) {
+              throw new Error('Undefined repository');
+            }
+
+            if (command.token) {
+              await updateRepositoryToken(repo.id!, command.token);
+            }
+
+            spinner.start('Opening pull request');
+            const { data: pullRequest, message } = await createPullRequest(repo.id!);
+            if (!pullRequest) {
+              spinner.fail(message);
+              return;
+            }
+            spinner.succeed(chalk.green(pullRequest.url));
+
+            pullRequest.updates.forEach((update) => {
+              console.log(chalk.red(update.from), '→', chalk.green(update.to), '-', chalk.whiteBright(update.name), `(${update.kind})`);
+            });
+          } catch (error) {
+            spinner.fail(error.message);
+          }
+        });
+      } catch (error) {
+        spinner.fail(error.message);
+      }
+    });
+
+export default pullRequestCommand;
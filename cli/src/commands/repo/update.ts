@@ -32,12 +32,16 @@ const updateRepoCommand = () =>
 
             const updatedBody = await updateRepositoryPackages(repo.id!);
 
-            const { packageList } = updatedBody.data;
+            const { packageList, lastScan } = updatedBody.data;
             if (!packageList) {
               spinner.warn('There is no package in this repository');
               return;
             }
 
+            if (lastScan && !lastScan.manifestChanged) {
+              spinner.info('Package files are not changed since last scan, only registry versions are checked');
+            }
+
             packageList.forEach((pkg) => {
               let name = chalk.whiteBright(pkg.name);
               let current = chalk.green(pkg.version.current);
@@ -7,15 +7,76 @@ export type Package = {
   name: string;
   version: PackageVersion;
   file: string;
+  path: string;
   isOutdated: boolean;
 };
 
+export type ScanResult = {
+  scannedAt: string;
+  manifestChanged: boolean;
+  registryChanged: boolean;
+};
+
 export type Repo = {
   id?: string;
   userID: string;
   name: string;
   owner: string;
   path: string;
+  ref: string;
   provider: string;
   packageList: [Package];
+  hasToken: boolean;
+  commitSHA: string;
+  lastScan?: ScanResult;
+  manifests?: { [path: string]: Package[] };
+};
+
+export type PackageUpdate = {
+  name: string;
+  path: string;
+  file: string;
+  from: string;
+  to: string;
+  kind: 'major' | 'minor' | 'patch';
+  changelogUrl?: string;
+};
+
+export type PullRequest = {
+  url: string;
+  branch: string;
+  updates: PackageUpdate[];
+};
+
+export type ScanFailure = {
+  name: string;
+  path: string;
+  message: string;
+};
+
+export type ScanProgress = {
+  manifestsFound: number;
+  packagesFound: number;
+  packagesResolved: number;
+  failures: ScanFailure[];
+};
+
+export type ScanJob = {
+  id: string;
+  url: string;
+  ref: string;
+  status: 'queued' | 'running' | 'completed' | 'failed' | 'cancelled';
+  repoID?: string;
+  progress: ScanProgress;
+  error?: string;
+  createdAt: string;
+  updatedAt: string;
+};
+
+export type ScanEvent = {
+  type: 'status' | 'manifests' | 'package' | 'failure';
+  job: ScanJob;
+  manifests?: string[];
+  package?: Package;
+  failure?: ScanFailure;
 };
@@ -6,11 +6,19 @@ import (
 	"github.com/nozgurozturk/marvin/notifier/internal/config"
 	"github.com/nozgurozturk/marvin/notifier/internal/service"
 	"github.com/nozgurozturk/marvin/notifier/internal/storage"
+	"github.com/nozgurozturk/marvin/pkg/client"
+	"log"
 	"sync"
 )
 
 func main() {
-	config.Set()
+	cnf := config.Set()
+
+	// Registry requests use proxy and TLS settings
+	if err := client.Configure(cnf.Outbound); err != nil {
+		log.Fatal(err)
+	}
+
 	mongo, err := storage.MongoConnect()
 	if err != nil {
 		return
@@ -14,7 +14,24 @@ type Package struct {
 	Name       string         `json:"name" bson:"name"`
 	Version    PackageVersion `json:"version" bson:"version"`
 	File       string         `json:"file" bson:"file"`
+	Path       string         `json:"path" bson:"path"`
 	IsOutdated bool           `json:"isOutdated" bson:"isOutdated"`
+	// Outdated package that is ignored with a rule of repository
+	Ignored *IgnoredPackage `json:"ignored,omitempty" bson:"ignored,omitempty"`
+}
+
+type IgnoredPackage struct {
+	RuleID string     `json:"ruleID" bson:"ruleID"`
+	Reason string     `json:"reason" bson:"reason"`
+	Until  *time.Time `json:"until,omitempty" bson:"until,omitempty"`
+}
+
+// Snoozed packages are outdated again when snooze ends, even if repository is not scanned since
+func (p *Package) IsNotified(now time.Time) bool {
+	if p.IsOutdated {
+		return true
+	}
+	return p.Ignored != nil && p.Ignored.Until != nil && !now.Before(*p.Ignored.Until)
 }
 
 type Repo struct {
@@ -23,6 +40,7 @@ type Repo struct {
 	Name        string             `json:"name" bson:"name"`
 	Owner       string             `json:"owner" bson:"owner"`
 	Path        string             `json:"path" bson:"path"`
+	Ref         string             `json:"ref" bson:"ref"`
 	Provider    string             `json:"provider" bson:"provider"`
 	PackageList []*Package         `json:"packageList, omitempty" bson:"packageList,omitempty"`
 	CreatedAt   time.Time          `json:"createdAt" bson:"createdAt"`
@@ -34,6 +52,7 @@ type RepoDTO struct {
 	Name        string     `json:"name"`
 	Owner       string     `json:"owner"`
 	Path        string     `json:"path"`
+	Ref         string     `json:"ref"`
 	Provider    string     `json:"provider"`
 	PackageList []*Package `json:"packageList, omitempty"`
 }
@@ -57,6 +76,7 @@ func ToRepoDTO(repo *Repo) *RepoDTO {
 		Name:        repo.Name,
 		Owner:       repo.Owner,
 		Path:        repo.Path,
+		Ref:         repo.Ref,
 		Provider:    repo.Provider,
 		PackageList: repo.PackageList,
 	}
@@ -70,6 +90,7 @@ func ToRepo(repoDTO *RepoDTO) *Repo {
 		UserID:      userId,
 		Owner:       repoDTO.Owner,
 		Path:        repoDTO.Path,
+		Ref:         repoDTO.Ref,
 		Provider:    repoDTO.Provider,
 		PackageList: repoDTO.PackageList,
 	}
@@ -82,7 +103,7 @@ func ToRepo(repoDTO *RepoDTO) *Repo {
 	return repo
 }
 
-func ToPackageDTOs(rawPackages map[string]string, file string) []*Package {
+func ToPackageDTOs(rawPackages map[string]string, file string, path string) []*Package {
 	var packageDTOs []*Package
 
 	for key, value := range rawPackages {
@@ -94,6 +115,7 @@ func ToPackageDTOs(rawPackages map[string]string, file string) []*Package {
 				Last:    value,
 			},
 			File:       file,
+			Path:       path,
 			IsOutdated: false,
 		})
 	}
@@ -9,3 +9,5 @@ require (
 	github.com/nozgurozturk/marvin/pkg v0.0.0-20201118230847-2a78a9cfc3b0
 	go.mongodb.org/mongo-driver v1.4.3
 )
+
+replace github.com/nozgurozturk/marvin/pkg => ../pkg
@@ -88,8 +88,9 @@ func getAllAvailableSubscribers(s service.SubscriberService) []*entity.Subscribe
 func findOutdatedPackage(repoDTO *entity.RepoDTO) []*entity.Package {
 	var outdatedPackages []*entity.Package
 
+	now := time.Now().UTC()
 	for _, pkg := range repoDTO.PackageList {
-		if pkg.IsOutdated {
+		if pkg.IsNotified(now) {
 			outdatedPackages = append(outdatedPackages, pkg)
 		}
 	}
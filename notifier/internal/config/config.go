@@ -2,6 +2,7 @@ package config
 
 import (
 	"github.com/joho/godotenv"
+	"github.com/nozgurozturk/marvin/pkg/client"
 	"log"
 	"os"
 	"strconv"
@@ -12,9 +13,10 @@ var (
 )
 
 type configurations struct {
-	HTTP  *httpConfig
-	SMTP  *smtpConfig
-	Mongo *mongoConfig
+	HTTP     *httpConfig
+	SMTP     *smtpConfig
+	Mongo    *mongoConfig
+	Outbound client.TransportOptions
 }
 
 type httpConfig struct {
@@ -83,6 +85,15 @@ func Set() *configurations {
 		Query:    os.Getenv("MONGO_DB_QUERY"),
 	}
 
+	// outbound request config
+	cnf.Outbound = client.NewTransportOptions(
+		os.Getenv("OUTBOUND_PROXY"),
+		os.Getenv("OUTBOUND_NO_PROXY"),
+		os.Getenv("OUTBOUND_CA_BUNDLES"),
+		os.Getenv("OUTBOUND_CLIENT_CERTS"),
+		os.Getenv("OUTBOUND_CLIENT_KEYS"),
+	)
+
 	configs = cnf
 	return configs
 }
@@ -0,0 +1,114 @@
+/*
+ Package advisories finds security advisories of package versions in OSV database
+*/
+
+package advisories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nozgurozturk/marvin/pkg/client"
+	"strings"
+)
+
+// Severities of advisories from lowest to highest
+const (
+	Low      = "low"
+	Moderate = "moderate"
+	High     = "high"
+	Critical = "critical"
+)
+
+var severityRanks = map[string]int{
+	Low:      1,
+	Moderate: 2,
+	High:     3,
+	Critical: 4,
+}
+
+// OSV ecosystems of package files
+var ecosystems = map[string]string{
+	"package.json":  "npm",
+	"composer.json": "Packagist",
+}
+
+var apiUrl = "https://api.osv.dev"
+
+type Advisory struct {
+	ID       string
+	Summary  string
+	Severity string
+	Url      string
+}
+
+// Checks severity is known
+func IsSeverity(severity string) bool {
+	_, ok := severityRanks[severity]
+	return ok
+}
+
+// Checks severity a is higher than severity b
+func IsHigher(a string, b string) bool {
+	return severityRanks[a] > severityRanks[b]
+}
+
+// Finds advisories that affect version of package
+func Query(ctx context.Context, fileName string, name string, version string) ([]*Advisory, error) {
+
+	ecosystem, ok := ecosystems[fileName]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Undefined package file name: %s", fileName))
+	}
+
+	responseData, err := client.New(apiUrl).Post(ctx, "/v1/query", nil, map[string]interface{}{
+		"version": version,
+		"package": map[string]interface{}{
+			"name":      name,
+			"ecosystem": ecosystem,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Vulns []struct {
+			ID               string `json:"id"`
+			Summary          string `json:"summary"`
+			DatabaseSpecific struct {
+				Severity string `json:"severity"`
+			} `json:"database_specific"`
+		} `json:"vulns"`
+	}
+	if err := json.Unmarshal(responseData, &response); err != nil {
+		return nil, err
+	}
+
+	advisories := make([]*Advisory, len(response.Vulns))
+	for i, vuln := range response.Vulns {
+		advisories[i] = &Advisory{
+			ID:       vuln.ID,
+			Summary:  vuln.Summary,
+			Severity: severity(vuln.DatabaseSpecific.Severity),
+			Url:      fmt.Sprintf("https://osv.dev/vulnerability/%s", vuln.ID),
+		}
+	}
+
+	return advisories, nil
+}
+
+// Advisories without known severity are critical, so checks fail closed
+func severity(value string) string {
+	switch strings.ToLower(value) {
+	case Low:
+		return Low
+	case Moderate, "medium":
+		return Moderate
+	case High:
+		return High
+	default:
+		return Critical
+	}
+}
@@ -0,0 +1,25 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Returned when server responds with 4xx or 5xx status
+type StatusError struct {
+	Method     string
+	Url        string
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s responded with %d", e.Method, e.Url, e.StatusCode)
+}
+
+// Checks error is a response with given status
+func IsStatus(err error, statusCode int) bool {
+	statusErr, ok := err.(*StatusError)
+	return ok && statusErr.StatusCode == statusCode
+}
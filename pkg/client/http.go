@@ -1,69 +1,137 @@
-
 package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"time"
 )
 
 type HTTPClient interface {
-	Get(endpoint string, header map[string]string) ([]byte, error) // GET request
-	Post(endpoint string, header map[string]string, body map[string]interface{}) ([]byte, error) // POST request
+	Get(ctx context.Context, endpoint string, header map[string]string) ([]byte, error)                               // GET request
+	GetResponse(ctx context.Context, endpoint string, header map[string]string) (*Response, error)                    // GET request with status and headers
+	Post(ctx context.Context, endpoint string, header map[string]string, body map[string]interface{}) ([]byte, error) // POST request
+}
+
+// Response of request with status code and headers
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
 }
 
+// Options of client, zero values use defaults
+type Options struct {
+	// Transport of requests, DefaultTransport is used if it is nil
+	Transport http.RoundTripper
+	// Timeout of every attempt
+	Timeout time.Duration
+	// Retries of idempotent requests, -1 disables retries
+	MaxRetries int
+}
+
+const (
+	defaultTimeout    = 10 * time.Second
+	defaultMaxRetries = 2
+)
+
+// Shared transport keeps connections alive between clients
+// Tests can replace it with custom round tripper
+// Configure replaces it with proxy and TLS settings
+var DefaultTransport http.RoundTripper = newTransport(http.ProxyFromEnvironment, nil)
+
 type Http struct {
 	baseUrl string
+	options Options
 }
 
 // Creates custom http client for http request
 func New(baseUrl string) HTTPClient {
+	return NewWithOptions(baseUrl, Options{})
+}
+
+// Creates custom http client with transport, timeout and retry options
+func NewWithOptions(baseUrl string, options Options) HTTPClient {
+	if options.Timeout == 0 {
+		options.Timeout = defaultTimeout
+	}
+	if options.MaxRetries == 0 {
+		options.MaxRetries = defaultMaxRetries
+	}
 	return &Http{
 		baseUrl: baseUrl,
+		options: options,
 	}
 }
 
-func (c *Http) Get(endpoint string, header map[string]string) ([]byte, error) {
+func (c *Http) Get(ctx context.Context, endpoint string, header map[string]string) ([]byte, error) {
 
-	timeout := 10 * time.Second
-	client := http.Client{
-		Timeout: timeout,
+	response, err := c.GetResponse(ctx, endpoint, header)
+	if err != nil {
+		return nil, err
 	}
 
-	request, err := http.NewRequest("GET", c.baseUrl+endpoint, nil)
+	return response.Body, nil
+}
+
+// GET request, response is returned with its status code and headers
+// Responses with error status are returned as *StatusError
+func (c *Http) GetResponse(ctx context.Context, endpoint string, header map[string]string) (*Response, error) {
+	return c.do(ctx, http.MethodGet, endpoint, header, nil)
+}
+
+// POST request, it is not retried
+func (c *Http) Post(ctx context.Context, endpoint string, header map[string]string, body map[string]interface{}) ([]byte, error) {
+	requestBody, err := json.Marshal(body)
 	if err != nil {
 		return nil, err
 	}
-	for key, value := range header {
-		request.Header.Add(key, value)
-	}
 
-	response, err := client.Do(request)
+	response, err := c.do(ctx, http.MethodPost, endpoint, header, requestBody)
 	if err != nil {
 		return nil, err
 	}
 
-	defer response.Body.Close()
+	return response.Body, nil
+}
 
-	data, _ := ioutil.ReadAll(response.Body)
+// Sends request, idempotent requests are retried after network errors and server errors
+func (c *Http) do(ctx context.Context, method string, endpoint string, header map[string]string, body []byte) (*Response, error) {
 
-	return data, nil
-}
+	retries := c.options.MaxRetries
+	if !isIdempotent(method) || retries < 0 {
+		retries = 0
+	}
 
-// POST request
-func (c *Http) Post(endpoint string, header map[string]string, body map[string]interface{}) ([]byte, error) {
-	requestBody, err := json.Marshal(body)
-	if err != nil {
-		return nil, err
+	for attempt := 0; ; attempt++ {
+		response, err := c.send(ctx, method, endpoint, header, body)
+
+		if attempt >= retries || !shouldRetry(ctx, err) {
+			return response, err
+		}
+
+		select {
+		case <-time.After(backoff(attempt)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
-	timeout := 10 * time.Second
-	client := http.Client{
-		Timeout: timeout,
+}
+
+func (c *Http) send(ctx context.Context, method string, endpoint string, header map[string]string, body []byte) (*Response, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, c.options.Timeout)
+	defer cancel()
+
+	var requestBody io.Reader
+	if body != nil {
+		requestBody = bytes.NewReader(body)
 	}
 
-	request, err := http.NewRequest("POST", c.baseUrl+endpoint, bytes.NewBuffer(requestBody))
+	request, err := http.NewRequestWithContext(ctx, method, c.baseUrl+endpoint, requestBody)
 	if err != nil {
 		return nil, err
 	}
@@ -71,6 +139,15 @@ func (c *Http) Post(endpoint string, header map[string]string, body map[string]i
 		request.Header.Add(key, value)
 	}
 
+	transport := c.options.Transport
+	if transport == nil {
+		transport = DefaultTransport
+	}
+
+	client := http.Client{
+		Transport: transport,
+	}
+
 	response, err := client.Do(request)
 	if err != nil {
 		return nil, err
@@ -78,7 +155,24 @@ func (c *Http) Post(endpoint string, header map[string]string, body map[string]i
 
 	defer response.Body.Close()
 
-	data, _ := ioutil.ReadAll(response.Body)
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode >= http.StatusBadRequest {
+		return nil, &StatusError{
+			Method:     method,
+			Url:        request.URL.String(),
+			StatusCode: response.StatusCode,
+			Header:     response.Header,
+			Body:       data,
+		}
+	}
 
-	return data, nil
+	return &Response{
+		StatusCode: response.StatusCode,
+		Header:     response.Header,
+		Body:       data,
+	}, nil
 }
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatusError(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.Header().Set("X-RateLimit-Remaining", "10")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+		case "/unchanged":
+			w.WriteHeader(http.StatusNotModified)
+		default:
+			_, _ = w.Write([]byte("content"))
+		}
+	}))
+	defer server.Close()
+
+	c := New(server.URL)
+
+	_, err := c.GetResponse(context.Background(), "/missing", nil)
+	statusErr, ok := err.(*StatusError)
+	if !ok {
+		t.Fatalf("expected status error, got %v", err)
+	}
+	if statusErr.Method != http.MethodGet || statusErr.Url != server.URL+"/missing" || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("unexpected status error %+v", statusErr)
+	}
+	if statusErr.Header.Get("X-RateLimit-Remaining") != "10" || string(statusErr.Body) != `{"message":"Not Found"}` {
+		t.Errorf("expected headers and body of response, got %v %q", statusErr.Header, statusErr.Body)
+	}
+	if !IsStatus(err, http.StatusNotFound) || IsStatus(err, http.StatusInternalServerError) {
+		t.Error("expected error to be not found status")
+	}
+	if expected := "GET " + server.URL + "/missing responded with 404"; err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+
+	// Statuses under 400 are responses
+	response, err := c.GetResponse(context.Background(), "/unchanged", nil)
+	if err != nil || response.StatusCode != http.StatusNotModified {
+		t.Errorf("expected not modified response, got %v (%v)", response, err)
+	}
+
+	body, err := c.Get(context.Background(), "/file", nil)
+	if err != nil || string(body) != "content" {
+		t.Errorf("expected body, got %q (%v)", body, err)
+	}
+	if IsStatus(err, http.StatusNotFound) {
+		t.Error("expected nil error not to be status")
+	}
+}
+
+func TestServerErrorsAreRetried(t *testing.T) {
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if r.URL.Path == "/limited" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	body, err := New(server.URL).Get(context.Background(), "/file", nil)
+	if err != nil || string(body) != "content" {
+		t.Fatalf("expected body after retry, got %q (%v)", body, err)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+
+	// Rate limits are returned to caller
+	requests = 1
+	_, err = New(server.URL).Get(context.Background(), "/limited", nil)
+	if !IsStatus(err, http.StatusTooManyRequests) {
+		t.Errorf("expected rate limit status, got %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("expected rate limit not to be retried, got %d requests", requests-1)
+	}
+}
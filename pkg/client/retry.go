@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+const (
+	baseBackoff = 200 * time.Millisecond
+	maxBackoff  = 5 * time.Second
+)
+
+// Server errors that can be fixed by sending request again
+// Rate limits are not retried here, callers know how long they must wait
+var retryableStatuses = map[int]bool{
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// Checks request can be sent again, cancelled requests are never retried
+// Only timeouts and dropped connections are retried, other errors fail again with same request
+func shouldRetry(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err == nil {
+		return false
+	}
+	if statusErr, ok := err.(*StatusError); ok {
+		return retryableStatuses[statusErr.StatusCode]
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Exponential backoff with full jitter, attempts of many clients are spread
+func backoff(attempt int) time.Duration {
+	wait := baseBackoff << uint(attempt)
+	if wait > maxBackoff || wait <= 0 {
+		wait = maxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(wait)))
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// Errors are wrapped like errors of http client
+func requestError(err error) error {
+	return &url.Error{Op: "Get", URL: "https://registry.npmjs.org/react", Err: err}
+}
+
+func syscallError(errno syscall.Errno) error {
+	return requestError(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", errno)})
+}
+
+func TestShouldRetry(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"success", nil, false},
+		{"server error", &StatusError{StatusCode: http.StatusInternalServerError}, true},
+		{"unavailable", &StatusError{StatusCode: http.StatusServiceUnavailable}, true},
+		{"rate limit", &StatusError{StatusCode: http.StatusTooManyRequests}, false},
+		{"not found", &StatusError{StatusCode: http.StatusNotFound}, false},
+		{"timeout", requestError(timeoutError{}), true},
+		{"connection refused", syscallError(syscall.ECONNREFUSED), true},
+		{"connection reset", syscallError(syscall.ECONNRESET), true},
+		{"unexpected eof", requestError(io.ErrUnexpectedEOF), true},
+		{"unknown host", requestError(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "registry.corp", IsNotFound: true}}), false},
+		{"invalid certificate", requestError(errors.New("x509: certificate signed by unknown authority")), false},
+		{"unsupported scheme", requestError(errors.New("unsupported protocol scheme \"ftp\"")), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if retry := shouldRetry(context.Background(), tt.err); retry != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, retry)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if shouldRetry(ctx, syscallError(syscall.ECONNRESET)) {
+		t.Error("expected cancelled request not to be retried")
+	}
+}
+
+type roundTripFunc func(request *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+func TestRetries(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		method     string
+		maxRetries int
+		err        error
+		attempts   int
+	}{
+		{"dropped connection", http.MethodGet, 0, syscallError(syscall.ECONNRESET), defaultMaxRetries + 1},
+		{"post is not retried", http.MethodPost, 0, syscallError(syscall.ECONNRESET), 1},
+		{"retries are disabled", http.MethodGet, -1, syscallError(syscall.ECONNRESET), 1},
+		{"invalid certificate", http.MethodGet, 0, requestError(errors.New("x509: certificate signed by unknown authority")), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			c := NewWithOptions("https://registry.npmjs.org", Options{
+				MaxRetries: tt.maxRetries,
+				Transport: roundTripFunc(func(request *http.Request) (*http.Response, error) {
+					attempts++
+					return nil, tt.err
+				}),
+			}).(*Http)
+
+			if _, err := c.do(context.Background(), tt.method, "/react", nil, nil); err == nil {
+				t.Fatal("expected error")
+			}
+			if attempts != tt.attempts {
+				t.Errorf("expected %d attempts, got %d", tt.attempts, attempts)
+			}
+		})
+	}
+}
+
+func TestRetriesStopWhenContextIsDone(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	attempts := 0
+	c := NewWithOptions("https://registry.npmjs.org", Options{
+		Transport: roundTripFunc(func(request *http.Request) (*http.Response, error) {
+			attempts++
+			cancel()
+			return nil, syscallError(syscall.ECONNRESET)
+		}),
+	})
+
+	if _, err := c.Get(ctx, "/react", nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if attempts != 1 {
+		t.Errorf("expected request not to be retried after cancel, got %d attempts", attempts)
+	}
+}
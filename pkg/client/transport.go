@@ -0,0 +1,221 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"golang.org/x/net/http/httpproxy"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Any host key of CA bundles and client certificates
+const AnyHost = "*"
+
+// Outbound network settings of shared transport
+type TransportOptions struct {
+	// Proxy url of http and https requests, proxy environment variables are used if it is empty
+	Proxy string
+	// Comma separated hosts, domains and CIDRs that bypass proxy, NO_PROXY is used if it is empty
+	NoProxy string
+	// Host -> PEM file of trusted CAs, they are added to system CAs
+	CABundles map[string]string
+	// Host -> client certificate for mTLS
+	ClientCerts map[string]ClientCert
+}
+
+// PEM files of client certificate and its private key
+type ClientCert struct {
+	CertFile string
+	KeyFile  string
+}
+
+// Creates transport options from outbound settings of environment
+// CA bundles, client certificates and their keys are comma separated host=file pairs, * matches all hosts
+func NewTransportOptions(proxy string, noProxy string, caBundles string, clientCerts string, clientKeys string) TransportOptions {
+
+	keys := parseHostFiles(clientKeys)
+
+	certs := map[string]ClientCert{}
+	for host, certFile := range parseHostFiles(clientCerts) {
+		certs[host] = ClientCert{
+			CertFile: certFile,
+			KeyFile:  keys[host],
+		}
+	}
+
+	return TransportOptions{
+		Proxy:       proxy,
+		NoProxy:     noProxy,
+		CABundles:   parseHostFiles(caBundles),
+		ClientCerts: certs,
+	}
+}
+
+// Parses comma separated host=file pairs
+func parseHostFiles(value string) map[string]string {
+	files := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			continue
+		}
+		files[parts[0]] = parts[1]
+	}
+	return files
+}
+
+// Replaces DefaultTransport with proxy and TLS settings
+// Hosts with CA bundle or client certificate get their own transport
+func Configure(options TransportOptions) error {
+
+	proxy := proxyFunc(options.Proxy, options.NoProxy)
+
+	anyTLS, err := tlsConfig(options, AnyHost)
+	if err != nil {
+		return err
+	}
+
+	hosts := map[string]http.RoundTripper{}
+	for host := range options.CABundles {
+		if host == AnyHost {
+			continue
+		}
+		if hosts[host], err = hostTransport(options, host, proxy); err != nil {
+			return err
+		}
+	}
+	for host := range options.ClientCerts {
+		if host == AnyHost || hosts[host] != nil {
+			continue
+		}
+		if hosts[host], err = hostTransport(options, host, proxy); err != nil {
+			return err
+		}
+	}
+
+	DefaultTransport = &hostRouter{
+		fallback: newTransport(proxy, anyTLS),
+		hosts:    hosts,
+	}
+
+	return nil
+}
+
+// Routes requests to transport of their hosts
+type hostRouter struct {
+	fallback http.RoundTripper
+	hosts    map[string]http.RoundTripper
+}
+
+func (r *hostRouter) RoundTrip(request *http.Request) (*http.Response, error) {
+	// Host with port is more specific, for exp. registry.corp:8443
+	if transport, ok := r.hosts[request.URL.Host]; ok {
+		return transport.RoundTrip(request)
+	}
+	if transport, ok := r.hosts[request.URL.Hostname()]; ok {
+		return transport.RoundTrip(request)
+	}
+	return r.fallback.RoundTrip(request)
+}
+
+func newTransport(proxy func(*http.Request) (*url.URL, error), tlsConfig *tls.Config) *http.Transport {
+	return &http.Transport{
+		Proxy:                 proxy,
+		TLSClientConfig:       tlsConfig,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+func hostTransport(options TransportOptions, host string, proxy func(*http.Request) (*url.URL, error)) (http.RoundTripper, error) {
+	config, err := tlsConfig(options, host)
+	if err != nil {
+		return nil, err
+	}
+	return newTransport(proxy, config), nil
+}
+
+// Proxy settings override environment, empty settings keep environment values
+func proxyFunc(proxy string, noProxy string) func(*http.Request) (*url.URL, error) {
+
+	if proxy == "" && noProxy == "" {
+		return http.ProxyFromEnvironment
+	}
+
+	config := httpproxy.FromEnvironment()
+	if proxy != "" {
+		config.HTTPProxy = proxy
+		config.HTTPSProxy = proxy
+	}
+	if noProxy != "" {
+		config.NoProxy = noProxy
+	}
+
+	resolve := config.ProxyFunc()
+	return func(request *http.Request) (*url.URL, error) {
+		return resolve(request.URL)
+	}
+}
+
+// Creates TLS config of host, CA bundle of any host is trusted by every host
+// Nil config means system defaults
+func tlsConfig(options TransportOptions, host string) (*tls.Config, error) {
+
+	keys := []string{AnyHost}
+	if host != AnyHost {
+		keys = append(keys, host)
+	}
+
+	var bundles []string
+	for _, key := range keys {
+		if bundle := options.CABundles[key]; bundle != "" {
+			bundles = append(bundles, bundle)
+		}
+	}
+
+	cert, hasCert := options.ClientCerts[host]
+	if !hasCert {
+		cert, hasCert = options.ClientCerts[AnyHost]
+	}
+
+	if len(bundles) == 0 && !hasCert {
+		return nil, nil
+	}
+
+	config := &tls.Config{}
+
+	if len(bundles) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		config.RootCAs = pool
+	}
+
+	for _, bundle := range bundles {
+		pem, err := ioutil.ReadFile(bundle)
+		if err != nil {
+			return nil, fmt.Errorf("CA bundle of %s can not be read: %w", host, err)
+		}
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA bundle of %s does not contain certificates", host)
+		}
+	}
+
+	if hasCert {
+		certificate, err := tls.LoadX509KeyPair(cert.CertFile, cert.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("client certificate of %s can not be loaded: %w", host, err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewTransportOptions(t *testing.T) {
+
+	options := NewTransportOptions(
+		"http://proxy.corp:3128",
+		"localhost,.corp",
+		"*=/etc/ssl/corp.pem, registry.corp:8443=/etc/ssl/registry.pem,invalid,=/etc/ssl/empty.pem",
+		"registry.corp=/etc/ssl/client.pem,git.corp=/etc/ssl/git.pem",
+		"registry.corp=/etc/ssl/client.key",
+	)
+
+	if options.Proxy != "http://proxy.corp:3128" || options.NoProxy != "localhost,.corp" {
+		t.Errorf("unexpected proxy settings %s %s", options.Proxy, options.NoProxy)
+	}
+
+	expectedBundles := map[string]string{
+		AnyHost:              "/etc/ssl/corp.pem",
+		"registry.corp:8443": "/etc/ssl/registry.pem",
+	}
+	if !reflect.DeepEqual(options.CABundles, expectedBundles) {
+		t.Errorf("expected CA bundles %v, got %v", expectedBundles, options.CABundles)
+	}
+
+	// Certificates without key are kept, Configure fails to load them
+	expectedCerts := map[string]ClientCert{
+		"registry.corp": {CertFile: "/etc/ssl/client.pem", KeyFile: "/etc/ssl/client.key"},
+		"git.corp":      {CertFile: "/etc/ssl/git.pem"},
+	}
+	if !reflect.DeepEqual(options.ClientCerts, expectedCerts) {
+		t.Errorf("expected client certificates %v, got %v", expectedCerts, options.ClientCerts)
+	}
+}
+
+func TestNewTransportOptionsEmpty(t *testing.T) {
+
+	defaultTransport := DefaultTransport
+	defer func() { DefaultTransport = defaultTransport }()
+
+	options := NewTransportOptions("", "", "", "", "")
+
+	if len(options.CABundles) != 0 || len(options.ClientCerts) != 0 {
+		t.Errorf("expected empty options, got %+v", options)
+	}
+	if err := Configure(options); err != nil {
+		t.Errorf("expected empty options to configure default transport, got %v", err)
+	}
+}
@@ -62,4 +62,27 @@ func Forbidden(message string) *AppError {
 		Status:  http.StatusForbidden,
 		Error:   http.StatusText(http.StatusForbidden),
 	}
-}
\ No newline at end of file
+}
+func ServiceUnavailable(message string) *AppError {
+	return &AppError{
+		Message: message,
+		Status:  http.StatusServiceUnavailable,
+		Error:   http.StatusText(http.StatusServiceUnavailable),
+	}
+}
+
+func NotAcceptable(message string) *AppError {
+	return &AppError{
+		Message: message,
+		Status:  http.StatusNotAcceptable,
+		Error:   http.StatusText(http.StatusNotAcceptable),
+	}
+}
+
+func TooManyRequests(message string) *AppError {
+	return &AppError{
+		Message: message,
+		Status:  http.StatusTooManyRequests,
+		Error:   http.StatusText(http.StatusTooManyRequests),
+	}
+}
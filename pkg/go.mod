@@ -3,6 +3,6 @@ module github.com/nozgurozturk/marvin/pkg
 go 1.15
 
 require (
-	golang.org/x/net v0.0.0-20201110031124-69a78807bb2b // indirect
-	golang.org/x/tools v0.0.0-20201116182000-1d699438d2cf // indirect
+	github.com/go-git/go-git/v5 v5.1.0
+	golang.org/x/net v0.0.0-20201110031124-69a78807bb2b
 )
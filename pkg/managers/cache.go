@@ -0,0 +1,58 @@
+package managers
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Registry versions are shared by all repositories, they are kept for a while to save registry requests
+const registryCacheTTL = 10 * time.Minute
+
+type cachedVersion struct {
+	version   string
+	expiresAt time.Time
+}
+
+// package file name:registry name -> cachedVersion
+var registryCache sync.Map
+
+// Wraps manager with in memory registry version cache
+type cachedManager struct {
+	manager  Manager
+	fileName string
+}
+
+func (c *cachedManager) GetRegistryVersion(ctx context.Context, registryName string) (string, error) {
+
+	key := c.fileName + ":" + registryName
+
+	if value, ok := registryCache.Load(key); ok {
+		cached := value.(cachedVersion)
+		if time.Now().Before(cached.expiresAt) {
+			return cached.version, nil
+		}
+	}
+
+	registryVersion, err := c.manager.GetRegistryVersion(ctx, registryName)
+	if err != nil {
+		return "", err
+	}
+
+	registryCache.Store(key, cachedVersion{
+		version:   registryVersion,
+		expiresAt: time.Now().Add(registryCacheTTL),
+	})
+
+	return registryVersion, nil
+}
+
+// Changelog urls are not cached, they are only requested for updates
+func (c *cachedManager) GetChangelogUrl(ctx context.Context, registryName string) (string, error) {
+	return c.manager.GetChangelogUrl(ctx, registryName)
+}
+
+// Release infos are not cached, they are only requested for policy checks
+func (c *cachedManager) GetReleaseInfo(ctx context.Context, registryName string, version string, latest string) (*ReleaseInfo, error) {
+	return c.manager.GetReleaseInfo(ctx, registryName, version, latest)
+}
@@ -1,6 +1,7 @@
 package managers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/nozgurozturk/marvin/pkg/client"
@@ -11,7 +12,7 @@ type Composer struct {
 	apiUrl string
 }
 
-func (p *Composer) GetRegistryVersion(registryName string) (string, error) {
+func (p *Composer) GetRegistryVersion(ctx context.Context, registryName string) (string, error) {
 
 	// If registry is not contain owner pass
 	// For exp. "php": 7.0
@@ -21,7 +22,7 @@ func (p *Composer) GetRegistryVersion(registryName string) (string, error) {
 
 	endpoint := fmt.Sprintf("/packages/%s.json", registryName)
 
-	registryData, err := client.New(p.apiUrl).Get(endpoint, nil)
+	registryData, err := client.New(p.apiUrl).Get(ctx, endpoint, nil)
 	if err != nil {
 		return "", err
 	}
@@ -68,3 +69,79 @@ func (p *Composer) GetRegistryVersion(registryName string) (string, error) {
 	return registryVersion, nil
 
 }
+
+// Gets releases page from repository field of registry
+func (p *Composer) GetChangelogUrl(ctx context.Context, registryName string) (string, error) {
+
+	registryPage := fmt.Sprintf("https://packagist.org/packages/%s", registryName)
+
+	if !strings.Contains(registryName, "/") {
+		return registryPage, nil
+	}
+
+	endpoint := fmt.Sprintf("/packages/%s.json", registryName)
+
+	registryData, err := client.New(p.apiUrl).Get(ctx, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	var registry struct {
+		Package struct {
+			Repository string `json:"repository"`
+		} `json:"package"`
+	}
+	if err := json.Unmarshal(registryData, &registry); err != nil {
+		return "", err
+	}
+
+	return changelogUrl(registry.Package.Repository, registryPage), nil
+}
+
+// Gets abandoned field and release dates of versions from registry, abandoned packages are deprecated
+func (p *Composer) GetReleaseInfo(ctx context.Context, registryName string, version string, latest string) (*ReleaseInfo, error) {
+
+	// Platform packages are not in registry, for exp. "php": 7.0
+	if !strings.Contains(registryName, "/") {
+		return &ReleaseInfo{}, nil
+	}
+
+	endpoint := fmt.Sprintf("/packages/%s.json", registryName)
+
+	registryData, err := client.New(p.apiUrl).Get(ctx, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	var registry struct {
+		Package struct {
+			Abandoned interface{} `json:"abandoned"`
+			Versions  map[string]struct {
+				Time string `json:"time"`
+			} `json:"versions"`
+		} `json:"package"`
+	}
+	if err := json.Unmarshal(registryData, &registry); err != nil {
+		return nil, err
+	}
+
+	times := make(map[string]string, len(registry.Package.Versions))
+	for key, value := range registry.Package.Versions {
+		times[key] = value.Time
+	}
+
+	info := &ReleaseInfo{
+		ReleasedAt:       releaseTime(times, version),
+		LatestReleasedAt: releaseTime(times, latest),
+	}
+
+	// Abandoned is true or name of replacement package
+	switch abandoned := registry.Package.Abandoned.(type) {
+	case bool:
+		if abandoned {
+			info.Deprecated = "Package is abandoned"
+		}
+	case string:
+		info.Deprecated = fmt.Sprintf("Package is abandoned, use %s instead", abandoned)
+	}
+
+	return info, nil
+}
@@ -5,8 +5,13 @@
 package managers
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"github.com/nozgurozturk/marvin/pkg/utils"
+	"net/url"
+	"strings"
+	"time"
 )
 
 const (
@@ -15,21 +20,75 @@ const (
 )
 
 type Manager interface {
-	GetRegistryVersion(registryName string) (string, error) // Gets registry's version
+	GetRegistryVersion(ctx context.Context, registryName string) (string, error)                                  // Gets registry's version
+	GetChangelogUrl(ctx context.Context, registryName string) (string, error)                                     // Gets releases page of registry's source repository
+	GetReleaseInfo(ctx context.Context, registryName string, version string, latest string) (*ReleaseInfo, error) // Gets deprecation and release dates of versions
+}
+
+// Registry details of installed version, zero times are unknown
+type ReleaseInfo struct {
+	// Deprecation message of version or package, it is empty if it is not deprecated
+	Deprecated       string
+	ReleasedAt       time.Time
+	LatestReleasedAt time.Time
 }
 
 // Creates new manager with given file name
+// Registry versions of managers are cached
 func NewManager(fileName string) (Manager, error) {
 	switch fileName {
 	case npm:
 		p := new(Npm)
 		p.apiUrl = "https://registry.npmjs.org"
-		return p, nil
+		return &cachedManager{manager: p, fileName: fileName}, nil
 	case composer:
 		p := new(Composer)
 		p.apiUrl = "https://packagist.org"
-		return p, nil
+		return &cachedManager{manager: p, fileName: fileName}, nil
 	default:
 		return nil, errors.New(fmt.Sprintf("Undefined package file name: %s", fileName))
 	}
 }
+
+// Converts source repository of registry to its releases page
+// Repositories that are not hosted in github or gitlab fall back to registry page
+// For exp. git+https://github.com/owner/name.git -> https://github.com/owner/name/releases
+func changelogUrl(repository string, registryPage string) string {
+
+	repository = strings.TrimPrefix(repository, "git+")
+	repository = strings.Replace(repository, "git://", "https://", 1)
+	repository = strings.Replace(repository, "ssh://git@", "https://", 1)
+
+	// npm shorthand, for exp. github:owner/name
+	if strings.HasPrefix(repository, "github:") {
+		repository = "https://github.com/" + strings.TrimPrefix(repository, "github:")
+	}
+
+	u, err := url.Parse(repository)
+	if err != nil || (u.Host != "github.com" && u.Host != "gitlab.com") {
+		return registryPage
+	}
+
+	return fmt.Sprintf("https://%s%s/releases", u.Host, strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), ".git"))
+}
+
+// Finds release time of version, versions with same numbers are used if version is not released exactly
+// For exp. 1.2 -> 1.2.0, v2.0.0 -> 2.0.0, pre-releases and dev versions are skipped
+func releaseTime(times map[string]string, version string) time.Time {
+
+	released, ok := times[version]
+	if !ok {
+		for key, value := range times {
+			if strings.ContainsAny(key, "-+") || strings.Contains(key, "dev") {
+				continue
+			}
+			if utils.CompareVersionNumbers(key, version) == 0 {
+				released = value
+				break
+			}
+		}
+	}
+
+	releasedAt, _ := time.Parse(time.RFC3339, released)
+	return releasedAt
+}
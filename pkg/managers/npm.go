@@ -1,6 +1,7 @@
 package managers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/nozgurozturk/marvin/pkg/client"
@@ -10,10 +11,10 @@ type Npm struct {
 	apiUrl string
 }
 
-func (n *Npm) GetRegistryVersion(registryName string) (string, error) {
+func (n *Npm) GetRegistryVersion(ctx context.Context, registryName string) (string, error) {
 
 	endpoint := fmt.Sprintf("/%s", registryName)
-	registryData, err := client.New(n.apiUrl).Get(endpoint, nil)
+	registryData, err := client.New(n.apiUrl).Get(ctx, endpoint, nil)
 	if err != nil {
 		return "", err
 	}
@@ -25,3 +26,61 @@ func (n *Npm) GetRegistryVersion(registryName string) (string, error) {
 	return registryVersion, nil
 }
 
+
+// Gets releases page from repository field of registry
+func (n *Npm) GetChangelogUrl(ctx context.Context, registryName string) (string, error) {
+
+	endpoint := fmt.Sprintf("/%s", registryName)
+	registryData, err := client.New(n.apiUrl).Get(ctx, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	var registry struct {
+		Repository interface{} `json:"repository"`
+	}
+	if err := json.Unmarshal(registryData, &registry); err != nil {
+		return "", err
+	}
+
+	// Repository can be url or object with url
+	var repository string
+	switch r := registry.Repository.(type) {
+	case string:
+		repository = r
+	case map[string]interface{}:
+		repository, _ = r["url"].(string)
+	}
+
+	return changelogUrl(repository, fmt.Sprintf("https://www.npmjs.com/package/%s", registryName)), nil
+}
+
+// Gets deprecation message of version from versions field and release dates from time field of registry
+func (n *Npm) GetReleaseInfo(ctx context.Context, registryName string, version string, latest string) (*ReleaseInfo, error) {
+
+	endpoint := fmt.Sprintf("/%s", registryName)
+	registryData, err := client.New(n.apiUrl).Get(ctx, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	var registry struct {
+		Time     map[string]string `json:"time"`
+		Versions map[string]struct {
+			Deprecated interface{} `json:"deprecated"`
+		} `json:"versions"`
+	}
+	if err := json.Unmarshal(registryData, &registry); err != nil {
+		return nil, err
+	}
+
+	info := &ReleaseInfo{
+		ReleasedAt:       releaseTime(registry.Time, version),
+		LatestReleasedAt: releaseTime(registry.Time, latest),
+	}
+
+	// Deprecated is a message, some old registries have false
+	if deprecated, ok := registry.Versions[version].Deprecated.(string); ok {
+		info.Deprecated = deprecated
+	}
+
+	return info, nil
+}
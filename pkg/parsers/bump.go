@@ -0,0 +1,139 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Top level objects of package files that contain package versions
+var dependencySections = map[string][]string{
+	npm:      {"dependencies", "devDependencies", "peerDependencies", "optionalDependencies"},
+	composer: {"require", "require-dev"},
+}
+
+// Version of package in package file with its byte offsets
+type versionSpan struct {
+	start   int
+	end     int
+	version string
+}
+
+// Rewrites versions of packages in package file
+// Only version strings are replaced, so indentation, key order and other fields are kept as they are
+// versions: package name -> new version constraint
+func Bump(fileName string, data []byte, versions map[string]string) ([]byte, error) {
+
+	sections, ok := dependencySections[fileName]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Undefined package file: %s", fileName))
+	}
+
+	isSection := map[string]bool{}
+	for _, section := range sections {
+		isSection[section] = true
+	}
+
+	type frame struct {
+		object    bool
+		section   bool
+		expectKey bool
+		key       string
+	}
+
+	var stack []*frame
+	var spans []versionSpan
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	for {
+		start := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var top *frame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+
+		if delim, ok := token.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				// Dependency sections are objects in root object
+				section := len(stack) == 1 && top.object && isSection[top.key] && delim == '{'
+				if top != nil && top.object {
+					top.expectKey = true
+				}
+				stack = append(stack, &frame{object: delim == '{', section: section, expectKey: delim == '{'})
+			case '}', ']':
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+
+		if top == nil || !top.object {
+			continue
+		}
+
+		if top.expectKey {
+			top.key, _ = token.(string)
+			top.expectKey = false
+			continue
+		}
+
+		top.expectKey = true
+
+		if _, isString := token.(string); !isString || !top.section {
+			continue
+		}
+
+		version, ok := versions[top.key]
+		if !ok {
+			continue
+		}
+
+		// Raw value starts after colon and whitespaces
+		end := int(decoder.InputOffset())
+		quote := bytes.IndexByte(data[start:end], '"')
+		if quote < 0 {
+			continue
+		}
+
+		spans = append(spans, versionSpan{start: start + quote, end: end, version: version})
+	}
+
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].start > spans[j].start
+	})
+
+	bumped := append([]byte(nil), data...)
+	for _, span := range spans {
+		value, err := quoteVersion(span.version)
+		if err != nil {
+			return nil, err
+		}
+		bumped = append(bumped[:span.start], append(value, bumped[span.end:]...)...)
+	}
+
+	return bumped, nil
+}
+
+// Quotes version without escaping range operators like >=
+func quoteVersion(version string) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(version); err != nil {
+		return nil, err
+	}
+	return []byte(strings.TrimSuffix(buffer.String(), "\n")), nil
+}
@@ -0,0 +1,100 @@
+package parsers
+
+import (
+	"github.com/nozgurozturk/marvin/pkg/utils"
+	"testing"
+)
+
+func TestBump(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		fileName string
+		data     string
+		// package name -> latest version
+		latest   map[string]string
+		expected string
+	}{
+		{
+			name:     "caret",
+			fileName: "package.json",
+			data:     `{"dependencies": {"react": "^16.8.0"}}`,
+			latest:   map[string]string{"react": "17.0.1"},
+			expected: `{"dependencies": {"react": "^17.0.1"}}`,
+		},
+		{
+			name:     "tilde with minor precision",
+			fileName: "package.json",
+			data:     `{"devDependencies": {"jest": "~25.1"}}`,
+			latest:   map[string]string{"jest": "26.6.3"},
+			expected: `{"devDependencies": {"jest": "~26.6"}}`,
+		},
+		{
+			name:     "greater or equal",
+			fileName: "package.json",
+			data:     `{"peerDependencies": {"react-dom": ">=16.8.0"}}`,
+			latest:   map[string]string{"react-dom": "17.0.1"},
+			expected: `{"peerDependencies": {"react-dom": ">=17.0.1"}}`,
+		},
+		{
+			name:     "whitespace and key order",
+			fileName: "package.json",
+			data:     "{\n    \"name\":\"app\",\n    \"dependencies\" :  {\n\t\"lodash\"  :   \"^4.17.0\",\n\t\"axios\":\"0.19.0\"\n    }\n}\n",
+			latest:   map[string]string{"lodash": "4.17.20", "axios": "0.21.0"},
+			expected: "{\n    \"name\":\"app\",\n    \"dependencies\" :  {\n\t\"lodash\"  :   \"^4.17.20\",\n\t\"axios\":\"0.21.0\"\n    }\n}\n",
+		},
+		{
+			name:     "fields outside of dependency sections",
+			fileName: "package.json",
+			data:     `{"version": "1.0.0", "config": {"react": "^16.8.0"}, "dependencies": {"react": "^16.8.0"}}`,
+			latest:   map[string]string{"react": "17.0.1", "version": "2.0.0"},
+			expected: `{"version": "1.0.0", "config": {"react": "^16.8.0"}, "dependencies": {"react": "^17.0.1"}}`,
+		},
+		{
+			name:     "composer",
+			fileName: "composer.json",
+			data:     "{\n  \"require\": {\n    \"php\": \">=7.2\",\n    \"laravel/framework\": \"^7.0\"\n  }\n}",
+			latest:   map[string]string{"laravel/framework": "8.12.3"},
+			expected: "{\n  \"require\": {\n    \"php\": \">=7.2\",\n    \"laravel/framework\": \"^8.12\"\n  }\n}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			file, err := DecodePackageFile(tt.fileName, []byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// New constraints are created from current constraints in dependency sections like pull requests do
+			bumps := map[string]string{}
+			for _, section := range dependencySections[tt.fileName] {
+				dependencies, _ := file[section].(map[string]interface{})
+				for name, current := range dependencies {
+					latest, ok := tt.latest[name]
+					if !ok {
+						continue
+					}
+					if bumped, ok := utils.BumpVersion(current.(string), latest); ok {
+						bumps[name] = bumped
+					}
+				}
+			}
+
+			bumped, err := Bump(tt.fileName, []byte(tt.data), bumps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(bumped) != tt.expected {
+				t.Errorf("expected\n%s\ngot\n%s", tt.expected, bumped)
+			}
+		})
+	}
+}
+
+func TestBumpUndefinedFile(t *testing.T) {
+	if _, err := Bump("Gemfile", []byte(""), nil); err == nil {
+		t.Error("expected error of undefined package file")
+	}
+}
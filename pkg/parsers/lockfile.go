@@ -0,0 +1,93 @@
+package parsers
+
+import (
+	"strings"
+)
+
+// JSON lock files, yarn and pnpm lock files are only used to detect changes
+const (
+	NpmLock        = "package-lock.json"
+	NpmShrinkwrap  = "npm-shrinkwrap.json"
+	ComposerLock   = "composer.lock"
+	nodeModulesDir = "node_modules/"
+)
+
+// Installed version of package and its license if lock file declares it
+type LockedPackage struct {
+	Version string
+	License string
+}
+
+// Checks file is a lock file that pins installed versions
+func IsLockFile(fileName string) bool {
+	return lockFiles[fileName]
+}
+
+// Gets package file that is pinned by lock file
+func LockedPackageFile(lockFileName string) string {
+	switch lockFileName {
+	case NpmLock, NpmShrinkwrap:
+		return npm
+	case ComposerLock:
+		return composer
+	default:
+		return ""
+	}
+}
+
+// Gets installed packages in lock file with their names
+// npm v2, v3 -> "packages": {"node_modules/name": {"version": "1.0.0", "license": "MIT"}}
+// npm v1     -> "dependencies": {"name": {"version": "1.0.0"}}
+// composer   -> "packages": [{"name": "vendor/name", "version": "1.0.0", "license": ["MIT"]}]
+func ParseLockFile(fileName string, file map[string]interface{}) map[string]*LockedPackage {
+
+	packages := map[string]*LockedPackage{}
+
+	switch fileName {
+	case NpmLock, NpmShrinkwrap:
+		if installed, ok := file["packages"].(map[string]interface{}); ok {
+			for key, value := range installed {
+				// Nested installs are dependencies of other packages
+				name := strings.TrimPrefix(key, nodeModulesDir)
+				if name == key || strings.Contains(name, "/"+nodeModulesDir) {
+					continue
+				}
+				if item, ok := value.(map[string]interface{}); ok {
+					packages[name] = &LockedPackage{
+						Version: toString(item["version"]),
+						License: toString(item["license"]),
+					}
+				}
+			}
+			return packages
+		}
+
+		dependencies, _ := file["dependencies"].(map[string]interface{})
+		for name, value := range dependencies {
+			if item, ok := value.(map[string]interface{}); ok {
+				packages[name] = &LockedPackage{Version: toString(item["version"])}
+			}
+		}
+	case ComposerLock:
+		for _, key := range []string{"packages", "packages-dev"} {
+			installed, _ := file[key].([]interface{})
+			for _, value := range installed {
+				item, ok := value.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				packages[toString(item["name"])] = &LockedPackage{
+					Version: strings.TrimPrefix(toString(item["version"]), "v"),
+					License: strings.Join(toStrings(item["license"]), " OR "),
+				}
+			}
+		}
+	}
+
+	return packages
+}
+
+func toString(value interface{}) string {
+	s, _ := value.(string)
+	return s
+}
@@ -4,8 +4,10 @@
 package parsers
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"path"
 )
 
 const (
@@ -13,6 +15,15 @@ const (
 	composer = "composer.json"
 )
 
+// Lock files that pin installed versions of package files
+var lockFiles = map[string]bool{
+	NpmLock:          true,
+	NpmShrinkwrap:    true,
+	"yarn.lock":      true,
+	"pnpm-lock.yaml": true,
+	ComposerLock:     true,
+}
+
 type Parser interface {
 	Parse(file map[string]interface{}) map[string]string // Parses package file
 }
@@ -28,3 +39,38 @@ func NewParser(packageFileName string) (Parser, error) {
 		return nil, errors.New(fmt.Sprintf("Undefined package file: %s", packageFileName))
 	}
 }
+
+// Checks path is a package file, workspace descriptor or lock file
+// Changes in other files do not affect packages of repository
+func IsManifestPath(filePath string) bool {
+	fileName := path.Base(filePath)
+	switch fileName {
+	case npm, composer, Lerna, PnpmWorkspace:
+		return true
+	default:
+		return lockFiles[fileName]
+	}
+}
+
+// Checks file can be parsed, lock files of yarn and pnpm are not parsed
+func IsSupportedFile(fileName string) bool {
+	switch fileName {
+	case npm, composer, Lerna, PnpmWorkspace, NpmLock, NpmShrinkwrap, ComposerLock:
+		return true
+	default:
+		return false
+	}
+}
+
+// Decodes content of package file, workspace descriptor or lock file with matching file name
+func DecodePackageFile(fileName string, data []byte) (map[string]interface{}, error) {
+	if fileName == PnpmWorkspace {
+		return ParsePnpmWorkspace(data), nil
+	}
+
+	var packageFile map[string]interface{}
+	if err := json.Unmarshal(data, &packageFile); err != nil {
+		return nil, err
+	}
+	return packageFile, nil
+}
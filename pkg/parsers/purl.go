@@ -0,0 +1,84 @@
+package parsers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Package url types of package files, https://github.com/package-url/purl-spec
+var purlTypes = map[string]string{
+	npm:      "npm",
+	composer: "composer",
+}
+
+// Creates package url of package, version is omitted if it is empty
+// @scope/name -> pkg:npm/%40scope/name@1.0.0, vendor/name -> pkg:composer/vendor/name@1.0.0
+func Purl(packageFileName string, name string, version string) string {
+
+	purlType, ok := purlTypes[packageFileName]
+	if !ok {
+		return ""
+	}
+
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		segments[i] = purlEscape(segment)
+	}
+
+	purl := fmt.Sprintf("pkg:%s/%s", purlType, strings.Join(segments, "/"))
+	if version != "" {
+		purl += "@" + purlEscape(version)
+	}
+
+	return purl
+}
+
+// Parses package url to package file name of its ecosystem, package name and version
+func ParsePurl(purl string) (packageFileName string, name string, version string, err error) {
+
+	if !strings.HasPrefix(purl, "pkg:") {
+		return "", "", "", errors.New(fmt.Sprintf("Invalid package url: %s", purl))
+	}
+
+	// Qualifiers and subpath do not change package
+	rest := strings.TrimPrefix(purl, "pkg:")
+	if i := strings.IndexAny(rest, "?#"); i >= 0 {
+		rest = rest[:i]
+	}
+
+	if i := strings.LastIndex(rest, "@"); i > strings.LastIndex(rest, "/") {
+		if version, err = url.PathUnescape(rest[i+1:]); err != nil {
+			return "", "", "", err
+		}
+		rest = rest[:i]
+	}
+
+	segments := strings.Split(strings.Trim(rest, "/"), "/")
+	if len(segments) < 2 {
+		return "", "", "", errors.New(fmt.Sprintf("Invalid package url: %s", purl))
+	}
+
+	for fileName, purlType := range purlTypes {
+		if strings.ToLower(segments[0]) == purlType {
+			packageFileName = fileName
+		}
+	}
+	if packageFileName == "" {
+		return "", "", "", errors.New(fmt.Sprintf("Unsupported package url type: %s", segments[0]))
+	}
+
+	for i, segment := range segments[1:] {
+		if segments[i+1], err = url.PathUnescape(segment); err != nil {
+			return "", "", "", err
+		}
+	}
+
+	return packageFileName, strings.Join(segments[1:], "/"), version, nil
+}
+
+// @ separates version, so it is escaped in names
+func purlEscape(s string) string {
+	return strings.ReplaceAll(url.PathEscape(s), "@", "%40")
+}
@@ -0,0 +1,102 @@
+package parsers
+
+import (
+	"errors"
+)
+
+// Gets packages of CycloneDX or SPDX JSON document with their versions
+// Packages are grouped by package file name of their ecosystem, components of other ecosystems and components without versions are returned as unsupported
+func ParseSBOM(file map[string]interface{}) (map[string]map[string]string, []string, error) {
+
+	packages := map[string]map[string]string{}
+	var unsupported []string
+
+	add := func(purl string, name string, version string) {
+		fileName, pkgName, pkgVersion, err := ParsePurl(purl)
+		if err != nil {
+			if purl != "" {
+				name = purl
+			}
+			unsupported = append(unsupported, name)
+			return
+		}
+		if pkgVersion == "" {
+			pkgVersion = version
+		}
+		// Packages without versions can not be compared
+		if pkgVersion == "" {
+			unsupported = append(unsupported, purl)
+			return
+		}
+		if packages[fileName] == nil {
+			packages[fileName] = map[string]string{}
+		}
+		packages[fileName][pkgName] = pkgVersion
+	}
+
+	switch {
+	case file["bomFormat"] == "CycloneDX":
+		components, _ := file["components"].([]interface{})
+		walkComponents(components, func(component map[string]interface{}) {
+			add(toString(component["purl"]), toString(component["name"]), toString(component["version"]))
+		})
+	case file["spdxVersion"] != nil:
+		described := describedElements(file)
+		items, _ := file["packages"].([]interface{})
+		for _, value := range items {
+			item, ok := value.(map[string]interface{})
+			if !ok || described[toString(item["SPDXID"])] {
+				continue
+			}
+			add(spdxPurl(item), toString(item["name"]), toString(item["versionInfo"]))
+		}
+	default:
+		return nil, nil, errors.New("SBOM must be a CycloneDX or SPDX JSON document")
+	}
+
+	return packages, unsupported, nil
+}
+
+// Visits components and their nested components
+func walkComponents(components []interface{}, visit func(component map[string]interface{})) {
+	for _, value := range components {
+		component, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		visit(component)
+		nested, _ := component["components"].([]interface{})
+		walkComponents(nested, visit)
+	}
+}
+
+// Gets packages that document describes, they are scanned project instead of dependencies
+func describedElements(file map[string]interface{}) map[string]bool {
+
+	described := map[string]bool{}
+
+	for _, id := range toStrings(file["documentDescribes"]) {
+		described[id] = true
+	}
+
+	relationships, _ := file["relationships"].([]interface{})
+	for _, value := range relationships {
+		relationship, ok := value.(map[string]interface{})
+		if ok && relationship["relationshipType"] == "DESCRIBES" {
+			described[toString(relationship["relatedSpdxElement"])] = true
+		}
+	}
+
+	return described
+}
+
+func spdxPurl(item map[string]interface{}) string {
+	refs, _ := item["externalRefs"].([]interface{})
+	for _, value := range refs {
+		ref, ok := value.(map[string]interface{})
+		if ok && ref["referenceType"] == "purl" {
+			return toString(ref["referenceLocator"])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+package parsers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseSBOM(t *testing.T) {
+
+	tests := []struct {
+		name string
+		file string
+		// package file name -> package name -> version
+		packages    map[string]map[string]string
+		unsupported []string
+	}{
+		{
+			// Nested components are dependencies too, metadata component is scanned project
+			name: "cyclonedx",
+			file: "cyclonedx.json",
+			packages: map[string]map[string]string{
+				"package.json":  {"react": "17.0.1", "@babel/core": "7.12.3", "json5": "2.1.3"},
+				"composer.json": {"symfony/console": "v5.2.1"},
+			},
+			unsupported: []string{"pkg:golang/github.com/gin-gonic/gin@v1.6.3", "pkg:npm/left-pad", "vendor.js"},
+		},
+		{
+			// Packages in documentDescribes and DESCRIBES relationships are scanned projects
+			name: "spdx",
+			file: "spdx.json",
+			packages: map[string]map[string]string{
+				"package.json":  {"react": "17.0.1"},
+				"composer.json": {"monolog/monolog": "2.2.0"},
+			},
+			unsupported: []string{"pkg:pypi/requests@2.25.1", "lodash"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ioutil.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var file map[string]interface{}
+			if err := json.Unmarshal(data, &file); err != nil {
+				t.Fatal(err)
+			}
+
+			packages, unsupported, err := ParseSBOM(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(packages, tt.packages) {
+				t.Errorf("expected packages %v, got %v", tt.packages, packages)
+			}
+			if !reflect.DeepEqual(unsupported, tt.unsupported) {
+				t.Errorf("expected unsupported %v, got %v", tt.unsupported, unsupported)
+			}
+		})
+	}
+}
+
+func TestParseSBOMOfUnknownFormat(t *testing.T) {
+
+	if _, _, err := ParseSBOM(map[string]interface{}{"dependencies": map[string]interface{}{"react": "^17.0.1"}}); err == nil {
+		t.Error("expected error of package file that is not an SBOM")
+	}
+}
@@ -0,0 +1,174 @@
+package parsers
+
+import (
+	"path"
+	"strings"
+)
+
+// Workspace descriptor files, they are not package files but declare workspace globs
+const (
+	Lerna         = "lerna.json"
+	PnpmWorkspace = "pnpm-workspace.yaml"
+)
+
+// Checks file is only declares workspaces
+func IsWorkspaceDescriptor(fileName string) bool {
+	return fileName == Lerna || fileName == PnpmWorkspace
+}
+
+// Gets workspace globs that are declared in package file
+// npm, yarn  -> "workspaces": ["packages/*"] or "workspaces": {"packages": ["packages/*"]}
+// lerna, pnpm -> "packages": ["packages/*"]
+// composer   -> "repositories": [{"type": "path", "url": "packages/*"}]
+func WorkspaceGlobs(fileName string, file map[string]interface{}) []string {
+	switch fileName {
+	case npm:
+		if workspaces, ok := file["workspaces"].(map[string]interface{}); ok {
+			return toStrings(workspaces["packages"])
+		}
+		return toStrings(file["workspaces"])
+	case Lerna, PnpmWorkspace:
+		return toStrings(file["packages"])
+	case composer:
+		var globs []string
+		repositories, _ := file["repositories"].([]interface{})
+		for _, r := range repositories {
+			repository, ok := r.(map[string]interface{})
+			if !ok || repository["type"] != "path" {
+				continue
+			}
+			if u, ok := repository["url"].(string); ok {
+				globs = append(globs, u)
+			}
+		}
+		return globs
+	default:
+		return nil
+	}
+}
+
+// Removes nested package files that are not member of workspaces which are declared in root directory
+// If root directory does not declare any workspace for package file, all of them are kept
+// Workspace descriptor files are removed from result
+func FilterWorkspaces(files map[string]interface{}) map[string]interface{} {
+
+	// package file name -> workspace globs
+	globs := map[string][]string{}
+
+	for filePath, file := range files {
+		if path.Dir(filePath) != "." {
+			continue
+		}
+		content, ok := file.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		fileName := path.Base(filePath)
+		manager := fileName
+		// lerna and pnpm workspaces contain npm packages
+		if IsWorkspaceDescriptor(fileName) {
+			manager = npm
+		}
+		globs[manager] = append(globs[manager], WorkspaceGlobs(fileName, content)...)
+	}
+
+	filtered := map[string]interface{}{}
+
+	for filePath, file := range files {
+		fileName := path.Base(filePath)
+		if IsWorkspaceDescriptor(fileName) {
+			continue
+		}
+
+		dir := path.Dir(filePath)
+		patterns := globs[fileName]
+		if dir == "." || len(patterns) == 0 || matchWorkspace(patterns, dir) {
+			filtered[filePath] = file
+		}
+	}
+
+	return filtered
+}
+
+// Checks directory is matched by workspace globs, globs starting with ! exclude directory
+func matchWorkspace(patterns []string, dir string) bool {
+	matched := false
+	for _, pattern := range patterns {
+		exclude := strings.HasPrefix(pattern, "!")
+		pattern = strings.Trim(strings.TrimPrefix(strings.TrimPrefix(pattern, "!"), "./"), "/")
+
+		if matchGlob(strings.Split(pattern, "/"), strings.Split(dir, "/")) {
+			matched = !exclude
+		}
+	}
+	return matched
+}
+
+// Matches path segments with glob segments, ** matches zero or more segments
+func matchGlob(pattern []string, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchGlob(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(segments) == 0 {
+		return false
+	}
+
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+
+	return matchGlob(pattern[1:], segments[1:])
+}
+
+// Parses packages list of pnpm-workspace.yaml
+// packages:
+//   - 'packages/*'
+//   - '!**/test/**'
+func ParsePnpmWorkspace(data []byte) map[string]interface{} {
+
+	var packages []interface{}
+	inPackages := false
+
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		// Top level key
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") && !strings.HasPrefix(trimmed, "-") {
+			inPackages = trimmed == "packages:"
+			continue
+		}
+
+		if inPackages && strings.HasPrefix(trimmed, "-") {
+			item := strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))
+			packages = append(packages, strings.Trim(item, `"'`))
+		}
+	}
+
+	return map[string]interface{}{
+		"packages": packages,
+	}
+}
+
+func toStrings(value interface{}) []string {
+	var result []string
+	list, _ := value.([]interface{})
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
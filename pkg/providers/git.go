@@ -0,0 +1,375 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gitclient "github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/nozgurozturk/marvin/pkg/client"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Git reads package files from git objects without hosting APIs
+// Accepts file, ssh and http(s) clone urls, ref can be given as fragment
+// For exp. file:///srv/git/app.git#release-2.x
+type Git struct {
+	url        *url.URL
+	token      string
+	maxSize    int64
+	repository *git.Repository
+}
+
+// Returned when clone url is not allowed by git options
+var ErrGitNotAllowed = errors.New("git clone url is not allowed")
+
+// Returned when cloned objects exceed max size
+var ErrCloneTooLarge = errors.New("repository is too large to clone")
+
+// Clones are kept in memory, objects over this size are not read
+const defaultMaxCloneSize = 100 << 20
+
+// Git provider can read disk of server and reach internal hosts, so it is disabled by default
+type GitOptions struct {
+	Enabled bool
+	// Directories that file urls can point into
+	LocalRoots []string
+	// Hosts that ssh, git and http(s) clone urls can point to
+	Hosts []string
+	// Max size of cloned objects in bytes, zero uses default size
+	MaxSize int64
+}
+
+// Checks clone url is enabled and points into allowed directories or hosts
+func (o GitOptions) allows(u *url.URL) error {
+
+	if !o.Enabled {
+		return ErrGitNotAllowed
+	}
+
+	if u.Scheme == "file" {
+		if (u.Host == "" || u.Host == "localhost") && isInLocalRoots(u.Path, o.LocalRoots) {
+			return nil
+		}
+		return fmt.Errorf("%w: %s is not in allowed directories", ErrGitNotAllowed, u.Path)
+	}
+
+	for _, host := range o.Hosts {
+		if strings.EqualFold(host, u.Hostname()) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: host %s is not allowed", ErrGitNotAllowed, u.Hostname())
+}
+
+func (o GitOptions) maxSize() int64 {
+	if o.MaxSize <= 0 {
+		return defaultMaxCloneSize
+	}
+	return o.MaxSize
+}
+
+// Symlinks are resolved, so links in allowed directories can not point outside of them
+func isInLocalRoots(localPath string, roots []string) bool {
+
+	resolved, err := filepath.EvalSymlinks(filepath.Clean(localPath))
+	if err != nil {
+		return false
+	}
+
+	for _, root := range roots {
+		resolvedRoot, err := filepath.EvalSymlinks(filepath.Clean(root))
+		if err != nil {
+			continue
+		}
+		if resolved == resolvedRoot || strings.HasPrefix(resolved, resolvedRoot+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Memory storage that fails when stored objects exceed max size
+type limitedStorage struct {
+	*memory.Storage
+	size    int64
+	maxSize int64
+}
+
+func newLimitedStorage(maxSize int64) *limitedStorage {
+	return &limitedStorage{Storage: memory.NewStorage(), maxSize: maxSize}
+}
+
+func (s *limitedStorage) SetEncodedObject(object plumbing.EncodedObject) (plumbing.Hash, error) {
+	s.size += object.Size()
+	if s.size > s.maxSize {
+		return plumbing.ZeroHash, ErrCloneTooLarge
+	}
+	return s.Storage.SetEncodedObject(object)
+}
+
+// Http(s) clones use shared transport, proxy and TLS settings of client are applied
+func init() {
+	httpClient := githttp.NewClient(&http.Client{Transport: sharedTransport{}})
+	gitclient.InstallProtocol("http", httpClient)
+	gitclient.InstallProtocol("https", httpClient)
+}
+
+// Resolves transport on every request, client.Configure can replace it after init
+type sharedTransport struct{}
+
+func (sharedTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	return client.DefaultTransport.RoundTrip(request)
+}
+
+// Resolves owner, name and ref from clone url
+// Owner is parent directory of repository
+func (g *Git) UrlResolver() (string, string, string, error) {
+	p := strings.TrimSuffix(g.url.Path, "/")
+
+	owner := path.Base(path.Dir(p))
+	name := strings.TrimSuffix(path.Base(p), ".git")
+	if name == "" || name == "." || name == "/" {
+		return "", "", "", ErrInvalidUrl
+	}
+
+	return owner, name, g.url.Fragment, nil
+}
+
+// Opens local repository or clones remote repository into memory
+func (g *Git) open(ctx context.Context, ref string) (*git.Repository, error) {
+
+	if g.url.Scheme == "file" {
+		return git.PlainOpen(g.url.Path)
+	}
+
+	cloneUrl := *g.url
+	cloneUrl.Fragment = ""
+
+	// Only commit of ref is fetched, history and other refs are not needed to read files
+	options := &git.CloneOptions{
+		URL:          cloneUrl.String(),
+		Depth:        1,
+		SingleBranch: true,
+		Tags:         git.NoTags,
+	}
+
+	// Token is sent as basic auth password to http(s) remotes
+	if g.token != "" && strings.HasPrefix(g.url.Scheme, "http") {
+		options.Auth = &githttp.BasicAuth{
+			Username: "marvin",
+			Password: g.token,
+		}
+	}
+
+	// Default branch is cloned when ref is not given
+	if ref == "" {
+		return g.clone(ctx, options)
+	}
+
+	// Commits can not be fetched by hash, they are searched in history of default branch
+	if isCommitSHA(ref) {
+		options.Depth = 0
+		return g.clone(ctx, options)
+	}
+
+	options.ReferenceName = plumbing.NewBranchReferenceName(ref)
+	repository, err := g.clone(ctx, options)
+	if err == nil || !isRemoteRefNotFound(err) {
+		return repository, err
+	}
+
+	options.ReferenceName = plumbing.NewTagReferenceName(ref)
+	repository, err = g.clone(ctx, options)
+	if err != nil && isRemoteRefNotFound(err) {
+		return nil, fmt.Errorf("ref is not found: %s", ref)
+	}
+
+	return repository, err
+}
+
+func (g *Git) clone(ctx context.Context, options *git.CloneOptions) (*git.Repository, error) {
+
+	repository, err := git.CloneContext(ctx, newLimitedStorage(g.maxSize), nil, options)
+	if err == transport.ErrRepositoryNotFound {
+		return nil, ErrRepositoryNotFound
+	}
+	if errors.Is(err, ErrCloneTooLarge) {
+		return nil, ErrCloneTooLarge
+	}
+
+	return repository, err
+}
+
+// Branch or tag that is not in remote, go-git does not have typed error for it
+func isRemoteRefNotFound(err error) bool {
+	return strings.HasPrefix(err.Error(), "couldn't find remote ref")
+}
+
+// Opens repository once, next reads use same objects
+func (g *Git) repositoryAt(ctx context.Context, ref string) (*git.Repository, error) {
+
+	if g.repository != nil {
+		return g.repository, nil
+	}
+
+	repository, err := g.open(ctx, ref)
+	if err != nil {
+		if err == git.ErrRepositoryNotExists {
+			return nil, ErrRepositoryNotFound
+		}
+		return nil, err
+	}
+	g.repository = repository
+
+	return repository, nil
+}
+
+// Resolves branch, tag or commit hash to commit
+// Empty ref resolves to HEAD
+func resolveCommit(repository *git.Repository, ref string) (*object.Commit, error) {
+
+	if ref == "" {
+		head, err := repository.Head()
+		if err != nil {
+			return nil, err
+		}
+		return repository.CommitObject(head.Hash())
+	}
+
+	names := []plumbing.ReferenceName{
+		plumbing.NewBranchReferenceName(ref),
+		plumbing.NewRemoteReferenceName(git.DefaultRemoteName, ref),
+		plumbing.NewTagReferenceName(ref),
+		plumbing.ReferenceName(ref),
+	}
+
+	for _, name := range names {
+		reference, err := repository.Reference(name, true)
+		if err != nil {
+			continue
+		}
+
+		// Annotated tags point to tag objects
+		if tag, err := repository.TagObject(reference.Hash()); err == nil {
+			return tag.Commit()
+		}
+
+		return repository.CommitObject(reference.Hash())
+	}
+
+	if plumbing.IsHash(ref) {
+		return repository.CommitObject(plumbing.NewHash(ref))
+	}
+
+	return nil, fmt.Errorf("ref is not found: %s", ref)
+}
+
+// Gets commit sha of ref, empty ref means HEAD
+func (g *Git) GetHeadSHA(ctx context.Context, owner string, name string, ref string) (string, error) {
+
+	repository, err := g.repositoryAt(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+
+	commit, err := resolveCommit(repository, ref)
+	if err != nil {
+		return "", err
+	}
+
+	return commit.Hash.String(), nil
+}
+
+// Gets recursive repository tree of commit at ref
+func (g *Git) GetRepositoryTree(ctx context.Context, owner string, name string, ref string) ([]map[string]interface{}, error) {
+
+	repository, err := g.repositoryAt(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := resolveCommit(repository, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	commitTree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	var tree []map[string]interface{}
+
+	err = commitTree.Files().ForEach(func(f *object.File) error {
+		tree = append(tree, map[string]interface{}{
+			"id":   f.Hash.String(),
+			"sha":  f.Hash.String(),
+			"name": path.Base(f.Name),
+			"path": f.Name,
+			"type": "blob",
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tree, nil
+}
+
+func (g *Git) FindPackagesInfo(tree []map[string]interface{}) []map[string]interface{} {
+	return findPackagesInfo(tree)
+}
+
+// Gets package files from blob objects with their paths in repository
+func (g *Git) GetPackageFiles(ctx context.Context, files []map[string]interface{}) (map[string]interface{}, error) {
+
+	if g.repository == nil {
+		return nil, errors.New("repository tree must be read before package files")
+	}
+
+	packageFiles := map[string]interface{}{}
+
+	for _, file := range files {
+		blob, err := g.repository.BlobObject(plumbing.NewHash(file["id"].(string)))
+		if err != nil {
+			return nil, err
+		}
+
+		reader, err := blob.Reader()
+		if err != nil {
+			return nil, err
+		}
+
+		packagesData, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		packageFile, err := decodePackageFile(file["name"].(string), packagesData)
+		if err != nil {
+			return nil, err
+		}
+
+		filePath := file["path"].(string)
+		if filePath != "" {
+			packageFiles[filePath] = packageFile
+		}
+	}
+
+	return packageFiles, nil
+}
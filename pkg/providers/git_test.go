@@ -0,0 +1,163 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Creates repository with two commits on master and release/v1 branch
+func createTestRepository(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "marvin-git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	repository, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repository.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commit := func(content string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, "package.json"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := worktree.Add("package.json"); err != nil {
+			t.Fatal(err)
+		}
+		signature := &object.Signature{Name: "marvin", Email: "marvin@example.com", When: time.Now()}
+		if _, err := worktree.Commit("update", &git.CommitOptions{Author: signature}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	commit(`{"name": "app", "version": "1.0.0"}`)
+	commit(`{"name": "app", "version": "1.1.0"}`)
+
+	head, err := repository.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	branch := plumbing.NewHashReference(plumbing.NewBranchReferenceName("release/v1"), head.Hash())
+	if err := repository.Storer.SetReference(branch); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+func TestGitOptionsAllows(t *testing.T) {
+
+	root := createTestRepository(t)
+	outside, err := ioutil.TempDir("", "marvin-outside")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outside)
+
+	link := filepath.Join(root, "link")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Fatal(err)
+	}
+
+	options := GitOptions{
+		Enabled:    true,
+		LocalRoots: []string{root},
+		Hosts:      []string{"git.example.com"},
+	}
+
+	tests := []struct {
+		name    string
+		options GitOptions
+		url     string
+		allowed bool
+	}{
+		{"disabled", GitOptions{LocalRoots: []string{root}}, "file://" + root, false},
+		{"local root", options, "file://" + root, true},
+		{"outside of local root", options, "file://" + outside, false},
+		{"parent of local root", options, "file://" + root + "/..", false},
+		{"symlink to outside", options, "file://" + link, false},
+		{"remote file host", options, "file://git.example.com" + root, false},
+		{"allowed host", options, "ssh://git@GIT.example.com/app.git", true},
+		{"allowed host with port", options, "https://git.example.com:8443/app.git", true},
+		{"internal host", options, "http://169.254.169.254/app.git", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = tt.options.allows(u)
+			if tt.allowed && err != nil {
+				t.Errorf("expected %s to be allowed, got %v", tt.url, err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrGitNotAllowed) {
+				t.Errorf("expected %s to be rejected, got %v", tt.url, err)
+			}
+		})
+	}
+}
+
+func TestGitCloneRef(t *testing.T) {
+
+	dir := createTestRepository(t)
+	g := &Git{url: &url.URL{Scheme: "file", Path: dir}, maxSize: defaultMaxCloneSize}
+	options := &git.CloneOptions{
+		URL:           "file://" + dir,
+		Depth:         1,
+		SingleBranch:  true,
+		Tags:          git.NoTags,
+		ReferenceName: plumbing.NewBranchReferenceName("release/v1"),
+	}
+
+	repository, err := g.clone(context.Background(), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	iter, err := repository.CommitObjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	_ = iter.ForEach(func(*object.Commit) error {
+		count++
+		return nil
+	})
+	if count != 1 {
+		t.Errorf("expected only commit of ref to be cloned, got %d commits", count)
+	}
+
+	options.ReferenceName = plumbing.NewBranchReferenceName("missing")
+	if _, err := g.clone(context.Background(), options); err == nil || !isRemoteRefNotFound(err) {
+		t.Errorf("expected remote ref error, got %v", err)
+	}
+}
+
+func TestGitCloneMaxSize(t *testing.T) {
+
+	dir := createTestRepository(t)
+	g := &Git{url: &url.URL{Scheme: "file", Path: dir}, maxSize: 64}
+	options := &git.CloneOptions{URL: "file://" + dir, Depth: 1, SingleBranch: true, Tags: git.NoTags}
+
+	if _, err := g.clone(context.Background(), options); err != ErrCloneTooLarge {
+		t.Errorf("expected %v, got %v", ErrCloneTooLarge, err)
+	}
+}
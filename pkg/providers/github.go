@@ -1,33 +1,168 @@
 package providers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/nozgurozturk/marvin/pkg/client"
 	"net/url"
+	"path"
 	"strings"
 )
 
 type Github struct {
 	url    *url.URL
 	apiUrl string
+	token  string
 }
 
-func (g *Github) UrlResolver() (string, string) {
+// Creates request headers with media type, token is added if it is given
+func (g *Github) headers(accept string) map[string]string {
+	headers := map[string]string{
+		"Accept": accept,
+	}
+	if g.token != "" {
+		headers["Authorization"] = "token " + g.token
+	}
+	return headers
+}
+
+// Resolves owner, name and ref from repository url
+// Ref is taken from tree or blob urls, for exp. /owner/name/tree/release-2.x
+// Branches can contain slashes, so rest of url is returned and ResolveRef finds ref in it
+// Empty ref means default branch of repository
+func (g *Github) UrlResolver() (string, string, string, error) {
 	path := g.url.Path
 	p := strings.Split(path, "/")
-	return p[1], p[2]
+
+	if len(p) < 3 || p[1] == "" || strings.TrimSuffix(p[2], ".git") == "" {
+		return "", "", "", ErrInvalidUrl
+	}
+
+	var ref string
+	if len(p) > 4 && (p[3] == "tree" || p[3] == "blob") {
+		ref = strings.Trim(strings.Join(p[4:], "/"), "/")
+	}
+
+	return p[1], strings.TrimSuffix(p[2], ".git"), ref, nil
 }
 
-func (g *Github) GetRepositoryTree(owner string, name string) ([]map[string]interface{}, error) {
+// Finds the longest branch or tag that url ref starts with, for exp. feature/login of feature/login/src/web
+// Refs without slashes and commit shas are returned without requests
+func (g *Github) ResolveRef(ctx context.Context, owner string, name string, urlRef string) (string, error) {
 
-	endpoint := fmt.Sprintf("/repos/%s/%s/contents", owner, name)
-	headers := map[string]string{
-		"Accept": "application/vnd.github.v3+json",
+	segments := strings.Split(urlRef, "/")
+	if len(segments) == 1 || isCommitSHA(segments[0]) {
+		return segments[0], nil
 	}
 
-	treeData, err := client.New(g.apiUrl).Get(endpoint, headers)
+	// Every candidate starts with first segment, so matching refs of it contain all candidates
+	// Matching refs are paginated, next pages are read from Link header
+	refs := map[string]bool{}
+	for _, kind := range []string{"heads", "tags"} {
+		endpoint := fmt.Sprintf("/repos/%s/%s/git/matching-refs/%s/%s?per_page=100", owner, name, kind, url.PathEscape(segments[0]))
+		headers := g.headers("application/vnd.github.v3+json")
+
+		for endpoint != "" {
+			response, err := getResponse(ctx, g.apiUrl, endpoint, headers, ErrRepositoryNotFound)
+			if err != nil {
+				return "", err
+			}
+
+			var matching []struct {
+				Ref string `json:"ref"`
+			}
+			if err := json.Unmarshal(response.Body, &matching); err != nil {
+				return "", err
+			}
+			for _, m := range matching {
+				refs[strings.TrimPrefix(m.Ref, "refs/"+kind+"/")] = true
+			}
+
+			endpoint = nextPage(response.Header, g.apiUrl)
+		}
+	}
+
+	return longestRef(segments, refs)
+}
+
+// Gets commit sha of ref, empty ref means default branch
+func (g *Github) GetHeadSHA(ctx context.Context, owner string, name string, ref string) (string, error) {
+
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	endpoint := fmt.Sprintf("/repos/%s/%s/commits/%s", owner, name, url.PathEscape(ref))
+	// sha media type returns only commit sha as plain text
+	headers := g.headers("application/vnd.github.v3.sha")
+
+	shaData, err := get(ctx, g.apiUrl, endpoint, headers, ErrRepositoryNotFound)
+	if err != nil {
+		return "", err
+	}
+
+	sha := strings.TrimSpace(string(shaData))
+
+	// GitHub returns json message if repository or ref is not exist
+	if !isCommitSHA(sha) {
+		return "", ErrRepositoryNotFound
+	}
+
+	return sha, nil
+}
+
+// Gets recursive repository tree at ref
+// GitHub truncates recursive trees of large repositories, subtrees are walked one by one then
+func (g *Github) GetRepositoryTree(ctx context.Context, owner string, name string, ref string) ([]map[string]interface{}, error) {
+
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	response, err := g.getTree(ctx, owner, name, ref, true)
+	if err != nil {
+		return nil, err
+	}
+
+	items := response.Tree
+	if response.Truncated {
+		items, err = g.walkTree(ctx, owner, name, response.Sha)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var tree []map[string]interface{}
+	for _, file := range items {
+		if file["type"] != "blob" {
+			continue
+		}
+		filePath, _ := file["path"].(string)
+		file["name"] = path.Base(filePath)
+		tree = append(tree, file)
+	}
+
+	return tree, nil
+}
+
+type githubTree struct {
+	Sha       string                   `json:"sha"`
+	Tree      []map[string]interface{} `json:"tree"`
+	Truncated bool                     `json:"truncated"`
+}
+
+// Gets tree of ref or tree sha, paths of items are relative to tree
+func (g *Github) getTree(ctx context.Context, owner string, name string, ref string, recursive bool) (*githubTree, error) {
+
+	endpoint := fmt.Sprintf("/repos/%s/%s/git/trees/%s", owner, name, url.PathEscape(ref))
+	if recursive {
+		endpoint += "?recursive=1"
+	}
+	headers := g.headers("application/vnd.github.v3+json")
+
+	treeData, err := get(ctx, g.apiUrl, endpoint, headers, ErrRefNotFound)
 	if err != nil {
 		return nil, err
 	}
@@ -35,56 +170,215 @@ func (g *Github) GetRepositoryTree(owner string, name string) ([]map[string]inte
 	if treeData == nil {
 		return nil, errors.New("repository is not exist")
 	}
-	var tree []map[string]interface{}
-	if err := json.Unmarshal(treeData, &tree); err != nil {
+	response := new(githubTree)
+	if err := json.Unmarshal(treeData, response); err != nil {
 		return nil, err
 	}
 
-	return tree, nil
+	return response, nil
 }
 
+// Walks subtrees of root tree without recursive flag, paths of items are joined with their parents
+// Directories of installed dependencies are not walked, their package files are not scanned
+func (g *Github) walkTree(ctx context.Context, owner string, name string, rootSha string) ([]map[string]interface{}, error) {
 
-func (g *Github) FindPackagesInfo(tree []map[string]interface{}) []map[string]interface{} {
+	type subtree struct {
+		path string
+		sha  string
+	}
 
-	var packagesInfo []map[string]interface{}
+	var items []map[string]interface{}
+	queue := []subtree{{sha: rootSha}}
 
-	for _, file := range tree {
-		if file["name"] == "package.json" {
-			packagesInfo = append(packagesInfo, file)
+	for requests := 0; len(queue) > 0; requests++ {
+		if requests == maxTreeRequests {
+			return nil, ErrTreeTooLarge
 		}
-		if file["name"] == "composer.json" {
-			packagesInfo = append(packagesInfo, file)
+
+		current := queue[0]
+		queue = queue[1:]
+
+		response, err := g.getTree(ctx, owner, name, current.sha, false)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range response.Tree {
+			itemPath, _ := item["path"].(string)
+			itemPath = path.Join(current.path, itemPath)
+			item["path"] = itemPath
+
+			if item["type"] == "tree" && !isIgnoredPath(itemPath) {
+				sha, _ := item["sha"].(string)
+				queue = append(queue, subtree{path: itemPath, sha: sha})
+			}
+			items = append(items, item)
 		}
 	}
 
-	return packagesInfo
+	return items, nil
+}
+
+func (g *Github) FindPackagesInfo(tree []map[string]interface{}) []map[string]interface{} {
+	return findPackagesInfo(tree)
 }
 
-func (g *Github) GetPackageFiles(files []map[string]interface{}) (map[string]interface{}, error) {
+// Gets package files with their paths in repository
+func (g *Github) GetPackageFiles(ctx context.Context, files []map[string]interface{}) (map[string]interface{}, error) {
 
 	packageFiles := map[string]interface{}{}
 
 	for _, file := range files {
-		endpoint := file["download_url"].(string)
-		headers := map[string]string{
-			"Content-Type": "application/json",
-		}
+		// Blob api url of file
+		endpoint := file["url"].(string)
+		headers := g.headers("application/vnd.github.v3.raw")
 
-		packagesData, err := client.New("").Get(endpoint, headers)
+		packagesData, err := get(ctx, "", endpoint, headers, ErrFileNotFound)
 		if err != nil {
 			return nil, err
 		}
 
-		var packageFile map[string]interface{}
-		if err := json.Unmarshal(packagesData, &packageFile); err != nil {
+		packageFile, err := decodePackageFile(file["name"].(string), packagesData)
+		if err != nil {
 			return nil, err
 		}
 
-		fileName := file["name"].(string)
-		if fileName != "" {
-			packageFiles[fileName] = packageFile
+		filePath := file["path"].(string)
+		if filePath != "" {
+			packageFiles[filePath] = packageFile
 		}
 	}
 
 	return packageFiles, nil
 }
+
+// Gets raw file content at ref
+func (g *Github) GetFile(ctx context.Context, owner string, name string, ref string, filePath string) ([]byte, error) {
+
+	endpoint := fmt.Sprintf("/repos/%s/%s/contents/%s", owner, name, escapePath(filePath))
+	if ref != "" {
+		endpoint = fmt.Sprintf("%s?ref=%s", endpoint, url.QueryEscape(ref))
+	}
+	headers := g.headers("application/vnd.github.v3.raw")
+
+	return get(ctx, g.apiUrl, endpoint, headers, ErrFileNotFound)
+}
+
+/*
+	1. Get Base -> default branch if base is not given
+	2. Create Tree -> updated files on top of base commit
+	3. Create Commit -> single commit with all files
+	4. Create Branch -> points to commit
+	5. Create Pull Request -> branch into base
+*/
+func (g *Github) CreatePullRequest(ctx context.Context, owner string, name string, request *PullRequest) (string, error) {
+
+	base := request.Base
+	if base == "" {
+		defaultBranch, err := g.getDefaultBranch(ctx, owner, name)
+		if err != nil {
+			return "", err
+		}
+		base = defaultBranch
+	}
+
+	baseSHA, err := g.GetHeadSHA(ctx, owner, name, base)
+	if err != nil {
+		return "", err
+	}
+
+	var entries []interface{}
+	for filePath, content := range request.Files {
+		entries = append(entries, map[string]interface{}{
+			"path":    filePath,
+			"mode":    "100644",
+			"type":    "blob",
+			"content": string(content),
+		})
+	}
+
+	treeSHA, err := g.post(ctx, fmt.Sprintf("/repos/%s/%s/git/trees", owner, name), map[string]interface{}{
+		"base_tree": baseSHA,
+		"tree":      entries,
+	}, "sha")
+	if err != nil {
+		return "", err
+	}
+
+	commitSHA, err := g.post(ctx, fmt.Sprintf("/repos/%s/%s/git/commits", owner, name), map[string]interface{}{
+		"message": request.Message,
+		"tree":    treeSHA,
+		"parents": []string{baseSHA},
+	}, "sha")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = g.post(ctx, fmt.Sprintf("/repos/%s/%s/git/refs", owner, name), map[string]interface{}{
+		"ref": "refs/heads/" + request.Branch,
+		"sha": commitSHA,
+	}, "ref")
+	if err != nil {
+		return "", err
+	}
+
+	return g.post(ctx, fmt.Sprintf("/repos/%s/%s/pulls", owner, name), map[string]interface{}{
+		"title": request.Title,
+		"head":  request.Branch,
+		"base":  base,
+		"body":  request.Body,
+	}, "html_url")
+}
+
+// Gets default branch of repository
+func (g *Github) getDefaultBranch(ctx context.Context, owner string, name string) (string, error) {
+
+	endpoint := fmt.Sprintf("/repos/%s/%s", owner, name)
+	headers := g.headers("application/vnd.github.v3+json")
+
+	repoData, err := get(ctx, g.apiUrl, endpoint, headers, ErrRepositoryNotFound)
+	if err != nil {
+		return "", err
+	}
+
+	var repo struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := json.Unmarshal(repoData, &repo); err != nil {
+		return "", err
+	}
+
+	if repo.DefaultBranch == "" {
+		return "", ErrRepositoryNotFound
+	}
+
+	return repo.DefaultBranch, nil
+}
+
+// Posts json body and returns string field of response
+// GitHub responses without field contain error message
+func (g *Github) post(ctx context.Context, endpoint string, body map[string]interface{}, field string) (string, error) {
+
+	headers := g.headers("application/vnd.github.v3+json")
+	headers["Content-Type"] = "application/json"
+
+	responseData, err := client.New(g.apiUrl).Post(ctx, endpoint, headers, body)
+	if statusErr, ok := err.(*client.StatusError); ok {
+		return "", fmt.Errorf("github: %s", errorMessage(statusErr.Body))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(responseData, &response); err != nil {
+		return "", err
+	}
+
+	value, ok := response[field].(string)
+	if !ok {
+		return "", fmt.Errorf("github: %v", response["message"])
+	}
+
+	return value, nil
+}
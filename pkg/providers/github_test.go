@@ -0,0 +1,250 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func treePaths(tree []map[string]interface{}) []string {
+	var paths []string
+	for _, file := range tree {
+		paths = append(paths, file["path"].(string))
+	}
+	return paths
+}
+
+func TestGithubGetRepositoryTree(t *testing.T) {
+
+	api, server := newFakeApi(t)
+	api.handle("GET /repos/owner/name/git/trees/main", http.StatusOK, map[string]interface{}{
+		"sha": "root-sha",
+		"tree": []map[string]interface{}{
+			{"path": "package.json", "type": "blob", "sha": "a"},
+			{"path": "web", "type": "tree", "sha": "web-sha"},
+			{"path": "web/package.json", "type": "blob", "sha": "b"},
+		},
+	})
+
+	p := newTestProvider(t, "https://github.com/owner/name", server.URL)
+
+	tree, err := p.GetRepositoryTree(context.Background(), "owner", "name", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if paths := treePaths(tree); !reflect.DeepEqual(paths, []string{"package.json", "web/package.json"}) {
+		t.Errorf("unexpected blobs %v", paths)
+	}
+	if tree[1]["name"] != "package.json" {
+		t.Errorf("unexpected name of blob %v", tree[1]["name"])
+	}
+	if routes := api.routesOf(); len(routes) != 1 {
+		t.Errorf("expected only recursive tree request, got %v", routes)
+	}
+}
+
+func TestGithubGetRepositoryTreeTruncated(t *testing.T) {
+
+	api, server := newFakeApi(t)
+	// Recursive tree misses nested package files of large repository
+	api.handle("GET /repos/owner/name/git/trees/main", http.StatusOK, map[string]interface{}{
+		"sha":       "root-sha",
+		"truncated": true,
+		"tree": []map[string]interface{}{
+			{"path": "package.json", "type": "blob", "sha": "a"},
+		},
+	})
+	api.handle("GET /repos/owner/name/git/trees/root-sha", http.StatusOK, map[string]interface{}{
+		"sha": "root-sha",
+		"tree": []map[string]interface{}{
+			{"path": "package.json", "type": "blob", "sha": "a", "url": server.URL + "/repos/owner/name/git/blobs/a"},
+			{"path": "packages", "type": "tree", "sha": "packages-sha"},
+			{"path": "node_modules", "type": "tree", "sha": "node-modules-sha"},
+		},
+	})
+	api.handle("GET /repos/owner/name/git/trees/packages-sha", http.StatusOK, map[string]interface{}{
+		"sha": "packages-sha",
+		"tree": []map[string]interface{}{
+			{"path": "web", "type": "tree", "sha": "web-sha"},
+		},
+	})
+	api.handle("GET /repos/owner/name/git/trees/web-sha", http.StatusOK, map[string]interface{}{
+		"sha": "web-sha",
+		"tree": []map[string]interface{}{
+			{"path": "package.json", "type": "blob", "sha": "b"},
+			{"path": "composer.json", "type": "blob", "sha": "c"},
+		},
+	})
+
+	p := newTestProvider(t, "https://github.com/owner/name", server.URL)
+
+	tree, err := p.GetRepositoryTree(context.Background(), "owner", "name", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"package.json", "packages/web/package.json", "packages/web/composer.json"}
+	if paths := treePaths(tree); !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected blobs %v, got %v", expected, paths)
+	}
+	if tree[0]["url"] == nil {
+		t.Error("expected blob url of walked tree")
+	}
+
+	// Installed dependencies are not walked
+	for _, route := range api.routesOf() {
+		if route == "GET /repos/owner/name/git/trees/node-modules-sha" {
+			t.Errorf("unexpected request %s", route)
+		}
+	}
+}
+
+func TestGithubGetRepositoryTreeTooLarge(t *testing.T) {
+
+	api, server := newFakeApi(t)
+	api.handle("GET /repos/owner/name/git/trees/main", http.StatusOK, map[string]interface{}{"sha": "root-sha", "truncated": true})
+	// Tree that contains itself is walked until request limit
+	api.handle("GET /repos/owner/name/git/trees/root-sha", http.StatusOK, map[string]interface{}{
+		"sha":  "root-sha",
+		"tree": []map[string]interface{}{{"path": "src", "type": "tree", "sha": "root-sha"}},
+	})
+
+	p := newTestProvider(t, "https://github.com/owner/name", server.URL)
+
+	if _, err := p.GetRepositoryTree(context.Background(), "owner", "name", "main"); err != ErrTreeTooLarge {
+		t.Errorf("expected %v, got %v", ErrTreeTooLarge, err)
+	}
+	if routes := api.routesOf(); len(routes) != maxTreeRequests+1 {
+		t.Errorf("expected %d requests, got %d", maxTreeRequests+1, len(routes))
+	}
+}
+
+func TestGithubUrlResolver(t *testing.T) {
+
+	tests := []struct {
+		rawUrl string
+		owner  string
+		name   string
+		ref    string
+		err    error
+	}{
+		{"https://github.com/owner/name", "owner", "name", "", nil},
+		{"https://github.com/owner/name.git", "owner", "name", "", nil},
+		{"https://github.com/owner/name/tree/release-2.x", "owner", "name", "release-2.x", nil},
+		// Rest of url is resolved with ResolveRef
+		{"https://github.com/owner/name/tree/feature/login", "owner", "name", "feature/login", nil},
+		{"https://github.com/owner/name/blob/feature/login/web/package.json", "owner", "name", "feature/login/web/package.json", nil},
+		{"https://github.com/owner/name/tree/main/", "owner", "name", "main", nil},
+		{"https://github.com", "", "", "", ErrInvalidUrl},
+		{"https://github.com/owner", "", "", "", ErrInvalidUrl},
+		{"https://github.com/owner/", "", "", "", ErrInvalidUrl},
+		{"https://github.com//name", "", "", "", ErrInvalidUrl},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rawUrl, func(t *testing.T) {
+			u, _ := url.Parse(tt.rawUrl)
+			owner, name, ref, err := (&Github{url: u}).UrlResolver()
+			if owner != tt.owner || name != tt.name || ref != tt.ref || err != tt.err {
+				t.Errorf("expected %s %s %s (%v), got %s %s %s (%v)", tt.owner, tt.name, tt.ref, tt.err, owner, name, ref, err)
+			}
+		})
+	}
+}
+
+func TestGithubResolveRef(t *testing.T) {
+
+	api, server := newFakeApi(t)
+	api.handle("GET /repos/owner/name/git/matching-refs/heads/feature", http.StatusOK, []map[string]string{
+		{"ref": "refs/heads/feature"},
+		{"ref": "refs/heads/feature/login"},
+		{"ref": "refs/heads/feature/login-page"},
+	})
+	api.handle("GET /repos/owner/name/git/matching-refs/tags/feature", http.StatusOK, []map[string]string{})
+	api.handle("GET /repos/owner/name/git/matching-refs/heads/v2", http.StatusOK, []map[string]string{})
+	api.handle("GET /repos/owner/name/git/matching-refs/tags/v2", http.StatusOK, []map[string]string{
+		{"ref": "refs/tags/v2/1.0"},
+	})
+	api.handle("GET /repos/owner/name/git/matching-refs/heads/unknown", http.StatusOK, []map[string]string{})
+	api.handle("GET /repos/owner/name/git/matching-refs/tags/unknown", http.StatusOK, []map[string]string{})
+
+	p := newTestProvider(t, "https://github.com/owner/name", server.URL).(*Github)
+
+	tests := []struct {
+		urlRef string
+		ref    string
+		err    error
+	}{
+		{"main", "main", nil},
+		{testBaseSHA + "/web/package.json", testBaseSHA, nil},
+		{"feature/login", "feature/login", nil},
+		// The longest branch is used, not the first segment
+		{"feature/login/web/package.json", "feature/login", nil},
+		{"feature/web/package.json", "feature", nil},
+		{"v2/1.0/composer.json", "v2/1.0", nil},
+		{"unknown/branch", "", ErrRefNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.urlRef, func(t *testing.T) {
+			ref, err := p.ResolveRef(context.Background(), "owner", "name", tt.urlRef)
+			if ref != tt.ref || err != tt.err {
+				t.Errorf("expected %q (%v), got %q (%v)", tt.ref, tt.err, ref, err)
+			}
+		})
+	}
+
+	// Refs without slashes do not need requests
+	for _, route := range api.routesOf() {
+		if route == "GET /repos/owner/name/git/matching-refs/heads/main" {
+			t.Errorf("unexpected request %s", route)
+		}
+	}
+}
+
+func TestGithubResolveRefReadsEveryPage(t *testing.T) {
+
+	resetEtagCache(t)
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+		refs := []map[string]string{}
+		switch r.URL.Path {
+		case "/repos/owner/name/git/matching-refs/heads/release":
+			if r.URL.Query().Get("page") == "" {
+				w.Header().Set("Link", `<http://`+r.Host+`/repos/owner/name/git/matching-refs/heads/release?per_page=100&page=2>; rel="next", <http://`+r.Host+`/repos/owner/name/git/matching-refs/heads/release?per_page=100&page=2>; rel="last"`)
+				refs = append(refs, map[string]string{"ref": "refs/heads/release/1.x"})
+			} else {
+				refs = append(refs, map[string]string{"ref": "refs/heads/release/2.x"})
+			}
+		case "/repos/owner/name/git/matching-refs/tags/release":
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(refs)
+	}))
+	defer server.Close()
+
+	p := newTestProvider(t, "https://github.com/owner/name", server.URL).(*Github)
+
+	ref, err := p.ResolveRef(context.Background(), "owner", "name", "release/2.x/package.json")
+	if err != nil || ref != "release/2.x" {
+		t.Fatalf("expected branch of second page, got %q (%v)", ref, err)
+	}
+
+	expected := []string{
+		"/repos/owner/name/git/matching-refs/heads/release?per_page=100",
+		"/repos/owner/name/git/matching-refs/heads/release?per_page=100&page=2",
+		"/repos/owner/name/git/matching-refs/tags/release?per_page=100",
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("expected %v, got %v", expected, requests)
+	}
+}
@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,120 +14,344 @@ import (
 type Gitlab struct {
 	url    *url.URL
 	apiUrl string
+	token  string
 }
 
-func (g *Gitlab) UrlResolver() (string, string) {
-	path := g.url.Path
-	p := strings.Split(path, "/")
-	return p[1], p[2]
+// Creates request headers, token is added if it is given
+func (g *Gitlab) headers() map[string]string {
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+	if g.token != "" {
+		headers["PRIVATE-TOKEN"] = g.token
+	}
+	return headers
 }
 
-// Gets Repository ID for consume gitlab's API for next requests
-func (g *Gitlab) getRepositoryID(namespace string, name string) (string, error) {
+// Resolves namespace, name and ref from repository url
+// Namespace can contain nested groups, for exp. /group/subgroup/name/-/tree/release-2.x
+// Branches can contain slashes, so rest of tree or blob url is returned and ResolveRef finds ref in it
+// Empty ref means default branch of repository
+func (g *Gitlab) UrlResolver() (string, string, string, error) {
+	path := strings.Trim(g.url.Path, "/")
+	p := strings.Split(path, "/")
+
+	var ref string
+	for i, segment := range p {
+		// Gitlab separates project path and project pages with -
+		if segment == "-" {
+			if i+2 < len(p) && (p[i+1] == "tree" || p[i+1] == "blob") {
+				ref = strings.Join(p[i+2:], "/")
+			}
+			p = p[:i]
+			break
+		}
+	}
 
-	var repoId float64
+	// Projects are in at least one group or user namespace
+	if len(p) < 2 || p[0] == "" {
+		return "", "", "", ErrInvalidUrl
+	}
 
-	endpoint := fmt.Sprintf("/projects?search=%s", name)
-	headers := map[string]string{
-		"Content-Type": "application/json",
+	namespace := strings.Join(p[:len(p)-1], "/")
+	name := strings.TrimSuffix(p[len(p)-1], ".git")
+	if name == "" {
+		return "", "", "", ErrInvalidUrl
 	}
 
-	repoData, err := client.New(g.apiUrl).Get(endpoint, headers)
+	return namespace, name, ref, nil
+}
+
+// Finds the longest branch or tag that url ref starts with, for exp. feature/login of feature/login/src/web
+// Refs without slashes and commit shas are returned without requests
+func (g *Gitlab) ResolveRef(ctx context.Context, namespace string, name string, urlRef string) (string, error) {
+
+	segments := strings.Split(urlRef, "/")
+	if len(segments) == 1 || isCommitSHA(segments[0]) {
+		return segments[0], nil
+	}
+
+	projectID, err := g.getRepositoryID(ctx, namespace, name)
 	if err != nil {
 		return "", err
 	}
 
-	var repoList []map[string]interface{}
-	if err := json.Unmarshal(repoData, &repoList); err != nil {
-		panic(err)
-	}
+	// Search with ^ finds refs that start with first segment, so they contain all candidates
+	// Results are paginated, next pages are read from Link header
+	refs := map[string]bool{}
+	for _, kind := range []string{"branches", "tags"} {
+		endpoint := fmt.Sprintf("/projects/%s/repository/%s?search=%s&per_page=100", projectID, kind, url.QueryEscape("^"+segments[0]))
 
-	pathWithNamespace := fmt.Sprintf("%s/%s", namespace, name)
-	for _, repo := range repoList {
-		if repo["path_with_namespace"] == pathWithNamespace {
-			repoId = repo["id"].(float64)
-			break
+		for endpoint != "" {
+			response, err := getResponse(ctx, g.apiUrl, endpoint, g.headers(), ErrRepositoryNotFound)
+			if err != nil {
+				return "", err
+			}
+
+			var matching []struct {
+				Name string `json:"name"`
+			}
+			if err := json.Unmarshal(response.Body, &matching); err != nil {
+				return "", err
+			}
+			for _, m := range matching {
+				refs[m.Name] = true
+			}
+
+			endpoint = nextPage(response.Header, g.apiUrl)
 		}
 	}
 
-	id := strconv.Itoa(int(repoId))
-	return id, nil
+	return longestRef(segments, refs)
 }
 
-func (g *Gitlab) GetRepositoryTree(namespace string, name string) ([]map[string]interface{}, error) {
+// Gitlab project that is used for next requests
+type gitlabProject struct {
+	ID            *int   `json:"id"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+// Gets project with its url encoded path with namespace
+func (g *Gitlab) getProject(ctx context.Context, namespace string, name string) (*gitlabProject, error) {
+
+	pathWithNamespace := fmt.Sprintf("%s/%s", namespace, name)
 
-	projectID, err := g.getRepositoryID(namespace, name)
+	endpoint := fmt.Sprintf("/projects/%s", url.PathEscape(pathWithNamespace))
+	headers := g.headers()
+
+	repoData, err := get(ctx, g.apiUrl, endpoint, headers, ErrRepositoryNotFound)
 	if err != nil {
 		return nil, err
 	}
 
-	endpoint := fmt.Sprintf("/projects/%s/repository/tree", projectID)
-	headers := map[string]string{
-		"Content-Type": "application/json",
+	project := new(gitlabProject)
+	if err := json.Unmarshal(repoData, project); err != nil {
+		return nil, err
+	}
+
+	// Gitlab returns message without id if project is not exist or private
+	if project.ID == nil {
+		return nil, ErrRepositoryNotFound
 	}
 
-	treeData, err := client.New(g.apiUrl).Get(endpoint, headers)
+	return project, nil
+}
+
+// Gets Repository ID for consume gitlab's API for next requests
+func (g *Gitlab) getRepositoryID(ctx context.Context, namespace string, name string) (string, error) {
+
+	project, err := g.getProject(ctx, namespace, name)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	if treeData == nil {
-		return nil, errors.New("repository is not exist")
+	return strconv.Itoa(*project.ID), nil
+}
+
+// Gets commit sha of ref, empty ref means default branch
+func (g *Gitlab) GetHeadSHA(ctx context.Context, namespace string, name string, ref string) (string, error) {
+
+	project, err := g.getProject(ctx, namespace, name)
+	if err != nil {
+		return "", err
 	}
-	var tree []map[string]interface{}
-	if err := json.Unmarshal(treeData, &tree); err != nil {
-		return nil, err
+
+	if ref == "" {
+		ref = project.DefaultBranch
 	}
 
-	// add tree item to project id for getting package files
-	for _, file := range tree {
-		file["projectId"] = projectID
+	endpoint := fmt.Sprintf("/projects/%d/repository/commits/%s", *project.ID, url.PathEscape(ref))
+	headers := g.headers()
+
+	commitData, err := get(ctx, g.apiUrl, endpoint, headers, ErrRefNotFound)
+	if err != nil {
+		return "", err
 	}
 
-	return tree, nil
+	var commit struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(commitData, &commit); err != nil {
+		return "", err
+	}
+
+	// Gitlab returns message without id if ref is not exist
+	if !isCommitSHA(commit.ID) {
+		return "", ErrRefNotFound
+	}
+
+	return commit.ID, nil
 }
 
-func (g *Gitlab) FindPackagesInfo(tree []map[string]interface{}) []map[string]interface{} {
+// Gets recursive repository tree at ref
+// Gitlab paginates tree, every page is requested until last page
+func (g *Gitlab) GetRepositoryTree(ctx context.Context, namespace string, name string, ref string) ([]map[string]interface{}, error) {
+
+	projectID, err := g.getRepositoryID(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := g.headers()
+
+	var tree []map[string]interface{}
+
+	for page := 1; ; page++ {
+		endpoint := fmt.Sprintf("/projects/%s/repository/tree?recursive=true&per_page=%d&page=%d", projectID, treePageSize, page)
+		if ref != "" {
+			endpoint = fmt.Sprintf("%s&ref=%s", endpoint, url.QueryEscape(ref))
+		}
+
+		treeData, err := get(ctx, g.apiUrl, endpoint, headers, ErrRefNotFound)
+		if err != nil {
+			return nil, err
+		}
 
-	var packagesInfo []map[string]interface{}
+		if treeData == nil {
+			return nil, errors.New("repository is not exist")
+		}
+		var items []map[string]interface{}
+		if err := json.Unmarshal(treeData, &items); err != nil {
+			return nil, err
+		}
 
-	for _, file := range tree {
-		if file["name"] == "package.json" {
-			packagesInfo = append(packagesInfo, file)
+		for _, file := range items {
+			if file["type"] != "blob" {
+				continue
+			}
+			// add tree item to project id for getting package files
+			file["projectId"] = projectID
+			// id of tree item is blob sha
+			file["sha"] = file["id"]
+			tree = append(tree, file)
 		}
-		if file["name"] == "composer.json" {
-			packagesInfo = append(packagesInfo, file)
+
+		if len(items) < treePageSize {
+			break
 		}
 	}
 
-	return packagesInfo
+	return tree, nil
+}
+
+func (g *Gitlab) FindPackagesInfo(tree []map[string]interface{}) []map[string]interface{} {
+	return findPackagesInfo(tree)
 }
 
-func (g *Gitlab) GetPackageFiles(files []map[string]interface{}) (map[string]interface{}, error) {
+// Gets package files with their paths in repository
+func (g *Gitlab) GetPackageFiles(ctx context.Context, files []map[string]interface{}) (map[string]interface{}, error) {
 
 	packageFiles := map[string]interface{}{}
 
 	for _, file := range files {
 		endpoint := fmt.Sprintf("/projects/%s/repository/blobs/%s/raw", file["projectId"].(string), file["id"].(string))
-		headers := map[string]string{
-			"Content-Type": "application/json",
-		}
+		headers := g.headers()
 
-		packagesData, err := client.New(g.apiUrl).Get(endpoint, headers)
+		packagesData, err := get(ctx, g.apiUrl, endpoint, headers, ErrFileNotFound)
 		if err != nil {
 			return nil, err
 		}
 
-		var packageFile map[string]interface{}
-		if err := json.Unmarshal(packagesData, &packageFile); err != nil {
+		packageFile, err := decodePackageFile(file["name"].(string), packagesData)
+		if err != nil {
 			return nil, err
 		}
 
-		fileName := file["name"].(string)
-		if fileName != "" {
-			packageFiles[fileName] = packageFile
+		filePath := file["path"].(string)
+		if filePath != "" {
+			packageFiles[filePath] = packageFile
 		}
 	}
 
 	return packageFiles, nil
 }
+
+// Gets raw file content at ref, empty ref means default branch
+func (g *Gitlab) GetFile(ctx context.Context, namespace string, name string, ref string, filePath string) ([]byte, error) {
+
+	project, err := g.getProject(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if ref == "" {
+		ref = project.DefaultBranch
+	}
+
+	endpoint := fmt.Sprintf("/projects/%d/repository/files/%s/raw?ref=%s", *project.ID, url.PathEscape(filePath), url.QueryEscape(ref))
+
+	return get(ctx, g.apiUrl, endpoint, g.headers(), ErrFileNotFound)
+}
+
+/*
+	1. Get Base -> default branch if base is not given
+	2. Create Commit -> creates branch from base with updated files
+	3. Create Merge Request -> branch into base
+*/
+func (g *Gitlab) CreatePullRequest(ctx context.Context, namespace string, name string, request *PullRequest) (string, error) {
+
+	project, err := g.getProject(ctx, namespace, name)
+	if err != nil {
+		return "", err
+	}
+
+	base := request.Base
+	if base == "" {
+		base = project.DefaultBranch
+	}
+
+	var actions []interface{}
+	for filePath, content := range request.Files {
+		actions = append(actions, map[string]interface{}{
+			"action":    "update",
+			"file_path": filePath,
+			"content":   string(content),
+		})
+	}
+
+	_, err = g.post(ctx, fmt.Sprintf("/projects/%d/repository/commits", *project.ID), map[string]interface{}{
+		"branch":         request.Branch,
+		"start_branch":   base,
+		"commit_message": request.Message,
+		"actions":        actions,
+	}, "id")
+	if err != nil {
+		return "", err
+	}
+
+	return g.post(ctx, fmt.Sprintf("/projects/%d/merge_requests", *project.ID), map[string]interface{}{
+		"source_branch":        request.Branch,
+		"target_branch":        base,
+		"title":                request.Title,
+		"description":          request.Body,
+		"remove_source_branch": true,
+	}, "web_url")
+}
+
+// Posts json body and returns string field of response
+// Gitlab responses without field contain message or error
+func (g *Gitlab) post(ctx context.Context, endpoint string, body map[string]interface{}, field string) (string, error) {
+
+	responseData, err := client.New(g.apiUrl).Post(ctx, endpoint, g.headers(), body)
+	if statusErr, ok := err.(*client.StatusError); ok {
+		return "", fmt.Errorf("gitlab: %s", errorMessage(statusErr.Body))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(responseData, &response); err != nil {
+		return "", err
+	}
+
+	value, ok := response[field].(string)
+	if !ok {
+		if message, ok := response["message"]; ok {
+			return "", fmt.Errorf("gitlab: %v", message)
+		}
+		return "", fmt.Errorf("gitlab: %v", response["error"])
+	}
+
+	return value, nil
+}
@@ -0,0 +1,121 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestGitlabUrlResolver(t *testing.T) {
+
+	tests := []struct {
+		rawUrl    string
+		namespace string
+		name      string
+		ref       string
+		err       error
+	}{
+		{"https://gitlab.com/group/name", "group", "name", "", nil},
+		{"https://gitlab.com/group/name/-/tree/release-2.x", "group", "name", "release-2.x", nil},
+		// Rest of url is resolved with ResolveRef
+		{"https://gitlab.com/group/name/-/tree/feature/login", "group", "name", "feature/login", nil},
+		{"https://gitlab.com/group/name/-/blob/feature/login/web/package.json", "group", "name", "feature/login/web/package.json", nil},
+		{"https://gitlab.com/group/name/-/blob/main/package.json", "group", "name", "main/package.json", nil},
+		// Namespaces contain nested groups
+		{"https://gitlab.com/group/subgroup/name", "group/subgroup", "name", "", nil},
+		{"https://gitlab.com/group/subgroup/team/name/-/tree/main", "group/subgroup/team", "name", "main", nil},
+		{"https://gitlab.com/group/subgroup/name.git", "group/subgroup", "name", "", nil},
+		{"https://gitlab.com/group/name/", "group", "name", "", nil},
+		// Other project pages do not have ref
+		{"https://gitlab.com/group/name/-/merge_requests/1", "group", "name", "", nil},
+		{"https://gitlab.com/group/name/-/tree", "group", "name", "", nil},
+		{"https://gitlab.com", "", "", "", ErrInvalidUrl},
+		{"https://gitlab.com/", "", "", "", ErrInvalidUrl},
+		{"https://gitlab.com/group", "", "", "", ErrInvalidUrl},
+		{"https://gitlab.com/group/.git", "", "", "", ErrInvalidUrl},
+		{"https://gitlab.com/-/tree/main", "", "", "", ErrInvalidUrl},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rawUrl, func(t *testing.T) {
+			u, _ := url.Parse(tt.rawUrl)
+			namespace, name, ref, err := (&Gitlab{url: u}).UrlResolver()
+			if namespace != tt.namespace || name != tt.name || ref != tt.ref || err != tt.err {
+				t.Errorf("expected %s %s %s (%v), got %s %s %s (%v)", tt.namespace, tt.name, tt.ref, tt.err, namespace, name, ref, err)
+			}
+		})
+	}
+}
+
+func TestGitlabResolveRef(t *testing.T) {
+
+	api, server := newFakeApi(t)
+	api.handle("GET /projects/group%2Fname", http.StatusOK, map[string]interface{}{"id": 7, "default_branch": "main"})
+	api.handle("GET /projects/7/repository/branches", http.StatusOK, []map[string]string{
+		{"name": "feature"},
+		{"name": "feature/login"},
+		{"name": "feature/login-page"},
+	})
+	api.handle("GET /projects/7/repository/tags", http.StatusOK, []map[string]string{
+		{"name": "v2/1.0"},
+	})
+
+	p := newTestProvider(t, "https://gitlab.com/group/name", server.URL).(*Gitlab)
+
+	tests := []struct {
+		urlRef string
+		ref    string
+		err    error
+	}{
+		{"main", "main", nil},
+		{testBaseSHA + "/web/package.json", testBaseSHA, nil},
+		{"feature/login", "feature/login", nil},
+		// The longest branch is used, not the first segment
+		{"feature/login/web/package.json", "feature/login", nil},
+		{"feature/web/package.json", "feature", nil},
+		{"v2/1.0/composer.json", "v2/1.0", nil},
+		{"unknown/branch", "", ErrRefNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.urlRef, func(t *testing.T) {
+			ref, err := p.ResolveRef(context.Background(), "group", "name", tt.urlRef)
+			if ref != tt.ref || err != tt.err {
+				t.Errorf("expected %q (%v), got %q (%v)", tt.ref, tt.err, ref, err)
+			}
+		})
+	}
+
+	// Refs without slashes do not need requests
+	if routes := api.routesOf(); len(routes) != 5*3 {
+		t.Errorf("expected project, branches and tags to be requested for 5 refs, got %v", routes)
+	}
+}
+
+func TestGitlabGetProject(t *testing.T) {
+
+	api, server := newFakeApi(t)
+	api.handle("GET /projects/group%2Fsubgroup%2Fname", http.StatusOK, map[string]interface{}{"id": 7, "default_branch": "main"})
+	// Gitlab responds with message to projects that are private for token
+	api.handle("GET /projects/group%2Fprivate", http.StatusOK, map[string]interface{}{"message": "404 Project Not Found"})
+
+	p := newTestProvider(t, "https://gitlab.com/group/subgroup/name", server.URL).(*Gitlab)
+
+	project, err := p.getProject(context.Background(), "group/subgroup", "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *project.ID != 7 || project.DefaultBranch != "main" {
+		t.Errorf("unexpected project %+v", project)
+	}
+	if routes := api.routesOf(); len(routes) != 1 || routes[0] != "GET /projects/group%2Fsubgroup%2Fname" {
+		t.Errorf("expected path with namespace to be encoded as one segment, got %v", routes)
+	}
+
+	for _, name := range []string{"private", "missing"} {
+		if _, err := p.getProject(context.Background(), "group", name); err != ErrRepositoryNotFound {
+			t.Errorf("expected %v for %s project, got %v", ErrRepositoryNotFound, name, err)
+		}
+	}
+}
@@ -1,41 +1,223 @@
 /*
 Package providers is resolve repository data with given providers
-Accepted providers [github, gitlab]
+Accepted providers [github, gitlab, git]
 */
 package providers
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/nozgurozturk/marvin/pkg/parsers"
 	"net/url"
+	"strings"
 )
 
 const (
-	github    = "github.com"
-	gitlab    = "gitlab.com"
+	github = "github.com"
+	gitlab = "gitlab.com"
 )
 
+// Returned when provider does not have repository with given path
+var ErrRepositoryNotFound = errors.New("repository is not found")
+
+// Returned when repository does not have branch or tag of url
+var ErrRefNotFound = errors.New("ref is not found")
+
+// Returned when file is not found at ref
+var ErrFileNotFound = errors.New("file is not found")
+
+// Returned when repository url does not contain owner and name
+var ErrInvalidUrl = errors.New("repository url must contain owner and name")
+
+// Returned when tree of repository can not be read with maximum number of tree requests
+var ErrTreeTooLarge = errors.New("repository tree is too large")
+
+// Page size of paginated tree requests
+const treePageSize = 100
+
+// Maximum number of subtree requests when recursive tree is truncated
+const maxTreeRequests = 500
+
+// Package files and workspace descriptors that are searched in repository tree
+var packageFileNames = map[string]bool{
+	"package.json":        true,
+	"composer.json":       true,
+	parsers.Lerna:         true,
+	parsers.PnpmWorkspace: true,
+	// Installed versions and licenses of packages are read from JSON lock files
+	parsers.NpmLock:       true,
+	parsers.NpmShrinkwrap: true,
+	parsers.ComposerLock:  true,
+}
+
+// Directories that contain installed dependencies, package files in them are not scanned
+var ignoredDirectories = []string{"node_modules", "vendor"}
+
 type Provider interface {
-	UrlResolver() (string, string)                                                  // Gets owner and name of repository
-	GetRepositoryTree(owner string, name string) ([]map[string]interface{}, error)  // Gets repository tree of main directory
-	FindPackagesInfo(tree []map[string]interface{}) []map[string]interface{}        // Gets package manager file info from provider's API
-	GetPackageFiles(files []map[string]interface{}) (map[string]interface{}, error) // Gets packages files with name and version
+	UrlResolver() (string, string, string, error)                                                                   // Gets owner, name and ref of repository
+	GetHeadSHA(ctx context.Context, owner string, name string, ref string) (string, error)                          // Gets commit sha that ref points to
+	GetRepositoryTree(ctx context.Context, owner string, name string, ref string) ([]map[string]interface{}, error) // Gets recursive repository tree at ref, blobs have sha
+	FindPackagesInfo(tree []map[string]interface{}) []map[string]interface{}                                        // Gets package manager file info from provider's API
+	GetPackageFiles(ctx context.Context, files []map[string]interface{}) (map[string]interface{}, error)            // Gets packages files keyed by their paths
+}
+
+// Providers that can find refs with slashes in urls, for exp. /tree/feature/login/src
+type RefResolver interface {
+	ResolveRef(ctx context.Context, owner string, name string, urlRef string) (string, error) // Gets branch, tag or commit that url ref starts with
+}
+
+// Providers that can open pull requests with updated package files
+type PullRequester interface {
+	GetFile(ctx context.Context, owner string, name string, ref string, filePath string) ([]byte, error)    // Gets raw file content at ref
+	CreatePullRequest(ctx context.Context, owner string, name string, request *PullRequest) (string, error) // Creates branch with files and opens pull request, returns its web url
+}
+
+// Pull request that commits files into new branch
+type PullRequest struct {
+	// Target branch, default branch of repository is used if it is empty
+	Base    string
+	Branch  string
+	Title   string
+	Body    string
+	Message string
+	// File path -> new content
+	Files map[string][]byte
+}
+
+// Options of provider, zero value reads public repositories from public hosts
+type Options struct {
+	// Access token that is sent to provider, private repositories and pull requests need it
+	Token string
+	// Overrides api url of github and gitlab, for exp. self hosted instances or fake servers
+	ApiUrl string
+	// Clone urls are rejected unless git provider is enabled
+	Git GitOptions
 }
 
 // Detect provider from given url
+// Clone urls with file, ssh or git scheme and .git urls of unknown hosts are read by git
 func GetProvider(u *url.URL) (Provider, error) {
+	return NewProvider(u, Options{})
+}
+
+// Creates provider of given url with options
+func NewProvider(u *url.URL, options Options) (Provider, error) {
+	if isCloneUrl(u) {
+		if err := options.Git.allows(u); err != nil {
+			return nil, err
+		}
+		g := new(Git)
+		g.url = u
+		g.token = options.Token
+		g.maxSize = options.Git.maxSize()
+		return g, nil
+	}
+
 	switch u.Host {
 	case github:
 		g := new(Github)
 		g.url = u
-		g.apiUrl = "https://api.github.com"
+		g.apiUrl = apiUrlOrDefault(options.ApiUrl, "https://api.github.com")
+		g.token = options.Token
 		return g, nil
 	case gitlab:
 		g := new(Gitlab)
 		g.url = u
-		g.apiUrl = "https://gitlab.com/api/v4"
+		g.apiUrl = apiUrlOrDefault(options.ApiUrl, "https://gitlab.com/api/v4")
+		g.token = options.Token
 		return g, nil
 	default:
 		return nil, errors.New(fmt.Sprintf("Undefined provider type: %s", u.Host))
 	}
 }
+
+// Escapes every segment of file path
+func escapePath(filePath string) string {
+	return (&url.URL{Path: filePath}).EscapedPath()
+}
+
+func apiUrlOrDefault(apiUrl string, defaultUrl string) string {
+	if apiUrl == "" {
+		return defaultUrl
+	}
+	return strings.TrimSuffix(apiUrl, "/")
+}
+
+// Checks text is full hex commit sha
+func isCommitSHA(text string) bool {
+	if len(text) != 40 {
+		return false
+	}
+	for _, c := range text {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// Gets the longest ref that is made of first segments of url ref
+func longestRef(segments []string, refs map[string]bool) (string, error) {
+	for i := len(segments); i > 0; i-- {
+		if candidate := strings.Join(segments[:i], "/"); refs[candidate] {
+			return candidate, nil
+		}
+	}
+	return "", ErrRefNotFound
+}
+
+// Finds package files in recursive tree, tree items must have name and path
+func findPackagesInfo(tree []map[string]interface{}) []map[string]interface{} {
+
+	var packagesInfo []map[string]interface{}
+
+	for _, file := range tree {
+		name, _ := file["name"].(string)
+		filePath, _ := file["path"].(string)
+		if !packageFileNames[name] || isIgnoredPath(filePath) {
+			continue
+		}
+		packagesInfo = append(packagesInfo, file)
+	}
+
+	return packagesInfo
+}
+
+// Checks path is in directory of installed dependencies
+func isIgnoredPath(filePath string) bool {
+	for _, segment := range strings.Split(filePath, "/") {
+		for _, ignored := range ignoredDirectories {
+			if segment == ignored {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Decodes raw package file with matching file name
+func decodePackageFile(fileName string, data []byte) (map[string]interface{}, error) {
+	if fileName == parsers.PnpmWorkspace {
+		return parsers.ParsePnpmWorkspace(data), nil
+	}
+
+	var packageFile map[string]interface{}
+	if err := json.Unmarshal(data, &packageFile); err != nil {
+		return nil, err
+	}
+	return packageFile, nil
+}
+
+// Checks url must be read by git instead of hosting API
+func isCloneUrl(u *url.URL) bool {
+	switch u.Scheme {
+	case "file", "ssh", "git":
+		return true
+	case "http", "https":
+		return u.Host != github && u.Host != gitlab && strings.HasSuffix(u.Path, ".git")
+	default:
+		return false
+	}
+}
@@ -0,0 +1,258 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Request that fake provider received
+type fakeRequest struct {
+	route string
+	body  map[string]interface{}
+}
+
+// Fake provider api, routes are method and escaped path, for exp. POST /repos/owner/name/pulls
+type fakeApi struct {
+	t        *testing.T
+	routes   map[string]func(body map[string]interface{}) (int, interface{})
+	requests []fakeRequest
+}
+
+func newFakeApi(t *testing.T) (*fakeApi, *httptest.Server) {
+	api := &fakeApi{t: t, routes: map[string]func(map[string]interface{}) (int, interface{}){}}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	return api, server
+}
+
+func (f *fakeApi) handle(route string, status int, response interface{}) {
+	f.routes[route] = func(map[string]interface{}) (int, interface{}) {
+		return status, response
+	}
+}
+
+func (f *fakeApi) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	route := r.Method + " " + r.URL.EscapedPath()
+
+	var body map[string]interface{}
+	if data, _ := ioutil.ReadAll(r.Body); len(data) > 0 {
+		if err := json.Unmarshal(data, &body); err != nil {
+			f.t.Errorf("%s: invalid body: %v", route, err)
+		}
+	}
+	f.requests = append(f.requests, fakeRequest{route: route, body: body})
+
+	handler, ok := f.routes[route]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message": "Not Found"}`))
+		return
+	}
+
+	status, response := handler(body)
+	w.WriteHeader(status)
+	if text, ok := response.(string); ok {
+		_, _ = w.Write([]byte(text))
+		return
+	}
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (f *fakeApi) routesOf() []string {
+	var routes []string
+	for _, request := range f.requests {
+		routes = append(routes, request.route)
+	}
+	return routes
+}
+
+func (f *fakeApi) bodyOf(route string) map[string]interface{} {
+	for _, request := range f.requests {
+		if request.route == route {
+			return request.body
+		}
+	}
+	f.t.Fatalf("%s is not requested", route)
+	return nil
+}
+
+func newTestProvider(t *testing.T, rawUrl string, apiUrl string) Provider {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewProvider(u, Options{Token: "secret", ApiUrl: apiUrl})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+const testBaseSHA = "0123456789abcdef0123456789abcdef01234567"
+
+var testPullRequest = &PullRequest{
+	Branch:  "marvin/update-1",
+	Title:   "Update react to ^17.0.1",
+	Body:    "body",
+	Message: "Update react to ^17.0.1",
+	Files: map[string][]byte{
+		"web/package.json": []byte(`{"dependencies": {"react": "^17.0.1"}}`),
+	},
+}
+
+func TestGithubCreatePullRequest(t *testing.T) {
+
+	api, server := newFakeApi(t)
+	api.handle("GET /repos/owner/name", http.StatusOK, map[string]interface{}{"default_branch": "main"})
+	api.handle("GET /repos/owner/name/commits/main", http.StatusOK, testBaseSHA)
+	api.handle("POST /repos/owner/name/git/trees", http.StatusCreated, map[string]interface{}{"sha": "tree-sha"})
+	api.handle("POST /repos/owner/name/git/commits", http.StatusCreated, map[string]interface{}{"sha": "commit-sha"})
+	api.handle("POST /repos/owner/name/git/refs", http.StatusCreated, map[string]interface{}{"ref": "refs/heads/marvin/update-1"})
+	api.handle("POST /repos/owner/name/pulls", http.StatusCreated, map[string]interface{}{"html_url": "https://github.com/owner/name/pull/1"})
+
+	p := newTestProvider(t, "https://github.com/owner/name", server.URL)
+
+	pullRequestUrl, err := p.(PullRequester).CreatePullRequest(context.Background(), "owner", "name", testPullRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pullRequestUrl != "https://github.com/owner/name/pull/1" {
+		t.Errorf("unexpected pull request url %s", pullRequestUrl)
+	}
+
+	expectedRoutes := []string{
+		"GET /repos/owner/name",
+		"GET /repos/owner/name/commits/main",
+		"POST /repos/owner/name/git/trees",
+		"POST /repos/owner/name/git/commits",
+		"POST /repos/owner/name/git/refs",
+		"POST /repos/owner/name/pulls",
+	}
+	if routes := api.routesOf(); !reflect.DeepEqual(routes, expectedRoutes) {
+		t.Fatalf("expected requests %v, got %v", expectedRoutes, routes)
+	}
+
+	tree := api.bodyOf("POST /repos/owner/name/git/trees")
+	if tree["base_tree"] != testBaseSHA {
+		t.Errorf("tree is not created on top of base commit: %v", tree["base_tree"])
+	}
+	entries, _ := tree["tree"].([]interface{})
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 tree entry, got %v", tree["tree"])
+	}
+	entry := entries[0].(map[string]interface{})
+	if entry["path"] != "web/package.json" || entry["content"] != `{"dependencies": {"react": "^17.0.1"}}` {
+		t.Errorf("unexpected tree entry %v", entry)
+	}
+
+	commit := api.bodyOf("POST /repos/owner/name/git/commits")
+	if commit["tree"] != "tree-sha" || !reflect.DeepEqual(commit["parents"], []interface{}{testBaseSHA}) {
+		t.Errorf("unexpected commit %v", commit)
+	}
+
+	ref := api.bodyOf("POST /repos/owner/name/git/refs")
+	if ref["ref"] != "refs/heads/marvin/update-1" || ref["sha"] != "commit-sha" {
+		t.Errorf("unexpected branch %v", ref)
+	}
+
+	pull := api.bodyOf("POST /repos/owner/name/pulls")
+	if pull["head"] != "marvin/update-1" || pull["base"] != "main" || pull["title"] != testPullRequest.Title {
+		t.Errorf("unexpected pull request %v", pull)
+	}
+}
+
+func TestGithubCreatePullRequestWithExistingBranch(t *testing.T) {
+
+	api, server := newFakeApi(t)
+	api.handle("GET /repos/owner/name/commits/release", http.StatusOK, testBaseSHA)
+	api.handle("POST /repos/owner/name/git/trees", http.StatusCreated, map[string]interface{}{"sha": "tree-sha"})
+	api.handle("POST /repos/owner/name/git/commits", http.StatusCreated, map[string]interface{}{"sha": "commit-sha"})
+	api.handle("POST /repos/owner/name/git/refs", http.StatusUnprocessableEntity, map[string]interface{}{"message": "Reference already exists"})
+
+	p := newTestProvider(t, "https://github.com/owner/name", server.URL)
+
+	request := *testPullRequest
+	request.Base = "release"
+
+	_, err := p.(PullRequester).CreatePullRequest(context.Background(), "owner", "name", &request)
+	if err == nil || !strings.Contains(err.Error(), "Reference already exists") {
+		t.Fatalf("expected error of provider, got %v", err)
+	}
+
+	for _, route := range api.routesOf() {
+		if route == "GET /repos/owner/name" || route == "POST /repos/owner/name/pulls" {
+			t.Errorf("unexpected request %s", route)
+		}
+	}
+}
+
+func TestGitlabCreatePullRequest(t *testing.T) {
+
+	api, server := newFakeApi(t)
+	api.handle("GET /projects/group%2Fsubgroup%2Fname", http.StatusOK, map[string]interface{}{"id": 7, "default_branch": "main"})
+	api.handle("POST /projects/7/repository/commits", http.StatusCreated, map[string]interface{}{"id": testBaseSHA})
+	api.handle("POST /projects/7/merge_requests", http.StatusCreated, map[string]interface{}{"web_url": "https://gitlab.com/group/subgroup/name/-/merge_requests/1"})
+
+	p := newTestProvider(t, "https://gitlab.com/group/subgroup/name", server.URL)
+
+	pullRequestUrl, err := p.(PullRequester).CreatePullRequest(context.Background(), "group/subgroup", "name", testPullRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pullRequestUrl != "https://gitlab.com/group/subgroup/name/-/merge_requests/1" {
+		t.Errorf("unexpected merge request url %s", pullRequestUrl)
+	}
+
+	expectedRoutes := []string{
+		"GET /projects/group%2Fsubgroup%2Fname",
+		"POST /projects/7/repository/commits",
+		"POST /projects/7/merge_requests",
+	}
+	if routes := api.routesOf(); !reflect.DeepEqual(routes, expectedRoutes) {
+		t.Fatalf("expected requests %v, got %v", expectedRoutes, routes)
+	}
+
+	commit := api.bodyOf("POST /projects/7/repository/commits")
+	if commit["branch"] != "marvin/update-1" || commit["start_branch"] != "main" {
+		t.Errorf("branch is not created from default branch: %v", commit)
+	}
+	actions, _ := commit["actions"].([]interface{})
+	if len(actions) != 1 {
+		t.Fatalf("expected 1 commit action, got %v", commit["actions"])
+	}
+	action := actions[0].(map[string]interface{})
+	if action["action"] != "update" || action["file_path"] != "web/package.json" {
+		t.Errorf("unexpected commit action %v", action)
+	}
+
+	mergeRequest := api.bodyOf("POST /projects/7/merge_requests")
+	if mergeRequest["source_branch"] != "marvin/update-1" || mergeRequest["target_branch"] != "main" {
+		t.Errorf("unexpected merge request %v", mergeRequest)
+	}
+}
+
+func TestGitlabCreatePullRequestWithError(t *testing.T) {
+
+	api, server := newFakeApi(t)
+	api.handle("GET /projects/group%2Fname", http.StatusOK, map[string]interface{}{"id": 7, "default_branch": "main"})
+	api.handle("POST /projects/7/repository/commits", http.StatusBadRequest, map[string]interface{}{"message": "A file with this name doesn't exist"})
+
+	p := newTestProvider(t, "https://gitlab.com/group/name", server.URL)
+
+	_, err := p.(PullRequester).CreatePullRequest(context.Background(), "group", "name", testPullRequest)
+	if err == nil || !strings.Contains(err.Error(), "doesn't exist") {
+		t.Fatalf("expected error of provider, got %v", err)
+	}
+
+	if routes := api.routesOf(); len(routes) != 2 {
+		t.Errorf("merge request is created after failed commit: %v", routes)
+	}
+}
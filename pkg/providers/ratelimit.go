@@ -0,0 +1,142 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Longest wait for exhausted quota, requests fail instead of waiting longer
+const maxRateLimitWait = time.Minute
+
+// Quota of provider's API, it is read from rate limit headers of last response
+type RateLimit struct {
+	Host      string    `json:"host"`
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Returned when quota of host is exhausted and reset is too far
+type RateLimitError struct {
+	Host  string
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit of %s is exhausted until %s", e.Host, e.Reset.Format(time.RFC3339))
+}
+
+// host -> *RateLimit
+var rateLimits sync.Map
+
+// Gets current quota of every host that is requested
+func RateLimits() []*RateLimit {
+
+	var limits []*RateLimit
+
+	rateLimits.Range(func(_, value interface{}) bool {
+		limit := *value.(*RateLimit)
+		limits = append(limits, &limit)
+		return true
+	})
+
+	sort.Slice(limits, func(i, j int) bool {
+		return limits[i].Host < limits[j].Host
+	})
+
+	return limits
+}
+
+// Saves quota of host from response headers
+// GitHub -> X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset
+// GitLab -> RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset
+func updateRateLimit(host string, header http.Header) {
+
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		remaining, err := strconv.Atoi(header.Get(prefix + "Remaining"))
+		if err != nil {
+			continue
+		}
+
+		limit, _ := strconv.Atoi(header.Get(prefix + "Limit"))
+		reset, _ := strconv.ParseInt(header.Get(prefix+"Reset"), 10, 64)
+
+		rateLimits.Store(host, &RateLimit{
+			Host:      host,
+			Limit:     limit,
+			Remaining: remaining,
+			Reset:     time.Unix(reset, 0).UTC(),
+			UpdatedAt: time.Now().UTC(),
+		})
+		return
+	}
+}
+
+// Waits until reset if quota of host is exhausted
+func waitForQuota(ctx context.Context, host string) error {
+
+	value, ok := rateLimits.Load(host)
+	if !ok {
+		return nil
+	}
+
+	limit := value.(*RateLimit)
+	if limit.Remaining > 0 {
+		return nil
+	}
+
+	wait := time.Until(limit.Reset)
+	if wait <= 0 {
+		return nil
+	}
+
+	if wait > maxRateLimitWait {
+		return &RateLimitError{Host: host, Reset: limit.Reset}
+	}
+
+	return sleep(ctx, wait)
+}
+
+// Sleeps until duration passes or context is cancelled
+func sleep(ctx context.Context, wait time.Duration) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Checks response is rejected because of rate limit and gets wait duration
+// Secondary rate limits of GitHub send Retry-After without exhausting quota
+func rateLimitWait(host string, statusCode int, header http.Header) (time.Duration, bool) {
+
+	if statusCode != http.StatusForbidden && statusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	value, ok := rateLimits.Load(host)
+	if !ok {
+		return 0, statusCode == http.StatusTooManyRequests
+	}
+
+	limit := value.(*RateLimit)
+	if limit.Remaining > 0 {
+		return 0, statusCode == http.StatusTooManyRequests
+	}
+
+	return time.Until(limit.Reset), true
+}
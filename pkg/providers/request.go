@@ -0,0 +1,229 @@
+package providers
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/nozgurozturk/marvin/pkg/client"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Maximum total size of responses that are kept with their ETags, trees of large repositories are megabytes
+const etagCacheBytes = 32 << 20
+
+type etagEntry struct {
+	key    string
+	etag   string
+	header http.Header
+	body   []byte
+}
+
+// Size of entry in cache, headers are counted with response body
+func (e *etagEntry) size() int {
+	size := len(e.key) + len(e.etag) + len(e.body)
+	for k, values := range e.header {
+		size += len(k)
+		for _, v := range values {
+			size += len(v)
+		}
+	}
+	return size
+}
+
+// Responses with ETags, unchanged trees and files are not downloaded again
+// Conditional requests that return 304 do not count against GitHub's quota
+// Least recently used responses are removed when cache is full, recent list starts with the most recent
+var etagCache = struct {
+	sync.Mutex
+	entries map[string]*list.Element
+	recent  *list.List
+	// Total size of entries
+	size int
+}{entries: map[string]*list.Element{}, recent: list.New()}
+
+// Gets body of response, see getResponse
+func get(ctx context.Context, baseUrl string, endpoint string, headers map[string]string, notFound error) ([]byte, error) {
+
+	response, err := getResponse(ctx, baseUrl, endpoint, headers, notFound)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Body, nil
+}
+
+// Headers are returned for pagination links, headers of cached response are returned when it is not modified
+/*
+	1. Wait For Quota -> host's quota is exhausted
+	2. Send Request -> with If-None-Match of cached response
+	3. Save Quota -> from rate limit headers
+	4. Check Status -> cached body, retry after rate limit, not found error of caller or error
+	5. Cache Response -> with its ETag
+*/
+func getResponse(ctx context.Context, baseUrl string, endpoint string, headers map[string]string, notFound error) (*client.Response, error) {
+
+	requestUrl := baseUrl + endpoint
+	host := hostOf(requestUrl)
+	key := cacheKey(requestUrl, headers)
+
+	for attempt := 0; ; attempt++ {
+
+		if err := waitForQuota(ctx, host); err != nil {
+			return nil, err
+		}
+
+		requestHeaders := map[string]string{}
+		for k, v := range headers {
+			requestHeaders[k] = v
+		}
+
+		cached := loadEtag(key)
+		if cached != nil {
+			requestHeaders["If-None-Match"] = cached.etag
+		}
+
+		response, err := client.New(baseUrl).GetResponse(ctx, endpoint, requestHeaders)
+
+		if statusErr, ok := err.(*client.StatusError); ok {
+			updateRateLimit(host, statusErr.Header)
+
+			if wait, limited := rateLimitWait(host, statusErr.StatusCode, statusErr.Header); limited {
+				// Request is retried once if reset is close
+				if attempt == 0 && wait <= maxRateLimitWait {
+					if err := sleep(ctx, wait); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				return nil, &RateLimitError{Host: host, Reset: time.Now().Add(wait).UTC()}
+			}
+
+			if statusErr.StatusCode == http.StatusNotFound {
+				return nil, notFound
+			}
+
+			return nil, fmt.Errorf("%s responded with %d: %s", host, statusErr.StatusCode, errorMessage(statusErr.Body))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		updateRateLimit(host, response.Header)
+
+		if response.StatusCode == http.StatusNotModified && cached != nil {
+			return &client.Response{StatusCode: http.StatusOK, Header: cached.header, Body: cached.body}, nil
+		}
+
+		if etag := response.Header.Get("ETag"); etag != "" {
+			storeEtag(key, &etagEntry{etag: etag, header: response.Header, body: response.Body})
+		}
+
+		return response, nil
+	}
+}
+
+func loadEtag(key string) *etagEntry {
+	etagCache.Lock()
+	defer etagCache.Unlock()
+
+	element, ok := etagCache.entries[key]
+	if !ok {
+		return nil
+	}
+	etagCache.recent.MoveToFront(element)
+	return element.Value.(*etagEntry)
+}
+
+func storeEtag(key string, entry *etagEntry) {
+	etagCache.Lock()
+	defer etagCache.Unlock()
+
+	entry.key = key
+	if element, ok := etagCache.entries[key]; ok {
+		etagCache.recent.Remove(element)
+		delete(etagCache.entries, key)
+		etagCache.size -= element.Value.(*etagEntry).size()
+	}
+
+	// Responses that are larger than cache would remove every response
+	if entry.size() > etagCacheBytes {
+		return
+	}
+
+	for etagCache.size+entry.size() > etagCacheBytes {
+		oldest := etagCache.recent.Back()
+		etagCache.recent.Remove(oldest)
+		delete(etagCache.entries, oldest.Value.(*etagEntry).key)
+		etagCache.size -= oldest.Value.(*etagEntry).size()
+	}
+
+	etagCache.entries[key] = etagCache.recent.PushFront(entry)
+	etagCache.size += entry.size()
+}
+
+// Headers that contain access tokens of providers
+var credentialHeaders = map[string]bool{
+	"Authorization": true,
+	"PRIVATE-TOKEN": true,
+}
+
+// Responses are cached for url and headers, so different tokens and media types do not share responses
+// Tokens are hashed, so cache does not keep them in memory
+func cacheKey(requestUrl string, headers map[string]string) string {
+	keys := make([]string, 0, len(headers))
+	for k, v := range headers {
+		if credentialHeaders[k] {
+			sum := sha256.Sum256([]byte(v))
+			v = hex.EncodeToString(sum[:])
+		}
+		keys = append(keys, k+"="+v)
+	}
+	sort.Strings(keys)
+	return requestUrl + "\x00" + strings.Join(keys, "\x00")
+}
+
+// Gets endpoint of next page from Link header, it is empty on last page
+// Links to other hosts are not followed
+func nextPage(header http.Header, baseUrl string) string {
+	for _, link := range strings.Split(header.Get("Link"), ",") {
+		parts := strings.Split(link, ";")
+		target := strings.Trim(strings.TrimSpace(parts[0]), "<>")
+		for _, param := range parts[1:] {
+			if strings.TrimSpace(param) == `rel="next"` && strings.HasPrefix(target, baseUrl+"/") {
+				return strings.TrimPrefix(target, baseUrl)
+			}
+		}
+	}
+	return ""
+}
+
+func hostOf(requestUrl string) string {
+	u, err := url.Parse(requestUrl)
+	if err != nil {
+		return requestUrl
+	}
+	return u.Host
+}
+
+// Gets message of provider's error body, body is returned as it is if it is not json
+func errorMessage(body []byte) string {
+	var response map[string]interface{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return string(body)
+	}
+	if message, ok := response["message"]; ok {
+		return fmt.Sprint(message)
+	}
+	if message, ok := response["error"]; ok {
+		return fmt.Sprint(message)
+	}
+	return string(body)
+}
@@ -0,0 +1,212 @@
+package providers
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNotFoundErrors(t *testing.T) {
+
+	// Every missing resource responds 404 like providers do
+	api, server := newFakeApi(t)
+	api.handle("GET /projects/group%2Fname", http.StatusOK, map[string]interface{}{"id": 7, "default_branch": "main"})
+
+	github := newTestProvider(t, "https://github.com/owner/name", server.URL)
+	gitlab := newTestProvider(t, "https://gitlab.com/group/name", server.URL)
+
+	tests := []struct {
+		name     string
+		request  func() error
+		expected error
+	}{
+		{"github repository", func() error {
+			_, err := github.GetHeadSHA(context.Background(), "owner", "name", "main")
+			return err
+		}, ErrRepositoryNotFound},
+		{"github tree", func() error {
+			_, err := github.GetRepositoryTree(context.Background(), "owner", "name", testBaseSHA)
+			return err
+		}, ErrRefNotFound},
+		{"github file", func() error {
+			_, err := github.(PullRequester).GetFile(context.Background(), "owner", "name", "main", "web/package.json")
+			return err
+		}, ErrFileNotFound},
+		{"github blob", func() error {
+			_, err := github.GetPackageFiles(context.Background(), []map[string]interface{}{
+				{"url": server.URL + "/repos/owner/name/git/blobs/a", "name": "package.json", "path": "package.json"},
+			})
+			return err
+		}, ErrFileNotFound},
+		{"gitlab project", func() error {
+			_, err := gitlab.GetHeadSHA(context.Background(), "group", "other", "main")
+			return err
+		}, ErrRepositoryNotFound},
+		{"gitlab ref", func() error {
+			_, err := gitlab.GetHeadSHA(context.Background(), "group", "name", "missing")
+			return err
+		}, ErrRefNotFound},
+		{"gitlab tree", func() error {
+			_, err := gitlab.GetRepositoryTree(context.Background(), "group", "name", "missing")
+			return err
+		}, ErrRefNotFound},
+		{"gitlab file", func() error {
+			_, err := gitlab.(PullRequester).GetFile(context.Background(), "group", "name", "main", "composer.json")
+			return err
+		}, ErrFileNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.request(); err != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+// Replaces shared cache with empty one during test
+func resetEtagCache(t *testing.T) {
+	etagCache.Lock()
+	entries, recent, size := etagCache.entries, etagCache.recent, etagCache.size
+	etagCache.entries, etagCache.recent, etagCache.size = map[string]*list.Element{}, list.New(), 0
+	etagCache.Unlock()
+
+	t.Cleanup(func() {
+		etagCache.Lock()
+		etagCache.entries, etagCache.recent, etagCache.size = entries, recent, size
+		etagCache.Unlock()
+	})
+}
+
+func TestEtagCacheEvictsLeastRecentlyUsed(t *testing.T) {
+
+	resetEtagCache(t)
+
+	// Four responses fill the cache
+	body := make([]byte, etagCacheBytes/4-8)
+	for i := 0; i < 4; i++ {
+		storeEtag(fmt.Sprint(i), &etagEntry{etag: fmt.Sprint(i), body: body})
+	}
+
+	// Oldest response is used again, so the second one is the least recently used
+	if entry := loadEtag("0"); entry == nil || entry.etag != "0" {
+		t.Fatalf("expected cached response, got %v", entry)
+	}
+	// Replaced response is not counted twice
+	storeEtag("2", &etagEntry{etag: "2-new", body: body})
+
+	storeEtag("new", &etagEntry{etag: "new", body: body})
+
+	if loadEtag("1") != nil {
+		t.Error("expected least recently used response to be removed")
+	}
+	for _, key := range []string{"0", "new", "3"} {
+		if loadEtag(key) == nil {
+			t.Errorf("expected response %s to be kept", key)
+		}
+	}
+	if entry := loadEtag("2"); entry == nil || entry.etag != "2-new" {
+		t.Errorf("expected replaced response, got %v", entry)
+	}
+
+	// Response larger than cache is not kept and does not remove others
+	storeEtag("large", &etagEntry{etag: "large", body: make([]byte, etagCacheBytes)})
+	if loadEtag("large") != nil || len(etagCache.entries) != 4 || etagCache.recent.Len() != 4 {
+		t.Errorf("expected large response not to be cached, got %d entries and %d recent", len(etagCache.entries), etagCache.recent.Len())
+	}
+
+	size := 0
+	for element := etagCache.recent.Front(); element != nil; element = element.Next() {
+		size += element.Value.(*etagEntry).size()
+	}
+	if size > etagCacheBytes || etagCache.size != size {
+		t.Errorf("expected %d bytes in cache, got %d", size, etagCache.size)
+	}
+}
+
+func TestCacheKeyHashesTokens(t *testing.T) {
+
+	key := cacheKey("https://api.github.com/repos/owner/name", map[string]string{"Authorization": "token secret", "Accept": "application/json"})
+	if strings.Contains(key, "secret") {
+		t.Errorf("expected token not to be in cache key, got %q", key)
+	}
+	if key != cacheKey("https://api.github.com/repos/owner/name", map[string]string{"Accept": "application/json", "Authorization": "token secret"}) {
+		t.Error("expected same key for same token")
+	}
+
+	tokens := []map[string]string{
+		{"Accept": "application/json"},
+		{"Accept": "application/json", "Authorization": "token other"},
+		{"Accept": "application/json", "PRIVATE-TOKEN": "token secret"},
+	}
+	for _, headers := range tokens {
+		if key == cacheKey("https://api.github.com/repos/owner/name", headers) {
+			t.Errorf("expected different key for %v", headers)
+		}
+	}
+	if other := cacheKey("https://gitlab.com/api/v4/projects/1", map[string]string{"PRIVATE-TOKEN": "secret"}); strings.Contains(other, "secret") {
+		t.Errorf("expected gitlab token not to be in cache key, got %q", other)
+	}
+}
+
+func TestGetWithEtag(t *testing.T) {
+
+	resetEtagCache(t)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	for i := 0; i < 2; i++ {
+		body, err := get(context.Background(), server.URL, "/file", nil, ErrFileNotFound)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != "content" {
+			t.Errorf("expected body of response, got %q", body)
+		}
+	}
+	if requests != 2 {
+		t.Errorf("expected conditional request, got %d requests", requests)
+	}
+}
+
+func TestNextPage(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		link     string
+		expected string
+	}{
+		{"next page", `<https://api.github.com/repos?page=2>; rel="next", <https://api.github.com/repos?page=5>; rel="last"`, "/repos?page=2"},
+		{"next page is not first", `<https://api.github.com/repos?page=1>; rel="prev", <https://api.github.com/repos?page=3>; rel="next"`, "/repos?page=3"},
+		{"last page", `<https://api.github.com/repos?page=1>; rel="first", <https://api.github.com/repos?page=4>; rel="prev"`, ""},
+		{"other host", `<https://example.com/repos?page=2>; rel="next"`, ""},
+		{"without link", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.link != "" {
+				header.Set("Link", tt.link)
+			}
+			if next := nextPage(header, "https://api.github.com"); next != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, next)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package utils
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 // Compares semantic version of packages
 // 1.0.0 -> major.minor.patch
@@ -23,3 +27,99 @@ func CompareVersions(latestVersion string, currentVersion string) bool {
 	}
 	return false
 }
+
+// Compares version numbers of constraints, range operators are skipped
+// Returns -1 if a is older, 1 if a is newer and 0 if numbers are same
+func CompareVersionNumbers(a string, b string) int {
+	aNumbers := versionNumbers(a)
+	bNumbers := versionNumbers(b)
+
+	for i := range aNumbers {
+		if aNumbers[i] < bNumbers[i] {
+			return -1
+		}
+		if aNumbers[i] > bNumbers[i] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// Checks version is in range, comparators are separated by spaces and alternatives by ||
+// >=2.0.0 <3.0.0, ^1.2.0, ~1.2, 3.x, 1.0.0 || 2.0.0
+func SatisfiesRange(version string, versionRange string) bool {
+	for _, alternative := range strings.Split(versionRange, "||") {
+		comparators := strings.Fields(alternative)
+		if len(comparators) == 0 {
+			continue
+		}
+
+		satisfied := true
+		for _, comparator := range comparators {
+			if !satisfiesComparator(version, comparator) {
+				satisfied = false
+				break
+			}
+		}
+
+		if satisfied {
+			return true
+		}
+	}
+	return false
+}
+
+func satisfiesComparator(version string, comparator string) bool {
+	operatorEnd := strings.IndexFunc(comparator, func(r rune) bool {
+		return !strings.ContainsRune("<>=^~", r)
+	})
+	if operatorEnd < 0 {
+		return false
+	}
+
+	operator, target := comparator[:operatorEnd], strings.TrimPrefix(comparator[operatorEnd:], "v")
+	compared := CompareVersionNumbers(version, target)
+	versionParts, targetParts := versionNumbers(version), versionNumbers(target)
+
+	switch operator {
+	case ">":
+		return compared > 0
+	case ">=":
+		return compared >= 0
+	case "<":
+		return compared < 0
+	case "<=":
+		return compared <= 0
+	case "^":
+		// Major is kept, minor is kept for 0.x versions
+		if targetParts[0] == 0 {
+			return compared >= 0 && versionParts[0] == 0 && versionParts[1] == targetParts[1]
+		}
+		return compared >= 0 && versionParts[0] == targetParts[0]
+	case "~":
+		return compared >= 0 && versionParts[0] == targetParts[0] && versionParts[1] == targetParts[1]
+	case "", "=":
+		return matchesWildcard(version, target)
+	}
+	return false
+}
+
+// Missing segments and x, X or * segments match every number, 2 and 2.x.x are same
+func matchesWildcard(version string, pattern string) bool {
+	versionParts := versionNumbers(version)
+	segments := strings.Split(strings.SplitN(pattern, "-", 2)[0], ".")
+
+	for i, segment := range segments {
+		if i >= len(versionParts) {
+			break
+		}
+		if segment == "x" || segment == "X" || segment == "*" {
+			continue
+		}
+		number, err := strconv.Atoi(segment)
+		if err != nil || number != versionParts[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Kinds of version updates
+const (
+	Major = "major"
+	Minor = "minor"
+	Patch = "patch"
+)
+
+// Range operators that can be kept while bumping version constraint
+var bumpablePrefixes = map[string]bool{
+	"":   true,
+	"^":  true,
+	"~":  true,
+	"=":  true,
+	">=": true,
+	"v":  true,
+}
+
+// Gets kind of update from current version constraint to latest version
+func UpdateKind(currentVersion string, latestVersion string) string {
+	current := versionNumbers(currentVersion)
+	latest := versionNumbers(latestVersion)
+
+	if latest[0] != current[0] {
+		return Major
+	} else if latest[1] != current[1] {
+		return Minor
+	}
+	return Patch
+}
+
+// Bumps version constraint to latest version, range operator and precision of constraint are kept
+// ^1.2.3 -> ^2.0.1, ~7.4 -> ~8.1, 1.x -> 2.x
+// Complex ranges like ">=1.0 <2.0" or "1.0 || 2.0" can not be bumped
+func BumpVersion(constraint string, latestVersion string) (string, bool) {
+
+	versionStart := strings.IndexFunc(constraint, unicode.IsDigit)
+	if versionStart < 0 || latestVersion == "" {
+		return "", false
+	}
+
+	prefix, version := constraint[:versionStart], constraint[versionStart:]
+	if !bumpablePrefixes[prefix] || strings.ContainsAny(version, " |,<>") {
+		return "", false
+	}
+
+	segments := strings.Split(version, ".")
+
+	// Full versions and versions with pre-release or build suffix are replaced completely
+	if len(segments) >= 3 && !strings.ContainsAny(version, "*xX") || strings.ContainsAny(version, "-+") {
+		return prefix + latestVersion, true
+	}
+
+	latest := strings.Split(strings.SplitN(latestVersion, "-", 2)[0], ".")
+	for i, segment := range segments {
+		// Wildcards are kept
+		if segment == "*" || segment == "x" || segment == "X" {
+			continue
+		}
+		if i < len(latest) {
+			segments[i] = latest[i]
+		}
+	}
+
+	return prefix + strings.Join(segments, "."), true
+}
+
+// Parses major, minor and patch numbers of version, range operators are skipped
+func versionNumbers(version string) [3]int {
+	var numbers [3]int
+	version = strings.TrimLeft(version, "^~>=<v ")
+	fmt.Sscanf(version, "%d.%d.%d", &numbers[0], &numbers[1], &numbers[2])
+	return numbers
+}
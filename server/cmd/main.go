@@ -1,6 +1,7 @@
 package main
 
 import (
+	"github.com/nozgurozturk/marvin/pkg/client"
 	_ "github.com/nozgurozturk/marvin/server/docs"
 	"github.com/nozgurozturk/marvin/server/internal/config"
 	"github.com/nozgurozturk/marvin/server/internal/router"
@@ -24,6 +25,12 @@ import (
 func main() {
 
 	cnf := config.Set()
+
+	// Provider and registry requests use proxy and TLS settings
+	if err := client.Configure(cnf.Outbound); err != nil {
+		log.Fatal(err)
+	}
+
 	mongo, err := storage.MongoConnect()
 	if err != nil {
 		return
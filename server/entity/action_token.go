@@ -0,0 +1,36 @@
+package entity
+
+// Action of link that is sent by email
+type TokenAction string
+
+const (
+	ActionResetPassword  TokenAction = "reset-password"
+	ActionChangeEmail    TokenAction = "change-email"
+	ActionConfirmAccount TokenAction = "confirm-account"
+)
+
+// Single use token of email links, it is kept until link is used or expired
+type ActionToken struct {
+	UserID string `json:"userID"`
+	// New address of email change, it is empty for password resets
+	Email string `json:"email,omitempty"`
+}
+
+type ForgotPassword struct {
+	Email string `json:"email"`
+}
+
+type ResetPassword struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type EmailChange struct {
+	Email string `json:"email"`
+	// Current password, it is not required for users without password
+	Password string `json:"password"`
+}
+
+type ResendConfirmation struct {
+	Email string `json:"email"`
+}
@@ -0,0 +1,105 @@
+package entity
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"time"
+)
+
+type APITokenScope string
+
+const (
+	// Read scope allows GET and HEAD requests
+	ScopeRead APITokenScope = "read"
+	// Write scope allows every request
+	ScopeWrite APITokenScope = "write"
+)
+
+// Every API token starts with prefix, so they are told apart from JWTs
+const APITokenPrefix = "mvn_"
+
+func (s APITokenScope) IsValid() bool {
+	return s == ScopeRead || s == ScopeWrite
+}
+
+// Long lived token for CI jobs, only hash of token is stored
+type APIToken struct {
+	ID     primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID primitive.ObjectID `json:"userID" bson:"userID"`
+	Name   string             `json:"name" bson:"name"`
+	Scopes []APITokenScope    `json:"scopes" bson:"scopes"`
+	Hash   string             `json:"-" bson:"hash"`
+	// First characters of token to recognize it in list
+	Prefix     string     `json:"prefix" bson:"prefix"`
+	LastUsedAt *time.Time `json:"lastUsedAt" bson:"lastUsedAt,omitempty"`
+	ExpiresAt  *time.Time `json:"expiresAt" bson:"expiresAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt" bson:"createdAt"`
+}
+
+type APITokenDTO struct {
+	ID         string          `json:"id"`
+	Name       string          `json:"name"`
+	Scopes     []APITokenScope `json:"scopes"`
+	Prefix     string          `json:"prefix"`
+	LastUsedAt *time.Time      `json:"lastUsedAt"`
+	ExpiresAt  *time.Time      `json:"expiresAt"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	// Token is only returned when it is created
+	Token string `json:"token,omitempty"`
+}
+
+type APITokenRequest struct {
+	Name   string          `json:"name"`
+	Scopes []APITokenScope `json:"scopes"`
+	// Token does not expire if it is zero
+	ExpiresInDays int `json:"expiresInDays"`
+}
+
+func (t *APIToken) IsExpired() bool {
+	return t.ExpiresAt != nil && time.Now().UTC().After(*t.ExpiresAt)
+}
+
+func (t *APIToken) HasScope(scope APITokenScope) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks scopes of token allow request method
+func (t *APIToken) Allows(method string) bool {
+	for _, scope := range t.Scopes {
+		if scope == ScopeWrite {
+			return true
+		}
+		if scope == ScopeRead && (method == http.MethodGet || method == http.MethodHead) {
+			return true
+		}
+	}
+	return false
+}
+
+func ToAPITokenDTO(token *APIToken) *APITokenDTO {
+	return &APITokenDTO{
+		ID:         token.ID.Hex(),
+		Name:       token.Name,
+		Scopes:     token.Scopes,
+		Prefix:     token.Prefix,
+		LastUsedAt: token.LastUsedAt,
+		ExpiresAt:  token.ExpiresAt,
+		CreatedAt:  token.CreatedAt,
+	}
+}
+
+func ToAPITokenDTOs(tokens []*APIToken) []*APITokenDTO {
+
+	tokenDTOs := make([]*APITokenDTO, len(tokens))
+
+	for i, item := range tokens {
+		tokenDTOs[i] = ToAPITokenDTO(item)
+	}
+
+	return tokenDTOs
+}
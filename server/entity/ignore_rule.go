@@ -0,0 +1,36 @@
+package entity
+
+import "time"
+
+// Outdated packages that match rule are ignored, every condition that is set must match
+type IgnoreRule struct {
+	ID string `json:"id" bson:"id"`
+	// Package name or glob, for exp. lodash or @types/*
+	Package string `json:"package" bson:"package"`
+	// Updates of these kinds are ignored, for exp. major
+	UpdateKinds []string `json:"updateKinds,omitempty" bson:"updateKinds,omitempty"`
+	// Latest versions in range are ignored, for exp. >=5.0.0 <6.0.0
+	Versions string `json:"versions,omitempty" bson:"versions,omitempty"`
+	// Package is snoozed until date
+	SnoozeUntil *time.Time `json:"snoozeUntil,omitempty" bson:"snoozeUntil,omitempty"`
+	// Package is snoozed until a version newer than this is released
+	UntilVersion string    `json:"untilVersion,omitempty" bson:"untilVersion,omitempty"`
+	Reason       string    `json:"reason" bson:"reason"`
+	CreatedAt    time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// Rule that package is ignored with, snoozed packages are outdated again after until
+type IgnoredPackage struct {
+	RuleID string     `json:"ruleID" bson:"ruleID"`
+	Reason string     `json:"reason" bson:"reason"`
+	Until  *time.Time `json:"until,omitempty" bson:"until,omitempty"`
+}
+
+type IgnoreRuleRequest struct {
+	Package      string     `json:"package"`
+	UpdateKinds  []string   `json:"updateKinds"`
+	Versions     string     `json:"versions"`
+	SnoozeUntil  *time.Time `json:"snoozeUntil"`
+	UntilVersion string     `json:"untilVersion"`
+	Reason       string     `json:"reason"`
+}
@@ -0,0 +1,24 @@
+package entity
+
+// Content of package file, lock file or workspace descriptor with its path in repository
+type ManifestFile struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+type ManifestScanRequest struct {
+	Files []*ManifestFile `json:"files"`
+	// Packages are saved as snapshot of repository if it is given
+	RepoID    string `json:"repoID,omitempty"`
+	Ref       string `json:"ref,omitempty"`
+	CommitSHA string `json:"commitSHA,omitempty"`
+}
+
+// Packages of uploaded package files with their registry versions
+type ManifestScanDTO struct {
+	Packages []*Package `json:"packages"`
+	// Files that can not be parsed, for exp. yarn.lock
+	Unsupported []string       `json:"unsupported"`
+	Failures    []*ScanFailure `json:"failures"`
+	Snapshot    *SnapshotDTO   `json:"snapshot,omitempty"`
+}
@@ -0,0 +1,29 @@
+package entity
+
+// Account of user in OAuth provider
+type Identity struct {
+	// github or gitlab
+	Provider string `json:"provider" bson:"provider"`
+	// Host of repositories that are read with token
+	Host     string `json:"host" bson:"host"`
+	ID       string `json:"id" bson:"id"`
+	Username string `json:"username" bson:"username"`
+	// Granted token, it is saved only if user allows repository access
+	Token string `json:"-" bson:"token,omitempty"`
+}
+
+// User info that is returned by OAuth provider
+type OAuthProfile struct {
+	ID            string
+	Username      string
+	Name          string
+	Email         string
+	EmailVerified bool
+}
+
+// State of authorization request, it is kept until provider redirects back
+type OAuthState struct {
+	Provider string `json:"provider"`
+	// Granted token is saved for private repositories
+	RepoAccess bool `json:"repoAccess"`
+}
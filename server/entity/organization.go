@@ -0,0 +1,129 @@
+package entity
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+type Role string
+
+// Roles of organization members, every role can do what lower roles can do
+const (
+	// Owners manage members, tokens and secrets and delete repositories
+	RoleOwner Role = "owner"
+	// Maintainers add, scan and update repositories and their subscribers
+	RoleMaintainer Role = "maintainer"
+	// Viewers read repositories, scans and subscribers
+	RoleViewer Role = "viewer"
+)
+
+var roleRanks = map[Role]int{
+	RoleViewer:     1,
+	RoleMaintainer: 2,
+	RoleOwner:      3,
+}
+
+func (r Role) IsValid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// Checks role has permissions of given role
+func (r Role) Includes(role Role) bool {
+	return roleRanks[r] >= roleRanks[role]
+}
+
+type Member struct {
+	UserID primitive.ObjectID `json:"userID" bson:"userID"`
+	Role   Role               `json:"role" bson:"role"`
+}
+
+// Repositories of organization are shared with its members
+type Organization struct {
+	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	Name      string             `json:"name" bson:"name"`
+	Members   []*Member          `json:"members" bson:"members"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+type MemberDTO struct {
+	UserID string `json:"userID"`
+	Role   Role   `json:"role"`
+}
+
+type OrganizationDTO struct {
+	ID        string       `json:"id"`
+	Name      string       `json:"name"`
+	Members   []*MemberDTO `json:"members"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+type OrganizationRequest struct {
+	Name string `json:"name"`
+}
+
+type MemberRequest struct {
+	// Email of user, it is used when member is added
+	Email string `json:"email,omitempty"`
+	Role  Role   `json:"role"`
+}
+
+// Role of user in organization, empty role is returned if user is not a member
+func (o *Organization) RoleOf(userID string) Role {
+	for _, member := range o.Members {
+		if member.UserID.Hex() == userID {
+			return member.Role
+		}
+	}
+	return ""
+}
+
+// Role of user in organization, empty role is returned if user is not a member
+func (o *OrganizationDTO) RoleOf(userID string) Role {
+	for _, member := range o.Members {
+		if member.UserID == userID {
+			return member.Role
+		}
+	}
+	return ""
+}
+
+// Counts owners, organizations can not be left without an owner
+func (o *Organization) OwnerCount() int {
+	count := 0
+	for _, member := range o.Members {
+		if member.Role == RoleOwner {
+			count++
+		}
+	}
+	return count
+}
+
+func ToOrganizationDTOs(organizations []*Organization) []*OrganizationDTO {
+
+	organizationDTOs := make([]*OrganizationDTO, len(organizations))
+
+	for i, item := range organizations {
+		organizationDTOs[i] = ToOrganizationDTO(item)
+	}
+
+	return organizationDTOs
+}
+
+func ToOrganizationDTO(organization *Organization) *OrganizationDTO {
+
+	members := make([]*MemberDTO, len(organization.Members))
+	for i, member := range organization.Members {
+		members[i] = &MemberDTO{
+			UserID: member.UserID.Hex(),
+			Role:   member.Role,
+		}
+	}
+
+	return &OrganizationDTO{
+		ID:        organization.ID.Hex(),
+		Name:      organization.Name,
+		Members:   members,
+		CreatedAt: organization.CreatedAt,
+	}
+}
@@ -0,0 +1,45 @@
+package entity
+
+// Checks of CI gate, checks that are not set are skipped
+type Policy struct {
+	// Outdated packages whose updates are major
+	MaxOutdatedMajors *int `json:"maxOutdatedMajors,omitempty"`
+	// Deprecated versions and abandoned packages are not allowed
+	NoDeprecated bool `json:"noDeprecated,omitempty"`
+	// Advisories above severity are not allowed, none does not allow any advisory
+	MaxSeverity string `json:"maxSeverity,omitempty"`
+	// Sum of years between installed versions and latest versions
+	MaxLibyears *float64 `json:"maxLibyears,omitempty"`
+}
+
+// Packages of repository or uploaded package files are checked
+type PolicyRequest struct {
+	RepoID string          `json:"repoID,omitempty"`
+	Files  []*ManifestFile `json:"files,omitempty"`
+	Policy *Policy         `json:"policy"`
+}
+
+type PolicyViolation struct {
+	// Check that is violated, for exp. maxOutdatedMajors
+	Check   string `json:"check"`
+	Package string `json:"package,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+	Url     string `json:"url,omitempty"`
+}
+
+type PolicySummary struct {
+	Packages        int     `json:"packages"`
+	OutdatedMajors  int     `json:"outdatedMajors"`
+	Deprecated      int     `json:"deprecated"`
+	Vulnerabilities int     `json:"vulnerabilities"`
+	Libyears        float64 `json:"libyears"`
+}
+
+type PolicyResult struct {
+	Passed     bool               `json:"passed"`
+	Summary    *PolicySummary     `json:"summary"`
+	Violations []*PolicyViolation `json:"violations"`
+	// Packages whose registry details or advisories can not be resolved
+	Failures []*ScanFailure `json:"failures"`
+}
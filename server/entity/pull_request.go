@@ -0,0 +1,35 @@
+package entity
+
+// Package that is selected for update, package is updated in all package files if path is empty
+type PackageSelector struct {
+	Name string `json:"name"`
+	Path string `json:"path,omitempty"`
+}
+
+type PullRequestRequest struct {
+	ID string `json:"id"`
+	// All outdated packages are updated if it is empty
+	Packages []*PackageSelector `json:"packages,omitempty"`
+}
+
+type RepoTokenRequest struct {
+	ID    string `json:"id"`
+	Token string `json:"token"`
+}
+
+// Version bump of package in pull request
+type PackageUpdate struct {
+	Name         string `json:"name"`
+	Path         string `json:"path"`
+	File         string `json:"file"`
+	From         string `json:"from"`
+	To           string `json:"to"`
+	Kind         string `json:"kind"`
+	ChangelogUrl string `json:"changelogUrl,omitempty"`
+}
+
+type PullRequestDTO struct {
+	Url     string           `json:"url"`
+	Branch  string           `json:"branch"`
+	Updates []*PackageUpdate `json:"updates"`
+}
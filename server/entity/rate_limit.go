@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+// Number of requests that are allowed in window
+type RateLimit struct {
+	Name   string
+	Max    int64
+	Window time.Duration
+}
+
+// Requests that are counted in current window
+type RateLimitUsage struct {
+	Count int64
+	// Remaining time of window, limit is reset after it
+	ResetIn time.Duration
+}
+
+// Remaining returns number of requests that are allowed until window is reset
+func (u *RateLimitUsage) Remaining(limit RateLimit) int64 {
+	if u.Count >= limit.Max {
+		return 0
+	}
+	return limit.Max - u.Count
+}
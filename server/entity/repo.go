@@ -8,34 +8,99 @@ import (
 type PackageVersion struct {
 	Current string `json:"current" bson:"current"`
 	Last    string `json:"last" bson:"last"`
+	// Installed version in lock file of package file
+	Locked string `json:"locked,omitempty" bson:"locked,omitempty"`
 }
 
 type Package struct {
 	Name       string         `json:"name" bson:"name"`
 	Version    PackageVersion `json:"version" bson:"version"`
 	File       string         `json:"file" bson:"file"`
+	Path       string         `json:"path" bson:"path"`
 	IsOutdated bool           `json:"isOutdated" bson:"isOutdated"`
+	// License that is declared in lock file
+	License string `json:"license,omitempty" bson:"license,omitempty"`
+	// Outdated package that is ignored with a rule, it is not counted as outdated
+	Ignored *IgnoredPackage `json:"ignored,omitempty" bson:"ignored,omitempty"`
+	// Deprecation message of installed version in registry
+	Deprecated string `json:"deprecated,omitempty" bson:"deprecated,omitempty"`
+	// Security advisories that affect installed version
+	Advisories []*Advisory `json:"advisories,omitempty" bson:"advisories,omitempty"`
+}
+
+// Security advisory of OSV database
+type Advisory struct {
+	ID string `json:"id" bson:"id"`
+	// low, moderate, high or critical
+	Severity string `json:"severity" bson:"severity"`
+	Summary  string `json:"summary" bson:"summary"`
+	Url      string `json:"url" bson:"url"`
+}
+
+// Blob sha of package file at scanned commit
+type ManifestBlob struct {
+	Path string `json:"path" bson:"path"`
+	SHA  string `json:"sha" bson:"sha"`
+}
+
+// Changes that are found in last scan of repository
+type ScanResult struct {
+	ScannedAt time.Time `json:"scannedAt" bson:"scannedAt"`
+	// Package files are downloaded and parsed again
+	ManifestChanged bool `json:"manifestChanged" bson:"manifestChanged"`
+	// Latest version of at least one package is changed in registry
+	RegistryChanged bool `json:"registryChanged" bson:"registryChanged"`
 }
 
 type Repo struct {
-	ID          primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
-	UserID      primitive.ObjectID `json:"userID" bson:"userID"`
-	Name        string             `json:"name" bson:"name"`
-	Owner       string             `json:"owner" bson:"owner"`
-	Path        string             `json:"path" bson:"path"`
-	Provider    string             `json:"provider" bson:"provider"`
-	PackageList []*Package         `json:"packageList, omitempty" bson:"packageList,omitempty"`
-	CreatedAt   time.Time          `json:"createdAt" bson:"createdAt"`
+	ID     primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID primitive.ObjectID `json:"userID" bson:"userID"`
+	// Repositories of organizations are shared with members, user is creator of repository
+	OrgID       *primitive.ObjectID `json:"orgID,omitempty" bson:"orgID,omitempty"`
+	Name        string              `json:"name" bson:"name"`
+	Owner       string              `json:"owner" bson:"owner"`
+	Path        string              `json:"path" bson:"path"`
+	Ref         string              `json:"ref" bson:"ref"`
+	Provider    string              `json:"provider" bson:"provider"`
+	PackageList []*Package          `json:"packageList, omitempty" bson:"packageList,omitempty"`
+	// Secret of push webhooks, it signs or authorizes incoming events
+	WebhookSecret string `json:"webhookSecret" bson:"webhookSecret"`
+	// Secret of public badge url, repositories are user owned
+	BadgeToken string `json:"badgeToken" bson:"badgeToken,omitempty"`
+	// Access token of provider, it is never sent to clients
+	Token string `json:"-" bson:"token,omitempty"`
+	// User that saved token, token is only used for actions of this user and background scans
+	TokenUserID *primitive.ObjectID `json:"tokenUserID,omitempty" bson:"tokenUserID,omitempty"`
+	// Commit and package file blobs of last scan
+	CommitSHA     string          `json:"commitSHA" bson:"commitSHA"`
+	ManifestBlobs []*ManifestBlob `json:"manifestBlobs" bson:"manifestBlobs"`
+	LastScan      *ScanResult     `json:"lastScan" bson:"lastScan,omitempty"`
+	IgnoreRules   []*IgnoreRule   `json:"ignoreRules" bson:"ignoreRules,omitempty"`
+	CreatedAt     time.Time       `json:"createdAt" bson:"createdAt"`
 }
 
 type RepoDTO struct {
 	ID          *string    `json:"id,omitempty"`
 	UserID      string     `json:"userID"`
+	OrgID       string     `json:"orgID,omitempty"`
 	Name        string     `json:"name"`
 	Owner       string     `json:"owner"`
 	Path        string     `json:"path"`
+	Ref         string     `json:"ref"`
 	Provider    string     `json:"provider"`
 	PackageList []*Package `json:"packageList, omitempty"`
+	// Secrets are omitted for members below maintainer role
+	WebhookSecret string          `json:"webhookSecret,omitempty"`
+	BadgeToken    string          `json:"badgeToken,omitempty"`
+	Token         string          `json:"-"`
+	TokenUserID   string          `json:"tokenUserID,omitempty"`
+	HasToken      bool            `json:"hasToken"`
+	CommitSHA     string          `json:"commitSHA"`
+	ManifestBlobs []*ManifestBlob `json:"manifestBlobs"`
+	LastScan      *ScanResult     `json:"lastScan"`
+	IgnoreRules   []*IgnoreRule   `json:"ignoreRules"`
+	// Packages grouped by manifest path
+	Manifests map[string][]*Package `json:"manifests,omitempty"`
 }
 
 type RepoIDRequest struct {
@@ -44,6 +109,18 @@ type RepoIDRequest struct {
 
 type RepoUrlRequest struct {
 	Url string `json:"url"`
+	// Branch, tag or commit to track, overrides ref in url
+	Ref string `json:"ref,omitempty"`
+	// Organization that repository is created in, repository belongs to user if it is empty
+	OrgID string `json:"orgID,omitempty"`
+	// OAuth token of user is saved to repository for background scans and pull requests of user
+	SaveToken bool `json:"saveToken,omitempty"`
+}
+
+type RepoOwnerRequest struct {
+	ID string `json:"id"`
+	// Repository is moved to user if it is empty
+	OrgID string `json:"orgID"`
 }
 
 func ToRepoDTOs(repos []*Repo) []*RepoDTO {
@@ -61,28 +138,81 @@ func ToRepoDTO(repo *Repo) *RepoDTO {
 
 	id := repo.ID.Hex()
 
+	orgID := ""
+	if repo.OrgID != nil {
+		orgID = repo.OrgID.Hex()
+	}
+
+	tokenUserID := ""
+	if repo.TokenUserID != nil {
+		tokenUserID = repo.TokenUserID.Hex()
+	}
+
 	return &RepoDTO{
-		ID:          &id,
-		UserID:      repo.UserID.Hex(),
-		Name:        repo.Name,
-		Owner:       repo.Owner,
-		Path:        repo.Path,
-		Provider:    repo.Provider,
-		PackageList: repo.PackageList,
+		ID:            &id,
+		UserID:        repo.UserID.Hex(),
+		OrgID:         orgID,
+		Name:          repo.Name,
+		Owner:         repo.Owner,
+		Path:          repo.Path,
+		Ref:           repo.Ref,
+		Provider:      repo.Provider,
+		PackageList:   repo.PackageList,
+		WebhookSecret: repo.WebhookSecret,
+		BadgeToken:    repo.BadgeToken,
+		Token:         repo.Token,
+		TokenUserID:   tokenUserID,
+		HasToken:      repo.Token != "",
+		CommitSHA:     repo.CommitSHA,
+		ManifestBlobs: repo.ManifestBlobs,
+		LastScan:      repo.LastScan,
+		IgnoreRules:   repo.IgnoreRules,
+		Manifests:     GroupPackagesByPath(repo.PackageList),
 	}
 }
 
+// Copy of repository that members with given role can see, viewers can not see webhook secret and badge token
+func (r *RepoDTO) ForRole(role Role) *RepoDTO {
+	if role.Includes(RoleMaintainer) {
+		return r
+	}
+
+	visible := *r
+	visible.WebhookSecret = ""
+	visible.BadgeToken = ""
+	visible.Token = ""
+	return &visible
+}
+
 func ToRepo(repoDTO *RepoDTO) *Repo {
 
 	userId, _ := primitive.ObjectIDFromHex(repoDTO.UserID)
 
 	repo := &Repo{
-		Name:        repoDTO.Name,
-		UserID:      userId,
-		Owner:       repoDTO.Owner,
-		Path:        repoDTO.Path,
-		Provider:    repoDTO.Provider,
-		PackageList: repoDTO.PackageList,
+		Name:          repoDTO.Name,
+		UserID:        userId,
+		Owner:         repoDTO.Owner,
+		Path:          repoDTO.Path,
+		Ref:           repoDTO.Ref,
+		Provider:      repoDTO.Provider,
+		PackageList:   repoDTO.PackageList,
+		WebhookSecret: repoDTO.WebhookSecret,
+		BadgeToken:    repoDTO.BadgeToken,
+		Token:         repoDTO.Token,
+		CommitSHA:     repoDTO.CommitSHA,
+		ManifestBlobs: repoDTO.ManifestBlobs,
+		LastScan:      repoDTO.LastScan,
+		IgnoreRules:   repoDTO.IgnoreRules,
+	}
+
+	if repoDTO.OrgID != "" {
+		orgID, _ := primitive.ObjectIDFromHex(repoDTO.OrgID)
+		repo.OrgID = &orgID
+	}
+
+	if repoDTO.TokenUserID != "" {
+		tokenUserID, _ := primitive.ObjectIDFromHex(repoDTO.TokenUserID)
+		repo.TokenUserID = &tokenUserID
 	}
 
 	if repoDTO.ID != nil {
@@ -95,7 +225,7 @@ func ToRepo(repoDTO *RepoDTO) *Repo {
 	return repo
 }
 
-func ToPackageDTOs(rawPackages map[string]string, file string) []*Package {
+func ToPackageDTOs(rawPackages map[string]string, file string, path string) []*Package {
 
 	var packageDTOs []*Package
 
@@ -107,9 +237,31 @@ func ToPackageDTOs(rawPackages map[string]string, file string) []*Package {
 				Last:    value,
 			},
 			File:       file,
+			Path:       path,
 			IsOutdated: false,
 		})
 	}
 
 	return packageDTOs
 }
+
+// Groups packages with their manifest paths
+// Packages that are scanned before path tracking are grouped by file name
+func GroupPackagesByPath(packages []*Package) map[string][]*Package {
+
+	if len(packages) == 0 {
+		return nil
+	}
+
+	manifests := make(map[string][]*Package)
+
+	for _, pkg := range packages {
+		path := pkg.Path
+		if path == "" {
+			path = pkg.File
+		}
+		manifests[path] = append(manifests[path], pkg)
+	}
+
+	return manifests
+}
@@ -0,0 +1,9 @@
+package entity
+
+// Packages of uploaded CycloneDX or SPDX document with their registry versions
+type SBOMScanDTO struct {
+	Packages []*Package `json:"packages"`
+	// Components of ecosystems that do not have package managers or components without versions
+	Unsupported []string       `json:"unsupported"`
+	Failures    []*ScanFailure `json:"failures"`
+}
@@ -0,0 +1,103 @@
+package entity
+
+import "time"
+
+// Statuses of scan jobs
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Background scan of new repository, it is kept in redis for a day
+type ScanJob struct {
+	ID     string `json:"id"`
+	UserID string `json:"userID"`
+	// Organization that repository is created in
+	OrgID string `json:"orgID,omitempty"`
+	Url   string `json:"url"`
+	Ref   string `json:"ref"`
+	// OAuth token of user is saved to created repository
+	SaveToken bool   `json:"saveToken,omitempty"`
+	Status    string `json:"status"`
+	// Created repository, it is set when job is completed
+	RepoID    string        `json:"repoID,omitempty"`
+	Progress  *ScanProgress `json:"progress"`
+	Error     string        `json:"error,omitempty"`
+	CreatedAt time.Time     `json:"createdAt"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+}
+
+type ScanProgress struct {
+	ManifestsFound   int            `json:"manifestsFound"`
+	PackagesFound    int            `json:"packagesFound"`
+	PackagesResolved int            `json:"packagesResolved"`
+	Failures         []*ScanFailure `json:"failures"`
+}
+
+// Package whose registry version can not be resolved, or package file that can not be parsed
+type ScanFailure struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+type ScanJobDTO struct {
+	ID        string        `json:"id"`
+	OrgID     string        `json:"orgID,omitempty"`
+	Url       string        `json:"url"`
+	Ref       string        `json:"ref"`
+	Status    string        `json:"status"`
+	RepoID    string        `json:"repoID,omitempty"`
+	Progress  *ScanProgress `json:"progress"`
+	Error     string        `json:"error,omitempty"`
+	CreatedAt time.Time     `json:"createdAt"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+}
+
+// Checks job can not change anymore
+func (j *ScanJob) IsFinished() bool {
+	return IsFinishedStatus(j.Status)
+}
+
+func IsFinishedStatus(status string) bool {
+	return status == JobCompleted || status == JobFailed || status == JobCancelled
+}
+
+func ToScanJobDTO(job *ScanJob) *ScanJobDTO {
+	return &ScanJobDTO{
+		ID:        job.ID,
+		OrgID:     job.OrgID,
+		Url:       job.Url,
+		Ref:       job.Ref,
+		Status:    job.Status,
+		RepoID:    job.RepoID,
+		Progress:  job.Progress,
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+	}
+}
+
+// Types of scan events
+const (
+	// Status or progress of job is changed, finished statuses are last event
+	ScanEventStatus = "status"
+	// Package files and workspace descriptors are found
+	ScanEventManifests = "manifests"
+	// Registry version of package is resolved
+	ScanEventPackage = "package"
+	// Registry version of package can not be resolved
+	ScanEventFailure = "failure"
+)
+
+// Event of running scan, every event carries current job
+type ScanEvent struct {
+	Type      string       `json:"type"`
+	Job       *ScanJobDTO  `json:"job"`
+	Manifests []string     `json:"manifests,omitempty"`
+	Package   *Package     `json:"package,omitempty"`
+	Failure   *ScanFailure `json:"failure,omitempty"`
+}
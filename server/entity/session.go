@@ -0,0 +1,53 @@
+package entity
+
+import "time"
+
+// Login of user on a device, refresh tokens of session are rotated on every refresh
+type Session struct {
+	ID     string `json:"id"`
+	UserID string `json:"userID"`
+	// Uuid of latest refresh token, older tokens of session are reused tokens
+	TokenID    string    `json:"tokenID"`
+	Device     string    `json:"device"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+type SessionDTO struct {
+	ID         string    `json:"id"`
+	Device     string    `json:"device"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	// Session of request
+	Current bool `json:"current"`
+}
+
+// Client that logins or refreshes session
+type SessionClient struct {
+	Device string
+	IP     string
+}
+
+func ToSessionDTO(session *Session, currentID string) *SessionDTO {
+	return &SessionDTO{
+		ID:         session.ID,
+		Device:     session.Device,
+		IP:         session.IP,
+		CreatedAt:  session.CreatedAt,
+		LastSeenAt: session.LastSeenAt,
+		ExpiresAt:  session.ExpiresAt,
+		Current:    session.ID == currentID,
+	}
+}
+
+func ToSessionDTOs(sessions []*Session, currentID string) []*SessionDTO {
+	sessionDTOs := make([]*SessionDTO, len(sessions))
+	for i, session := range sessions {
+		sessionDTOs[i] = ToSessionDTO(session, currentID)
+	}
+	return sessionDTOs
+}
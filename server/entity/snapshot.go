@@ -0,0 +1,115 @@
+package entity
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Source of snapshots that are created from uploaded package files
+const SnapshotSourceUpload = "upload"
+
+// Packages of repository at scanned commit, snapshots are never updated
+type Snapshot struct {
+	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	RepoID    primitive.ObjectID `json:"repoID" bson:"repoID"`
+	UserID    primitive.ObjectID `json:"userID" bson:"userID"`
+	Ref       string             `json:"ref" bson:"ref"`
+	CommitSHA string             `json:"commitSHA" bson:"commitSHA"`
+	Packages  []*Package         `json:"packages" bson:"packages"`
+	Result    *ScanResult        `json:"result" bson:"result,omitempty"`
+	// Snapshots of uploaded package files are not scanned from provider
+	Source string `json:"source,omitempty" bson:"source,omitempty"`
+	// Counts are kept for listing snapshots without packages
+	PackageCount  int       `json:"packageCount" bson:"packageCount"`
+	OutdatedCount int       `json:"outdatedCount" bson:"outdatedCount"`
+	CreatedAt     time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+type SnapshotDTO struct {
+	ID            string      `json:"id"`
+	RepoID        string      `json:"repoID"`
+	Ref           string      `json:"ref"`
+	CommitSHA     string      `json:"commitSHA"`
+	Packages      []*Package  `json:"packages,omitempty"`
+	Result        *ScanResult `json:"result"`
+	Source        string      `json:"source,omitempty"`
+	PackageCount  int         `json:"packageCount"`
+	OutdatedCount int         `json:"outdatedCount"`
+	CreatedAt     time.Time   `json:"createdAt"`
+}
+
+// Package whose version constraint is changed between snapshots
+type PackageChange struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	File string `json:"file"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Changes from older snapshot to newer snapshot
+type SnapshotDiff struct {
+	From       *SnapshotDTO     `json:"from"`
+	To         *SnapshotDTO     `json:"to"`
+	Added      []*Package       `json:"added"`
+	Removed    []*Package       `json:"removed"`
+	Upgraded   []*PackageChange `json:"upgraded"`
+	Downgraded []*PackageChange `json:"downgraded"`
+	// Range operator is changed without changing version, for exp. ^1.2.0 -> ~1.2.0
+	Changed []*PackageChange `json:"changed"`
+	// Packages that are up to date in older snapshot
+	NewlyOutdated []*Package `json:"newlyOutdated"`
+}
+
+func NewSnapshot(repo *RepoDTO) *Snapshot {
+
+	repoID, _ := primitive.ObjectIDFromHex(*repo.ID)
+	userID, _ := primitive.ObjectIDFromHex(repo.UserID)
+
+	outdated := 0
+	for _, pkg := range repo.PackageList {
+		if pkg.IsOutdated {
+			outdated++
+		}
+	}
+
+	return &Snapshot{
+		RepoID:        repoID,
+		UserID:        userID,
+		Ref:           repo.Ref,
+		CommitSHA:     repo.CommitSHA,
+		Packages:      repo.PackageList,
+		Result:        repo.LastScan,
+		PackageCount:  len(repo.PackageList),
+		OutdatedCount: outdated,
+		CreatedAt:     time.Now().UTC(),
+	}
+}
+
+func ToSnapshotDTO(snapshot *Snapshot) *SnapshotDTO {
+	return &SnapshotDTO{
+		ID:            snapshot.ID.Hex(),
+		RepoID:        snapshot.RepoID.Hex(),
+		Ref:           snapshot.Ref,
+		CommitSHA:     snapshot.CommitSHA,
+		Packages:      snapshot.Packages,
+		Result:        snapshot.Result,
+		Source:        snapshot.Source,
+		PackageCount:  snapshot.PackageCount,
+		OutdatedCount: snapshot.OutdatedCount,
+		CreatedAt:     snapshot.CreatedAt,
+	}
+}
+
+// Snapshots are listed without packages
+func ToSnapshotSummaryDTOs(snapshots []*Snapshot) []*SnapshotDTO {
+
+	snapshotDTOs := make([]*SnapshotDTO, len(snapshots))
+
+	for i, item := range snapshots {
+		snapshotDTOs[i] = ToSnapshotDTO(item)
+		snapshotDTOs[i].Packages = nil
+	}
+
+	return snapshotDTOs
+}
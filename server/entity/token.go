@@ -7,6 +7,8 @@ type Token struct {
 	UserID     string
 	Email      string
 	Authorized bool
+	// Tokens of same login share session id
+	SessionID string
 }
 
 // Public Token that is consumed by confirmation mail and update notify preferences
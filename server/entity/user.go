@@ -14,15 +14,21 @@ type User struct {
 	Email       string             `json:"email" bson:"email"`
 	IsConfirmed bool               `json:"isConfirmed" bson:"isConfirmed"`
 	Password    string             `json:"password" bson:"password"`
-	CreatedAt   time.Time          `json:"createdAt" bson:"createdAt"`
+	// Admins are set in database, they can see instance wide information
+	IsAdmin bool `json:"isAdmin" bson:"isAdmin"`
+	// Linked OAuth accounts
+	Identities []*Identity `json:"identities" bson:"identities,omitempty"`
+	CreatedAt  time.Time   `json:"createdAt" bson:"createdAt"`
 }
 
 type UserDTO struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	IsConfirmed bool   `json:"isConfirmed"`
-	Email       string `json:"email"`
-	Password    string `json:"password"`
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	IsConfirmed bool        `json:"isConfirmed"`
+	Email       string      `json:"email"`
+	Password    string      `json:"password"`
+	IsAdmin     bool        `json:"isAdmin"`
+	Identities  []*Identity `json:"identities"`
 }
 
 type UserResponse struct {
@@ -40,6 +46,7 @@ func ToUser(userDTO *UserDTO) *User {
 		Email:       userDTO.Email,
 		IsConfirmed: userDTO.IsConfirmed,
 		Password:    userDTO.Password,
+		Identities:  userDTO.Identities,
 	}
 }
 
@@ -50,6 +57,8 @@ func ToUserDTO(user *User) *UserDTO {
 		Email:       user.Email,
 		IsConfirmed: user.IsConfirmed,
 		Password:    user.Password,
+		IsAdmin:     user.IsAdmin,
+		Identities:  user.Identities,
 	}
 }
 
@@ -82,6 +91,19 @@ func ValidateEmail(email string) string {
 	return ""
 }
 
+// Validates user's password
+func ValidatePassword(password string) string {
+	password = strings.TrimSpace(password)
+
+	if password == "" {
+		return "Password is required"
+	}
+	if len(password) < 8 {
+		return "Password must be minimum 8 character long"
+	}
+	return ""
+}
+
 // Validates user's  [name, password, email]
 func ValidateUser(user *UserDTO) string {
 
@@ -95,11 +117,8 @@ func ValidateUser(user *UserDTO) string {
 	}
 
 	user.Password = strings.TrimSpace(user.Password)
-	if user.Password == "" {
-		return "Password is required"
-	}
-	if len(user.Password) < 8 {
-		return "Password must be minimum 8 character long"
+	if isValid := ValidatePassword(user.Password); isValid != "" {
+		return isValid
 	}
 
 	user.Email = strings.TrimSpace(user.Email)
@@ -0,0 +1,145 @@
+package entity
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Push event that is normalized from provider's webhook payload
+type PushEvent struct {
+	// Branch or tag name that is pushed
+	Ref string
+	// Default branch of repository, it is used when repository does not track a ref
+	DefaultBranch string
+	// Added, modified and removed paths in pushed commits
+	ChangedPaths []string
+	// False when payload does not contain all changed paths
+	Complete bool
+}
+
+type pushCommit struct {
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
+}
+
+type githubPushPayload struct {
+	Ref        string        `json:"ref"`
+	Before     string        `json:"before"`
+	After      string        `json:"after"`
+	Created    bool          `json:"created"`
+	Deleted    bool          `json:"deleted"`
+	Forced     bool          `json:"forced"`
+	Commits    []*pushCommit `json:"commits"`
+	Repository struct {
+		DefaultBranch string `json:"default_branch"`
+	} `json:"repository"`
+}
+
+type gitlabPushPayload struct {
+	Ref               string        `json:"ref"`
+	TotalCommitsCount int           `json:"total_commits_count"`
+	Commits           []*pushCommit `json:"commits"`
+	Project           struct {
+		DefaultBranch string `json:"default_branch"`
+	} `json:"project"`
+}
+
+type bitbucketPushPayload struct {
+	Push struct {
+		Changes []struct {
+			New *struct {
+				Name string `json:"name"`
+			} `json:"new"`
+		} `json:"changes"`
+	} `json:"push"`
+	Repository struct {
+		MainBranch *struct {
+			Name string `json:"name"`
+		} `json:"mainbranch"`
+	} `json:"repository"`
+}
+
+// Parses GitHub push event payload
+// Commits of force pushes and new branches do not contain all changes between before and after,
+// for exp. reset branch or branch that is created from existing commits has empty commits
+func ToGithubPushEvent(body []byte) (*PushEvent, error) {
+	payload := new(githubPushPayload)
+	if err := json.Unmarshal(body, payload); err != nil {
+		return nil, err
+	}
+
+	complete := !payload.Forced && !payload.Created
+	if len(payload.Commits) == 0 && payload.Before != payload.After {
+		complete = false
+	}
+	// Deleted refs do not have packages to scan
+	if payload.Deleted {
+		complete = true
+	}
+
+	return &PushEvent{
+		Ref:           trimRefPrefix(payload.Ref),
+		DefaultBranch: payload.Repository.DefaultBranch,
+		ChangedPaths:  changedPaths(payload.Commits),
+		Complete:      complete,
+	}, nil
+}
+
+// Parses GitLab push hook payload
+// GitLab sends at most 20 commits, rest of them are not known
+func ToGitlabPushEvent(body []byte) (*PushEvent, error) {
+	payload := new(gitlabPushPayload)
+	if err := json.Unmarshal(body, payload); err != nil {
+		return nil, err
+	}
+
+	return &PushEvent{
+		Ref:           trimRefPrefix(payload.Ref),
+		DefaultBranch: payload.Project.DefaultBranch,
+		ChangedPaths:  changedPaths(payload.Commits),
+		Complete:      payload.TotalCommitsCount <= len(payload.Commits),
+	}, nil
+}
+
+// Parses Bitbucket repo:push payload
+// Bitbucket does not send changed paths, so event is never complete
+func ToBitbucketPushEvent(body []byte) (*PushEvent, error) {
+	payload := new(bitbucketPushPayload)
+	if err := json.Unmarshal(body, payload); err != nil {
+		return nil, err
+	}
+
+	event := &PushEvent{
+		Complete: false,
+	}
+
+	if payload.Repository.MainBranch != nil {
+		event.DefaultBranch = payload.Repository.MainBranch.Name
+	}
+
+	for _, change := range payload.Push.Changes {
+		// Deleted branches do not have new state
+		if change.New != nil {
+			event.Ref = change.New.Name
+			break
+		}
+	}
+
+	return event, nil
+}
+
+func changedPaths(commits []*pushCommit) []string {
+	var paths []string
+	for _, commit := range commits {
+		paths = append(paths, commit.Added...)
+		paths = append(paths, commit.Modified...)
+		paths = append(paths, commit.Removed...)
+	}
+	return paths
+}
+
+func trimRefPrefix(ref string) string {
+	ref = strings.TrimPrefix(ref, "refs/heads/")
+	return strings.TrimPrefix(ref, "refs/tags/")
+}
@@ -0,0 +1,40 @@
+package entity
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestToGithubPushEvent(t *testing.T) {
+
+	const (
+		before = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+		after  = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	)
+
+	tests := []struct {
+		name     string
+		body     string
+		paths    []string
+		complete bool
+	}{
+		{"push", `{"ref": "refs/heads/main", "before": "` + before + `", "after": "` + after + `", "commits": [{"added": ["web/package.json"], "modified": ["README.md"]}]}`, []string{"web/package.json", "README.md"}, true},
+		{"force push", `{"ref": "refs/heads/main", "before": "` + before + `", "after": "` + after + `", "forced": true, "commits": [{"modified": ["README.md"]}]}`, []string{"README.md"}, false},
+		{"reset without commits", `{"ref": "refs/heads/main", "before": "` + before + `", "after": "` + after + `", "forced": true, "commits": []}`, nil, false},
+		{"created branch without commits", `{"ref": "refs/heads/feature", "before": "0000000000000000000000000000000000000000", "after": "` + after + `", "created": true, "commits": []}`, nil, false},
+		{"fast forward without commits", `{"ref": "refs/heads/main", "before": "` + before + `", "after": "` + after + `", "commits": []}`, nil, false},
+		{"deleted branch", `{"ref": "refs/heads/feature", "before": "` + before + `", "after": "0000000000000000000000000000000000000000", "deleted": true, "commits": []}`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := ToGithubPushEvent([]byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(event.ChangedPaths, tt.paths) || event.Complete != tt.complete {
+				t.Errorf("expected %v (complete: %v), got %v (complete: %v)", tt.paths, tt.complete, event.ChangedPaths, event.Complete)
+			}
+		})
+	}
+}
@@ -26,3 +26,5 @@ require (
 	golang.org/x/text v0.3.4 // indirect
 	golang.org/x/tools v0.0.0-20201118030313-598b068a9102 // indirect
 )
+
+replace github.com/nozgurozturk/marvin/pkg => ../pkg
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/nozgurozturk/marvin/pkg/providers"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"net/http"
+)
+
+// AdminHandler needs admin user
+func AdminHandler(router fiber.Router) {
+	router.Get("/rate-limits", findRateLimits())
+}
+
+// findRateLimits is a function to returns current quota of provider hosts
+// @Summary Returns rate limit quota of every provider host that is requested
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} entity.Response{data=[]providers.RateLimit}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Router /api/admin/rate-limits [get]
+func findRateLimits() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+
+		response := entity.ToResponse(
+			"Rate limits of providers",
+			http.StatusOK,
+			providers.RateLimits(),
+		)
+		return c.Status(response.Status).JSON(response)
+	}
+}
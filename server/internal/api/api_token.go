@@ -0,0 +1,123 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/nozgurozturk/marvin/pkg/errors"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"github.com/nozgurozturk/marvin/server/internal/service"
+	"net/http"
+)
+
+func APITokenHandler(router fiber.Router, apiTokenService service.APITokenService) {
+	router.Post("/", createAPIToken(apiTokenService))
+	router.Get("/", findAllAPIToken(apiTokenService))
+	router.Delete("/:id", revokeAPIToken(apiTokenService))
+}
+
+// API tokens can not manage tokens or account, so a leaked token can be revoked
+func rejectAPIToken(c *fiber.Ctx) *errors.AppError {
+	if c.Locals("apiToken") != nil {
+		return errors.Forbidden("Login is required, API tokens can not be used")
+	}
+	return nil
+}
+
+// createAPIToken is a function to create personal API token
+// @Summary Create personal API token, token is only returned in this response
+// @Tags token
+// @Accept json
+// @Produce json
+// @Param request body entity.APITokenRequest true "Token"
+// @Success 201 {object} entity.Response{data=entity.APITokenDTO}
+// @Failure 400 {object} errors.AppError{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 422 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/token [post]
+func createAPIToken(s service.APITokenService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := rejectAPIToken(c); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		requestBody := new(entity.APITokenRequest)
+		if err := c.BodyParser(&requestBody); err != nil {
+			e := errors.UnprocessableEntity("Invalid request body")
+			return c.Status(e.Status).JSON(e)
+		}
+
+		userID, _ := c.Locals("user").(string)
+
+		created, err := s.Create(userID, requestBody)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse(
+			"Token is created, copy it now, it is not shown again.",
+			http.StatusCreated,
+			created,
+		)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// findAllAPIToken is a function to returns personal API tokens of user
+// @Summary Returns personal API tokens of user without token values
+// @Tags token
+// @Produce json
+// @Success 200 {object} entity.Response{data=[]entity.APITokenDTO}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/token [get]
+func findAllAPIToken(s service.APITokenService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := rejectAPIToken(c); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		userID, _ := c.Locals("user").(string)
+
+		found, err := s.FindAll(userID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse(
+			"All API tokens",
+			http.StatusOK,
+			found,
+		)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// revokeAPIToken is a function to revoke personal API token
+// @Summary Revoke personal API token, requests with token are rejected immediately
+// @Tags token
+// @Produce json
+// @Param id path string true "Token id"
+// @Success 200 {object} entity.Response{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/token/{id} [delete]
+func revokeAPIToken(s service.APITokenService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := rejectAPIToken(c); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		userID, _ := c.Locals("user").(string)
+
+		if err := s.Revoke(c.Params("id"), userID); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse("API token is revoked", http.StatusOK, nil)
+		return c.Status(response.Status).JSON(response)
+	}
+}
@@ -13,12 +13,19 @@ import (
 )
 
 // AuthHandler
-func AuthHandler(router fiber.Router, authService service.AuthService, userService service.UserService, subService service.SubscriberService) {
-	router.Post("/login", login(authService, userService))
+func AuthHandler(router fiber.Router, authService service.AuthService, userService service.UserService, subService service.SubscriberService, rateLimitService service.RateLimitService) {
+	router.Post("/login", login(authService, userService, rateLimitService))
 	router.Post("/signup", signUp(authService, userService))
 	router.Get("/logout", logout(authService))
 	router.Post("/refresh", refresh(authService, userService))
 	router.Get("/confirm", confirmAccount(authService, userService))
+	router.Post("/confirm/resend", resendConfirmation(authService, userService, rateLimitService))
+	router.Post("/password/forgot", forgotPassword(authService, userService, rateLimitService))
+	router.Get("/password/reset", resetPasswordPage())
+	router.Post("/password/reset", resetPassword(authService, userService))
+	router.Get("/email/confirm", confirmEmail(authService, userService))
+	router.Get("/oauth/:provider", oauthLogin(authService))
+	router.Get("/oauth/:provider/callback", oauthCallback(authService, userService))
 }
 
 // login is a function to authenticate user
@@ -30,9 +37,10 @@ func AuthHandler(router fiber.Router, authService service.AuthService, userServi
 // @Success 200 {object} entity.Response{data=entity.TokenResponse}
 // @Failure 401 {object} errors.AppError{}
 // @Failure 422 {object} errors.AppError{}
+// @Failure 429 {object} errors.AppError{}
 // @Failure 500 {object} errors.AppError{}
 // @Router /auth/login [post]
-func login(authService service.AuthService, userService service.UserService) fiber.Handler {
+func login(authService service.AuthService, userService service.UserService, rateLimitService service.RateLimitService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 
 		authLogin := new(entity.Login)
@@ -41,6 +49,12 @@ func login(authService service.AuthService, userService service.UserService) fib
 			return c.Status(parseError.Status).JSON(parseError)
 		}
 
+		// Locked accounts are rejected even if password is correct
+		err := rateLimitService.CheckLogin(authLogin.Email)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
 		currentUser, err := userService.FindByEmail(authLogin.Email)
 		if err != nil {
 			return c.Status(err.Status).JSON(err)
@@ -48,16 +62,24 @@ func login(authService service.AuthService, userService service.UserService) fib
 
 		// Compares hashed password
 		if passwordErr := entity.VerifyPassword(currentUser.Password, authLogin.Password); passwordErr != nil {
+			if err := rateLimitService.LoginFailed(authLogin.Email); err != nil {
+				return c.Status(err.Status).JSON(err)
+			}
 			err := errors.Unauthorized("Password is not correct")
 			return c.Status(err.Status).JSON(err)
 		}
 
-		tokens, err := app.CreateToken(currentUser)
+		err = rateLimitService.LoginSucceeded(authLogin.Email)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		tokens, err := app.CreateToken(currentUser, "")
 		if err != nil {
 			return c.Status(err.Status).JSON(err)
 		}
 
-		err = authService.CreateAuth(tokens.RefreshToken)
+		err = authService.CreateSession(tokens, sessionClient(c))
 		if err != nil {
 			return c.Status(err.Status).JSON(err)
 		}
@@ -87,6 +109,7 @@ func login(authService service.AuthService, userService service.UserService) fib
 // @Success 200 {object} entity.Response{data=entity.TokenResponse}
 // @Failure 409 {object} errors.AppError{}
 // @Failure 422 {object} errors.AppError{}
+// @Failure 429 {object} errors.AppError{}
 // @Failure 500 {object} errors.AppError{}
 // @Router /auth/signup [post]
 func signUp(authService service.AuthService, userService service.UserService) fiber.Handler {
@@ -113,38 +136,19 @@ func signUp(authService service.AuthService, userService service.UserService) fi
 			return c.Status(err.Status).JSON(err)
 		}
 
-		tokens, err := app.CreateToken(createdUser)
+		tokens, err := app.CreateToken(createdUser, "")
 		if err != nil {
 			return c.Status(err.Status).JSON(err)
 		}
 
-		err = authService.CreateAuth(tokens.RefreshToken)
+		err = authService.CreateSession(tokens, sessionClient(c))
 		if err != nil {
 			return c.Status(err.Status).JSON(err)
 		}
 
-		// Gets serve configuration
-		cnf := config.Get().HTTP
-
-		// Creates email template data
-		templateData := struct {
-			User string
-			Link string
-		}{
-			User: createdUser.Name,
-			Link: fmt.Sprintf("http://%s%s/auth/confirm?t=%s", cnf.Host, cnf.Port, tokens.RefreshToken.Token),
-		}
-
-		emailBody, parsErr := app.ParseHTMLTemplate("./web/email-signup-verify.html", templateData)
-		if parsErr != nil {
-			e := errors.InternalServer(parsErr.Error())
-			return c.Status(e.Status).JSON(e)
-		}
-
-		emailErr := app.SendEmail(createdUser.Email, "Marvin | Please confirm your email", emailBody)
-		if emailErr != nil {
-			e := errors.InternalServer(emailErr.Error())
-			return c.Status(e.Status).JSON(e)
+		err = sendConfirmation(authService, createdUser)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
 		}
 
 		tokenStrings := entity.TokenDetailsToResponse(tokens)
@@ -179,23 +183,24 @@ func logout(authService service.AuthService) fiber.Handler {
 		}
 
 		token, err := app.ValidateToken(t, "Access")
-		claims := token.Claims.(jwt.MapClaims)
-		uuid, _ := claims["uuid"].(string)
-
 		if err != nil {
-			if token != nil {
-				err := authService.DeleteAuth(uuid)
-				if err != nil {
-					return c.Status(err.Status).JSON(err)
-				}
-			}
 			return c.Status(err.Status).JSON(err)
 		}
 
-		err = authService.DeleteAuth(uuid)
+		claims := token.Claims.(jwt.MapClaims)
+		uuid, _ := claims["uuid"].(string)
+		userID, _ := claims["userID"].(string)
+		sessionID, _ := claims["session"].(string)
 
-		if err != nil {
-			return c.Status(err.Status).JSON(err)
+		// Tokens that are created before sessions only have uuid
+		if sessionID == "" {
+			if err := authService.DeleteAuth(uuid); err != nil {
+				return c.Status(err.Status).JSON(err)
+			}
+		} else {
+			if err := authService.RevokeSession(userID, sessionID); err != nil && err.Status != http.StatusNotFound {
+				return c.Status(err.Status).JSON(err)
+			}
 		}
 
 		response := entity.ToResponse(
@@ -214,6 +219,7 @@ func logout(authService service.AuthService) fiber.Handler {
 // @Produce json
 // @Success 200 {object} entity.Response{data=entity.TokenResponse}
 // @Failure 401 {object} errors.AppError{}
+// @Failure 429 {object} errors.AppError{}
 // @Failure 500 {object} errors.AppError{}
 // @Router /auth/refresh [post]
 func refresh(authService service.AuthService, userService service.UserService) fiber.Handler {
@@ -233,40 +239,39 @@ func refresh(authService service.AuthService, userService service.UserService) f
 		}
 
 		token, err := app.ValidateToken(requestBody.RefreshToken, "Refresh")
-		claims := token.Claims.(jwt.MapClaims)
-		uuid, _ := claims["uuid"].(string)
-
 		if err != nil {
-			if token != nil {
-				err := authService.DeleteAuth(uuid)
-				if err != nil {
-					return c.Status(err.Status).JSON(err)
-				}
-			}
 			return c.Status(err.Status).JSON(err)
 		}
 
-		userID, err := authService.FindAuth(uuid)
-		if err != nil {
+		claims := token.Claims.(jwt.MapClaims)
+		uuid, _ := claims["uuid"].(string)
+		sessionID, _ := claims["session"].(string)
+
+		// Tokens that are created before sessions can not be rotated
+		if sessionID == "" {
+			if err := authService.DeleteAuth(uuid); err != nil {
+				return c.Status(err.Status).JSON(err)
+			}
+			err := errors.Unauthorized("Session is expired, please login again")
 			return c.Status(err.Status).JSON(err)
 		}
 
-		foundUser, err := userService.FindByID(userID)
+		session, err := authService.FindSession(sessionID)
 		if err != nil {
 			return c.Status(err.Status).JSON(err)
 		}
 
-		tokens, err := app.CreateToken(foundUser)
+		foundUser, err := userService.FindByID(session.UserID)
 		if err != nil {
 			return c.Status(err.Status).JSON(err)
 		}
 
-		err = authService.CreateAuth(tokens.RefreshToken)
+		tokens, err := app.CreateToken(foundUser, session.ID)
 		if err != nil {
 			return c.Status(err.Status).JSON(err)
 		}
 
-		err = authService.DeleteAuth(uuid)
+		err = authService.RotateSession(session, uuid, tokens, sessionClient(c))
 		if err != nil {
 			return c.Status(err.Status).JSON(err)
 		}
@@ -291,85 +296,290 @@ func refresh(authService service.AuthService, userService service.UserService) f
 func confirmAccount(authService service.AuthService, userService service.UserService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 
-		tokenString := c.Query("t")
-		token, err := app.ValidateToken(tokenString, "Refresh")
-		claims := token.Claims.(jwt.MapClaims)
-		uuid, _ := claims["uuid"].(string)
+		actionToken, err := authService.FindActionToken(entity.ActionConfirmAccount, c.Query("t"))
+		if err != nil {
+			return renderError(c, err)
+		}
+
+		foundUser, err := userService.FindByID(actionToken.UserID)
+		if err != nil {
+			return renderError(c, err)
+		}
+
+		err = userService.Confirm(foundUser.ID)
+		if err != nil {
+			return renderError(c, err)
+		}
 
+		return c.Status(http.StatusOK).Render("page-signup-result", fiber.Map{
+			"User": foundUser.Name,
+		})
+	}
+}
+
+// resendConfirmation is a function to send verification email again
+// @Summary Send verification email again, response does not tell whether email is registered
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body entity.ResendConfirmation true "Email"
+// @Success 200 {object} entity.Response{}
+// @Failure 422 {object} errors.AppError{}
+// @Failure 429 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /auth/confirm/resend [post]
+func resendConfirmation(authService service.AuthService, userService service.UserService, rateLimitService service.RateLimitService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+
+		requestBody := new(entity.ResendConfirmation)
+		if err := c.BodyParser(requestBody); err != nil {
+			parseError := errors.UnprocessableEntity("Invalid request body")
+			return c.Status(parseError.Status).JSON(parseError)
+		}
+
+		response := entity.ToResponse(
+			"If your account is not confirmed, a new confirmation link is sent to your email",
+			http.StatusOK,
+			nil,
+		)
+
+		foundUser, err := userService.FindByEmail(requestBody.Email)
 		if err != nil {
-			if token != nil {
-				err := authService.DeleteAuth(uuid)
-				if err != nil {
-					return c.Status(err.Status).Render("page-error", fiber.Map{
-						"ErrorStatus":  err.Status,
-						"ErrorMessage": err.Message,
-						"Error":        err.Error,
-					})
-				}
+			if err.Status == http.StatusNotFound {
+				return c.Status(response.Status).JSON(response)
 			}
-			return c.Status(err.Status).Render("page-error", fiber.Map{
-				"ErrorStatus":  err.Status,
-				"ErrorMessage": err.Message,
-				"Error":        err.Error,
-			})
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if foundUser.IsConfirmed {
+			return c.Status(response.Status).JSON(response)
+		}
+
+		// Same response is returned, so limit does not tell whether email is registered
+		if err := rateLimitService.AllowEmail(foundUser.Email); err != nil {
+			if err.Status == http.StatusTooManyRequests {
+				return c.Status(response.Status).JSON(response)
+			}
+			return c.Status(err.Status).JSON(err)
 		}
 
-		userID, err := authService.FindAuth(uuid)
+		err = sendConfirmation(authService, foundUser)
 		if err != nil {
-			return c.Status(err.Status).Render("page-error", fiber.Map{
-				"ErrorStatus":  err.Status,
-				"ErrorMessage": err.Message,
-				"Error":        err.Error,
-			})
+			return c.Status(err.Status).JSON(err)
 		}
 
-		foundUser, err := userService.FindByID(userID)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// forgotPassword is a function to send password reset link by email
+// @Summary Send password reset link, response does not tell whether email is registered
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body entity.ForgotPassword true "Email"
+// @Success 200 {object} entity.Response{}
+// @Failure 422 {object} errors.AppError{}
+// @Failure 429 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /auth/password/forgot [post]
+func forgotPassword(authService service.AuthService, userService service.UserService, rateLimitService service.RateLimitService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+
+		requestBody := new(entity.ForgotPassword)
+		if err := c.BodyParser(requestBody); err != nil {
+			parseError := errors.UnprocessableEntity("Invalid request body")
+			return c.Status(parseError.Status).JSON(parseError)
+		}
+
+		response := entity.ToResponse(
+			"If an account exists for this email, a password reset link is sent to it",
+			http.StatusOK,
+			nil,
+		)
+
+		foundUser, err := userService.FindByEmail(requestBody.Email)
 		if err != nil {
-			return c.Status(err.Status).Render("page-error", fiber.Map{
-				"ErrorStatus":  err.Status,
-				"ErrorMessage": err.Message,
-				"Error":        err.Error,
-			})
+			if err.Status == http.StatusNotFound {
+				return c.Status(response.Status).JSON(response)
+			}
+			return c.Status(err.Status).JSON(err)
 		}
 
-		err = userService.Confirm(foundUser.ID)
+		// Same response is returned, so limit does not tell whether email is registered
+		if err := rateLimitService.AllowEmail(foundUser.Email); err != nil {
+			if err.Status == http.StatusTooManyRequests {
+				return c.Status(response.Status).JSON(response)
+			}
+			return c.Status(err.Status).JSON(err)
+		}
+
+		token, err := authService.CreateActionToken(entity.ActionResetPassword, &entity.ActionToken{UserID: foundUser.ID})
 		if err != nil {
-			return c.Status(err.Status).Render("page-error", fiber.Map{
-				"ErrorStatus":  err.Status,
-				"ErrorMessage": err.Message,
-				"Error":        err.Error,
-			})
+			return c.Status(err.Status).JSON(err)
 		}
 
-		tokens, err := app.CreateToken(foundUser)
+		err = sendEmail(foundUser.Email, "Marvin | Reset your password", "email-password-reset", emailTemplate{
+			User: foundUser.Name,
+			Link: authLink("/auth/password/reset", token),
+		})
 		if err != nil {
-			return c.Status(err.Status).Render("page-error", fiber.Map{
-				"ErrorStatus":  err.Status,
-				"ErrorMessage": err.Message,
-				"Error":        err.Error,
-			})
+			return c.Status(err.Status).JSON(err)
+		}
+
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// resetPasswordPage is a function to render form of password reset link
+// @Summary Password reset form
+// @Tags auth
+// @Param t query string true "token"
+// @Produce html
+// @Router /auth/password/reset [get]
+func resetPasswordPage() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+
+		// Token is used when form is submitted, so previews of email clients do not consume it
+		if c.Query("t") == "" {
+			return renderError(c, errors.BadRequest("Token is required"))
+		}
+
+		return c.Status(http.StatusOK).Render("page-password-reset", fiber.Map{})
+	}
+}
+
+// resetPassword is a function to set new password with token of reset link
+// @Summary Set new password with token of reset link
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body entity.ResetPassword true "Token and new password"
+// @Success 200 {object} entity.Response{}
+// @Failure 400 {object} errors.AppError{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 422 {object} errors.AppError{}
+// @Failure 429 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /auth/password/reset [post]
+func resetPassword(authService service.AuthService, userService service.UserService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+
+		requestBody := new(entity.ResetPassword)
+		if err := c.BodyParser(requestBody); err != nil {
+			parseError := errors.UnprocessableEntity("Invalid request body")
+			return c.Status(parseError.Status).JSON(parseError)
+		}
+
+		// Invalid passwords should not use up the link
+		if validationError := entity.ValidatePassword(requestBody.Password); validationError != "" {
+			err := errors.BadRequest(validationError)
+			return c.Status(err.Status).JSON(err)
 		}
 
-		err = authService.CreateAuth(tokens.RefreshToken)
+		actionToken, err := authService.FindActionToken(entity.ActionResetPassword, requestBody.Token)
 		if err != nil {
-			return c.Status(err.Status).Render("page-error", fiber.Map{
-				"ErrorStatus":  err.Status,
-				"ErrorMessage": err.Message,
-				"Error":        err.Error,
-			})
+			return c.Status(err.Status).JSON(err)
 		}
 
-		err = authService.DeleteAuth(uuid)
+		err = userService.UpdatePassword(actionToken.UserID, requestBody.Password)
 		if err != nil {
-			return c.Status(err.Status).Render("page-error", fiber.Map{
-				"ErrorStatus":  err.Status,
-				"ErrorMessage": err.Message,
-				"Error":        err.Error,
-			})
+			return c.Status(err.Status).JSON(err)
 		}
 
-		return c.Status(http.StatusOK).Render("page-signup-result", fiber.Map{
-			"User": foundUser.Name,
+		// Someone else could be logged in with old password
+		err = authService.RevokeSessions(actionToken.UserID, "")
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse("Your password is changed, you can login with your new password", http.StatusOK, nil)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// confirmEmail is a function to change user's email with link that is sent to new address
+// @Summary Verify new email of user
+// @Tags auth
+// @Param t query string true "token"
+// @Produce html
+// @Router /auth/email/confirm [get]
+func confirmEmail(authService service.AuthService, userService service.UserService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+
+		actionToken, err := authService.FindActionToken(entity.ActionChangeEmail, c.Query("t"))
+		if err != nil {
+			return renderError(c, err)
+		}
+
+		updated, err := userService.UpdateEmail(actionToken.UserID, actionToken.Email)
+		if err != nil {
+			return renderError(c, err)
+		}
+
+		return c.Status(http.StatusOK).Render("page-email-result", fiber.Map{
+			"User":  updated.Name,
+			"Email": updated.Email,
 		})
 	}
 }
+
+// Sends link of account confirmation, link can be used once
+func sendConfirmation(authService service.AuthService, user *entity.UserDTO) *errors.AppError {
+
+	token, err := authService.CreateActionToken(entity.ActionConfirmAccount, &entity.ActionToken{UserID: user.ID})
+	if err != nil {
+		return err
+	}
+
+	return sendEmail(user.Email, "Marvin | Please confirm your email", "email-signup-verify", emailTemplate{
+		User: user.Name,
+		Link: authLink("/auth/confirm", token),
+	})
+}
+
+// Device and IP of session are shown in session list
+func sessionClient(c *fiber.Ctx) *entity.SessionClient {
+	return &entity.SessionClient{
+		Device: c.Get(fiber.HeaderUserAgent),
+		IP:     c.IP(),
+	}
+}
+
+// Data of email templates that has action link
+type emailTemplate struct {
+	User string
+	Link string
+}
+
+// Tests replace it, so emails are not sent
+var sendEmail = parseAndSendEmail
+
+// Parses email template in web directory and sends it
+func parseAndSendEmail(to string, subject string, template string, data interface{}) *errors.AppError {
+
+	emailBody, err := app.ParseHTMLTemplate("./web/"+template+".html", data)
+	if err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	if err := app.SendEmail(to, subject, emailBody); err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	return nil
+}
+
+// Link of email actions that are handled by server
+func authLink(path string, token string) string {
+	cnf := config.Get().HTTP
+	return fmt.Sprintf("http://%s%s%s?t=%s", cnf.Host, cnf.Port, path, token)
+}
+
+func renderError(c *fiber.Ctx, err *errors.AppError) error {
+	return c.Status(err.Status).Render("page-error", fiber.Map{
+		"ErrorStatus":  err.Status,
+		"ErrorMessage": err.Message,
+		"Error":        err.Error,
+	})
+}
@@ -0,0 +1,343 @@
+package api
+
+import (
+	"github.com/go-redis/redis/v8"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nozgurozturk/marvin/pkg/errors"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"github.com/nozgurozturk/marvin/server/internal/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeActionToken struct {
+	action      entity.TokenAction
+	actionToken *entity.ActionToken
+	expires     time.Duration
+	expiresAt   time.Time
+}
+
+func (r *fakeAuthRepository) CreateActionToken(action entity.TokenAction, token string, actionToken *entity.ActionToken, expires time.Duration) error {
+	if r.tokens == nil {
+		r.tokens = map[string]*fakeActionToken{}
+	}
+	r.tokens[token] = &fakeActionToken{action: action, actionToken: actionToken, expires: expires, expiresAt: time.Now().Add(expires)}
+	return nil
+}
+
+func (r *fakeAuthRepository) FindActionToken(action entity.TokenAction, token string) (*entity.ActionToken, error) {
+	found, ok := r.tokens[token]
+	if !ok || found.action != action {
+		return nil, redis.Nil
+	}
+	delete(r.tokens, token)
+	if time.Now().After(found.expiresAt) {
+		return nil, redis.Nil
+	}
+	return found.actionToken, nil
+}
+
+func (r *fakeAuthRepository) FindSessions(userID string) ([]*entity.Session, error) {
+	var sessions []*entity.Session
+	for _, session := range r.sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *fakeAuthRepository) DeleteSession(session *entity.Session) error {
+	delete(r.sessions, session.ID)
+	return nil
+}
+
+// Passwords are hashed like in user repository
+func (r *fakeUserRepository) Update(user *entity.User) (*entity.User, error) {
+	found := r.find(func(u *entity.User) bool { return u.ID == user.ID })
+	if found == nil {
+		return nil, nil
+	}
+	if user.Password != "" {
+		hashed, err := entity.HashPassword(user.Password)
+		if err != nil {
+			return nil, err
+		}
+		found.Password = string(hashed)
+	}
+	return found, nil
+}
+
+// Rate limit that allows emails until limited is set
+type fakeRateLimitService struct {
+	service.RateLimitService
+	limited bool
+}
+
+func (s *fakeRateLimitService) AllowEmail(to string) *errors.AppError {
+	if s.limited {
+		return errors.TooManyRequests("Too many emails are sent to this address")
+	}
+	return nil
+}
+
+type sentEmail struct {
+	to       string
+	template string
+	link     string
+}
+
+type authTest struct {
+	app      *fiber.App
+	authRepo *fakeAuthRepository
+	userRepo *fakeUserRepository
+	limit    *fakeRateLimitService
+	emails   []sentEmail
+}
+
+// Serves email routes for users, requests of user routes are made by first user
+func newAuthTest(t *testing.T, users ...*entity.User) *authTest {
+
+	setTestConfig(t, nil)
+
+	a := &authTest{
+		authRepo: &fakeAuthRepository{sessions: map[string]*entity.Session{}},
+		userRepo: &fakeUserRepository{users: users},
+		limit:    &fakeRateLimitService{},
+	}
+
+	previous := sendEmail
+	sendEmail = func(to string, subject string, template string, data interface{}) *errors.AppError {
+		a.emails = append(a.emails, sentEmail{to: to, template: template, link: data.(emailTemplate).Link})
+		return nil
+	}
+	t.Cleanup(func() { sendEmail = previous })
+
+	authService := service.NewAuthService(a.authRepo)
+	userService := service.NewUserService(a.userRepo)
+
+	a.app = fiber.New()
+	a.app.Post("/auth/confirm/resend", resendConfirmation(authService, userService, a.limit))
+	a.app.Post("/auth/password/forgot", forgotPassword(authService, userService, a.limit))
+	a.app.Post("/auth/password/reset", resetPassword(authService, userService))
+	a.app.Put("/api/user/email", func(c *fiber.Ctx) error {
+		c.Locals("user", users[0].ID.Hex())
+		return c.Next()
+	}, changeEmail(authService, userService, a.limit))
+
+	return a
+}
+
+func (a *authTest) send(t *testing.T, method string, path string, body string) int {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	response, err := a.app.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response.StatusCode
+}
+
+// Gets token of link in last sent email
+func (a *authTest) lastToken(t *testing.T) string {
+	t.Helper()
+	if len(a.emails) == 0 {
+		t.Fatal("expected email to be sent")
+	}
+	link, err := url.Parse(a.emails[len(a.emails)-1].link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return link.Query().Get("t")
+}
+
+func passwordUser(t *testing.T, password string) *entity.User {
+	hashed, err := entity.HashPassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := existingUser()
+	user.Password = string(hashed)
+	user.IsConfirmed = true
+	return user
+}
+
+func TestResetPasswordRevokesSessionsAndLinkIsUsedOnce(t *testing.T) {
+
+	user := passwordUser(t, "old-password")
+	a := newAuthTest(t, user)
+	userID := user.ID.Hex()
+	a.authRepo.sessions["laptop"] = &entity.Session{ID: "laptop", UserID: userID}
+	a.authRepo.sessions["phone"] = &entity.Session{ID: "phone", UserID: userID}
+	a.authRepo.sessions["other"] = &entity.Session{ID: "other", UserID: primitive.NewObjectID().Hex()}
+
+	if status := a.send(t, http.MethodPost, "/auth/password/forgot", `{"email":"octocat@example.com"}`); status != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, status)
+	}
+	token := a.lastToken(t)
+	if a.emails[0].to != user.Email || a.emails[0].template != "email-password-reset" {
+		t.Errorf("unexpected email %+v", a.emails[0])
+	}
+	if expires := a.authRepo.tokens[token].expires; expires != time.Hour {
+		t.Errorf("expected reset link to expire in an hour, got %s", expires)
+	}
+
+	// Invalid password does not use up the link
+	if status := a.send(t, http.MethodPost, "/auth/password/reset", `{"token":"`+token+`","password":"short"}`); status != http.StatusBadRequest {
+		t.Fatalf("expected %d, got %d", http.StatusBadRequest, status)
+	}
+
+	if status := a.send(t, http.MethodPost, "/auth/password/reset", `{"token":"`+token+`","password":"new-password"}`); status != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, status)
+	}
+	if entity.VerifyPassword(user.Password, "new-password") != nil {
+		t.Error("expected password to be changed")
+	}
+	if len(a.authRepo.sessions) != 1 || a.authRepo.sessions["other"] == nil {
+		t.Errorf("expected every session of user to be revoked, got %v", a.authRepo.sessions)
+	}
+
+	if status := a.send(t, http.MethodPost, "/auth/password/reset", `{"token":"`+token+`","password":"another-password"}`); status != http.StatusUnauthorized {
+		t.Errorf("expected reused link to be rejected with %d, got %d", http.StatusUnauthorized, status)
+	}
+	if entity.VerifyPassword(user.Password, "new-password") != nil {
+		t.Error("expected reused link not to change password")
+	}
+}
+
+func TestResetPasswordRejectsExpiredLink(t *testing.T) {
+
+	user := passwordUser(t, "old-password")
+	a := newAuthTest(t, user)
+
+	if status := a.send(t, http.MethodPost, "/auth/password/forgot", `{"email":"octocat@example.com"}`); status != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, status)
+	}
+	token := a.lastToken(t)
+	a.authRepo.tokens[token].expiresAt = time.Now().Add(-time.Second)
+
+	if status := a.send(t, http.MethodPost, "/auth/password/reset", `{"token":"`+token+`","password":"new-password"}`); status != http.StatusUnauthorized {
+		t.Errorf("expected %d, got %d", http.StatusUnauthorized, status)
+	}
+	if entity.VerifyPassword(user.Password, "old-password") != nil {
+		t.Error("expected expired link not to change password")
+	}
+
+	if status := a.send(t, http.MethodPost, "/auth/password/reset", `{"token":"","password":"new-password"}`); status != http.StatusBadRequest {
+		t.Errorf("expected empty token to be rejected with %d, got %d", http.StatusBadRequest, status)
+	}
+}
+
+func TestForgotPasswordDoesNotTellWhetherEmailIsRegistered(t *testing.T) {
+
+	a := newAuthTest(t, passwordUser(t, "old-password"))
+
+	if status := a.send(t, http.MethodPost, "/auth/password/forgot", `{"email":"unknown@example.com"}`); status != http.StatusOK {
+		t.Errorf("expected %d, got %d", http.StatusOK, status)
+	}
+
+	a.limit.limited = true
+	if status := a.send(t, http.MethodPost, "/auth/password/forgot", `{"email":"octocat@example.com"}`); status != http.StatusOK {
+		t.Errorf("expected limited request to get %d, got %d", http.StatusOK, status)
+	}
+
+	if len(a.emails) != 0 || len(a.authRepo.tokens) != 0 {
+		t.Errorf("expected no email to be sent, got %+v", a.emails)
+	}
+}
+
+func TestResendConfirmation(t *testing.T) {
+
+	unconfirmed := existingUser()
+	confirmed := passwordUser(t, "password")
+	confirmed.Email = "confirmed@example.com"
+	a := newAuthTest(t, unconfirmed, confirmed)
+
+	tests := []struct {
+		name    string
+		email   string
+		limited bool
+		sent    bool
+	}{
+		{"unconfirmed user", unconfirmed.Email, false, true},
+		{"confirmed user", confirmed.Email, false, false},
+		{"unknown email", "unknown@example.com", false, false},
+		{"limited email", unconfirmed.Email, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a.emails = nil
+			a.limit.limited = tt.limited
+
+			if status := a.send(t, http.MethodPost, "/auth/confirm/resend", `{"email":"`+tt.email+`"}`); status != http.StatusOK {
+				t.Fatalf("expected %d, got %d", http.StatusOK, status)
+			}
+			if sent := len(a.emails) == 1; sent != tt.sent {
+				t.Fatalf("expected email to be sent %v, got %+v", tt.sent, a.emails)
+			}
+			if tt.sent {
+				if expires := a.authRepo.tokens[a.lastToken(t)].expires; expires != 24*time.Hour {
+					t.Errorf("expected confirmation link to expire in a day, got %s", expires)
+				}
+			}
+		})
+	}
+}
+
+func TestChangeEmail(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		password string
+		body     string
+		status   int
+	}{
+		{"oauth user without password", "", `{"email":"new@example.com"}`, http.StatusOK},
+		{"correct password", "password", `{"email":"new@example.com","password":"password"}`, http.StatusOK},
+		{"wrong password", "password", `{"email":"new@example.com","password":"wrong-password"}`, http.StatusUnauthorized},
+		{"missing password", "password", `{"email":"new@example.com"}`, http.StatusUnauthorized},
+		{"taken email", "", `{"email":"taken@example.com"}`, http.StatusConflict},
+		{"same email", "", `{"email":"octocat@example.com"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := existingUser()
+			user.Password = ""
+			if tt.password != "" {
+				user = passwordUser(t, tt.password)
+			}
+			taken := existingUser()
+			taken.Email = "taken@example.com"
+			a := newAuthTest(t, user, taken)
+
+			if status := a.send(t, http.MethodPut, "/api/user/email", tt.body); status != tt.status {
+				t.Fatalf("expected %d, got %d", tt.status, status)
+			}
+			if tt.status != http.StatusOK {
+				if len(a.emails) != 0 {
+					t.Errorf("expected no email to be sent, got %+v", a.emails)
+				}
+				return
+			}
+
+			token := a.lastToken(t)
+			if a.emails[0].to != "new@example.com" || a.emails[0].template != "email-change-verify" {
+				t.Errorf("expected link to be sent to new address, got %+v", a.emails[0])
+			}
+			found := a.authRepo.tokens[token]
+			if found.action != entity.ActionChangeEmail || found.actionToken.UserID != user.ID.Hex() || found.actionToken.Email != "new@example.com" {
+				t.Errorf("unexpected token %+v", found.actionToken)
+			}
+			if user.Email != "octocat@example.com" {
+				t.Error("expected email not to be changed before it is confirmed")
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nozgurozturk/marvin/server/internal/report"
+	"github.com/nozgurozturk/marvin/server/internal/service"
+	"net/http"
+)
+
+// Badges are cached by browsers and image proxies, for exp. camo of GitHub
+const badgeMaxAge = 5 * 60
+
+// BadgeHandler no need authentication, badges are protected with repository's badge token
+func BadgeHandler(router fiber.Router, repoService service.RepoService) {
+	router.Get("/:id/:token", findBadge(repoService))
+}
+
+// findBadge is a function to render status badge of repository
+// @Summary Returns SVG badge with vulnerable, deprecated and outdated package counts
+// @Tags badge
+// @Produce image/svg+xml
+// @Param id path string true "Repository id"
+// @Param token path string true "Badge token"
+// @Success 200 {string} string
+// @Success 304 {string} string
+// @Failure 404 {object} errors.AppError{}
+// @Router /badge/{id}/{token} [get]
+func findBadge(s service.RepoService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		repo, err := s.FindByBadgeToken(c.Params("id"), c.Params("token"))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		badge := report.RenderBadge(repo)
+
+		hash := sha1.Sum(badge)
+		etag := fmt.Sprintf(`"%s"`, hex.EncodeToString(hash[:]))
+
+		c.Set(fiber.HeaderCacheControl, fmt.Sprintf("public, max-age=%d", badgeMaxAge))
+		c.Set(fiber.HeaderETag, etag)
+
+		if c.Get(fiber.HeaderIfNoneMatch) == etag {
+			return c.SendStatus(http.StatusNotModified)
+		}
+
+		c.Set(fiber.HeaderContentType, "image/svg+xml")
+		return c.Status(http.StatusOK).Send(badge)
+	}
+}
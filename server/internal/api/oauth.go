@@ -0,0 +1,164 @@
+package api
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nozgurozturk/marvin/pkg/errors"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"github.com/nozgurozturk/marvin/server/internal/app"
+	"github.com/nozgurozturk/marvin/server/internal/service"
+	"net/http"
+	"time"
+)
+
+// Cookie that ties authorization request to browser that started it, callbacks of other browsers are rejected
+const oauthStateCookie = "oauth_state"
+
+func setOAuthStateCookie(c *fiber.Ctx, value string, expires time.Time) {
+	c.Cookie(&fiber.Cookie{
+		Name:     oauthStateCookie,
+		Value:    value,
+		Path:     "/auth/oauth",
+		Expires:  expires,
+		Secure:   c.Protocol() == "https",
+		HTTPOnly: true,
+		// Lax cookies are sent with top level redirect of provider
+		SameSite: "Lax",
+	})
+}
+
+// oauthLogin is a function to redirect user to provider's authorization page
+// @Summary Login with GitHub or GitLab account
+// @Tags auth
+// @Param provider path string true "github or gitlab"
+// @Param repos query bool false "Grant access to private repositories"
+// @Success 302
+// @Failure 404 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /auth/oauth/{provider} [get]
+func oauthLogin(authService service.AuthService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+
+		provider, err := app.GetOAuthProvider(c.Params("provider"))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		state, err := app.NewOAuthState()
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		oauthState := &entity.OAuthState{
+			Provider:   provider.Name,
+			RepoAccess: c.Query("repos") == "true",
+		}
+
+		err = authService.CreateOAuthState(state, oauthState)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		// Session cookie, state itself expires in store
+		setOAuthStateCookie(c, app.OAuthStateHash(state), time.Time{})
+
+		return c.Redirect(provider.AuthCodeUrl(state, oauthState.RepoAccess), http.StatusFound)
+	}
+}
+
+// oauthCallback is a function to login user that is redirected back from provider
+// @Summary Login with authorization code of provider
+// @Tags auth
+// @Produce json
+// @Param provider path string true "github or gitlab"
+// @Param code query string true "Authorization code"
+// @Param state query string true "State"
+// @Success 200 {object} entity.Response{data=entity.TokenResponse}
+// @Failure 400 {object} errors.AppError{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /auth/oauth/{provider}/callback [get]
+func oauthCallback(authService service.AuthService, userService service.UserService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+
+		provider, err := app.GetOAuthProvider(c.Params("provider"))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		// User denied authorization
+		if denied := c.Query("error"); denied != "" {
+			err = errors.Unauthorized(fmt.Sprintf("Authorization is denied: %s", denied))
+			return c.Status(err.Status).JSON(err)
+		}
+
+		// State is used once, cookie is cleared with any result
+		stateHash := c.Cookies(oauthStateCookie)
+		setOAuthStateCookie(c, "", time.Unix(0, 0))
+
+		if !app.VerifyOAuthState(c.Query("state"), stateHash) {
+			err = errors.Unauthorized("Authorization request is not started in this browser")
+			return c.Status(err.Status).JSON(err)
+		}
+
+		oauthState, err := authService.FindOAuthState(c.Query("state"))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if oauthState.Provider != provider.Name {
+			err = errors.Unauthorized("Authorization request is expired or invalid")
+			return c.Status(err.Status).JSON(err)
+		}
+
+		accessToken, err := provider.Exchange(c.Context(), c.Query("code"))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		profile, err := provider.GetProfile(c.Context(), accessToken)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		identity := &entity.Identity{
+			Provider: provider.Name,
+			Host:     provider.Host,
+			ID:       profile.ID,
+			Username: profile.Username,
+		}
+		if oauthState.RepoAccess {
+			identity.Token = accessToken
+		}
+
+		currentUser, err := userService.LoginWithIdentity(profile, identity)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		tokens, err := app.CreateToken(currentUser, "")
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		err = authService.CreateSession(tokens, sessionClient(c))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		tokenStrings := entity.TokenDetailsToResponse(tokens)
+		user := entity.ToUserResponse(currentUser)
+
+		response := entity.ToResponse(
+			"Successful login",
+			http.StatusOK,
+			fiber.Map{
+				"tokens": tokenStrings,
+				"user":   user,
+			},
+		)
+
+		return c.Status(response.Status).JSON(response)
+	}
+}
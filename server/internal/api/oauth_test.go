@@ -0,0 +1,440 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/go-redis/redis/v8"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"github.com/nozgurozturk/marvin/server/internal/app"
+	"github.com/nozgurozturk/marvin/server/internal/config"
+	"github.com/nozgurozturk/marvin/server/internal/service"
+	"github.com/nozgurozturk/marvin/server/internal/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Loads config from given variables, required expiration variables are set if they are not given
+func setTestConfig(t *testing.T, env map[string]string) {
+	t.Helper()
+
+	defaults := map[string]string{
+		"HOST":           "localhost",
+		"PORT":           "8080",
+		"ACCESS_SECRET":  "access-secret",
+		"REFRESH_SECRET": "refresh-secret",
+		"ACCESS_EXPIRE":  "60",
+		"REFRESH_EXPIRE": "720",
+		"SUB_EXPIRE":     "24",
+	}
+	for key, value := range env {
+		defaults[key] = value
+	}
+
+	for key, value := range defaults {
+		key := key
+		previous, exist := os.LookupEnv(key)
+		if err := os.Setenv(key, value); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			if exist {
+				os.Setenv(key, previous)
+			} else {
+				os.Unsetenv(key)
+			}
+		})
+	}
+
+	config.Set()
+}
+
+// Auth store that keeps states, sessions and email links in memory
+type fakeAuthRepository struct {
+	storage.AuthRepository
+	states   map[string]*entity.OAuthState
+	sessions map[string]*entity.Session
+	tokens   map[string]*fakeActionToken
+}
+
+func (r *fakeAuthRepository) CreateOAuthState(state string, oauthState *entity.OAuthState, expires time.Duration) error {
+	r.states[state] = oauthState
+	return nil
+}
+
+func (r *fakeAuthRepository) FindOAuthState(state string) (*entity.OAuthState, error) {
+	oauthState, ok := r.states[state]
+	if !ok {
+		return nil, redis.Nil
+	}
+	delete(r.states, state)
+	return oauthState, nil
+}
+
+func (r *fakeAuthRepository) CreateSession(session *entity.Session) error {
+	r.sessions[session.ID] = session
+	return nil
+}
+
+// User store that keeps users in memory
+type fakeUserRepository struct {
+	storage.UserRepository
+	users []*entity.User
+}
+
+func (r *fakeUserRepository) find(match func(u *entity.User) bool) *entity.User {
+	for _, u := range r.users {
+		if match(u) {
+			return u
+		}
+	}
+	return nil
+}
+
+func (r *fakeUserRepository) FindByID(userID string) (*entity.User, error) {
+	return r.find(func(u *entity.User) bool { return u.ID.Hex() == userID }), nil
+}
+
+func (r *fakeUserRepository) FindByEmail(email string) (*entity.User, error) {
+	return r.find(func(u *entity.User) bool { return u.Email == email }), nil
+}
+
+func (r *fakeUserRepository) FindByIdentity(provider string, id string) (*entity.User, error) {
+	return r.find(func(u *entity.User) bool {
+		for _, identity := range u.Identities {
+			if identity.Provider == provider && identity.ID == id {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+func (r *fakeUserRepository) Create(user *entity.User) (*entity.User, error) {
+	user.ID = primitive.NewObjectID()
+	r.users = append(r.users, user)
+	return user, nil
+}
+
+func (r *fakeUserRepository) UpdateIdentities(userID string, identities []*entity.Identity) error {
+	if u, _ := r.FindByID(userID); u != nil {
+		u.Identities = identities
+	}
+	return nil
+}
+
+func (r *fakeUserRepository) Confirm(userID string, confirmed bool) error {
+	if u, _ := r.FindByID(userID); u != nil {
+		u.IsConfirmed = confirmed
+	}
+	return nil
+}
+
+// Stub of GitHub that exchanges valid-code with access-token, exchanges are counted
+type stubGithub struct {
+	host          string
+	exchanges     int
+	emailVerified bool
+}
+
+func (s *stubGithub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/login/oauth/access_token":
+		s.exchanges++
+		var body map[string]string
+		data, _ := ioutil.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &body)
+		if body["code"] != "valid-code" {
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "bad_verification_code"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "access-token"})
+	case "/user":
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": 42, "login": "octocat"})
+	case "/user/emails":
+		_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"email": "octocat@example.com", "primary": true, "verified": s.emailVerified},
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+type oauthTest struct {
+	app      *fiber.App
+	github   *stubGithub
+	auth     service.AuthService
+	authRepo *fakeAuthRepository
+	userRepo *fakeUserRepository
+}
+
+func newOAuthTest(t *testing.T, users ...*entity.User) *oauthTest {
+
+	github := &stubGithub{emailVerified: true}
+	server := httptest.NewServer(github)
+	t.Cleanup(server.Close)
+	github.host = strings.TrimPrefix(server.URL, "http://")
+
+	setTestConfig(t, map[string]string{
+		"GITHUB_CLIENT_ID":     "client-id",
+		"GITHUB_CLIENT_SECRET": "client-secret",
+		"GITHUB_OAUTH_URL":     server.URL,
+		"GITHUB_API_URL":       server.URL,
+	})
+
+	authRepo := &fakeAuthRepository{states: map[string]*entity.OAuthState{}, sessions: map[string]*entity.Session{}}
+	userRepo := &fakeUserRepository{users: users}
+	authService := service.NewAuthService(authRepo)
+
+	fiberApp := fiber.New()
+	fiberApp.Get("/auth/oauth/:provider", oauthLogin(authService))
+	fiberApp.Get("/auth/oauth/:provider/callback", oauthCallback(authService, service.NewUserService(userRepo)))
+
+	return &oauthTest{app: fiberApp, github: github, auth: authService, authRepo: authRepo, userRepo: userRepo}
+}
+
+// Sends callback from browser that started login with state
+func (o *oauthTest) callback(t *testing.T, provider string, code string, state string) int {
+	return o.callbackWithCookie(t, provider, code, state, app.OAuthStateHash(state)).StatusCode
+}
+
+func (o *oauthTest) callbackWithCookie(t *testing.T, provider string, code string, state string, stateHash string) *http.Response {
+	query := url.Values{}
+	query.Set("code", code)
+	query.Set("state", state)
+	request := httptest.NewRequest(http.MethodGet, "/auth/oauth/"+provider+"/callback?"+query.Encode(), nil)
+	if stateHash != "" {
+		request.AddCookie(&http.Cookie{Name: oauthStateCookie, Value: stateHash})
+	}
+	response, err := o.app.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+// Gets cookie that response sets
+func responseCookie(response *http.Response, name string) *http.Cookie {
+	for _, cookie := range response.Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func existingUser() *entity.User {
+	return &entity.User{
+		ID:       primitive.NewObjectID(),
+		Name:     "Octo Cat",
+		Email:    "octocat@example.com",
+		Password: "hash",
+	}
+}
+
+func TestOAuthCallbackLinksExistingAccountByEmail(t *testing.T) {
+
+	user := existingUser()
+	o := newOAuthTest(t, user)
+
+	if err := o.auth.CreateOAuthState("state", &entity.OAuthState{Provider: "github", RepoAccess: true}); err != nil {
+		t.Fatal(err.Message)
+	}
+
+	if status := o.callback(t, "github", "valid-code", "state"); status != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, status)
+	}
+
+	if len(o.userRepo.users) != 1 {
+		t.Fatalf("expected identity to be linked to existing user, got %d users", len(o.userRepo.users))
+	}
+	if len(user.Identities) != 1 {
+		t.Fatalf("expected 1 linked identity, got %d", len(user.Identities))
+	}
+	identity := user.Identities[0]
+	if identity.Provider != "github" || identity.ID != "42" || identity.Username != "octocat" || identity.Host != o.github.host {
+		t.Errorf("unexpected identity %+v", identity)
+	}
+	if identity.Token != "access-token" {
+		t.Errorf("expected granted token to be saved with repository access, got %q", identity.Token)
+	}
+	if !user.IsConfirmed {
+		t.Error("expected verified email of provider to confirm user")
+	}
+	if len(o.authRepo.sessions) != 1 {
+		t.Errorf("expected session of login, got %d sessions", len(o.authRepo.sessions))
+	}
+
+	// States can be used once
+	if status := o.callback(t, "github", "valid-code", "state"); status != http.StatusUnauthorized {
+		t.Errorf("expected reused state to be rejected, got %d", status)
+	}
+	if o.github.exchanges != 1 {
+		t.Errorf("expected code to be exchanged once, got %d exchanges", o.github.exchanges)
+	}
+}
+
+func TestOAuthCallbackWithoutRepoAccess(t *testing.T) {
+
+	o := newOAuthTest(t)
+
+	if err := o.auth.CreateOAuthState("state", &entity.OAuthState{Provider: "github"}); err != nil {
+		t.Fatal(err.Message)
+	}
+
+	if status := o.callback(t, "github", "valid-code", "state"); status != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, status)
+	}
+
+	if len(o.userRepo.users) != 1 {
+		t.Fatalf("expected new user, got %d users", len(o.userRepo.users))
+	}
+	created := o.userRepo.users[0]
+	if created.Email != "octocat@example.com" || !created.IsConfirmed || created.Password != "" {
+		t.Errorf("unexpected user %+v", created)
+	}
+	if token := created.Identities[0].Token; token != "" {
+		t.Errorf("expected token not to be saved without repository access, got %q", token)
+	}
+}
+
+func TestOAuthCallbackRejectsInvalidState(t *testing.T) {
+
+	o := newOAuthTest(t, existingUser())
+
+	if err := o.auth.CreateOAuthState("gitlab-state", &entity.OAuthState{Provider: "gitlab"}); err != nil {
+		t.Fatal(err.Message)
+	}
+
+	tests := []struct {
+		name  string
+		state string
+	}{
+		{"unknown state", "unknown"},
+		{"empty state", ""},
+		{"state of other provider", "gitlab-state"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := o.callback(t, "github", "valid-code", tt.state); status != http.StatusUnauthorized {
+				t.Errorf("expected %d, got %d", http.StatusUnauthorized, status)
+			}
+		})
+	}
+
+	if o.github.exchanges != 0 {
+		t.Errorf("expected code not to be exchanged with invalid state, got %d exchanges", o.github.exchanges)
+	}
+	if len(o.userRepo.users[0].Identities) != 0 {
+		t.Error("expected identity not to be linked with invalid state")
+	}
+}
+
+func TestOAuthCallbackRejectsUnverifiedEmail(t *testing.T) {
+
+	user := existingUser()
+	o := newOAuthTest(t, user)
+	o.github.emailVerified = false
+
+	if err := o.auth.CreateOAuthState("state", &entity.OAuthState{Provider: "github"}); err != nil {
+		t.Fatal(err.Message)
+	}
+
+	if status := o.callback(t, "github", "valid-code", "state"); status != http.StatusBadRequest {
+		t.Errorf("expected %d, got %d", http.StatusBadRequest, status)
+	}
+	if len(user.Identities) != 0 || len(o.userRepo.users) != 1 {
+		t.Error("expected unverified email not to be linked to existing account")
+	}
+}
+
+func TestOAuthCallbackRejectsInvalidCode(t *testing.T) {
+
+	o := newOAuthTest(t)
+
+	if err := o.auth.CreateOAuthState("state", &entity.OAuthState{Provider: "github"}); err != nil {
+		t.Fatal(err.Message)
+	}
+
+	if status := o.callback(t, "github", "expired-code", "state"); status != http.StatusUnauthorized {
+		t.Errorf("expected %d, got %d", http.StatusUnauthorized, status)
+	}
+	if len(o.userRepo.users) != 0 {
+		t.Error("expected user not to be created with invalid code")
+	}
+}
+
+func TestOAuthLoginTiesStateToBrowser(t *testing.T) {
+
+	o := newOAuthTest(t)
+
+	response, err := o.app.Test(httptest.NewRequest(http.MethodGet, "/auth/oauth/github", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusFound {
+		t.Fatalf("expected %d, got %d", http.StatusFound, response.StatusCode)
+	}
+
+	location, err := url.Parse(response.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := location.Query().Get("state")
+
+	cookie := responseCookie(response, oauthStateCookie)
+	if cookie == nil || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+		t.Fatalf("expected http only and lax state cookie, got %v", cookie)
+	}
+	if cookie.Value == state || cookie.Value != app.OAuthStateHash(state) {
+		t.Errorf("expected hash of state in cookie, got %q", cookie.Value)
+	}
+
+	callback := o.callbackWithCookie(t, "github", "valid-code", state, cookie.Value)
+	if callback.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, callback.StatusCode)
+	}
+	if cleared := responseCookie(callback, oauthStateCookie); cleared == nil || cleared.Value != "" || cleared.Expires.After(time.Now()) {
+		t.Errorf("expected state cookie to be cleared, got %v", cleared)
+	}
+}
+
+func TestOAuthCallbackRejectsOtherBrowser(t *testing.T) {
+
+	o := newOAuthTest(t, existingUser())
+
+	tests := []struct {
+		name      string
+		stateHash string
+	}{
+		{"without cookie", ""},
+		{"cookie of other state", app.OAuthStateHash("victim-state")},
+		{"state in cookie", "state"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// State of attacker is valid in store
+			if err := o.auth.CreateOAuthState("state", &entity.OAuthState{Provider: "github"}); err != nil {
+				t.Fatal(err.Message)
+			}
+			if status := o.callbackWithCookie(t, "github", "valid-code", "state", tt.stateHash).StatusCode; status != http.StatusUnauthorized {
+				t.Errorf("expected %d, got %d", http.StatusUnauthorized, status)
+			}
+		})
+	}
+
+	if o.github.exchanges != 0 {
+		t.Errorf("expected code not to be exchanged for other browser, got %d exchanges", o.github.exchanges)
+	}
+	if len(o.userRepo.users[0].Identities) != 0 {
+		t.Error("expected identity not to be linked for other browser")
+	}
+}
@@ -0,0 +1,310 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/nozgurozturk/marvin/pkg/errors"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"github.com/nozgurozturk/marvin/server/internal/service"
+	"net/http"
+)
+
+func OrganizationHandler(router fiber.Router, orgService service.OrganizationService) {
+	router.Post("/", createOrganization(orgService))
+	router.Get("/", findAllOrganization(orgService))
+	router.Get("/:id", findOrganization(orgService))
+	router.Put("/:id", renameOrganization(orgService))
+	router.Delete("/:id", deleteOrganization(orgService))
+	router.Post("/:id/members", addMember(orgService))
+	router.Put("/:id/members/:userID", updateMember(orgService))
+	router.Delete("/:id/members/:userID", removeMember(orgService))
+}
+
+// createOrganization is a function to create new organization
+// @Summary Create organization, user is its first owner
+// @Tags organization
+// @Accept json
+// @Produce json
+// @Param request body entity.OrganizationRequest true "Organization"
+// @Success 201 {object} entity.Response{data=entity.OrganizationDTO}
+// @Failure 400 {object} errors.AppError{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 422 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/organization [post]
+func createOrganization(s service.OrganizationService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestBody := new(entity.OrganizationRequest)
+		if err := c.BodyParser(&requestBody); err != nil {
+			e := errors.UnprocessableEntity("Invalid request body")
+			return c.Status(e.Status).JSON(e)
+		}
+
+		userID, _ := c.Locals("user").(string)
+
+		created, err := s.Create(requestBody.Name, userID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse(
+			"Organization is created.",
+			http.StatusCreated,
+			created,
+		)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// findAllOrganization is a function to returns organizations that user is member of
+// @Summary Returns organizations that user is member of
+// @Tags organization
+// @Produce json
+// @Success 200 {object} entity.Response{data=[]entity.OrganizationDTO}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/organization [get]
+func findAllOrganization(s service.OrganizationService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("user").(string)
+
+		found, err := s.FindAll(userID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse(
+			"All organizations that you are member of",
+			http.StatusOK,
+			found,
+		)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// findOrganization is a function to get organization with its members
+// @Summary Returns organization with its members
+// @Tags organization
+// @Produce json
+// @Param id path string true "Organization id"
+// @Success 200 {object} entity.Response{data=entity.OrganizationDTO}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Router /api/organization/{id} [get]
+func findOrganization(s service.OrganizationService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("user").(string)
+
+		organization, err := s.Authorize(c.Params("id"), userID, entity.RoleViewer)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse(
+			"Organization",
+			http.StatusOK,
+			organization,
+		)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// renameOrganization is a function to update name of organization
+// @Summary Update name of organization, owner role is required
+// @Tags organization
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization id"
+// @Param request body entity.OrganizationRequest true "Organization"
+// @Success 200 {object} entity.Response{data=entity.OrganizationDTO}
+// @Failure 400 {object} errors.AppError{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 422 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/organization/{id} [put]
+func renameOrganization(s service.OrganizationService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestBody := new(entity.OrganizationRequest)
+		if err := c.BodyParser(&requestBody); err != nil {
+			e := errors.UnprocessableEntity("Invalid request body")
+			return c.Status(e.Status).JSON(e)
+		}
+
+		userID, _ := c.Locals("user").(string)
+
+		if _, err := s.Authorize(c.Params("id"), userID, entity.RoleOwner); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		updated, err := s.Rename(c.Params("id"), requestBody.Name)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse(
+			"Organization is updated.",
+			http.StatusOK,
+			updated,
+		)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// deleteOrganization is a function to remove organization
+// @Summary Remove organization that does not have repositories, owner role is required
+// @Tags organization
+// @Produce json
+// @Param id path string true "Organization id"
+// @Success 200 {object} entity.Response{}
+// @Failure 400 {object} errors.AppError{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/organization/{id} [delete]
+func deleteOrganization(s service.OrganizationService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("user").(string)
+
+		if _, err := s.Authorize(c.Params("id"), userID, entity.RoleOwner); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if err := s.Delete(c.Params("id")); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse("Organization has been deleted", http.StatusOK, nil)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// addMember is a function to add user to organization
+// @Summary Add user with email to organization, owner role is required
+// @Tags organization
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization id"
+// @Param request body entity.MemberRequest true "Member"
+// @Success 201 {object} entity.Response{data=entity.OrganizationDTO}
+// @Failure 400 {object} errors.AppError{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 409 {object} errors.AppError{}
+// @Failure 422 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/organization/{id}/members [post]
+func addMember(s service.OrganizationService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestBody := new(entity.MemberRequest)
+		if err := c.BodyParser(&requestBody); err != nil {
+			e := errors.UnprocessableEntity("Invalid request body")
+			return c.Status(e.Status).JSON(e)
+		}
+
+		userID, _ := c.Locals("user").(string)
+
+		if _, err := s.Authorize(c.Params("id"), userID, entity.RoleOwner); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		updated, err := s.AddMember(c.Params("id"), requestBody.Email, requestBody.Role)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse(
+			"Member is added.",
+			http.StatusCreated,
+			updated,
+		)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// updateMember is a function to change role of member
+// @Summary Change role of member, owner role is required
+// @Tags organization
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization id"
+// @Param userID path string true "User id"
+// @Param request body entity.MemberRequest true "Member"
+// @Success 200 {object} entity.Response{data=entity.OrganizationDTO}
+// @Failure 400 {object} errors.AppError{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 422 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/organization/{id}/members/{userID} [put]
+func updateMember(s service.OrganizationService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestBody := new(entity.MemberRequest)
+		if err := c.BodyParser(&requestBody); err != nil {
+			e := errors.UnprocessableEntity("Invalid request body")
+			return c.Status(e.Status).JSON(e)
+		}
+
+		userID, _ := c.Locals("user").(string)
+
+		if _, err := s.Authorize(c.Params("id"), userID, entity.RoleOwner); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		updated, err := s.UpdateMember(c.Params("id"), c.Params("userID"), requestBody.Role)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse(
+			"Member is updated.",
+			http.StatusOK,
+			updated,
+		)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// removeMember is a function to remove member from organization
+// @Summary Remove member from organization, owner role is required unless members leave organization
+// @Tags organization
+// @Produce json
+// @Param id path string true "Organization id"
+// @Param userID path string true "User id"
+// @Success 200 {object} entity.Response{data=entity.OrganizationDTO}
+// @Failure 400 {object} errors.AppError{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/organization/{id}/members/{userID} [delete]
+func removeMember(s service.OrganizationService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("user").(string)
+
+		// Every member can leave organization
+		role := entity.RoleOwner
+		if c.Params("userID") == userID {
+			role = entity.RoleViewer
+		}
+
+		if _, err := s.Authorize(c.Params("id"), userID, role); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		updated, err := s.RemoveMember(c.Params("id"), c.Params("userID"))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse(
+			"Member is removed.",
+			http.StatusOK,
+			updated,
+		)
+		return c.Status(response.Status).JSON(response)
+	}
+}
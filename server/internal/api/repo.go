@@ -1,33 +1,74 @@
 package api
 
 import (
+	"bufio"
+	"encoding/json"
+	"fmt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/nozgurozturk/marvin/pkg/errors"
 	"github.com/nozgurozturk/marvin/server/entity"
-	"github.com/nozgurozturk/marvin/server/internal/app"
+	"github.com/nozgurozturk/marvin/server/internal/report"
 	"github.com/nozgurozturk/marvin/server/internal/service"
+	"io/ioutil"
 	"net/http"
+	"strings"
+	"time"
 )
 
-func RepositoryHandler(router fiber.Router, repoService service.RepoService, subService service.SubscriberService) {
-	router.Post("/", createRepo(repoService))
-	router.Get("/", findAllRepo(repoService))
+const (
+	// Comments keep idle connections open and detect closed clients
+	eventHeartbeatInterval = 15 * time.Second
+	// Streams of jobs that are never finished, for exp. instance is stopped, are closed
+	eventStreamTimeout = 5 * time.Minute
+)
+
+func RepositoryHandler(router fiber.Router, repoService service.RepoService, subService service.SubscriberService, orgService service.OrganizationService) {
+	router.Post("/", createRepo(repoService, orgService))
+	router.Get("/", findAllRepo(repoService, orgService))
 	router.Put("/", updateRepoPackages(repoService))
 	router.Delete("/", deleteRepo(repoService, subService))
+	router.Put("/webhook", rotateWebhookSecret(repoService))
+	router.Put("/badge", rotateBadgeToken(repoService))
+	router.Put("/token", updateRepoToken(repoService))
+	router.Put("/organization", transferRepo(repoService, orgService))
+	router.Post("/pull-request", createPullRequest(repoService))
+	router.Post("/sbom", scanSBOM(repoService))
+	router.Post("/manifest", scanManifests(repoService))
+	router.Post("/policy", evaluatePolicy(repoService))
+	router.Get("/jobs/:id", findScanJob(repoService))
+	router.Get("/jobs/:id/events", streamScanJob(repoService))
+	router.Delete("/jobs/:id", cancelScanJob(repoService))
+	router.Get("/:id/export", exportRepo(repoService))
+	router.Get("/:id/ignore", findIgnoreRules(repoService))
+	router.Post("/:id/ignore", addIgnoreRule(repoService))
+	router.Delete("/:id/ignore/:ruleID", deleteIgnoreRule(repoService))
+	router.Get("/:id/snapshots", findSnapshots(repoService))
+	router.Get("/:id/snapshots/diff", diffSnapshots(repoService))
+	router.Get("/:id/snapshots/:snapshotID", findSnapshot(repoService))
+}
+
+// Checks user of request has role in repository, users have every role in their own repositories
+func authorizeRepo(c *fiber.Ctx, s service.RepoService, repo *entity.RepoDTO, role entity.Role) *errors.AppError {
+	userID, _ := c.Locals("user").(string)
+	return s.Authorize(userID, repo, role)
 }
 
-// createRepo is a function to create new git repository
-// @Summary Create new git repository with packages
+// createRepo is a function to start scan of new git repository
+// @Summary Start background scan, git repository is created with packages when scan is completed
+// @Description Repository is created in organization if orgID is given, maintainer role is required
 // @Tags repo
 // @Accept json
 // @Produce json
 // @Param request body entity.RepoUrlRequest true "Url"
-// @Success 201 {object} entity.Response{data=entity.RepoDTO}
+// @Success 202 {object} entity.Response{data=entity.ScanJobDTO}
+// @Failure 400 {object} errors.AppError{}
 // @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
 // @Failure 422 {object} errors.AppError{}
 // @Failure 500 {object} errors.AppError{}
 // @Router /api/repository [post]
-func createRepo(s service.RepoService) fiber.Handler {
+func createRepo(s service.RepoService, o service.OrganizationService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		requestBody := new(entity.RepoUrlRequest)
 		if err := c.BodyParser(&requestBody); err != nil {
@@ -35,62 +76,71 @@ func createRepo(s service.RepoService) fiber.Handler {
 			return c.Status(e.Status).JSON(e)
 		}
 
-		token, err := app.ExtractToken(c)
-		if err != nil {
-			return c.Status(err.Status).JSON(err)
-		}
+		userID, _ := c.Locals("user").(string)
 
-		claims, err := app.ExtractTokenMetaData(token)
-		if err != nil {
-			return c.Status(err.Status).JSON(err)
+		if requestBody.OrgID != "" {
+			if _, err := o.Authorize(requestBody.OrgID, userID, entity.RoleMaintainer); err != nil {
+				return c.Status(err.Status).JSON(err)
+			}
 		}
 
-		exist, _ := s.FindByUrlAndUserID(requestBody.Url, claims.UserID)
+		exist, _ := s.FindByUrlAndUserID(requestBody.Url, userID)
 		if exist != nil {
-			err = errors.AlreadyExist("Repository is already exist")
+			err := errors.AlreadyExist("Repository is already exist")
 			return c.Status(err.Status).JSON(err)
 		}
 
-		repo, err := s.Create(requestBody.Url, claims.UserID)
+		job, err := s.Create(requestBody.Url, requestBody.Ref, requestBody.OrgID, userID, requestBody.SaveToken)
 		if err != nil {
 			return c.Status(err.Status).JSON(err)
 		}
 
 		response := entity.ToResponse(
-			"You successfully create an git repository.",
-			http.StatusCreated,
-			repo,
+			"Repository is scanning, it is created when scan is completed.",
+			http.StatusAccepted,
+			job,
 		)
 		return c.Status(response.Status).JSON(response)
 	}
 }
 
 // findAllRepo is a function to returns all git repository that user have
-// @Summary Returns all git repository that user have
+// @Summary Returns all git repository that user have, repositories of organization are returned if org is given
 // @Tags repo
 // @Accept json
 // @Produce json
+// @Param org query string false "Organization id"
 // @Success 200 {object} entity.Response{data=[]entity.RepoDTO}
 // @Failure 401 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
 // @Failure 422 {object} errors.AppError{}
 // @Failure 500 {object} errors.AppError{}
 // @Router /api/repository [get]
-func findAllRepo(s service.RepoService) fiber.Handler {
+func findAllRepo(s service.RepoService, o service.OrganizationService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 
-		token, err := app.ExtractToken(c)
-		if err != nil {
+		userID, _ := c.Locals("user").(string)
 
-			return c.Status(err.Status).JSON(err)
-		}
+		if orgID := c.Query("org"); orgID != "" {
+			organization, err := o.Authorize(orgID, userID, entity.RoleViewer)
+			if err != nil {
+				return c.Status(err.Status).JSON(err)
+			}
 
-		claims, err := app.ExtractTokenMetaData(token)
-		if err != nil {
+			found, err := s.FindAllByOrgID(orgID, organization.RoleOf(userID))
+			if err != nil {
+				return c.Status(err.Status).JSON(err)
+			}
 
-			return c.Status(err.Status).JSON(err)
+			response := entity.ToResponse(
+				"All repositories of organization",
+				http.StatusOK,
+				found,
+			)
+			return c.Status(response.Status).JSON(response)
 		}
 
-		found, err := s.FindAll(claims.UserID)
+		found, err := s.FindAll(userID)
 		if err != nil {
 			return c.Status(err.Status).JSON(err)
 		}
@@ -130,12 +180,13 @@ func updateRepoPackages(s service.RepoService) fiber.Handler {
 			return c.Status(err.Status).JSON(err)
 		}
 
-		if c.Locals("user") != repo.UserID {
-			err = errors.Forbidden("You don't have access")
+		if err = authorizeRepo(c, s, repo, entity.RoleMaintainer); err != nil {
 			return c.Status(err.Status).JSON(err)
 		}
 
-		updated, err := s.UpdatePackages(repo)
+		userID, _ := c.Locals("user").(string)
+
+		updated, err := s.UpdatePackages(repo, userID)
 		if err != nil {
 			return c.Status(err.Status).JSON(err)
 		}
@@ -176,8 +227,7 @@ func deleteRepo(s service.RepoService, sub service.SubscriberService) fiber.Hand
 			return c.Status(err.Status).JSON(err)
 		}
 
-		if c.Locals("user") != repo.UserID {
-			err = errors.Forbidden("You don't have access")
+		if err = authorizeRepo(c, s, repo, entity.RoleOwner); err != nil {
 			return c.Status(err.Status).JSON(err)
 		}
 
@@ -199,3 +249,868 @@ func deleteRepo(s service.RepoService, sub service.SubscriberService) fiber.Hand
 		return c.Status(response.Status).JSON(response)
 	}
 }
+
+// rotateWebhookSecret is a function to renew repository's webhook secret
+// @Summary Renew secret of push webhooks
+// @Tags repo
+// @Accept json
+// @Produce json
+// @Param request body entity.RepoIDRequest true "Id"
+// @Success 200 {object} entity.Response{data=entity.RepoDTO}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 422 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/repository/webhook [put]
+func rotateWebhookSecret(s service.RepoService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestBody := new(entity.RepoIDRequest)
+
+		if err := c.BodyParser(&requestBody); err != nil {
+			e := errors.UnprocessableEntity("Invalid request body")
+			return c.Status(e.Status).JSON(e)
+		}
+
+		repo, err := s.FindByID(requestBody.ID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if err = authorizeRepo(c, s, repo, entity.RoleOwner); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		updated, err := s.RotateWebhookSecret(requestBody.ID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse(
+			"Webhook secret is renewed.",
+			http.StatusOK,
+			updated,
+		)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// rotateBadgeToken is a function to renew repository's badge token
+// @Summary Renew token of public badge url, old badge urls stop working
+// @Tags repo
+// @Accept json
+// @Produce json
+// @Param request body entity.RepoIDRequest true "Id"
+// @Success 200 {object} entity.Response{data=entity.RepoDTO}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 422 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/repository/badge [put]
+func rotateBadgeToken(s service.RepoService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestBody := new(entity.RepoIDRequest)
+
+		if err := c.BodyParser(&requestBody); err != nil {
+			e := errors.UnprocessableEntity("Invalid request body")
+			return c.Status(e.Status).JSON(e)
+		}
+
+		repo, err := s.FindByID(requestBody.ID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if err = authorizeRepo(c, s, repo, entity.RoleMaintainer); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		updated, err := s.RotateBadgeToken(requestBody.ID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse(
+			"Badge token is renewed.",
+			http.StatusOK,
+			updated,
+		)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// transferRepo is a function to move repository between user and organizations
+// @Summary Move repository to organization, repository is moved to user if orgID is empty
+// @Description Owner role is required in repository and maintainer role is required in target organization
+// @Tags repo
+// @Accept json
+// @Produce json
+// @Param request body entity.RepoOwnerRequest true "Repository and organization"
+// @Success 200 {object} entity.Response{data=entity.RepoDTO}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 422 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/repository/organization [put]
+func transferRepo(s service.RepoService, o service.OrganizationService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestBody := new(entity.RepoOwnerRequest)
+
+		if err := c.BodyParser(&requestBody); err != nil {
+			e := errors.UnprocessableEntity("Invalid request body")
+			return c.Status(e.Status).JSON(e)
+		}
+
+		repo, err := s.FindByID(requestBody.ID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if err = authorizeRepo(c, s, repo, entity.RoleOwner); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		userID, _ := c.Locals("user").(string)
+
+		if requestBody.OrgID != "" {
+			if _, err = o.Authorize(requestBody.OrgID, userID, entity.RoleMaintainer); err != nil {
+				return c.Status(err.Status).JSON(err)
+			}
+		}
+
+		updated, err := s.Transfer(requestBody.ID, userID, requestBody.OrgID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse(
+			"Repository is moved.",
+			http.StatusOK,
+			updated,
+		)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// updateRepoToken is a function to save provider's access token of repository
+// @Summary Save access token of provider, it is used for private repositories and pull requests
+// @Tags repo
+// @Accept json
+// @Produce json
+// @Param request body entity.RepoTokenRequest true "Id and token"
+// @Success 200 {object} entity.Response{data=entity.RepoDTO}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 422 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/repository/token [put]
+func updateRepoToken(s service.RepoService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestBody := new(entity.RepoTokenRequest)
+
+		if err := c.BodyParser(&requestBody); err != nil {
+			e := errors.UnprocessableEntity("Invalid request body")
+			return c.Status(e.Status).JSON(e)
+		}
+
+		repo, err := s.FindByID(requestBody.ID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if err = authorizeRepo(c, s, repo, entity.RoleOwner); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		userID, _ := c.Locals("user").(string)
+
+		updated, err := s.UpdateToken(requestBody.ID, requestBody.Token, userID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse(
+			"Provider token is saved.",
+			http.StatusOK,
+			updated,
+		)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// createPullRequest is a function to open pull request that updates outdated packages
+// @Summary Open pull request or merge request with bumped package versions
+// @Tags repo
+// @Accept json
+// @Produce json
+// @Param request body entity.PullRequestRequest true "Id and packages"
+// @Success 201 {object} entity.Response{data=entity.PullRequestDTO}
+// @Failure 400 {object} errors.AppError{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 422 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/repository/pull-request [post]
+func createPullRequest(s service.RepoService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestBody := new(entity.PullRequestRequest)
+
+		if err := c.BodyParser(&requestBody); err != nil {
+			e := errors.UnprocessableEntity("Invalid request body")
+			return c.Status(e.Status).JSON(e)
+		}
+
+		repo, err := s.FindByID(requestBody.ID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if err = authorizeRepo(c, s, repo, entity.RoleMaintainer); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		userID, _ := c.Locals("user").(string)
+
+		pullRequest, err := s.CreatePullRequest(repo, requestBody.Packages, userID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse(
+			"Pull request is opened.",
+			http.StatusCreated,
+			pullRequest,
+		)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// findScanJob is a function to get status of repository scan
+// @Summary Returns status and progress of scan job
+// @Tags repo
+// @Accept json
+// @Produce json
+// @Param id path string true "Job id"
+// @Success 200 {object} entity.Response{data=entity.ScanJobDTO}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Router /api/repository/jobs/{id} [get]
+func findScanJob(s service.RepoService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		job, err := s.FindScanJob(c.Params("id"))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if c.Locals("user") != job.UserID {
+			err = errors.Forbidden("You don't have access")
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse(
+			"Scan job is "+job.Status,
+			http.StatusOK,
+			entity.ToScanJobDTO(job),
+		)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// streamScanJob is a function to watch repository scan live
+// @Summary Stream scan events as server-sent events until scan is finished
+// @Description First event is current job, then manifests, package, failure and status events follow
+// @Tags repo
+// @Produce text/event-stream
+// @Param id path string true "Job id"
+// @Success 200 {object} entity.ScanEvent{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/repository/jobs/{id}/events [get]
+func streamScanJob(s service.RepoService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		job, err := s.FindScanJob(c.Params("id"))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if c.Locals("user") != job.UserID {
+			err = errors.Forbidden("You don't have access")
+			return c.Status(err.Status).JSON(err)
+		}
+
+		job, events, closeEvents, err := s.SubscribeScanJob(job.ID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		c.Set("Content-Type", "text/event-stream")
+		c.Set("Cache-Control", "no-cache")
+		c.Set("Connection", "keep-alive")
+		c.Set("X-Accel-Buffering", "no")
+
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			defer closeEvents()
+
+			current := &entity.ScanEvent{
+				Type: entity.ScanEventStatus,
+				Job:  entity.ToScanJobDTO(job),
+			}
+			if writeEvent(w, current) != nil || job.IsFinished() {
+				return
+			}
+
+			heartbeat := time.NewTicker(eventHeartbeatInterval)
+			defer heartbeat.Stop()
+			timeout := time.After(eventStreamTimeout)
+
+			for {
+				select {
+				case event, ok := <-events:
+					if !ok || writeEvent(w, event) != nil {
+						return
+					}
+					if event.Type == entity.ScanEventStatus && entity.IsFinishedStatus(event.Job.Status) {
+						return
+					}
+				case <-heartbeat.C:
+					fmt.Fprint(w, ": heartbeat\n\n")
+					if w.Flush() != nil {
+						return
+					}
+				case <-timeout:
+					return
+				}
+			}
+		})
+
+		return nil
+	}
+}
+
+// Writes server-sent event, error means client is disconnected
+func writeEvent(w *bufio.Writer, event *entity.ScanEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return w.Flush()
+}
+
+// cancelScanJob is a function to stop repository scan
+// @Summary Cancel scan job, repository is not created
+// @Tags repo
+// @Accept json
+// @Produce json
+// @Param id path string true "Job id"
+// @Success 202 {object} entity.Response{data=entity.ScanJobDTO}
+// @Failure 400 {object} errors.AppError{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/repository/jobs/{id} [delete]
+func cancelScanJob(s service.RepoService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		job, err := s.FindScanJob(c.Params("id"))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if c.Locals("user") != job.UserID {
+			err = errors.Forbidden("You don't have access")
+			return c.Status(err.Status).JSON(err)
+		}
+
+		job, err = s.CancelScanJob(job.ID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse(
+			"Scan job is cancelling.",
+			http.StatusAccepted,
+			entity.ToScanJobDTO(job),
+		)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// findSnapshots is a function to list scan history of repository
+// @Summary Returns scan snapshots without packages, newest snapshot is first
+// @Tags repo
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository id"
+// @Success 200 {object} entity.Response{data=[]entity.SnapshotDTO}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/repository/{id}/snapshots [get]
+func findSnapshots(s service.RepoService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		repo, err := s.FindByID(c.Params("id"))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if err = authorizeRepo(c, s, repo, entity.RoleViewer); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		snapshots, err := s.FindSnapshots(*repo.ID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse(
+			"Scan snapshots of repository",
+			http.StatusOK,
+			snapshots,
+		)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// findSnapshot is a function to get packages of a scan
+// @Summary Returns scan snapshot with packages
+// @Tags repo
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository id"
+// @Param snapshotID path string true "Snapshot id"
+// @Success 200 {object} entity.Response{data=entity.SnapshotDTO}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/repository/{id}/snapshots/{snapshotID} [get]
+func findSnapshot(s service.RepoService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		repo, err := s.FindByID(c.Params("id"))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if err = authorizeRepo(c, s, repo, entity.RoleViewer); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		snapshot, err := s.FindSnapshot(*repo.ID, c.Params("snapshotID"))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse(
+			"Scan snapshot of repository",
+			http.StatusOK,
+			snapshot,
+		)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// diffSnapshots is a function to compare two scans of repository
+// @Summary Returns added, removed, upgraded, downgraded and newly outdated packages between snapshots
+// @Description Latest snapshot is used if to is empty, snapshot before to is used if from is empty
+// @Tags repo
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository id"
+// @Param from query string false "Older snapshot id"
+// @Param to query string false "Newer snapshot id"
+// @Success 200 {object} entity.Response{data=entity.SnapshotDiff}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/repository/{id}/snapshots/diff [get]
+func diffSnapshots(s service.RepoService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		repo, err := s.FindByID(c.Params("id"))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if err = authorizeRepo(c, s, repo, entity.RoleViewer); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		diff, err := s.DiffSnapshots(*repo.ID, c.Query("from"), c.Query("to"))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse(
+			"Changes between snapshots",
+			http.StatusOK,
+			diff,
+		)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// exportRepo is a function to export packages of latest scan
+// @Summary Returns latest scan as csv, markdown, sarif or junit report
+// @Description Format query parameter overrides Accept header
+// @Tags repo
+// @Produce text/csv,text/markdown,application/sarif+json,application/xml
+// @Param id path string true "Repository id"
+// @Param format query string false "Report format" Enums(csv, markdown, sarif, junit)
+// @Success 200 {string} string
+// @Failure 400 {object} errors.AppError{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 406 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/repository/{id}/export [get]
+func exportRepo(s service.RepoService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		format := c.Query("format")
+		if format == "" {
+			format = report.FormatFromAccept(c.Get(fiber.HeaderAccept))
+			if format == "" {
+				err := errors.NotAcceptable("Accepted formats are csv, markdown, sarif and junit")
+				return c.Status(err.Status).JSON(err)
+			}
+		}
+
+		if !report.IsFormat(format) {
+			err := errors.BadRequest("Invalid report format")
+			return c.Status(err.Status).JSON(err)
+		}
+
+		repo, err := s.FindByID(c.Params("id"))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if err = authorizeRepo(c, s, repo, entity.RoleViewer); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		body, renderErr := report.Render(format, repo)
+		if renderErr != nil {
+			err = errors.InternalServer(renderErr.Error())
+			return c.Status(err.Status).JSON(err)
+		}
+
+		c.Set(fiber.HeaderContentType, report.ContentType(format))
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", report.FileName(format, repo)))
+		return c.Status(http.StatusOK).Send(body)
+	}
+}
+
+// findIgnoreRules is a function to returns ignore rules of git repository
+// @Summary Returns ignore rules of git repository
+// @Tags repo
+// @Produce json
+// @Param id path string true "Repository id"
+// @Success 200 {object} entity.Response{data=[]entity.IgnoreRule}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Router /api/repository/{id}/ignore [get]
+func findIgnoreRules(s service.RepoService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		repo, err := s.FindByID(c.Params("id"))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if err = authorizeRepo(c, s, repo, entity.RoleViewer); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		rules := repo.IgnoreRules
+		if rules == nil {
+			rules = []*entity.IgnoreRule{}
+		}
+
+		response := entity.ToResponse("Ignore rules of repository", http.StatusOK, rules)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// addIgnoreRule is a function to ignore outdated packages of git repository
+// @Summary Ignore outdated packages by name or glob, update kind, version range or snooze them
+// @Description Ignored packages are not counted as outdated in badges, reports and notifications
+// @Tags repo
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository id"
+// @Param request body entity.IgnoreRuleRequest true "Ignore rule"
+// @Success 201 {object} entity.Response{data=entity.RepoDTO}
+// @Failure 400 {object} errors.AppError{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 422 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/repository/{id}/ignore [post]
+func addIgnoreRule(s service.RepoService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestBody := new(entity.IgnoreRuleRequest)
+		if err := c.BodyParser(&requestBody); err != nil {
+			e := errors.UnprocessableEntity("Invalid request body")
+			return c.Status(e.Status).JSON(e)
+		}
+
+		repo, err := s.FindByID(c.Params("id"))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if err = authorizeRepo(c, s, repo, entity.RoleMaintainer); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		updated, err := s.AddIgnoreRule(repo, requestBody)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse("Ignore rule is added", http.StatusCreated, updated)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// deleteIgnoreRule is a function to remove ignore rule of git repository
+// @Summary Remove ignore rule, packages that it ignores are outdated again
+// @Tags repo
+// @Produce json
+// @Param id path string true "Repository id"
+// @Param ruleID path string true "Ignore rule id"
+// @Success 200 {object} entity.Response{data=entity.RepoDTO}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/repository/{id}/ignore/{ruleID} [delete]
+func deleteIgnoreRule(s service.RepoService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		repo, err := s.FindByID(c.Params("id"))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if err = authorizeRepo(c, s, repo, entity.RoleMaintainer); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		updated, err := s.DeleteIgnoreRule(repo, c.Params("ruleID"))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse("Ignore rule is removed", http.StatusOK, updated)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// scanSBOM is a function to check versions of packages in uploaded SBOM
+// @Summary Returns packages of CycloneDX or SPDX JSON document with their latest registry versions
+// @Tags repo
+// @Accept json
+// @Produce json
+// @Param request body object true "CycloneDX or SPDX JSON document"
+// @Success 200 {object} entity.Response{data=entity.SBOMScanDTO}
+// @Failure 400 {object} errors.AppError{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 422 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/repository/sbom [post]
+func scanSBOM(s service.RepoService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var file map[string]interface{}
+		if err := json.Unmarshal(c.Body(), &file); err != nil {
+			e := errors.UnprocessableEntity("Invalid SBOM document")
+			return c.Status(e.Status).JSON(e)
+		}
+
+		result, err := s.ScanSBOM(file)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse(
+			"Packages of SBOM",
+			http.StatusOK,
+			result,
+		)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// scanManifests is a function to check versions of packages in uploaded package files
+// @Summary Returns packages of uploaded package files with their latest registry versions
+// @Description Files are sent as JSON or multipart form, form field name is path of file unless it is files
+// @Description Packages are saved as snapshot of repository if repoID is given, maintainer role is required
+// @Tags repo
+// @Accept json,mpfd
+// @Produce json
+// @Param request body entity.ManifestScanRequest false "Package files"
+// @Success 200 {object} entity.Response{data=entity.ManifestScanDTO}
+// @Failure 400 {object} errors.AppError{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 422 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Failure 503 {object} errors.AppError{}
+// @Router /api/repository/manifest [post]
+func scanManifests(s service.RepoService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestBody := new(entity.ManifestScanRequest)
+
+		if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+			if err := parseManifestForm(c, requestBody); err != nil {
+				return c.Status(err.Status).JSON(err)
+			}
+		} else if err := c.BodyParser(&requestBody); err != nil {
+			e := errors.UnprocessableEntity("Invalid request body")
+			return c.Status(e.Status).JSON(e)
+		}
+
+		var repo *entity.RepoDTO
+		if requestBody.RepoID != "" {
+			found, err := s.FindByID(requestBody.RepoID)
+			if err != nil {
+				return c.Status(err.Status).JSON(err)
+			}
+
+			if err = authorizeRepo(c, s, found, entity.RoleMaintainer); err != nil {
+				return c.Status(err.Status).JSON(err)
+			}
+			repo = found
+		}
+
+		result, err := s.ScanManifests(requestBody, repo)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse(
+			"Packages of package files",
+			http.StatusOK,
+			result,
+		)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// Multipart file names do not keep directories, so other field names are used as paths
+// curl -F "files=@package.json" -F "packages/app/package.json=@packages/app/package.json"
+func parseManifestForm(c *fiber.Ctx, request *entity.ManifestScanRequest) *errors.AppError {
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return errors.UnprocessableEntity("Invalid multipart form")
+	}
+
+	request.RepoID = c.FormValue("repoID")
+	request.Ref = c.FormValue("ref")
+	request.CommitSHA = c.FormValue("commitSHA")
+
+	for field, headers := range form.File {
+		for _, header := range headers {
+			file, err := header.Open()
+			if err != nil {
+				return errors.UnprocessableEntity("Invalid multipart form")
+			}
+			content, err := ioutil.ReadAll(file)
+			file.Close()
+			if err != nil {
+				return errors.UnprocessableEntity("Invalid multipart form")
+			}
+
+			filePath := field
+			if field == "files" {
+				filePath = header.Filename
+			}
+
+			request.Files = append(request.Files, &entity.ManifestFile{
+				Path:    filePath,
+				Content: string(content),
+			})
+		}
+	}
+
+	return nil
+}
+
+// evaluatePolicy is a function to check packages with policy of CI gate
+// @Summary Checks packages of git repository or uploaded package files with policy, responds 412 if policy fails
+// @Description Viewer role is required for repositories, format=text returns plain text result for build logs
+// @Tags repo
+// @Accept json
+// @Produce json,plain
+// @Param request body entity.PolicyRequest true "Policy"
+// @Param format query string false "Result format" Enums(json, text)
+// @Success 200 {object} entity.Response{data=entity.PolicyResult}
+// @Failure 400 {object} errors.AppError{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 412 {object} entity.Response{data=entity.PolicyResult}
+// @Failure 422 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Failure 503 {object} errors.AppError{}
+// @Router /api/repository/policy [post]
+func evaluatePolicy(s service.RepoService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestBody := new(entity.PolicyRequest)
+		if err := c.BodyParser(&requestBody); err != nil {
+			e := errors.UnprocessableEntity("Invalid request body")
+			return c.Status(e.Status).JSON(e)
+		}
+
+		if (requestBody.RepoID == "") == (len(requestBody.Files) == 0) {
+			err := errors.BadRequest("Either repository id or package files is required")
+			return c.Status(err.Status).JSON(err)
+		}
+
+		var repo *entity.RepoDTO
+		if requestBody.RepoID != "" {
+			found, err := s.FindByID(requestBody.RepoID)
+			if err != nil {
+				return c.Status(err.Status).JSON(err)
+			}
+
+			if err = authorizeRepo(c, s, found, entity.RoleViewer); err != nil {
+				return c.Status(err.Status).JSON(err)
+			}
+			repo = found
+		}
+
+		result, err := s.EvaluatePolicy(requestBody, repo)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		// Failed policies are not successful responses, so curl --fail exits with error
+		status := http.StatusOK
+		message := "Policy is passed"
+		if !result.Passed {
+			status = http.StatusPreconditionFailed
+			message = "Policy is failed"
+		}
+
+		if c.Query("format") == "text" {
+			c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+			return c.Status(status).Send(report.RenderPolicy(result))
+		}
+
+		response := entity.ToResponse(message, status, result)
+		return c.Status(response.Status).JSON(response)
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"github.com/nozgurozturk/marvin/server/internal/service"
+	"net/http"
+)
+
+func SessionHandler(router fiber.Router, authService service.AuthService) {
+	router.Get("/", findAllSessions(authService))
+	router.Delete("/", revokeOtherSessions(authService))
+	router.Delete("/:id", revokeSession(authService))
+}
+
+// findAllSessions is a function to list active sessions of user
+// @Summary Returns active sessions with device, IP and last activity, session of request is marked as current
+// @Tags session
+// @Produce json
+// @Success 200 {object} entity.Response{data=[]entity.SessionDTO}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/session [get]
+func findAllSessions(a service.AuthService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := rejectAPIToken(c); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		userID, _ := c.Locals("user").(string)
+		sessionID, _ := c.Locals("session").(string)
+
+		sessions, err := a.FindSessions(userID, sessionID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse("Sessions", http.StatusOK, sessions)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// revokeOtherSessions is a function to logout from other devices
+// @Summary Revokes all sessions of user except session of request
+// @Tags session
+// @Produce json
+// @Success 200 {object} entity.Response{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/session [delete]
+func revokeOtherSessions(a service.AuthService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := rejectAPIToken(c); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		userID, _ := c.Locals("user").(string)
+		sessionID, _ := c.Locals("session").(string)
+
+		err := a.RevokeSessions(userID, sessionID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse("Other sessions are revoked", http.StatusOK, nil)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
+// revokeSession is a function to logout a device
+// @Summary Revokes session of user, its tokens can not be used anymore
+// @Tags session
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} entity.Response{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/session/{id} [delete]
+func revokeSession(a service.AuthService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := rejectAPIToken(c); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		userID, _ := c.Locals("user").(string)
+
+		err := a.RevokeSession(userID, c.Params("id"))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse("Session is revoked", http.StatusOK, nil)
+		return c.Status(response.Status).JSON(response)
+	}
+}
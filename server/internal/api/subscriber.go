@@ -14,11 +14,11 @@ import (
 )
 
 // SubscriberHandler
-func SubscriberHandler(router fiber.Router, subService service.SubscriberService, repoService service.RepoService) {
-	router.Post("/", createSubscriber(subService, repoService))
-	router.Post("/all", findAllSubscriber(subService))
-	router.Delete("/", deleteSubscriber(subService))
-	router.Post("/send", sendConfirm(subService, repoService))
+func SubscriberHandler(router fiber.Router, subService service.SubscriberService, repoService service.RepoService, rateLimitService service.RateLimitService) {
+	router.Post("/", createSubscriber(subService, repoService, rateLimitService))
+	router.Post("/all", findAllSubscriber(subService, repoService))
+	router.Delete("/", deleteSubscriber(subService, repoService))
+	router.Post("/send", sendConfirm(subService, repoService, rateLimitService))
 }
 
 // PublicSubscriberHandler no need authentication
@@ -37,10 +37,12 @@ func PublicSubscriberHandler(router fiber.Router, subService service.SubscriberS
 // @Param request body entity.SubscriberRequest true "Subscriber"
 // @Success 201 {object} entity.Response{data=entity.SubscriberDTO}
 // @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
 // @Failure 422 {object} errors.AppError{}
+// @Failure 429 {object} errors.AppError{}
 // @Failure 500 {object} errors.AppError{}
 // @Router /api/subscriber [post]
-func createSubscriber(s service.SubscriberService, r service.RepoService) fiber.Handler {
+func createSubscriber(s service.SubscriberService, r service.RepoService, l service.RateLimitService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		requestBody := new(entity.SubscriberRequest)
 		if err := c.BodyParser(&requestBody); err != nil {
@@ -53,8 +55,7 @@ func createSubscriber(s service.SubscriberService, r service.RepoService) fiber.
 			return c.Status(err.Status).JSON(err)
 		}
 
-		if c.Locals("user") != repository.UserID {
-			err = errors.Forbidden("You don't have access")
+		if err = authorizeRepo(c, r, repository, entity.RoleMaintainer); err != nil {
 			return c.Status(err.Status).JSON(err)
 		}
 
@@ -68,6 +69,11 @@ func createSubscriber(s service.SubscriberService, r service.RepoService) fiber.
 			return c.Status(existErr.Status).JSON(existErr)
 		}
 
+		err = l.AllowEmail(*requestBody.Email)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
 		subscriber, err := s.Create(*requestBody.Email, *requestBody.RepoID)
 		if err != nil {
 			return c.Status(err.Status).JSON(err)
@@ -120,10 +126,12 @@ func createSubscriber(s service.SubscriberService, r service.RepoService) fiber.
 // @Param request body entity.RepoIDRequest true "Id"
 // @Success 200 {object} entity.Response{data=[]entity.SubscriberDTO}
 // @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
 // @Failure 422 {object} errors.AppError{}
 // @Failure 500 {object} errors.AppError{}
 // @Router /api/subscriber/all [post]
-func findAllSubscriber(s service.SubscriberService) fiber.Handler {
+func findAllSubscriber(s service.SubscriberService, r service.RepoService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		requestBody := new(entity.RepoIDRequest)
 
@@ -132,6 +140,15 @@ func findAllSubscriber(s service.SubscriberService) fiber.Handler {
 			return c.Status(e.Status).JSON(e)
 		}
 
+		repository, err := r.FindByID(requestBody.ID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if err = authorizeRepo(c, r, repository, entity.RoleViewer); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
 		found, err := s.FindAll(requestBody.ID)
 
 		if err != nil {
@@ -155,10 +172,12 @@ func findAllSubscriber(s service.SubscriberService) fiber.Handler {
 // @Param request body entity.SubscriberIDRequest true "Id"
 // @Success 200 {object} entity.Response{}
 // @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
 // @Failure 422 {object} errors.AppError{}
 // @Failure 500 {object} errors.AppError{}
 // @Router /api/subscriber [delete]
-func deleteSubscriber(s service.SubscriberService) fiber.Handler {
+func deleteSubscriber(s service.SubscriberService, r service.RepoService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		requestBody := new(entity.SubscriberIDRequest)
 		if err := c.BodyParser(&requestBody); err != nil {
@@ -166,7 +185,21 @@ func deleteSubscriber(s service.SubscriberService) fiber.Handler {
 			return c.Status(e.Status).JSON(e)
 		}
 
-		err := s.Delete(requestBody.ID)
+		subscriber, err := s.FindByID(requestBody.ID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		repository, err := r.FindByID(subscriber.RepoID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if err = authorizeRepo(c, r, repository, entity.RoleMaintainer); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		err = s.Delete(requestBody.ID)
 		if err != nil {
 			return c.Status(err.Status).JSON(err)
 		}
@@ -184,10 +217,12 @@ func deleteSubscriber(s service.SubscriberService) fiber.Handler {
 // @Param request body entity.SubscriberRequest true "Subscriber"
 // @Success 201 {object} entity.Response{data=entity.SubscriberDTO}
 // @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
 // @Failure 422 {object} errors.AppError{}
+// @Failure 429 {object} errors.AppError{}
 // @Failure 500 {object} errors.AppError{}
 // @Router /api/subscriber/send [post]
-func sendConfirm(s service.SubscriberService, r service.RepoService) fiber.Handler {
+func sendConfirm(s service.SubscriberService, r service.RepoService, l service.RateLimitService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		requestBody := new(entity.SubscriberRequest)
 		if err := c.BodyParser(&requestBody); err != nil {
@@ -200,8 +235,7 @@ func sendConfirm(s service.SubscriberService, r service.RepoService) fiber.Handl
 			return c.Status(err.Status).JSON(err)
 		}
 
-		if c.Locals("user") != repository.UserID {
-			err = errors.Forbidden("You don't have access")
+		if err = authorizeRepo(c, r, repository, entity.RoleMaintainer); err != nil {
 			return c.Status(err.Status).JSON(err)
 		}
 
@@ -210,6 +244,11 @@ func sendConfirm(s service.SubscriberService, r service.RepoService) fiber.Handl
 			return c.Status(err.Status).JSON(err)
 		}
 
+		err = l.AllowEmail(exist.Email)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
 		token, err := app.CreateSubToken(exist)
 		if err != nil {
 			return c.Status(err.Status).JSON(err)
@@ -4,14 +4,15 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/nozgurozturk/marvin/pkg/errors"
 	"github.com/nozgurozturk/marvin/server/entity"
-	"github.com/nozgurozturk/marvin/server/internal/app"
 	"github.com/nozgurozturk/marvin/server/internal/service"
 	"net/http"
+	"strings"
 )
 
-func UserHandler(router fiber.Router, userService service.UserService, repoService service.RepoService) {
+func UserHandler(router fiber.Router, authService service.AuthService, userService service.UserService, repoService service.RepoService, orgService service.OrganizationService, apiTokenService service.APITokenService, rateLimitService service.RateLimitService) {
 	router.Put("/", updateUser(userService))
-	router.Delete("/", deleteUser(userService, repoService))
+	router.Put("/email", changeEmail(authService, userService, rateLimitService))
+	router.Delete("/", deleteUser(authService, userService, repoService, orgService, apiTokenService))
 }
 
 // updateUser is a function to update user values
@@ -22,17 +23,37 @@ func UserHandler(router fiber.Router, userService service.UserService, repoServi
 // @Param request body entity.UserDTO true "User"
 // @Success 200 {object} entity.Response{data=entity.UserDTO}
 // @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
 // @Failure 422 {object} errors.AppError{}
 // @Failure 500 {object} errors.AppError{}
 // @Router /api/user [put]
 func updateUser(s service.UserService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		if err := rejectAPIToken(c); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
 		var requestBody entity.UserDTO
 		if err := c.BodyParser(&requestBody); err != nil {
 			parseErr := errors.UnprocessableEntity("Invalid user body")
 			return c.Status(parseErr.Status).JSON(parseErr)
 		}
 
+		// Users can only update themselves
+		requestBody.ID, _ = c.Locals("user").(string)
+
+		// New email must be verified, it is changed with /api/user/email
+		if requestBody.Email != "" {
+			currentUser, err := s.FindByID(requestBody.ID)
+			if err != nil {
+				return c.Status(err.Status).JSON(err)
+			}
+			if requestBody.Email != currentUser.Email {
+				err := errors.BadRequest("Email can only be changed with /api/user/email")
+				return c.Status(err.Status).JSON(err)
+			}
+		}
+
 		updated, err := s.Update(&requestBody)
 		if err != nil {
 			return c.Status(err.Status).JSON(err)
@@ -43,33 +64,134 @@ func updateUser(s service.UserService) fiber.Handler {
 	}
 }
 
+// changeEmail is a function to send verification link to new email of user
+// @Summary Sends verification link to new email, email is changed when link is opened
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param request body entity.EmailChange true "New email and current password"
+// @Success 200 {object} entity.Response{}
+// @Failure 400 {object} errors.AppError{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
+// @Failure 409 {object} errors.AppError{}
+// @Failure 422 {object} errors.AppError{}
+// @Failure 429 {object} errors.AppError{}
+// @Failure 500 {object} errors.AppError{}
+// @Router /api/user/email [put]
+func changeEmail(a service.AuthService, s service.UserService, l service.RateLimitService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := rejectAPIToken(c); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		requestBody := new(entity.EmailChange)
+		if err := c.BodyParser(requestBody); err != nil {
+			parseErr := errors.UnprocessableEntity("Invalid request body")
+			return c.Status(parseErr.Status).JSON(parseErr)
+		}
+
+		requestBody.Email = strings.TrimSpace(requestBody.Email)
+		if validationError := entity.ValidateEmail(requestBody.Email); validationError != "" {
+			err := errors.BadRequest(validationError)
+			return c.Status(err.Status).JSON(err)
+		}
+
+		userID, _ := c.Locals("user").(string)
+		currentUser, err := s.FindByID(userID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if requestBody.Email == currentUser.Email {
+			err := errors.BadRequest("This is already your email")
+			return c.Status(err.Status).JSON(err)
+		}
+
+		// Users that signed up with OAuth provider do not have password
+		if currentUser.Password != "" {
+			if passwordErr := entity.VerifyPassword(currentUser.Password, requestBody.Password); passwordErr != nil {
+				err := errors.Unauthorized("Password is not correct")
+				return c.Status(err.Status).JSON(err)
+			}
+		}
+
+		exist, err := s.FindByEmail(requestBody.Email)
+		if err != nil && err.Status != http.StatusNotFound {
+			return c.Status(err.Status).JSON(err)
+		}
+		if exist != nil {
+			existErr := errors.AlreadyExist("This email is taken by an another user")
+			return c.Status(existErr.Status).JSON(existErr)
+		}
+
+		err = l.AllowEmail(requestBody.Email)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		token, err := a.CreateActionToken(entity.ActionChangeEmail, &entity.ActionToken{
+			UserID: currentUser.ID,
+			Email:  requestBody.Email,
+		})
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		err = sendEmail(requestBody.Email, "Marvin | Please confirm your new email", "email-change-verify", emailTemplate{
+			User: currentUser.Name,
+			Link: authLink("/auth/email/confirm", token),
+		})
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		response := entity.ToResponse("Please confirm your new email with link that is sent to it", http.StatusOK, nil)
+		return c.Status(response.Status).JSON(response)
+	}
+}
+
 // deleteUser is a function to remove user from store
 // @Summary Removes user
 // @Tags user
 // @Produce json
 // @Success 200 {object} entity.Response{}
 // @Success 200 {object} entity.Response{}
+// @Failure 400 {object} errors.AppError{}
 // @Failure 401 {object} errors.AppError{}
+// @Failure 403 {object} errors.AppError{}
 // @Failure 500 {object} errors.AppError{}
 // @Router /api/user [delete]
-func deleteUser(s service.UserService, r service.RepoService) fiber.Handler {
+func deleteUser(a service.AuthService, s service.UserService, r service.RepoService, o service.OrganizationService, t service.APITokenService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		token, err := app.ExtractToken(c)
+		if err := rejectAPIToken(c); err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		userID, _ := c.Locals("user").(string)
+
+		// Organizations must not lose their last owner
+		err := o.RemoveUser(userID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		err = s.Delete(userID)
 		if err != nil {
 			return c.Status(err.Status).JSON(err)
 		}
 
-		claims, err := app.ExtractTokenMetaData(token)
+		err = r.DeleteMany(userID)
 		if err != nil {
 			return c.Status(err.Status).JSON(err)
 		}
 
-		err = s.Delete(claims.UserID)
+		err = t.DeleteMany(userID)
 		if err != nil {
 			return c.Status(err.Status).JSON(err)
 		}
 
-		err = r.DeleteMany(claims.UserID)
+		err = a.RevokeSessions(userID, "")
 		if err != nil {
 			return c.Status(err.Status).JSON(err)
 		}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/nozgurozturk/marvin/pkg/errors"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"github.com/nozgurozturk/marvin/server/internal/app"
+	"github.com/nozgurozturk/marvin/server/internal/service"
+	"net/http"
+)
+
+// WebhookHandler no need authentication, events are verified with repository's webhook secret
+func WebhookHandler(router fiber.Router, repoService service.RepoService) {
+	router.Post("/github/:id", githubWebhook(repoService))
+	router.Post("/gitlab/:id", gitlabWebhook(repoService))
+	router.Post("/bitbucket/:id", bitbucketWebhook(repoService))
+}
+
+// githubWebhook is a function to rescan repository with GitHub push events
+// @Summary Receive GitHub push event
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository id"
+// @Success 200 {object} entity.Response{}
+// @Success 202 {object} entity.Response{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 422 {object} errors.AppError{}
+// @Router /webhook/github/{id} [post]
+func githubWebhook(s service.RepoService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		repo, err := s.FindByID(c.Params("id"))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if !app.VerifyWebhookSignature(repo.WebhookSecret, c.Body(), c.Get("X-Hub-Signature-256")) {
+			err = errors.Unauthorized("Invalid webhook signature")
+			return c.Status(err.Status).JSON(err)
+		}
+
+		// GitHub sends ping event when webhook is created
+		if c.Get("X-GitHub-Event") != "push" {
+			return pushIgnored(c)
+		}
+
+		event, parseErr := entity.ToGithubPushEvent(c.Body())
+		if parseErr != nil {
+			e := errors.UnprocessableEntity("Invalid push event")
+			return c.Status(e.Status).JSON(e)
+		}
+
+		return handlePush(c, s, repo, event)
+	}
+}
+
+// gitlabWebhook is a function to rescan repository with GitLab push hooks
+// @Summary Receive GitLab push hook
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository id"
+// @Success 200 {object} entity.Response{}
+// @Success 202 {object} entity.Response{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 422 {object} errors.AppError{}
+// @Router /webhook/gitlab/{id} [post]
+func gitlabWebhook(s service.RepoService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		repo, err := s.FindByID(c.Params("id"))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if !app.VerifyWebhookToken(repo.WebhookSecret, c.Get("X-Gitlab-Token")) {
+			err = errors.Unauthorized("Invalid webhook token")
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if c.Get("X-Gitlab-Event") != "Push Hook" {
+			return pushIgnored(c)
+		}
+
+		event, parseErr := entity.ToGitlabPushEvent(c.Body())
+		if parseErr != nil {
+			e := errors.UnprocessableEntity("Invalid push event")
+			return c.Status(e.Status).JSON(e)
+		}
+
+		return handlePush(c, s, repo, event)
+	}
+}
+
+// bitbucketWebhook is a function to rescan repository with Bitbucket push events
+// @Summary Receive Bitbucket push event
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository id"
+// @Success 200 {object} entity.Response{}
+// @Success 202 {object} entity.Response{}
+// @Failure 401 {object} errors.AppError{}
+// @Failure 404 {object} errors.AppError{}
+// @Failure 422 {object} errors.AppError{}
+// @Router /webhook/bitbucket/{id} [post]
+func bitbucketWebhook(s service.RepoService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		repo, err := s.FindByID(c.Params("id"))
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if !app.VerifyWebhookSignature(repo.WebhookSecret, c.Body(), c.Get("X-Hub-Signature")) {
+			err = errors.Unauthorized("Invalid webhook signature")
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if c.Get("X-Event-Key") != "repo:push" {
+			return pushIgnored(c)
+		}
+
+		event, parseErr := entity.ToBitbucketPushEvent(c.Body())
+		if parseErr != nil {
+			e := errors.UnprocessableEntity("Invalid push event")
+			return c.Status(e.Status).JSON(e)
+		}
+
+		return handlePush(c, s, repo, event)
+	}
+}
+
+func handlePush(c *fiber.Ctx, s service.RepoService, repo *entity.RepoDTO, event *entity.PushEvent) error {
+	if !s.HandlePush(repo, event) {
+		return pushIgnored(c)
+	}
+
+	response := entity.ToResponse(
+		"Package files are changed, repository is rescanning",
+		http.StatusAccepted,
+		nil,
+	)
+	return c.Status(response.Status).JSON(response)
+}
+
+func pushIgnored(c *fiber.Ctx) error {
+	response := entity.ToResponse(
+		"Event does not change package files",
+		http.StatusOK,
+		nil,
+	)
+	return c.Status(response.Status).JSON(response)
+}
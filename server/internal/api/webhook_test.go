@@ -0,0 +1,116 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nozgurozturk/marvin/pkg/errors"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"github.com/nozgurozturk/marvin/server/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Repository service that finds one repository and records pushes, scans are not started
+type fakeWebhookRepoService struct {
+	service.RepoService
+	repo   *entity.RepoDTO
+	pushes []*entity.PushEvent
+}
+
+func (s *fakeWebhookRepoService) FindByID(repoID string) (*entity.RepoDTO, *errors.AppError) {
+	if s.repo.ID == nil || *s.repo.ID != repoID {
+		return nil, errors.NotFound("Repository is not found")
+	}
+	return s.repo, nil
+}
+
+func (s *fakeWebhookRepoService) HandlePush(repoDTO *entity.RepoDTO, event *entity.PushEvent) bool {
+	s.pushes = append(s.pushes, event)
+	return true
+}
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookVerification(t *testing.T) {
+
+	const secret = "webhook-secret"
+	githubBody := []byte(`{"ref": "refs/heads/main", "commits": [{"modified": ["package.json"]}]}`)
+	gitlabBody := []byte(`{"ref": "refs/heads/main", "total_commits_count": 1, "commits": [{"modified": ["package.json"]}]}`)
+	bitbucketBody := []byte(`{"push": {"changes": [{"new": {"name": "main"}}]}}`)
+
+	tests := []struct {
+		name    string
+		path    string
+		body    []byte
+		headers map[string]string
+		status  int
+	}{
+		{"github signature", "/webhook/github/repo", githubBody, map[string]string{"X-Hub-Signature-256": sign(secret, githubBody), "X-GitHub-Event": "push"}, http.StatusAccepted},
+		{"github signature of other secret", "/webhook/github/repo", githubBody, map[string]string{"X-Hub-Signature-256": sign("other", githubBody), "X-GitHub-Event": "push"}, http.StatusUnauthorized},
+		{"github signature of other body", "/webhook/github/repo", githubBody, map[string]string{"X-Hub-Signature-256": sign(secret, gitlabBody), "X-GitHub-Event": "push"}, http.StatusUnauthorized},
+		{"github sha1 signature", "/webhook/github/repo", githubBody, map[string]string{"X-Hub-Signature-256": "sha1=" + sign(secret, githubBody)[7:], "X-GitHub-Event": "push"}, http.StatusUnauthorized},
+		{"github without signature", "/webhook/github/repo", githubBody, map[string]string{"X-GitHub-Event": "push"}, http.StatusUnauthorized},
+		{"github ping", "/webhook/github/repo", githubBody, map[string]string{"X-Hub-Signature-256": sign(secret, githubBody), "X-GitHub-Event": "ping"}, http.StatusOK},
+		{"gitlab token", "/webhook/gitlab/repo", gitlabBody, map[string]string{"X-Gitlab-Token": secret, "X-Gitlab-Event": "Push Hook"}, http.StatusAccepted},
+		{"gitlab other token", "/webhook/gitlab/repo", gitlabBody, map[string]string{"X-Gitlab-Token": "other", "X-Gitlab-Event": "Push Hook"}, http.StatusUnauthorized},
+		{"gitlab without token", "/webhook/gitlab/repo", gitlabBody, map[string]string{"X-Gitlab-Event": "Push Hook"}, http.StatusUnauthorized},
+		{"bitbucket signature", "/webhook/bitbucket/repo", bitbucketBody, map[string]string{"X-Hub-Signature": sign(secret, bitbucketBody), "X-Event-Key": "repo:push"}, http.StatusAccepted},
+		{"bitbucket signature of other secret", "/webhook/bitbucket/repo", bitbucketBody, map[string]string{"X-Hub-Signature": sign("other", bitbucketBody), "X-Event-Key": "repo:push"}, http.StatusUnauthorized},
+		{"unknown repository", "/webhook/github/other", githubBody, map[string]string{"X-Hub-Signature-256": sign(secret, githubBody), "X-GitHub-Event": "push"}, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoID := "repo"
+			s := &fakeWebhookRepoService{repo: &entity.RepoDTO{ID: &repoID, WebhookSecret: secret}}
+
+			app := fiber.New()
+			WebhookHandler(app.Group("/webhook"), s)
+
+			request := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewReader(tt.body))
+			for key, value := range tt.headers {
+				request.Header.Set(key, value)
+			}
+			response, err := app.Test(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if response.StatusCode != tt.status {
+				t.Errorf("expected %d, got %d", tt.status, response.StatusCode)
+			}
+			if handled := len(s.pushes) == 1; handled != (tt.status == http.StatusAccepted) {
+				t.Errorf("expected push to be handled: %v, got %d pushes", tt.status == http.StatusAccepted, len(s.pushes))
+			}
+		})
+	}
+}
+
+func TestWebhookRejectsRepositoryWithoutSecret(t *testing.T) {
+
+	repoID := "repo"
+	s := &fakeWebhookRepoService{repo: &entity.RepoDTO{ID: &repoID}}
+
+	app := fiber.New()
+	WebhookHandler(app.Group("/webhook"), s)
+
+	// Empty token does not match empty secret of old repositories
+	request := httptest.NewRequest(http.MethodPost, "/webhook/gitlab/repo", bytes.NewReader([]byte(`{}`)))
+	request.Header.Set("X-Gitlab-Token", "")
+	request.Header.Set("X-Gitlab-Event", "Push Hook")
+	response, err := app.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusUnauthorized || len(s.pushes) != 0 {
+		t.Errorf("expected %d without push, got %d", http.StatusUnauthorized, response.StatusCode)
+	}
+}
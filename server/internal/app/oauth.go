@@ -0,0 +1,253 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/nozgurozturk/marvin/pkg/client"
+	"github.com/nozgurozturk/marvin/pkg/errors"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"github.com/nozgurozturk/marvin/server/internal/config"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	GithubOAuth = "github"
+	GitlabOAuth = "gitlab"
+)
+
+// OAuth application of provider
+type OAuthProvider struct {
+	Name string
+	// Host of repositories that can be read with granted token
+	Host         string
+	AuthorizeUrl string
+	TokenUrl     string
+	ApiUrl       string
+	ClientID     string
+	ClientSecret string
+	// Scopes for login, repository scopes are added when user allows repository access
+	Scopes     []string
+	RepoScopes []string
+}
+
+// Gets OAuth application of provider from config
+func GetOAuthProvider(name string) (*OAuthProvider, *errors.AppError) {
+	cnf := config.Get()
+
+	switch name {
+	case GithubOAuth:
+		if cnf.OAuth.GithubClientID == "" {
+			break
+		}
+		oauthUrl := strings.TrimSuffix(cnf.OAuth.GithubUrl, "/")
+		apiUrl := "https://api.github.com"
+		if cnf.Provider.GithubApiUrl != "" {
+			apiUrl = strings.TrimSuffix(cnf.Provider.GithubApiUrl, "/")
+		}
+		return &OAuthProvider{
+			Name:         GithubOAuth,
+			Host:         hostOf(oauthUrl, "github.com"),
+			AuthorizeUrl: oauthUrl + "/login/oauth/authorize",
+			TokenUrl:     oauthUrl + "/login/oauth/access_token",
+			ApiUrl:       apiUrl,
+			ClientID:     cnf.OAuth.GithubClientID,
+			ClientSecret: cnf.OAuth.GithubClientSecret,
+			Scopes:       []string{"read:user", "user:email"},
+			RepoScopes:   []string{"repo"},
+		}, nil
+	case GitlabOAuth:
+		if cnf.OAuth.GitlabClientID == "" {
+			break
+		}
+		oauthUrl := strings.TrimSuffix(cnf.OAuth.GitlabUrl, "/")
+		apiUrl := oauthUrl + "/api/v4"
+		if cnf.Provider.GitlabApiUrl != "" {
+			apiUrl = strings.TrimSuffix(cnf.Provider.GitlabApiUrl, "/")
+		}
+		return &OAuthProvider{
+			Name:         GitlabOAuth,
+			Host:         hostOf(oauthUrl, "gitlab.com"),
+			AuthorizeUrl: oauthUrl + "/oauth/authorize",
+			TokenUrl:     oauthUrl + "/oauth/token",
+			ApiUrl:       apiUrl,
+			ClientID:     cnf.OAuth.GitlabClientID,
+			ClientSecret: cnf.OAuth.GitlabClientSecret,
+			Scopes:       []string{"read_user"},
+			RepoScopes:   []string{"api"},
+		}, nil
+	}
+
+	return nil, errors.NotFound(fmt.Sprintf("OAuth login with %s is not enabled", name))
+}
+
+// Repositories of self hosted instances are on host of OAuth url
+func hostOf(rawUrl string, defaultHost string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Host == "" {
+		return defaultHost
+	}
+	return u.Host
+}
+
+// Creates random state of authorization request
+func NewOAuthState() (string, *errors.AppError) {
+	state := make([]byte, 16)
+	if _, err := rand.Read(state); err != nil {
+		return "", errors.InternalServer(err.Error())
+	}
+	return hex.EncodeToString(state), nil
+}
+
+// Hash of state is kept in cookie of browser that starts login, state itself is only sent to provider
+func OAuthStateHash(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+// Checks state of callback belongs to browser, hashes are compared in constant time
+func VerifyOAuthState(state string, stateHash string) bool {
+	if state == "" || stateHash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(OAuthStateHash(state)), []byte(stateHash)) == 1
+}
+
+// Callback url of provider, it must be registered in OAuth application
+func (p *OAuthProvider) RedirectUrl() string {
+	cnf := config.Get().HTTP
+	return fmt.Sprintf("http://%s%s/auth/oauth/%s/callback", cnf.Host, cnf.Port, p.Name)
+}
+
+// Creates authorization url that user is redirected to
+func (p *OAuthProvider) AuthCodeUrl(state string, repoAccess bool) string {
+	scopes := p.Scopes
+	if repoAccess {
+		scopes = append(append([]string{}, p.Scopes...), p.RepoScopes...)
+	}
+
+	query := url.Values{}
+	query.Set("client_id", p.ClientID)
+	query.Set("redirect_uri", p.RedirectUrl())
+	query.Set("response_type", "code")
+	query.Set("scope", strings.Join(scopes, " "))
+	query.Set("state", state)
+
+	return p.AuthorizeUrl + "?" + query.Encode()
+}
+
+// Exchanges authorization code with access token
+func (p *OAuthProvider) Exchange(ctx context.Context, code string) (string, *errors.AppError) {
+
+	headers := map[string]string{
+		"Accept":       "application/json",
+		"Content-Type": "application/json",
+	}
+	body := map[string]interface{}{
+		"client_id":     p.ClientID,
+		"client_secret": p.ClientSecret,
+		"code":          code,
+		"grant_type":    "authorization_code",
+		"redirect_uri":  p.RedirectUrl(),
+	}
+
+	tokenData, err := client.New("").Post(ctx, p.TokenUrl, headers, body)
+	if statusErr, ok := err.(*client.StatusError); ok {
+		return "", errors.Unauthorized(fmt.Sprintf("%s responded with %d", p.Name, statusErr.StatusCode))
+	}
+	if err != nil {
+		return "", errors.InternalServer(err.Error())
+	}
+
+	var token struct {
+		AccessToken      string `json:"access_token"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(tokenData, &token); err != nil {
+		return "", errors.InternalServer(err.Error())
+	}
+
+	if token.AccessToken == "" {
+		return "", errors.Unauthorized(fmt.Sprintf("Authorization is failed: %s", token.ErrorDescription))
+	}
+
+	return token.AccessToken, nil
+}
+
+// Gets user info of access token
+func (p *OAuthProvider) GetProfile(ctx context.Context, accessToken string) (*entity.OAuthProfile, *errors.AppError) {
+
+	var user struct {
+		ID       int64  `json:"id"`
+		Login    string `json:"login"`
+		Username string `json:"username"`
+		Name     string `json:"name"`
+		Email    string `json:"email"`
+	}
+	if err := p.getJSON(ctx, "/user", accessToken, &user); err != nil {
+		return nil, err
+	}
+
+	profile := &entity.OAuthProfile{
+		ID:       strconv.FormatInt(user.ID, 10),
+		Username: user.Username,
+		Name:     user.Name,
+		Email:    user.Email,
+		// GitLab returns only confirmed primary email
+		EmailVerified: p.Name == GitlabOAuth && user.Email != "",
+	}
+
+	if p.Name == GithubOAuth {
+		profile.Username = user.Login
+
+		// Public email of GitHub can be empty or unverified, primary email is used
+		var emails []struct {
+			Email    string `json:"email"`
+			Primary  bool   `json:"primary"`
+			Verified bool   `json:"verified"`
+		}
+		if err := p.getJSON(ctx, "/user/emails", accessToken, &emails); err != nil {
+			return nil, err
+		}
+		for _, email := range emails {
+			if email.Primary {
+				profile.Email = email.Email
+				profile.EmailVerified = email.Verified
+			}
+		}
+	}
+
+	if profile.Name == "" {
+		profile.Name = profile.Username
+	}
+
+	return profile, nil
+}
+
+func (p *OAuthProvider) getJSON(ctx context.Context, endpoint string, accessToken string, v interface{}) *errors.AppError {
+
+	headers := map[string]string{
+		"Accept":        "application/json",
+		"Authorization": "Bearer " + accessToken,
+	}
+
+	data, err := client.New(p.ApiUrl).Get(ctx, endpoint, headers)
+	if statusErr, ok := err.(*client.StatusError); ok {
+		return errors.Unauthorized(fmt.Sprintf("%s responded with %d", p.Name, statusErr.StatusCode))
+	}
+	if err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,206 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/nozgurozturk/marvin/server/internal/config"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Loads config from given variables, required expiration variables are set if they are not given
+func setTestConfig(t *testing.T, env map[string]string) {
+	t.Helper()
+
+	defaults := map[string]string{
+		"HOST":           "localhost",
+		"PORT":           "8080",
+		"ACCESS_EXPIRE":  "60",
+		"REFRESH_EXPIRE": "720",
+		"SUB_EXPIRE":     "24",
+	}
+	for key, value := range env {
+		defaults[key] = value
+	}
+
+	for key, value := range defaults {
+		key := key
+		previous, exist := os.LookupEnv(key)
+		if err := os.Setenv(key, value); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			if exist {
+				os.Setenv(key, previous)
+			} else {
+				os.Unsetenv(key)
+			}
+		})
+	}
+
+	config.Set()
+}
+
+// Stub of GitHub authorization server and api, code is exchanged with token of user
+func newStubGithub(t *testing.T, code string, accessToken string) *httptest.Server {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		switch r.Method + " " + r.URL.Path {
+		case "POST /login/oauth/access_token":
+			var body map[string]string
+			data, _ := ioutil.ReadAll(r.Body)
+			_ = json.Unmarshal(data, &body)
+
+			if body["client_id"] != "client-id" || body["client_secret"] != "client-secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if body["redirect_uri"] != "http://localhost:8080/auth/oauth/github/callback" {
+				t.Errorf("unexpected redirect uri %s", body["redirect_uri"])
+			}
+			// GitHub responds 200 with error for invalid codes
+			if body["code"] != code {
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "bad_verification_code", "error_description": "The code passed is incorrect or expired."})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"access_token": accessToken, "token_type": "bearer"})
+		case "GET /user", "GET /user/emails":
+			if r.Header.Get("Authorization") != "Bearer "+accessToken {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Path == "/user" {
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": 42, "login": "octocat", "name": "", "email": "public@example.com"})
+				return
+			}
+			_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"email": "public@example.com", "primary": false, "verified": false},
+				{"email": "octocat@example.com", "primary": true, "verified": true},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestOAuthExchange(t *testing.T) {
+
+	server := newStubGithub(t, "valid-code", "access-token")
+	setTestConfig(t, map[string]string{
+		"GITHUB_CLIENT_ID":     "client-id",
+		"GITHUB_CLIENT_SECRET": "client-secret",
+		"GITHUB_OAUTH_URL":     server.URL,
+		"GITHUB_API_URL":       server.URL,
+	})
+
+	provider, appErr := GetOAuthProvider(GithubOAuth)
+	if appErr != nil {
+		t.Fatal(appErr.Message)
+	}
+
+	accessToken, appErr := provider.Exchange(context.Background(), "valid-code")
+	if appErr != nil {
+		t.Fatal(appErr.Message)
+	}
+	if accessToken != "access-token" {
+		t.Errorf("unexpected access token %s", accessToken)
+	}
+
+	_, appErr = provider.Exchange(context.Background(), "expired-code")
+	if appErr == nil || appErr.Status != http.StatusUnauthorized || !strings.Contains(appErr.Message, "incorrect or expired") {
+		t.Errorf("expected unauthorized error of invalid code, got %v", appErr)
+	}
+
+	provider.ClientSecret = "wrong-secret"
+	_, appErr = provider.Exchange(context.Background(), "valid-code")
+	if appErr == nil || appErr.Status != http.StatusUnauthorized {
+		t.Errorf("expected unauthorized error of rejected client, got %v", appErr)
+	}
+}
+
+func TestOAuthGetProfile(t *testing.T) {
+
+	server := newStubGithub(t, "valid-code", "access-token")
+	setTestConfig(t, map[string]string{
+		"GITHUB_CLIENT_ID": "client-id",
+		"GITHUB_OAUTH_URL": server.URL,
+		"GITHUB_API_URL":   server.URL,
+	})
+
+	provider, appErr := GetOAuthProvider(GithubOAuth)
+	if appErr != nil {
+		t.Fatal(appErr.Message)
+	}
+
+	profile, appErr := provider.GetProfile(context.Background(), "access-token")
+	if appErr != nil {
+		t.Fatal(appErr.Message)
+	}
+
+	// Verified primary email is used instead of public email
+	if profile.ID != "42" || profile.Username != "octocat" || profile.Name != "octocat" {
+		t.Errorf("unexpected profile %+v", profile)
+	}
+	if profile.Email != "octocat@example.com" || !profile.EmailVerified {
+		t.Errorf("expected verified primary email, got %s (verified: %v)", profile.Email, profile.EmailVerified)
+	}
+
+	if _, appErr := provider.GetProfile(context.Background(), "revoked-token"); appErr == nil || appErr.Status != http.StatusUnauthorized {
+		t.Errorf("expected unauthorized error of revoked token, got %v", appErr)
+	}
+}
+
+func TestOAuthAuthCodeUrl(t *testing.T) {
+
+	setTestConfig(t, map[string]string{
+		"GITLAB_CLIENT_ID": "client-id",
+		"GITLAB_OAUTH_URL": "https://gitlab.example.com/",
+	})
+
+	provider, appErr := GetOAuthProvider(GitlabOAuth)
+	if appErr != nil {
+		t.Fatal(appErr.Message)
+	}
+	if provider.ApiUrl != "https://gitlab.example.com/api/v4" {
+		t.Errorf("unexpected api url %s", provider.ApiUrl)
+	}
+	// Identities of self hosted instance match its repositories
+	if provider.Host != "gitlab.example.com" {
+		t.Errorf("expected host of self hosted instance, got %s", provider.Host)
+	}
+
+	tests := []struct {
+		repoAccess bool
+		scope      string
+	}{
+		{false, "read_user"},
+		{true, "read_user api"},
+	}
+
+	for _, tt := range tests {
+		authUrl, err := url.Parse(provider.AuthCodeUrl("state", tt.repoAccess))
+		if err != nil {
+			t.Fatal(err)
+		}
+		query := authUrl.Query()
+		if authUrl.Host != "gitlab.example.com" || authUrl.Path != "/oauth/authorize" {
+			t.Errorf("unexpected authorization url %s", authUrl)
+		}
+		if query.Get("state") != "state" || query.Get("scope") != tt.scope || query.Get("client_id") != "client-id" {
+			t.Errorf("unexpected authorization query %s", query.Encode())
+		}
+	}
+
+	if _, appErr := GetOAuthProvider(GithubOAuth); appErr == nil || appErr.Status != http.StatusNotFound {
+		t.Errorf("expected provider without client id to be disabled, got %v", appErr)
+	}
+}
@@ -101,10 +101,14 @@ func ExtractSubTokenMetaData(t string) (*entity.SubToken, *errors.AppError) {
 	}, nil
 }
 
-func CreateToken(u *entity.UserDTO) (*entity.TokenDetails, *errors.AppError) {
+// Creates token pair of session, new session is started if session id is empty
+func CreateToken(u *entity.UserDTO, sessionID string) (*entity.TokenDetails, *errors.AppError) {
 	var err error
 
 	uniqueID := uuid.New().String()
+	if sessionID == "" {
+		sessionID = uuid.New().String()
+	}
 
 	cnf := config.Get().HTTP
 	rt := &entity.Token{}
@@ -113,6 +117,7 @@ func CreateToken(u *entity.UserDTO) (*entity.TokenDetails, *errors.AppError) {
 	rt.UserID = u.ID
 	rt.Email = u.Email
 	rt.Authorized = u.IsConfirmed
+	rt.SessionID = sessionID
 
 	rtClaims := jwt.MapClaims{
 		"exp":    rt.Expires,
@@ -120,6 +125,7 @@ func CreateToken(u *entity.UserDTO) (*entity.TokenDetails, *errors.AppError) {
 		"userID": rt.UserID,
 		"email":  rt.Email,
 		"authorized": rt.Authorized,
+		"session":    rt.SessionID,
 	}
 
 	rtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, rtClaims)
@@ -135,6 +141,7 @@ func CreateToken(u *entity.UserDTO) (*entity.TokenDetails, *errors.AppError) {
 	at.UserID = u.ID
 	at.Email = u.Email
 	at.Authorized = u.IsConfirmed
+	at.SessionID = sessionID
 
 	atClaims := jwt.MapClaims{
 		"exp":    at.Expires,
@@ -142,6 +149,7 @@ func CreateToken(u *entity.UserDTO) (*entity.TokenDetails, *errors.AppError) {
 		"userID": at.UserID,
 		"email":  at.Email,
 		"authorized": at.Authorized,
+		"session":    at.SessionID,
 	}
 
 	atToken := jwt.NewWithClaims(jwt.SigningMethodHS256, atClaims)
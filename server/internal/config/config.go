@@ -2,9 +2,11 @@ package config
 
 import (
 	"github.com/joho/godotenv"
+	"github.com/nozgurozturk/marvin/pkg/client"
 	"log"
 	"os"
 	"strconv"
+	"strings"
 )
 
 var (
@@ -12,10 +14,14 @@ var (
 )
 
 type configurations struct {
-	HTTP  *httpConfig
-	SMTP  *smtpConfig
-	Mongo *mongoConfig
-	Redis *redisConfig
+	HTTP     *httpConfig
+	SMTP     *smtpConfig
+	Mongo    *mongoConfig
+	Redis    *redisConfig
+	Provider *providerConfig
+	OAuth    *oauthConfig
+	Outbound client.TransportOptions
+	Snapshot *snapshotConfig
 }
 
 type httpConfig struct {
@@ -27,6 +33,8 @@ type httpConfig struct {
 	RefreshSecret string
 	SubSecret     string
 	SubExpire     int64
+	// Header of client IP behind load balancer, for exp. X-Forwarded-For
+	ProxyHeader string
 }
 
 type smtpConfig struct {
@@ -51,6 +59,36 @@ type redisConfig struct {
 	DB       int
 }
 
+// Api urls of providers, empty urls use public hosts
+// Git provider is disabled by default, clone urls can point to disk of server or internal hosts
+type providerConfig struct {
+	GithubApiUrl    string
+	GitlabApiUrl    string
+	GitEnabled      bool
+	GitLocalRoots   []string
+	GitHosts        []string
+	GitMaxCloneSize int64
+}
+
+// OAuth applications, providers without client id are disabled
+// Urls point to authorization servers, they can be changed for self hosted instances or stubs
+type oauthConfig struct {
+	GithubClientID     string
+	GithubClientSecret string
+	GithubUrl          string
+	GitlabClientID     string
+	GitlabClientSecret string
+	GitlabUrl          string
+}
+
+// Retention of scan snapshots, zero disables limit
+type snapshotConfig struct {
+	// Newest snapshots that are kept for every repository
+	RetentionCount int64
+	// Snapshots older than days are deleted
+	RetentionDays int64
+}
+
 func Set() *configurations {
 
 	// load .env file
@@ -85,6 +123,7 @@ func Set() *configurations {
 		SubSecret:     os.Getenv("SUB_SECRET"),
 		SubExpire:     subExpire,
 		Port:          ":" + os.Getenv("PORT"),
+		ProxyHeader:   os.Getenv("PROXY_HEADER"),
 	}
 
 	// email client config
@@ -111,10 +150,72 @@ func Set() *configurations {
 		Password: os.Getenv("REDIS_DB_PASSWORD"),
 		DB:       0,
 	}
+
+	// git provider config
+	cnf.Provider = &providerConfig{
+		GithubApiUrl:    os.Getenv("GITHUB_API_URL"),
+		GitlabApiUrl:    os.Getenv("GITLAB_API_URL"),
+		GitEnabled:      os.Getenv("GIT_PROVIDER_ENABLED") == "true",
+		GitLocalRoots:   parseList(os.Getenv("GIT_LOCAL_ROOTS")),
+		GitHosts:        parseList(os.Getenv("GIT_HOSTS")),
+		GitMaxCloneSize: getEnvIntOrDefault("GIT_MAX_CLONE_SIZE_MB", 100) << 20,
+	}
+
+	// oauth config
+	cnf.OAuth = &oauthConfig{
+		GithubClientID:     os.Getenv("GITHUB_CLIENT_ID"),
+		GithubClientSecret: os.Getenv("GITHUB_CLIENT_SECRET"),
+		GithubUrl:          getEnvOrDefault("GITHUB_OAUTH_URL", "https://github.com"),
+		GitlabClientID:     os.Getenv("GITLAB_CLIENT_ID"),
+		GitlabClientSecret: os.Getenv("GITLAB_CLIENT_SECRET"),
+		GitlabUrl:          getEnvOrDefault("GITLAB_OAUTH_URL", "https://gitlab.com"),
+	}
+
+	// outbound request config
+	cnf.Outbound = client.NewTransportOptions(
+		os.Getenv("OUTBOUND_PROXY"),
+		os.Getenv("OUTBOUND_NO_PROXY"),
+		os.Getenv("OUTBOUND_CA_BUNDLES"),
+		os.Getenv("OUTBOUND_CLIENT_CERTS"),
+		os.Getenv("OUTBOUND_CLIENT_KEYS"),
+	)
+
+	// snapshot config
+	cnf.Snapshot = &snapshotConfig{
+		RetentionCount: getEnvIntOrDefault("SNAPSHOT_RETENTION_COUNT", 50),
+		RetentionDays:  getEnvIntOrDefault("SNAPSHOT_RETENTION_DAYS", 365),
+	}
+
 	configs = cnf
 	return configs
 }
 
+// Parses comma separated values
+func parseList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getEnvOrDefault(key string, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvIntOrDefault(key string, defaultValue int64) int64 {
+	value, err := strconv.ParseInt(os.Getenv(key), 10, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
 func Get() *configurations {
 	return configs
 }
@@ -0,0 +1,132 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/nozgurozturk/marvin/pkg/advisories"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"html"
+	"strings"
+)
+
+const (
+	badgeLabel = "dependencies"
+	// Average width of Verdana 11px characters and horizontal padding of badge parts
+	badgeCharWidth = 7
+	badgePadding   = 10
+)
+
+// Colors of badge from the least to the most severe, first matching limit of outdated share is used
+var badgeColors = []struct {
+	limit float64
+	color string
+}{
+	{0, "#4c1"},
+	{0.1, "#a4a61d"},
+	{0.25, "#dfb317"},
+	{0.5, "#fe7d37"},
+	{1, "#e05d44"},
+}
+
+// Deprecated and vulnerable packages are at least as severe as these colors, outdated share can make them worse
+const (
+	deprecatedColor = 2
+	vulnerableColor = 3
+	// Critical and high advisories
+	severeVulnerableColor = 4
+)
+
+// Renders shield badge with outdated, deprecated and vulnerable package counts of latest scan
+// Color of badge is the color of the most severe problem
+func RenderBadge(repo *entity.RepoDTO) []byte {
+
+	value, color := "unknown", "#9f9f9f"
+
+	if repo.LastScan != nil || len(repo.PackageList) > 0 {
+		outdated, deprecated, vulnerable := 0, 0, 0
+		severity := 0
+
+		for _, pkg := range repo.PackageList {
+			if pkg.IsOutdated {
+				outdated++
+			}
+			if pkg.Deprecated != "" {
+				deprecated++
+				severity = max(severity, deprecatedColor)
+			}
+			if len(pkg.Advisories) > 0 {
+				vulnerable++
+				severity = max(severity, vulnerableColor)
+			}
+			for _, advisory := range pkg.Advisories {
+				if advisory.Severity == advisories.High || advisory.Severity == advisories.Critical {
+					severity = severeVulnerableColor
+				}
+			}
+		}
+
+		var counts []string
+		for _, count := range []struct {
+			count int
+			name  string
+		}{{vulnerable, "vulnerable"}, {deprecated, "deprecated"}, {outdated, "outdated"}} {
+			if count.count > 0 {
+				counts = append(counts, fmt.Sprintf("%d %s", count.count, count.name))
+			}
+		}
+
+		value = "up to date"
+		if len(counts) > 0 {
+			value = strings.Join(counts, ", ")
+		}
+
+		share := 0.0
+		if len(repo.PackageList) > 0 {
+			share = float64(outdated) / float64(len(repo.PackageList))
+		}
+		for i, c := range badgeColors {
+			if share <= c.limit {
+				severity = max(severity, i)
+				break
+			}
+		}
+		color = badgeColors[severity].color
+	}
+
+	return renderShield(badgeLabel, value, color)
+}
+
+func max(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func renderShield(label string, value string, color string) []byte {
+
+	labelWidth := len(label)*badgeCharWidth + badgePadding
+	valueWidth := len(value)*badgeCharWidth + badgePadding
+	width := labelWidth + valueWidth
+
+	title := html.EscapeString(label + ": " + value)
+	label, value = html.EscapeString(label), html.EscapeString(value)
+
+	buffer := new(bytes.Buffer)
+	fmt.Fprintf(buffer, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s">`, width, title)
+	fmt.Fprintf(buffer, `<title>%s</title>`, title)
+	buffer.WriteString(`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`)
+	fmt.Fprintf(buffer, `<clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`, width)
+	fmt.Fprintf(buffer, `<g clip-path="url(#r)"><rect width="%d" height="20" fill="#555"/><rect x="%d" width="%d" height="20" fill="%s"/><rect width="%d" height="20" fill="url(#s)"/></g>`,
+		labelWidth, labelWidth, valueWidth, color, width)
+	buffer.WriteString(`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`)
+	for _, text := range []struct {
+		x     int
+		value string
+	}{{labelWidth / 2, label}, {labelWidth + valueWidth/2, value}} {
+		fmt.Fprintf(buffer, `<text x="%d" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%d" y="14">%s</text>`, text.x, text.value, text.x, text.value)
+	}
+	buffer.WriteString(`</g></svg>`)
+
+	return buffer.Bytes()
+}
@@ -0,0 +1,46 @@
+package report
+
+import (
+	"github.com/nozgurozturk/marvin/server/entity"
+	"strings"
+	"testing"
+)
+
+func TestRenderBadge(t *testing.T) {
+
+	outdated := &entity.Package{Name: "react", IsOutdated: true}
+	upToDate := &entity.Package{Name: "lodash"}
+	deprecated := &entity.Package{Name: "request", Deprecated: "request has been deprecated"}
+	moderate := &entity.Package{Name: "axios", Advisories: []*entity.Advisory{{ID: "GHSA-1", Severity: "moderate"}}}
+	critical := &entity.Package{Name: "minimist", Advisories: []*entity.Advisory{{ID: "GHSA-2", Severity: "low"}, {ID: "GHSA-3", Severity: "critical"}}}
+
+	tests := []struct {
+		name     string
+		packages []*entity.Package
+		value    string
+		color    string
+	}{
+		{"up to date", []*entity.Package{upToDate}, "up to date", "#4c1"},
+		{"few outdated", []*entity.Package{outdated, upToDate, upToDate, upToDate, upToDate, upToDate, upToDate, upToDate, upToDate, upToDate}, "1 outdated", "#a4a61d"},
+		{"deprecated", []*entity.Package{deprecated, upToDate}, "1 deprecated", "#dfb317"},
+		{"outdated share is more severe than deprecated", []*entity.Package{deprecated, outdated}, "1 deprecated, 1 outdated", "#fe7d37"},
+		{"moderate advisory", []*entity.Package{moderate, deprecated, upToDate, upToDate}, "1 vulnerable, 1 deprecated", "#fe7d37"},
+		{"critical advisory", []*entity.Package{critical, moderate, upToDate, upToDate}, "2 vulnerable", "#e05d44"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			badge := string(RenderBadge(&entity.RepoDTO{PackageList: tt.packages}))
+			if !strings.Contains(badge, "<title>dependencies: "+tt.value+"</title>") {
+				t.Errorf("expected value %q, got %s", tt.value, badge)
+			}
+			if !strings.Contains(badge, `fill="`+tt.color+`"`) {
+				t.Errorf("expected color %s, got %s", tt.color, badge)
+			}
+		})
+	}
+
+	if badge := string(RenderBadge(&entity.RepoDTO{})); !strings.Contains(badge, "dependencies: unknown") {
+		t.Errorf("expected unknown badge of repository without scan, got %s", badge)
+	}
+}
@@ -0,0 +1,32 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"strconv"
+)
+
+func renderCSV(packages []*entity.Package) ([]byte, error) {
+
+	buffer := new(bytes.Buffer)
+	writer := csv.NewWriter(buffer)
+
+	rows := [][]string{{"manifest", "name", "current", "latest", "outdated", "ignored"}}
+	for _, pkg := range packages {
+		rows = append(rows, []string{
+			manifestPath(pkg),
+			pkg.Name,
+			pkg.Version.Current,
+			pkg.Version.Last,
+			strconv.FormatBool(pkg.IsOutdated),
+			ignoreReason(pkg),
+		})
+	}
+
+	if err := writer.WriteAll(rows); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
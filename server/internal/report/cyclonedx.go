@@ -0,0 +1,130 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/nozgurozturk/marvin/pkg/parsers"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"strings"
+	"time"
+)
+
+const cycloneDXVersion = "1.5"
+
+type cycloneDXBOM struct {
+	BOMFormat    string                 `json:"bomFormat"`
+	SpecVersion  string                 `json:"specVersion"`
+	SerialNumber string                 `json:"serialNumber"`
+	Version      int                    `json:"version"`
+	Metadata     cycloneDXMetadata      `json:"metadata"`
+	Components   []*cycloneDXComponent  `json:"components"`
+	Dependencies []*cycloneDXDependency `json:"dependencies"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string `json:"timestamp"`
+	Tools     struct {
+		Components []*cycloneDXComponent `json:"components"`
+	} `json:"tools"`
+	Component *cycloneDXComponent `json:"component"`
+}
+
+type cycloneDXComponent struct {
+	Type       string               `json:"type"`
+	BOMRef     string               `json:"bom-ref,omitempty"`
+	Group      string               `json:"group,omitempty"`
+	Name       string               `json:"name"`
+	Version    string               `json:"version,omitempty"`
+	Purl       string               `json:"purl,omitempty"`
+	Licenses   []*cycloneDXLicense  `json:"licenses,omitempty"`
+	Properties []*cycloneDXProperty `json:"properties,omitempty"`
+}
+
+// License is either an SPDX expression or a license with free text name
+type cycloneDXLicense struct {
+	Expression string                 `json:"expression,omitempty"`
+	License    *cycloneDXNamedLicense `json:"license,omitempty"`
+}
+
+type cycloneDXNamedLicense struct {
+	Name string `json:"name"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+func renderCycloneDX(repo *entity.RepoDTO, packages []*entity.Package) ([]byte, error) {
+
+	root := &cycloneDXComponent{
+		Type:    "application",
+		BOMRef:  "root",
+		Name:    fmt.Sprintf("%s/%s", repo.Owner, repo.Name),
+		Version: repo.CommitSHA,
+	}
+
+	bom := &cycloneDXBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  cycloneDXVersion,
+		SerialNumber: "urn:uuid:" + newDocumentID(),
+		Version:      1,
+		Components:   []*cycloneDXComponent{},
+	}
+	bom.Metadata.Timestamp = now().UTC().Format(time.RFC3339)
+	bom.Metadata.Tools.Components = []*cycloneDXComponent{{Type: "application", Name: "marvin"}}
+	bom.Metadata.Component = root
+
+	dependency := &cycloneDXDependency{Ref: root.BOMRef, DependsOn: []string{}}
+
+	for _, pkg := range packages {
+		version := exactVersion(pkg)
+
+		component := &cycloneDXComponent{
+			Type: "library",
+			// Same package can be in more than one package file
+			BOMRef:  manifestPath(pkg) + ":" + pkg.Name,
+			Name:    pkg.Name,
+			Version: version,
+			Purl:    parsers.Purl(pkg.File, pkg.Name, version),
+			Properties: []*cycloneDXProperty{
+				{Name: "marvin:manifest", Value: manifestPath(pkg)},
+				{Name: "marvin:constraint", Value: pkg.Version.Current},
+			},
+		}
+
+		// Group is npm scope or composer vendor
+		if i := strings.LastIndex(pkg.Name, "/"); i > 0 {
+			component.Group = pkg.Name[:i]
+			component.Name = pkg.Name[i+1:]
+		}
+
+		if pkg.IsOutdated {
+			component.Properties = append(component.Properties, &cycloneDXProperty{Name: "marvin:latest", Value: pkg.Version.Last})
+		}
+
+		if pkg.License != "" {
+			component.Licenses = []*cycloneDXLicense{cycloneDXLicenseOf(pkg.License)}
+		}
+
+		bom.Components = append(bom.Components, component)
+		dependency.DependsOn = append(dependency.DependsOn, component.BOMRef)
+	}
+
+	bom.Dependencies = []*cycloneDXDependency{dependency}
+
+	return json.MarshalIndent(bom, "", "  ")
+}
+
+// Licenses of lock files are mostly SPDX ids or expressions, other texts are used as license names
+func cycloneDXLicenseOf(license string) *cycloneDXLicense {
+	if isSPDXExpression(license) {
+		return &cycloneDXLicense{Expression: license}
+	}
+	return &cycloneDXLicense{License: &cycloneDXNamedLicense{Name: license}}
+}
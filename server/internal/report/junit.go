@@ -0,0 +1,110 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+// Every manifest is a test suite and every package is a test case
+type junitTestSuite struct {
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Cases    []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func renderJUnit(repo *entity.RepoDTO, packages []*entity.Package) ([]byte, error) {
+
+	suites := &junitTestSuites{Name: fmt.Sprintf("%s/%s", repo.Owner, repo.Name)}
+
+	var suite *junitTestSuite
+	for _, pkg := range packages {
+		// Packages are sorted by manifest path
+		if suite == nil || suite.Name != manifestPath(pkg) {
+			suite = &junitTestSuite{Name: manifestPath(pkg)}
+			suites.Suites = append(suites.Suites, suite)
+		}
+
+		testCase := &junitTestCase{
+			Name:      pkg.Name,
+			ClassName: manifestPath(pkg),
+		}
+
+		if testCase.Failure = newJUnitFailure(pkg); testCase.Failure != nil {
+			suite.Failures++
+			suites.Failures++
+		}
+
+		// Ignored packages are skipped, so reports show their reasons
+		if pkg.Ignored != nil {
+			testCase.Skipped = &junitSkipped{Message: ignoreReason(pkg)}
+		}
+
+		suite.Cases = append(suite.Cases, testCase)
+		suite.Tests++
+		suites.Tests++
+	}
+
+	body, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), body...), nil
+}
+
+// Package fails once, type of failure is the most severe problem and text lists all problems
+func newJUnitFailure(pkg *entity.Package) *junitFailure {
+
+	var failure *junitFailure
+	var problems []string
+
+	if pkg.IsOutdated {
+		failure = &junitFailure{Message: fmt.Sprintf("%s is outdated", pkg.Name), Type: outdatedRuleID}
+		problems = append(problems, fmt.Sprintf("current: %s, latest: %s", pkg.Version.Current, pkg.Version.Last))
+	}
+
+	if pkg.Deprecated != "" {
+		failure = &junitFailure{Message: fmt.Sprintf("%s is deprecated", pkg.Name), Type: deprecatedRuleID}
+		problems = append(problems, fmt.Sprintf("deprecated: %s", pkg.Deprecated))
+	}
+
+	for _, advisory := range pkg.Advisories {
+		problems = append(problems, fmt.Sprintf("%s (%s): %s %s", advisory.ID, advisory.Severity, advisory.Summary, advisory.Url))
+	}
+	if len(pkg.Advisories) > 0 {
+		failure = &junitFailure{Message: fmt.Sprintf("%s is affected by %d advisories", pkg.Name, len(pkg.Advisories)), Type: vulnerableRuleID}
+	}
+
+	if failure != nil {
+		failure.Text = strings.Join(problems, "\n")
+	}
+	return failure
+}
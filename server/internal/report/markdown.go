@@ -0,0 +1,39 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"strings"
+)
+
+var markdownEscaper = strings.NewReplacer("|", "\\|", "\n", " ")
+
+func renderMarkdown(repo *entity.RepoDTO, packages []*entity.Package) []byte {
+
+	buffer := new(bytes.Buffer)
+
+	fmt.Fprintf(buffer, "# %s/%s\n\n", repo.Owner, repo.Name)
+	if repo.CommitSHA != "" {
+		fmt.Fprintf(buffer, "Ref `%s` at commit `%s`", repo.Ref, repo.CommitSHA)
+		if repo.LastScan != nil {
+			fmt.Fprintf(buffer, ", scanned at %s", repo.LastScan.ScannedAt.Format("2006-01-02 15:04 MST"))
+		}
+		buffer.WriteString("\n\n")
+	}
+
+	buffer.WriteString("| Manifest | Package | Current | Latest | Status | Reason |\n")
+	buffer.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+	for _, pkg := range packages {
+		fmt.Fprintf(buffer, "| %s | %s | %s | %s | %s | %s |\n",
+			markdownEscaper.Replace(manifestPath(pkg)),
+			markdownEscaper.Replace(pkg.Name),
+			markdownEscaper.Replace(pkg.Version.Current),
+			markdownEscaper.Replace(pkg.Version.Last),
+			status(pkg),
+			markdownEscaper.Replace(ignoreReason(pkg)),
+		)
+	}
+
+	return buffer.Bytes()
+}
@@ -0,0 +1,41 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/nozgurozturk/marvin/server/entity"
+)
+
+// Plain text result of CI gate for build logs
+func RenderPolicy(result *entity.PolicyResult) []byte {
+
+	buffer := new(bytes.Buffer)
+
+	verdict := "PASSED"
+	if !result.Passed {
+		verdict = "FAILED"
+	}
+
+	fmt.Fprintf(buffer, "%s packages=%d outdatedMajors=%d deprecated=%d vulnerabilities=%d libyears=%.2f\n",
+		verdict,
+		result.Summary.Packages,
+		result.Summary.OutdatedMajors,
+		result.Summary.Deprecated,
+		result.Summary.Vulnerabilities,
+		result.Summary.Libyears,
+	)
+
+	for _, violation := range result.Violations {
+		if violation.Package == "" {
+			fmt.Fprintf(buffer, "%s: %s\n", violation.Check, violation.Message)
+			continue
+		}
+		fmt.Fprintf(buffer, "%s: %s %s: %s\n", violation.Check, violation.Path, violation.Package, violation.Message)
+	}
+
+	for _, failure := range result.Failures {
+		fmt.Fprintf(buffer, "unresolved: %s %s: %s\n", failure.Path, failure.Name, failure.Message)
+	}
+
+	return buffer.Bytes()
+}
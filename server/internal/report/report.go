@@ -0,0 +1,167 @@
+package report
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"mime"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Formats of exported reports
+const (
+	CSV      = "csv"
+	Markdown = "markdown"
+	SARIF    = "sarif"
+	JUnit    = "junit"
+	// SBOM formats
+	CycloneDX = "cyclonedx"
+	SPDX      = "spdx"
+)
+
+// Tests replace them, so SBOM documents of same scan are equal
+var (
+	newDocumentID = func() string { return uuid.New().String() }
+	now           = time.Now
+)
+
+var exactVersionPattern = regexp.MustCompile(`^v?\d+\.\d+\.\d+([-+][0-9A-Za-z.+-]+)?$`)
+
+var contentTypes = map[string]string{
+	CSV:       "text/csv",
+	Markdown:  "text/markdown",
+	SARIF:     "application/sarif+json",
+	JUnit:     "application/xml",
+	CycloneDX: "application/vnd.cyclonedx+json",
+	SPDX:      "application/spdx+json",
+}
+
+var extensions = map[string]string{
+	CSV:       "csv",
+	Markdown:  "md",
+	SARIF:     "sarif",
+	JUnit:     "xml",
+	CycloneDX: "cdx.json",
+	SPDX:      "spdx.json",
+}
+
+// Other media types that clients send for formats
+var mediaTypes = map[string]string{
+	"text/csv":                       CSV,
+	"text/markdown":                  Markdown,
+	"text/x-markdown":                Markdown,
+	"application/sarif+json":         SARIF,
+	"application/xml":                JUnit,
+	"text/xml":                       JUnit,
+	"application/junit+xml":          JUnit,
+	"application/vnd.cyclonedx+json": CycloneDX,
+	"application/spdx+json":          SPDX,
+}
+
+func IsFormat(format string) bool {
+	_, ok := contentTypes[format]
+	return ok
+}
+
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// Name of downloaded report, for exp. marvin-dependencies.sarif
+func FileName(format string, repo *entity.RepoDTO) string {
+	return fmt.Sprintf("%s-dependencies.%s", repo.Name, extensions[format])
+}
+
+// Finds first format in accept header, empty string is returned if any format is not accepted
+func FormatFromAccept(accept string) string {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if format, ok := mediaTypes[mediaType]; ok {
+			return format
+		}
+	}
+	return ""
+}
+
+// Renders packages of latest scan of repository
+func Render(format string, repo *entity.RepoDTO) ([]byte, error) {
+
+	packages := sortedPackages(repo.PackageList)
+
+	switch format {
+	case CSV:
+		return renderCSV(packages)
+	case Markdown:
+		return renderMarkdown(repo, packages), nil
+	case SARIF:
+		return renderSARIF(repo, packages)
+	case JUnit:
+		return renderJUnit(repo, packages)
+	case CycloneDX:
+		return renderCycloneDX(repo, packages)
+	case SPDX:
+		return renderSPDX(repo, packages)
+	default:
+		return nil, fmt.Errorf("unknown report format: %s", format)
+	}
+}
+
+// Packages are sorted by manifest path and name, so reports of same scan are equal
+func sortedPackages(packages []*entity.Package) []*entity.Package {
+	sorted := make([]*entity.Package, len(packages))
+	copy(sorted, packages)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if manifestPath(sorted[i]) != manifestPath(sorted[j]) {
+			return manifestPath(sorted[i]) < manifestPath(sorted[j])
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+// Packages that are scanned before path tracking only have file name
+func manifestPath(pkg *entity.Package) string {
+	if pkg.Path == "" {
+		return pkg.File
+	}
+	return pkg.Path
+}
+
+// Versions of package urls, lock file version is used if it is known
+// Version constraints are not valid versions, for exp. ~1.2 or >=2.0, so they are omitted
+func exactVersion(pkg *entity.Package) string {
+	if pkg.Version.Locked != "" {
+		return pkg.Version.Locked
+	}
+	if exactVersionPattern.MatchString(pkg.Version.Current) {
+		return pkg.Version.Current
+	}
+	return ""
+}
+
+func status(pkg *entity.Package) string {
+	if pkg.IsOutdated {
+		return "outdated"
+	}
+	if pkg.Ignored != nil {
+		return "ignored"
+	}
+	return "up to date"
+}
+
+// Reason of ignore rule, empty if package is not ignored
+func ignoreReason(pkg *entity.Package) string {
+	if pkg.Ignored == nil {
+		return ""
+	}
+	if pkg.Ignored.Reason == "" {
+		return "ignored"
+	}
+	return pkg.Ignored.Reason
+}
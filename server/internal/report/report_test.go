@@ -0,0 +1,101 @@
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"reflect"
+	"testing"
+)
+
+func riskyPackages() []*entity.Package {
+	return []*entity.Package{
+		{
+			Name:       "request",
+			Version:    entity.PackageVersion{Current: "2.88.0", Last: "2.88.2"},
+			File:       "package.json",
+			IsOutdated: true,
+			Deprecated: "request has been deprecated",
+		},
+		{
+			Name:    "lodash",
+			Version: entity.PackageVersion{Current: "4.17.15", Last: "4.17.15"},
+			File:    "package.json",
+			Advisories: []*entity.Advisory{
+				{ID: "GHSA-p6mc-m468-83gw", Severity: "high", Summary: "Prototype Pollution"},
+				{ID: "GHSA-29mw-wpgm-hmr9", Severity: "moderate", Summary: "ReDoS"},
+			},
+		},
+		{
+			Name:    "react",
+			Version: entity.PackageVersion{Current: "17.0.1", Last: "17.0.1"},
+			File:    "package.json",
+		},
+	}
+}
+
+func TestRenderSARIF(t *testing.T) {
+
+	body, err := renderSARIF(&entity.RepoDTO{Owner: "owner", Name: "name"}, riskyPackages())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(body, &log); err != nil {
+		t.Fatal(err)
+	}
+
+	var rules []string
+	for _, rule := range log.Runs[0].Tool.Driver.Rules {
+		rules = append(rules, rule.ID+":"+rule.DefaultLevel.Level)
+	}
+	expectedRules := []string{"outdated-package:warning", "deprecated-package:warning", "vulnerable-package:error"}
+	if !reflect.DeepEqual(rules, expectedRules) {
+		t.Errorf("expected rules %v, got %v", expectedRules, rules)
+	}
+
+	var results []string
+	fingerprints := map[string]bool{}
+	for _, result := range log.Runs[0].Results {
+		results = append(results, result.RuleID+":"+result.Level)
+		fingerprints[result.PartialFingerprints["packageName/v1"]] = true
+	}
+	// Levels of vulnerable results come from severities of advisories
+	expectedResults := []string{"outdated-package:warning", "deprecated-package:warning", "vulnerable-package:error", "vulnerable-package:warning"}
+	if !reflect.DeepEqual(results, expectedResults) {
+		t.Errorf("expected results %v, got %v", expectedResults, results)
+	}
+	if len(fingerprints) != len(results) {
+		t.Errorf("expected unique fingerprint of every result, got %v", fingerprints)
+	}
+}
+
+func TestRenderJUnit(t *testing.T) {
+
+	body, err := renderJUnit(&entity.RepoDTO{Owner: "owner", Name: "name"}, riskyPackages())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var suites junitTestSuites
+	if err := xml.Unmarshal(body, &suites); err != nil {
+		t.Fatal(err)
+	}
+
+	if suites.Tests != 3 || suites.Failures != 2 {
+		t.Fatalf("expected 3 tests and 2 failures, got %d tests and %d failures", suites.Tests, suites.Failures)
+	}
+
+	// Most severe problem of package is type of failure
+	expected := map[string]string{"request": "deprecated-package", "lodash": "vulnerable-package"}
+	for _, testCase := range suites.Suites[0].Cases {
+		failureType := ""
+		if testCase.Failure != nil {
+			failureType = testCase.Failure.Type
+		}
+		if failureType != expected[testCase.Name] {
+			t.Errorf("expected failure %q of %s, got %q", expected[testCase.Name], testCase.Name, failureType)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/nozgurozturk/marvin/pkg/advisories"
+	"github.com/nozgurozturk/marvin/server/entity"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// Rules of scan findings
+const (
+	outdatedRuleID   = "outdated-package"
+	deprecatedRuleID = "deprecated-package"
+	vulnerableRuleID = "vulnerable-package"
+)
+
+var sarifRules = []struct {
+	id          string
+	description string
+	level       string
+}{
+	{outdatedRuleID, "Package version is behind latest registry version", "warning"},
+	{deprecatedRuleID, "Package version is deprecated in registry", "warning"},
+	{vulnerableRuleID, "Package version is affected by security advisory", "error"},
+}
+
+// Levels of advisory severities, GitHub code scanning shows errors as high alerts
+var sarifLevels = map[string]string{
+	advisories.Low:      "note",
+	advisories.Moderate: "warning",
+	advisories.High:     "error",
+	advisories.Critical: "error",
+}
+
+type sarifLog struct {
+	Version string      `json:"version"`
+	Schema  string      `json:"$schema"`
+	Runs    []*sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool                     sarifTool              `json:"tool"`
+	VersionControlProvenance []*sarifVersionControl `json:"versionControlProvenance,omitempty"`
+	Results                  []*sarifResult         `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string       `json:"name"`
+	Rules []*sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	DefaultLevel     struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type sarifVersionControl struct {
+	RepositoryUri string `json:"repositoryUri"`
+	RevisionID    string `json:"revisionId,omitempty"`
+	Branch        string `json:"branch,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID    string           `json:"ruleId"`
+	Level     string           `json:"level"`
+	Message   sarifMessage     `json:"message"`
+	Locations []*sarifLocation `json:"locations"`
+	// Results of same package are matched between scans
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			Uri string `json:"uri"`
+		} `json:"artifactLocation"`
+	} `json:"physicalLocation"`
+}
+
+func renderSARIF(repo *entity.RepoDTO, packages []*entity.Package) ([]byte, error) {
+
+	var rules []*sarifRule
+	for _, r := range sarifRules {
+		rule := &sarifRule{
+			ID:               r.id,
+			ShortDescription: sarifMessage{Text: r.description},
+		}
+		rule.DefaultLevel.Level = r.level
+		rules = append(rules, rule)
+	}
+
+	run := &sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:  "marvin",
+			Rules: rules,
+		}},
+		Results: []*sarifResult{},
+	}
+
+	// Path of repository is its url
+	if repo.Path != "" {
+		run.VersionControlProvenance = []*sarifVersionControl{{
+			RepositoryUri: repo.Path,
+			RevisionID:    repo.CommitSHA,
+			Branch:        repo.Ref,
+		}}
+	}
+
+	for _, pkg := range packages {
+		if pkg.IsOutdated {
+			run.Results = append(run.Results, newSarifResult(pkg, outdatedRuleID, "warning", "",
+				fmt.Sprintf("%s %s is outdated, latest version is %s", pkg.Name, pkg.Version.Current, pkg.Version.Last)))
+		}
+
+		if pkg.Deprecated != "" {
+			run.Results = append(run.Results, newSarifResult(pkg, deprecatedRuleID, "warning", "",
+				fmt.Sprintf("%s %s is deprecated: %s", pkg.Name, pkg.Version.Current, pkg.Deprecated)))
+		}
+
+		for _, advisory := range pkg.Advisories {
+			run.Results = append(run.Results, newSarifResult(pkg, vulnerableRuleID, sarifLevel(advisory.Severity), advisory.ID,
+				fmt.Sprintf("%s %s is affected by %s severity advisory %s: %s %s", pkg.Name, pkg.Version.Current, advisory.Severity, advisory.ID, advisory.Summary, advisory.Url)))
+		}
+	}
+
+	return json.MarshalIndent(&sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []*sarifRun{run},
+	}, "", "  ")
+}
+
+// Results of same package and rule are matched between scans, advisories of package are matched with their ids
+func newSarifResult(pkg *entity.Package, ruleID string, level string, advisoryID string, message string) *sarifResult {
+
+	location := new(sarifLocation)
+	location.PhysicalLocation.ArtifactLocation.Uri = manifestPath(pkg)
+
+	fingerprint := manifestPath(pkg) + ":" + pkg.Name
+	if ruleID != outdatedRuleID {
+		fingerprint += ":" + ruleID
+	}
+	if advisoryID != "" {
+		fingerprint += ":" + advisoryID
+	}
+
+	return &sarifResult{
+		RuleID:    ruleID,
+		Level:     level,
+		Message:   sarifMessage{Text: message},
+		Locations: []*sarifLocation{location},
+		PartialFingerprints: map[string]string{
+			"packageName/v1": fingerprint,
+		},
+	}
+}
+
+// Advisories with unknown severities are errors
+func sarifLevel(severity string) string {
+	if level, ok := sarifLevels[severity]; ok {
+		return level
+	}
+	return "error"
+}
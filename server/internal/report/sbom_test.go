@@ -0,0 +1,142 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"github.com/nozgurozturk/marvin/pkg/parsers"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update golden files of reports")
+
+// Serial numbers, namespaces and timestamps of documents are fixed until test is finished
+func setDocumentSeams(t *testing.T) {
+	previousID, previousNow := newDocumentID, now
+	newDocumentID = func() string { return "00000000-0000-0000-0000-000000000000" }
+	now = func() time.Time { return time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC) }
+	t.Cleanup(func() {
+		newDocumentID = previousID
+		now = previousNow
+	})
+}
+
+// Scoped and vendor names, lock file and constraint versions, outdated packages and every kind of license
+func sbomRepo() *entity.RepoDTO {
+	return &entity.RepoDTO{
+		Owner:     "owner",
+		Name:      "name",
+		Path:      "https://github.com/owner/name",
+		CommitSHA: "0123456789abcdef",
+		PackageList: []*entity.Package{
+			{
+				Name:    "symfony/console",
+				Version: entity.PackageVersion{Current: "v5.2.1", Last: "v5.2.1"},
+				File:    "composer.json",
+				Path:    "composer.json",
+				License: "MIT",
+			},
+			{
+				Name:       "react",
+				Version:    entity.PackageVersion{Current: "17.0.1", Last: "18.2.0"},
+				File:       "package.json",
+				Path:       "web/package.json",
+				IsOutdated: true,
+				License:    "SEE LICENSE IN LICENSE",
+			},
+			{
+				Name:    "@babel/core",
+				Version: entity.PackageVersion{Current: "^7.12.0", Last: "7.12.3", Locked: "7.12.3"},
+				File:    "package.json",
+				Path:    "package.json",
+				License: "MIT",
+			},
+			// Scanned before path tracking
+			{
+				Name:    "lodash",
+				Version: entity.PackageVersion{Current: "4.17.21", Last: "4.17.21"},
+				File:    "package.json",
+				License: "(MIT OR Apache-2.0)",
+			},
+			{
+				Name:       "monolog/monolog",
+				Version:    entity.PackageVersion{Current: "^2.1", Last: "2.2.0"},
+				File:       "composer.json",
+				Path:       "api/composer.json",
+				IsOutdated: true,
+			},
+		},
+	}
+}
+
+func TestRenderSBOM(t *testing.T) {
+
+	setDocumentSeams(t)
+
+	for _, format := range []string{CycloneDX, SPDX} {
+		format := format
+		t.Run(format, func(t *testing.T) {
+			body, err := Render(format, sbomRepo())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", FileName(format, sbomRepo()))
+			if *update {
+				if err := ioutil.WriteFile(golden, body, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(body, expected) {
+				t.Errorf("document is not equal to %s, run tests with -update if change is expected\n%s", golden, body)
+			}
+		})
+	}
+}
+
+// Exported documents are imported by SBOM scans, so same packages must be parsed back
+func TestParseRenderedSBOM(t *testing.T) {
+
+	expectedPackages := map[string]map[string]string{
+		"package.json":  {"@babel/core": "7.12.3", "lodash": "4.17.21", "react": "17.0.1"},
+		"composer.json": {"symfony/console": "v5.2.1"},
+	}
+	// Constraint is not a version, so package can not be compared
+	expectedUnsupported := []string{"pkg:composer/monolog/monolog"}
+
+	for _, format := range []string{CycloneDX, SPDX} {
+		format := format
+		t.Run(format, func(t *testing.T) {
+			body, err := Render(format, sbomRepo())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var file map[string]interface{}
+			if err := json.Unmarshal(body, &file); err != nil {
+				t.Fatal(err)
+			}
+
+			packages, unsupported, err := parsers.ParseSBOM(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(packages, expectedPackages) {
+				t.Errorf("expected packages %v, got %v", expectedPackages, packages)
+			}
+			if !reflect.DeepEqual(unsupported, expectedUnsupported) {
+				t.Errorf("expected unsupported %v, got %v", expectedUnsupported, unsupported)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/nozgurozturk/marvin/pkg/parsers"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"regexp"
+	"time"
+)
+
+const (
+	spdxVersion = "SPDX-2.3"
+	// Value of unknown fields
+	noAssertion = "NOASSERTION"
+)
+
+// License ids joined with AND, OR and WITH, for exp. (MIT OR Apache-2.0)
+var spdxExpressionPattern = regexp.MustCompile(`^\(?[A-Za-z0-9.+-]+( (AND|OR|WITH) \(?[A-Za-z0-9.+-]+\)?)*\)?$`)
+
+type spdxDocument struct {
+	SPDXVersion       string              `json:"spdxVersion"`
+	DataLicense       string              `json:"dataLicense"`
+	SPDXID            string              `json:"SPDXID"`
+	Name              string              `json:"name"`
+	DocumentNamespace string              `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo    `json:"creationInfo"`
+	Packages          []*spdxPackage      `json:"packages"`
+	Relationships     []*spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID           string             `json:"SPDXID"`
+	Name             string             `json:"name"`
+	VersionInfo      string             `json:"versionInfo,omitempty"`
+	DownloadLocation string             `json:"downloadLocation"`
+	FilesAnalyzed    bool               `json:"filesAnalyzed"`
+	LicenseConcluded string             `json:"licenseConcluded"`
+	LicenseDeclared  string             `json:"licenseDeclared"`
+	SourceInfo       string             `json:"sourceInfo,omitempty"`
+	ExternalRefs     []*spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+func renderSPDX(repo *entity.RepoDTO, packages []*entity.Package) ([]byte, error) {
+
+	name := fmt.Sprintf("%s/%s", repo.Owner, repo.Name)
+
+	root := &spdxPackage{
+		SPDXID:           "SPDXRef-Repository",
+		Name:             name,
+		VersionInfo:      repo.CommitSHA,
+		DownloadLocation: noAssertion,
+		LicenseConcluded: noAssertion,
+		LicenseDeclared:  noAssertion,
+	}
+	if repo.Path != "" {
+		root.DownloadLocation = repo.Path
+	}
+
+	document := &spdxDocument{
+		SPDXVersion:       spdxVersion,
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              name,
+		DocumentNamespace: fmt.Sprintf("https://spdx.org/spdxdocs/marvin-%s-%s", repo.Name, newDocumentID()),
+		CreationInfo: spdxCreationInfo{
+			Created:  now().UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: marvin"},
+		},
+		Packages: []*spdxPackage{root},
+		Relationships: []*spdxRelationship{{
+			SPDXElementID:      "SPDXRef-DOCUMENT",
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: root.SPDXID,
+		}},
+	}
+
+	for i, pkg := range packages {
+		version := exactVersion(pkg)
+
+		item := &spdxPackage{
+			// Ids can only contain letters, numbers, dots and dashes
+			SPDXID:           fmt.Sprintf("SPDXRef-Package-%d", i+1),
+			Name:             pkg.Name,
+			VersionInfo:      version,
+			DownloadLocation: noAssertion,
+			LicenseConcluded: noAssertion,
+			LicenseDeclared:  noAssertion,
+			SourceInfo:       fmt.Sprintf("%s %s in %s", pkg.Name, pkg.Version.Current, manifestPath(pkg)),
+		}
+
+		if isSPDXExpression(pkg.License) {
+			item.LicenseDeclared = pkg.License
+		}
+
+		if purl := parsers.Purl(pkg.File, pkg.Name, version); purl != "" {
+			item.ExternalRefs = []*spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  purl,
+			}}
+		}
+
+		document.Packages = append(document.Packages, item)
+		document.Relationships = append(document.Relationships, &spdxRelationship{
+			SPDXElementID:      root.SPDXID,
+			RelationshipType:   "DEPENDS_ON",
+			RelatedSPDXElement: item.SPDXID,
+		})
+	}
+
+	return json.MarshalIndent(document, "", "  ")
+}
+
+func isSPDXExpression(license string) bool {
+	return spdxExpressionPattern.MatchString(license)
+}
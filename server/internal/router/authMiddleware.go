@@ -4,15 +4,43 @@ import (
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gofiber/fiber/v2"
 	"github.com/nozgurozturk/marvin/pkg/errors"
+	"github.com/nozgurozturk/marvin/server/entity"
 	"github.com/nozgurozturk/marvin/server/internal/app"
 	"github.com/nozgurozturk/marvin/server/internal/service"
+	"net/http"
+	"strings"
 )
 
-func AuthMiddleware(authService service.AuthService) fiber.Handler {
+// POST endpoints that only read packages, read scope is enough for them
+// Method is part of key, so other methods of same path still need write scope
+var readOnlyRoutes = map[string]bool{
+	http.MethodPost + " /api/repository/policy": true,
+	http.MethodPost + " /api/repository/sbom":   true,
+}
+
+func AuthMiddleware(authService service.AuthService, apiTokenService service.APITokenService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 
 		tokenString, err := app.ExtractToken(c)
 
+		// Personal API tokens are accepted alongside access tokens
+		if strings.HasPrefix(tokenString, entity.APITokenPrefix) {
+			apiToken, err := apiTokenService.Authenticate(tokenString)
+			if err != nil {
+				return c.Status(err.Status).JSON(err)
+			}
+
+			readOnly := readOnlyRoutes[c.Method()+" "+strings.TrimSuffix(c.Path(), "/")] && apiToken.HasScope(entity.ScopeRead)
+			if !readOnly && !apiToken.Allows(c.Method()) {
+				scopeErr := errors.Forbidden("API token does not have write scope")
+				return c.Status(scopeErr.Status).JSON(scopeErr)
+			}
+
+			c.Locals("user", apiToken.UserID.Hex())
+			c.Locals("apiToken", apiToken.ID.Hex())
+			return c.Next()
+		}
+
 		token, err := app.ValidateToken(tokenString, "Access")
 		if err != nil {
 			if token != nil {
@@ -45,8 +73,36 @@ func AuthMiddleware(authService service.AuthService) fiber.Handler {
 			notAuthErr := errors.Unauthorized("Email confirmation is required")
 			return c.Status(notAuthErr.Status).JSON(notAuthErr)
 		}
+		// Tokens that are created before sessions do not have session id
+		sessionID, _ := claims["session"].(string)
+		if sessionID != "" {
+			// Activity is only shown in session list, request does not fail without it
+			_ = authService.TouchSession(sessionID, c.IP())
+			c.Locals("session", sessionID)
+		}
+
 		// Pass user id to handlers
 		c.Locals("user", userID)
 		return c.Next()
 	}
 }
+
+// AdminMiddleware must be used after AuthMiddleware
+func AdminMiddleware(userService service.UserService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+
+		userID, _ := c.Locals("user").(string)
+
+		user, err := userService.FindByID(userID)
+		if err != nil {
+			return c.Status(err.Status).JSON(err)
+		}
+
+		if !user.IsAdmin {
+			err = errors.Forbidden("You don't have access")
+			return c.Status(err.Status).JSON(err)
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,213 @@
+package router
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"github.com/nozgurozturk/marvin/server/internal/api"
+	"github.com/nozgurozturk/marvin/server/internal/config"
+	"github.com/nozgurozturk/marvin/server/internal/service"
+	"github.com/nozgurozturk/marvin/server/internal/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+const (
+	readToken  = "mvn_read-token"
+	writeToken = "mvn_write-token"
+)
+
+// Token store that keeps tokens by hash, lookups are recorded
+type fakeAPITokenRepository struct {
+	storage.APITokenRepository
+	tokens  map[string]*entity.APIToken
+	lookups []string
+}
+
+func (r *fakeAPITokenRepository) FindByHash(hash string) (*entity.APIToken, error) {
+	r.lookups = append(r.lookups, hash)
+	return r.tokens[hash], nil
+}
+
+func (r *fakeAPITokenRepository) UpdateLastUsed(tokenID primitive.ObjectID, usedAt time.Time) error {
+	return nil
+}
+
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Loads config that access tokens are verified with
+func setTestConfig(t *testing.T) {
+	t.Helper()
+
+	env := map[string]string{
+		"HOST":           "localhost",
+		"PORT":           "8080",
+		"ACCESS_SECRET":  "access-secret",
+		"REFRESH_SECRET": "refresh-secret",
+		"ACCESS_EXPIRE":  "60",
+		"REFRESH_EXPIRE": "720",
+		"SUB_EXPIRE":     "24",
+	}
+	for key, value := range env {
+		key := key
+		previous, exist := os.LookupEnv(key)
+		if err := os.Setenv(key, value); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			if exist {
+				os.Setenv(key, previous)
+			} else {
+				os.Unsetenv(key)
+			}
+		})
+	}
+
+	config.Set()
+}
+
+type middlewareTest struct {
+	app    *fiber.App
+	tokens *fakeAPITokenRepository
+	userID primitive.ObjectID
+}
+
+// Serves repository routes that respond with user of request, and routes that can not be used with API tokens
+func newMiddlewareTest(t *testing.T) *middlewareTest {
+
+	setTestConfig(t)
+
+	userID := primitive.NewObjectID()
+	tokens := &fakeAPITokenRepository{tokens: map[string]*entity.APIToken{
+		tokenHash(readToken):  {ID: primitive.NewObjectID(), UserID: userID, Scopes: []entity.APITokenScope{entity.ScopeRead}},
+		tokenHash(writeToken): {ID: primitive.NewObjectID(), UserID: userID, Scopes: []entity.APITokenScope{entity.ScopeRead, entity.ScopeWrite}},
+	}}
+	authService := service.NewAuthService(nil)
+	apiTokenService := service.NewAPITokenService(tokens)
+
+	app := fiber.New()
+	apiRouter := app.Group("/api", AuthMiddleware(authService, apiTokenService))
+
+	respondUser := func(c *fiber.Ctx) error {
+		user, _ := c.Locals("user").(string)
+		return c.SendString(user)
+	}
+	repoRouter := apiRouter.Group("/repository")
+	repoRouter.Get("/", respondUser)
+	repoRouter.Post("/", respondUser)
+	repoRouter.Delete("/:id", respondUser)
+	repoRouter.Post("/policy", respondUser)
+	repoRouter.Put("/policy", respondUser)
+	repoRouter.Post("/sbom", respondUser)
+
+	// Handlers reject API tokens before services are used
+	api.SessionHandler(apiRouter.Group("/session"), authService)
+	api.APITokenHandler(apiRouter.Group("/token"), apiTokenService)
+	api.UserHandler(apiRouter.Group("/user"), authService, nil, nil, nil, apiTokenService, nil)
+
+	return &middlewareTest{app: app, tokens: tokens, userID: userID}
+}
+
+func (m *middlewareTest) request(t *testing.T, method string, path string, token string) (int, string) {
+	request := httptest.NewRequest(method, path, nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	response, err := m.app.Test(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(response.Body)
+	return response.StatusCode, string(body)
+}
+
+func TestAuthMiddlewareScopesOfAPITokens(t *testing.T) {
+
+	m := newMiddlewareTest(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+	}{
+		{"read token reads", http.MethodGet, "/api/repository", readToken, http.StatusOK},
+		{"read token creates", http.MethodPost, "/api/repository", readToken, http.StatusForbidden},
+		{"read token deletes", http.MethodDelete, "/api/repository/1", readToken, http.StatusForbidden},
+		{"read token checks policy", http.MethodPost, "/api/repository/policy", readToken, http.StatusOK},
+		{"read token checks policy with trailing slash", http.MethodPost, "/api/repository/policy/", readToken, http.StatusOK},
+		{"read token scans sbom", http.MethodPost, "/api/repository/sbom", readToken, http.StatusOK},
+		{"read token changes read only path", http.MethodPut, "/api/repository/policy", readToken, http.StatusForbidden},
+		{"write token creates", http.MethodPost, "/api/repository", writeToken, http.StatusOK},
+		{"write token deletes", http.MethodDelete, "/api/repository/1", writeToken, http.StatusOK},
+		{"unknown token", http.MethodGet, "/api/repository", "mvn_unknown", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := m.request(t, tt.method, tt.path, tt.token)
+			if status != tt.status {
+				t.Fatalf("expected %d, got %d: %s", tt.status, status, body)
+			}
+			if status == http.StatusOK && body != m.userID.Hex() {
+				t.Errorf("expected user of token to be passed to handler, got %q", body)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareFindsAPITokensByHash(t *testing.T) {
+
+	m := newMiddlewareTest(t)
+
+	if status, _ := m.request(t, http.MethodGet, "/api/repository", readToken); status != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, status)
+	}
+	if len(m.tokens.lookups) != 1 || m.tokens.lookups[0] != tokenHash(readToken) {
+		t.Errorf("expected token to be looked up by its hash, got %v", m.tokens.lookups)
+	}
+
+	// Tokens without prefix are access tokens
+	if status, _ := m.request(t, http.MethodGet, "/api/repository", "read-token"); status != http.StatusUnauthorized {
+		t.Errorf("expected %d, got %d", http.StatusUnauthorized, status)
+	}
+	if len(m.tokens.lookups) != 1 {
+		t.Errorf("expected access token not to be looked up in API tokens, got %v", m.tokens.lookups)
+	}
+}
+
+func TestAPITokensCanNotManageAccount(t *testing.T) {
+
+	m := newMiddlewareTest(t)
+
+	routes := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/api/session"},
+		{http.MethodDelete, "/api/session"},
+		{http.MethodDelete, "/api/session/1"},
+		{http.MethodGet, "/api/token"},
+		{http.MethodPost, "/api/token"},
+		{http.MethodDelete, "/api/token/1"},
+		{http.MethodPut, "/api/user"},
+		{http.MethodPut, "/api/user/email"},
+		{http.MethodDelete, "/api/user"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			if status, body := m.request(t, route.method, route.path, writeToken); status != http.StatusForbidden {
+				t.Errorf("expected %d, got %d: %s", http.StatusForbidden, status, body)
+			}
+		})
+	}
+}
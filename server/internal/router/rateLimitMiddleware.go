@@ -0,0 +1,35 @@
+package router
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"github.com/nozgurozturk/marvin/server/internal/service"
+	"net/http"
+	"strconv"
+)
+
+// RateLimitMiddleware counts requests per user after AuthMiddleware, otherwise per IP
+// It is registered before handler of route, c.Next continues with handler
+func RateLimitMiddleware(rateLimitService service.RateLimitService, limit entity.RateLimit) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+
+		key := "ip:" + c.IP()
+		if userID, ok := c.Locals("user").(string); ok && userID != "" {
+			key = "user:" + userID
+		}
+
+		usage, err := rateLimitService.Allow(limit, key)
+		if usage != nil {
+			c.Set("X-RateLimit-Limit", strconv.FormatInt(limit.Max, 10))
+			c.Set("X-RateLimit-Remaining", strconv.FormatInt(usage.Remaining(limit), 10))
+		}
+		if err != nil {
+			if err.Status == http.StatusTooManyRequests {
+				c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(usage.ResetIn.Seconds()+0.5)))
+			}
+			return c.Status(err.Status).JSON(err)
+		}
+
+		return c.Next()
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/gofiber/template/html"
 	"github.com/nozgurozturk/marvin/pkg/errors"
 	"github.com/nozgurozturk/marvin/server/internal/api"
+	"github.com/nozgurozturk/marvin/server/internal/config"
 	"github.com/nozgurozturk/marvin/server/internal/service"
 	"github.com/nozgurozturk/marvin/server/internal/storage"
 )
@@ -21,6 +22,8 @@ func New(s storage.Store) *Server {
 	engine := html.New("./web", ".html")
 	app := fiber.New(fiber.Config{
 		Views: engine,
+		// Client IP of rate limits is read from header behind load balancers
+		ProxyHeader: config.Get().HTTP.ProxyHeader,
 	})
 	//app.Use(cors.New(cors.Config{
 	//	AllowCredentials: true,
@@ -38,23 +41,53 @@ func New(s storage.Store) *Server {
 
 func (s *Server) initializeRouters() {
 
+	limits := s.Service.RateLimit()
+
 	authRouter := s.Router.Group("/auth")
-	api.AuthHandler(authRouter, s.Service.Auth(), s.Service.User(), s.Service.Subscriber())
+	// Limits are registered before handlers of same routes
+	authRouter.Post("/login", RateLimitMiddleware(limits, service.AuthLimit))
+	authRouter.Post("/refresh", RateLimitMiddleware(limits, service.AuthLimit))
+	authRouter.Post("/password/reset", RateLimitMiddleware(limits, service.AuthLimit))
+	authRouter.Post("/signup", RateLimitMiddleware(limits, service.SignUpLimit))
+	authRouter.Post("/confirm/resend", RateLimitMiddleware(limits, service.EmailLimit))
+	authRouter.Post("/password/forgot", RateLimitMiddleware(limits, service.EmailLimit))
+	api.AuthHandler(authRouter, s.Service.Auth(), s.Service.User(), s.Service.Subscriber(), limits)
 
-	apiRouter := s.Router.Group("/api", AuthMiddleware(s.Service.Auth()))
+	apiRouter := s.Router.Group("/api", AuthMiddleware(s.Service.Auth(), s.Service.APIToken()))
 
 	userRouter := apiRouter.Group("/user")
-	api.UserHandler(userRouter, s.Service.User(), s.Service.Repo())
+	userRouter.Put("/email", RateLimitMiddleware(limits, service.EmailLimit))
+	api.UserHandler(userRouter, s.Service.Auth(), s.Service.User(), s.Service.Repo(), s.Service.Organization(), s.Service.APIToken(), limits)
 
 	repoRouter := apiRouter.Group("/repository")
-	api.RepositoryHandler(repoRouter, s.Service.Repo(), s.Service.Subscriber())
+	api.RepositoryHandler(repoRouter, s.Service.Repo(), s.Service.Subscriber(), s.Service.Organization())
+
+	sessionRouter := apiRouter.Group("/session")
+	api.SessionHandler(sessionRouter, s.Service.Auth())
+
+	tokenRouter := apiRouter.Group("/token")
+	api.APITokenHandler(tokenRouter, s.Service.APIToken())
+
+	orgRouter := apiRouter.Group("/organization")
+	api.OrganizationHandler(orgRouter, s.Service.Organization())
+
+	adminRouter := apiRouter.Group("/admin", AdminMiddleware(s.Service.User()))
+	api.AdminHandler(adminRouter)
 
 	subscriberRouter := apiRouter.Group("/subscriber")
-	api.SubscriberHandler(subscriberRouter, s.Service.Subscriber(), s.Service.Repo())
+	subscriberRouter.Post("/", RateLimitMiddleware(limits, service.EmailLimit))
+	subscriberRouter.Post("/send", RateLimitMiddleware(limits, service.EmailLimit))
+	api.SubscriberHandler(subscriberRouter, s.Service.Subscriber(), s.Service.Repo(), limits)
 
 	publicSubscriberRouter := s.Router.Group("/subscriber")
 	api.PublicSubscriberHandler(publicSubscriberRouter, s.Service.Subscriber(), s.Service.Repo())
 
+	webhookRouter := s.Router.Group("/webhook")
+	api.WebhookHandler(webhookRouter, s.Service.Repo())
+
+	badgeRouter := s.Router.Group("/badge")
+	api.BadgeHandler(badgeRouter, s.Service.Repo())
+
 	// Documentation
 	s.Router.Get("/docs/*", swagger.Handler)
 
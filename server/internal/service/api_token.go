@@ -0,0 +1,164 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/nozgurozturk/marvin/pkg/errors"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"github.com/nozgurozturk/marvin/server/internal/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"strings"
+	"time"
+)
+
+type APITokenService interface {
+	// Create creates new token, token itself is only returned here
+	Create(userID string, request *entity.APITokenRequest) (*entity.APITokenDTO, *errors.AppError)
+	// FindAll returns tokens of user without token values
+	FindAll(userID string) ([]*entity.APITokenDTO, *errors.AppError)
+	// Authenticate returns token if it exists and is not expired
+	Authenticate(token string) (*entity.APIToken, *errors.AppError)
+	// Revoke deletes token of user
+	Revoke(tokenID string, userID string) *errors.AppError
+	// DeleteMany deletes all tokens of user
+	DeleteMany(userID string) *errors.AppError
+}
+
+// Last usage of token is written at most once in this period, not on every request
+const apiTokenUsagePeriod = time.Minute
+
+// Length of token prefix that is shown in token list
+const apiTokenPrefixLength = 12
+
+type apiTokenService struct {
+	repository storage.APITokenRepository
+}
+
+func NewAPITokenService(r storage.APITokenRepository) APITokenService {
+	return &apiTokenService{
+		repository: r,
+	}
+}
+
+func (s *apiTokenService) Create(userID string, request *entity.APITokenRequest) (*entity.APITokenDTO, *errors.AppError) {
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return nil, errors.BadRequest("Token name is required")
+	}
+
+	if len(request.Scopes) == 0 {
+		return nil, errors.BadRequest("At least one scope is required")
+	}
+	for _, scope := range request.Scopes {
+		if !scope.IsValid() {
+			return nil, errors.BadRequest("Invalid scope " + string(scope) + ", scopes are read and write")
+		}
+	}
+
+	if request.ExpiresInDays < 0 {
+		return nil, errors.BadRequest("Expiry must be positive")
+	}
+
+	user, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.BadRequest("Invalid user id")
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+	value := entity.APITokenPrefix + secret
+
+	token := &entity.APIToken{
+		UserID: user,
+		Name:   name,
+		Scopes: request.Scopes,
+		Hash:   hashAPIToken(value),
+		Prefix: value[:apiTokenPrefixLength],
+	}
+
+	if request.ExpiresInDays > 0 {
+		expiresAt := time.Now().UTC().AddDate(0, 0, request.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	token, err = s.repository.Create(token)
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+
+	tokenDTO := entity.ToAPITokenDTO(token)
+	tokenDTO.Token = value
+
+	return tokenDTO, nil
+}
+
+func (s *apiTokenService) FindAll(userID string) ([]*entity.APITokenDTO, *errors.AppError) {
+
+	tokens, err := s.repository.FindAllByUserID(userID)
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+
+	return entity.ToAPITokenDTOs(tokens), nil
+}
+
+func (s *apiTokenService) Authenticate(value string) (*entity.APIToken, *errors.AppError) {
+
+	token, err := s.repository.FindByHash(hashAPIToken(value))
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+
+	if token == nil {
+		return nil, errors.Unauthorized("Unauthorized user")
+	}
+
+	if token.IsExpired() {
+		return nil, errors.Unauthorized("API token is expired")
+	}
+
+	now := time.Now().UTC()
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > apiTokenUsagePeriod {
+		// Request is not rejected because of usage time
+		if err := s.repository.UpdateLastUsed(token.ID, now); err != nil {
+			log.Printf("Last usage of API token %s is not updated: %s", token.ID.Hex(), err)
+		}
+		token.LastUsedAt = &now
+	}
+
+	return token, nil
+}
+
+// Tokens of other users are not found
+func (s *apiTokenService) Revoke(tokenID string, userID string) *errors.AppError {
+
+	token, err := s.repository.FindByID(tokenID)
+	if err != nil || token == nil || token.UserID.Hex() != userID {
+		return errors.NotFound("API token is not found")
+	}
+
+	if err = s.repository.Delete(tokenID); err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	return nil
+}
+
+func (s *apiTokenService) DeleteMany(userID string) *errors.AppError {
+
+	if err := s.repository.DeleteMany(userID); err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	return nil
+}
+
+// Tokens are random, so a fast hash is enough and lets tokens be found by hash
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -4,12 +4,43 @@ import (
 	"github.com/nozgurozturk/marvin/pkg/errors"
 	"github.com/nozgurozturk/marvin/server/entity"
 	"github.com/nozgurozturk/marvin/server/internal/storage"
+	"time"
 )
 
 type AuthService interface {
 	CreateAuth(token *entity.Token) *errors.AppError
 	DeleteAuth(uuid string) *errors.AppError
 	FindAuth(uuid string) (string, *errors.AppError)
+	CreateOAuthState(state string, oauthState *entity.OAuthState) *errors.AppError
+	FindOAuthState(state string) (*entity.OAuthState, *errors.AppError)
+	// CreateActionToken returns token of email link that expires after duration of action
+	CreateActionToken(action entity.TokenAction, actionToken *entity.ActionToken) (string, *errors.AppError)
+	// FindActionToken returns and removes token of email link
+	FindActionToken(action entity.TokenAction, token string) (*entity.ActionToken, *errors.AppError)
+	// CreateSession starts session of login with its first token pair
+	CreateSession(tokens *entity.TokenDetails, client *entity.SessionClient) *errors.AppError
+	// FindSession returns session with matching id
+	FindSession(sessionID string) (*entity.Session, *errors.AppError)
+	// RotateSession replaces refresh token of session, session is revoked if refresh token is reused
+	RotateSession(session *entity.Session, tokenID string, tokens *entity.TokenDetails, client *entity.SessionClient) *errors.AppError
+	// TouchSession updates last activity of session
+	TouchSession(sessionID string, ip string) *errors.AppError
+	// FindSessions returns active sessions of user, current session is marked
+	FindSessions(userID string, currentID string) ([]*entity.SessionDTO, *errors.AppError)
+	// RevokeSession removes session of user
+	RevokeSession(userID string, sessionID string) *errors.AppError
+	// RevokeSessions removes sessions of user except given session, all sessions are removed if it is empty
+	RevokeSessions(userID string, exceptID string) *errors.AppError
+}
+
+// Users have ten minutes to authorize application in provider
+const oauthStateExpire = 10 * time.Minute
+
+// Email links expire sooner if they give access to account
+var actionTokenExpire = map[entity.TokenAction]time.Duration{
+	entity.ActionResetPassword:  time.Hour,
+	entity.ActionChangeEmail:    24 * time.Hour,
+	entity.ActionConfirmAccount: 24 * time.Hour,
 }
 
 type authService struct {
@@ -49,3 +80,52 @@ func (r *authService) FindAuth(uuid string) (string, *errors.AppError) {
 	}
 	return id, nil
 }
+
+func (s *authService) CreateOAuthState(state string, oauthState *entity.OAuthState) *errors.AppError {
+
+	err := s.repository.CreateOAuthState(state, oauthState, oauthStateExpire)
+	if err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	return nil
+}
+
+func (s *authService) FindOAuthState(state string) (*entity.OAuthState, *errors.AppError) {
+
+	oauthState, err := s.repository.FindOAuthState(state)
+	if err != nil {
+		return nil, errors.Unauthorized("Authorization request is expired or invalid")
+	}
+
+	return oauthState, nil
+}
+
+func (s *authService) CreateActionToken(action entity.TokenAction, actionToken *entity.ActionToken) (string, *errors.AppError) {
+
+	token, err := newSecret()
+	if err != nil {
+		return "", errors.InternalServer(err.Error())
+	}
+
+	err = s.repository.CreateActionToken(action, token, actionToken, actionTokenExpire[action])
+	if err != nil {
+		return "", errors.InternalServer(err.Error())
+	}
+
+	return token, nil
+}
+
+func (s *authService) FindActionToken(action entity.TokenAction, token string) (*entity.ActionToken, *errors.AppError) {
+
+	if token == "" {
+		return nil, errors.BadRequest("Token is required")
+	}
+
+	actionToken, err := s.repository.FindActionToken(action, token)
+	if err != nil {
+		return nil, errors.Unauthorized("Link is expired or already used")
+	}
+
+	return actionToken, nil
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"github.com/nozgurozturk/marvin/pkg/errors"
+	"github.com/nozgurozturk/marvin/pkg/utils"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"path"
+	"strings"
+	"time"
+)
+
+var updateKinds = map[string]bool{
+	utils.Major: true,
+	utils.Minor: true,
+	utils.Patch: true,
+}
+
+func (s *repoService) AddIgnoreRule(repoDTO *entity.RepoDTO, request *entity.IgnoreRuleRequest) (*entity.RepoDTO, *errors.AppError) {
+
+	rule := &entity.IgnoreRule{
+		ID:           primitive.NewObjectID().Hex(),
+		Package:      strings.TrimSpace(request.Package),
+		UpdateKinds:  request.UpdateKinds,
+		Versions:     strings.TrimSpace(request.Versions),
+		SnoozeUntil:  request.SnoozeUntil,
+		UntilVersion: strings.TrimSpace(request.UntilVersion),
+		Reason:       strings.TrimSpace(request.Reason),
+		CreatedAt:    time.Now().UTC(),
+	}
+
+	if appErr := validateIgnoreRule(rule); appErr != nil {
+		return nil, appErr
+	}
+
+	return s.updateIgnoreRules(repoDTO, append(repoDTO.IgnoreRules, rule))
+}
+
+func (s *repoService) DeleteIgnoreRule(repoDTO *entity.RepoDTO, ruleID string) (*entity.RepoDTO, *errors.AppError) {
+
+	rules := make([]*entity.IgnoreRule, 0, len(repoDTO.IgnoreRules))
+	for _, rule := range repoDTO.IgnoreRules {
+		if rule.ID != ruleID {
+			rules = append(rules, rule)
+		}
+	}
+
+	if len(rules) == len(repoDTO.IgnoreRules) {
+		return nil, errors.NotFound("Ignore rule is not found")
+	}
+
+	return s.updateIgnoreRules(repoDTO, rules)
+}
+
+// Rules are applied to saved packages, so notifications follow them before next scan
+func (s *repoService) updateIgnoreRules(repoDTO *entity.RepoDTO, rules []*entity.IgnoreRule) (*entity.RepoDTO, *errors.AppError) {
+
+	applyIgnoreRules(repoDTO.PackageList, rules, time.Now().UTC())
+
+	updated, err := s.repository.UpdateIgnoreRules(*repoDTO.ID, rules, repoDTO.PackageList)
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+
+	return entity.ToRepoDTO(updated), nil
+}
+
+func validateIgnoreRule(rule *entity.IgnoreRule) *errors.AppError {
+
+	if rule.Package == "" {
+		return errors.BadRequest("Package name or glob is required")
+	}
+
+	if _, err := path.Match(rule.Package, ""); err != nil {
+		return errors.BadRequest("Invalid package glob " + rule.Package)
+	}
+
+	for _, kind := range rule.UpdateKinds {
+		if !updateKinds[kind] {
+			return errors.BadRequest("Invalid update kind " + kind + ", kinds are major, minor and patch")
+		}
+	}
+
+	if rule.SnoozeUntil != nil && !rule.SnoozeUntil.After(time.Now()) {
+		return errors.BadRequest("Snooze date must be in future")
+	}
+
+	return nil
+}
+
+// Rules are evaluated again with current time, so snoozed packages are outdated again when snooze ends
+// Packages are outdated before rules are applied if they are outdated or ignored
+func applyIgnoreRules(packages []*entity.Package, rules []*entity.IgnoreRule, now time.Time) {
+
+	for _, pkg := range packages {
+		isOutdated := pkg.IsOutdated || pkg.Ignored != nil
+
+		pkg.IsOutdated = isOutdated
+		pkg.Ignored = nil
+
+		if !isOutdated {
+			continue
+		}
+
+		for _, rule := range rules {
+			if ignoreRuleMatches(rule, pkg, now) {
+				pkg.IsOutdated = false
+				pkg.Ignored = &entity.IgnoredPackage{
+					RuleID: rule.ID,
+					Reason: rule.Reason,
+					Until:  rule.SnoozeUntil,
+				}
+				break
+			}
+		}
+	}
+}
+
+func ignoreRuleMatches(rule *entity.IgnoreRule, pkg *entity.Package, now time.Time) bool {
+
+	if matched, _ := path.Match(rule.Package, pkg.Name); !matched {
+		return false
+	}
+
+	if len(rule.UpdateKinds) > 0 {
+		kind := utils.UpdateKind(pkg.Version.Current, pkg.Version.Last)
+		matched := false
+		for _, ignoredKind := range rule.UpdateKinds {
+			if ignoredKind == kind {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if rule.Versions != "" && !utils.SatisfiesRange(pkg.Version.Last, rule.Versions) {
+		return false
+	}
+
+	if rule.SnoozeUntil != nil && !now.Before(*rule.SnoozeUntil) {
+		return false
+	}
+
+	if rule.UntilVersion != "" && utils.CompareVersionNumbers(pkg.Version.Last, rule.UntilVersion) > 0 {
+		return false
+	}
+
+	return true
+}
+
+func withIgnoreRules(repoDTO *entity.RepoDTO) *entity.RepoDTO {
+	applyIgnoreRules(repoDTO.PackageList, repoDTO.IgnoreRules, time.Now().UTC())
+	return repoDTO
+}
+
+func withIgnoreRulesAll(repoDTOs []*entity.RepoDTO) []*entity.RepoDTO {
+	for _, repoDTO := range repoDTOs {
+		withIgnoreRules(repoDTO)
+	}
+	return repoDTOs
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/nozgurozturk/marvin/pkg/errors"
+	"github.com/nozgurozturk/marvin/pkg/parsers"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"path"
+	"strings"
+	"time"
+)
+
+/*
+	1. Decode Files -> unsupported files are skipped
+	2. Get Packages -> same parsers as provider scans
+	3. Get Each Package Version
+	4. Compare Versions
+	5. Save Snapshot -> only if repository is given
+*/
+func (s *repoService) ScanManifests(request *entity.ManifestScanRequest, repoDTO *entity.RepoDTO) (*entity.ManifestScanDTO, *errors.AppError) {
+
+	packages, unsupported, failures, appErr := scanManifestFiles(request.Files)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	result := &entity.ManifestScanDTO{
+		Packages:    packages,
+		Unsupported: unsupported,
+		Failures:    failures,
+	}
+
+	if repoDTO == nil {
+		return result, nil
+	}
+
+	// Ignore rules of repository are applied like provider scans
+	applyIgnoreRules(packages, repoDTO.IgnoreRules, time.Now().UTC())
+
+	ref := request.Ref
+	if ref == "" {
+		ref = repoDTO.Ref
+	}
+
+	snapshot := entity.NewSnapshot(&entity.RepoDTO{
+		ID:          repoDTO.ID,
+		UserID:      repoDTO.UserID,
+		Ref:         ref,
+		CommitSHA:   request.CommitSHA,
+		PackageList: packages,
+		LastScan: &entity.ScanResult{
+			ScannedAt:       time.Now().UTC(),
+			ManifestChanged: true,
+		},
+	})
+	snapshot.Source = entity.SnapshotSourceUpload
+
+	created, err := s.createSnapshot(snapshot)
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+
+	result.Snapshot = entity.ToSnapshotSummaryDTOs([]*entity.Snapshot{created})[0]
+
+	return result, nil
+}
+
+// Parses uploaded files with same parsers as provider scans and resolves registry versions of their packages
+func scanManifestFiles(files []*entity.ManifestFile) ([]*entity.Package, []string, []*entity.ScanFailure, *errors.AppError) {
+
+	if len(files) == 0 {
+		return nil, nil, nil, errors.BadRequest("At least one package file is required")
+	}
+
+	packageFiles := map[string]interface{}{}
+	unsupported := []string{}
+	hasPackageFile := false
+
+	for _, file := range files {
+		filePath := path.Clean(strings.TrimPrefix(file.Path, "/"))
+		if filePath == "." || strings.HasPrefix(filePath, "..") {
+			return nil, nil, nil, errors.BadRequest(fmt.Sprintf("Invalid file path %s", file.Path))
+		}
+
+		fileName := path.Base(filePath)
+		if !parsers.IsSupportedFile(fileName) {
+			unsupported = append(unsupported, filePath)
+			continue
+		}
+
+		content, err := parsers.DecodePackageFile(fileName, []byte(file.Content))
+		if err != nil {
+			return nil, nil, nil, errors.BadRequest(fmt.Sprintf("%s can not be parsed: %s", filePath, err))
+		}
+
+		packageFiles[filePath] = content
+		if _, err := parsers.NewParser(fileName); err == nil {
+			hasPackageFile = true
+		}
+	}
+
+	if !hasPackageFile {
+		return nil, nil, nil, errors.BadRequest("Package file is not found")
+	}
+
+	failures := &failureCollector{failures: []*entity.ScanFailure{}}
+
+	packages, appErr := parseManifests(packageFiles, failures)
+	if appErr != nil {
+		return nil, nil, nil, appErr
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), scanTimeout)
+	defer cancel()
+
+	resolveRegistryVersions(ctx, packages, failures)
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, nil, providerError(err)
+	}
+
+	if packages == nil {
+		packages = []*entity.Package{}
+	}
+
+	return packages, unsupported, failures.failures, nil
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"github.com/nozgurozturk/marvin/pkg/errors"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"github.com/nozgurozturk/marvin/server/internal/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
+)
+
+type OrganizationService interface {
+	// Create creates new organization, user is its first owner
+	Create(name string, userID string) (*entity.OrganizationDTO, *errors.AppError)
+	// FindAll returns organizations that user is member of
+	FindAll(userID string) ([]*entity.OrganizationDTO, *errors.AppError)
+	// Authorize returns organization if user has given role or a higher role in it
+	Authorize(orgID string, userID string, role entity.Role) (*entity.OrganizationDTO, *errors.AppError)
+	// Rename updates name of organization
+	Rename(orgID string, name string) (*entity.OrganizationDTO, *errors.AppError)
+	// AddMember adds user with matching email to organization
+	AddMember(orgID string, email string, role entity.Role) (*entity.OrganizationDTO, *errors.AppError)
+	// UpdateMember changes role of member, last owner can not be demoted
+	UpdateMember(orgID string, userID string, role entity.Role) (*entity.OrganizationDTO, *errors.AppError)
+	// RemoveMember removes member from organization, last owner can not be removed
+	RemoveMember(orgID string, userID string) (*entity.OrganizationDTO, *errors.AppError)
+	// RemoveUser removes user from all organizations before user is deleted
+	RemoveUser(userID string) *errors.AppError
+	// Delete removes organization that does not have repositories
+	Delete(orgID string) *errors.AppError
+}
+
+type orgService struct {
+	repository storage.OrganizationRepository
+	users      storage.UserRepository
+	repos      storage.RepoRepository
+}
+
+func NewOrganizationService(o storage.OrganizationRepository, u storage.UserRepository, r storage.RepoRepository) OrganizationService {
+	return &orgService{
+		repository: o,
+		users:      u,
+		repos:      r,
+	}
+}
+
+func (s *orgService) Create(name string, userID string) (*entity.OrganizationDTO, *errors.AppError) {
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.BadRequest("Organization name is required")
+	}
+
+	owner, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.BadRequest("Invalid user id")
+	}
+
+	organization, err := s.repository.Create(&entity.Organization{
+		Name:    name,
+		Members: []*entity.Member{{UserID: owner, Role: entity.RoleOwner}},
+	})
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+
+	return entity.ToOrganizationDTO(organization), nil
+}
+
+func (s *orgService) FindAll(userID string) ([]*entity.OrganizationDTO, *errors.AppError) {
+
+	organizations, err := s.repository.FindAllByUserID(userID)
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+
+	return entity.ToOrganizationDTOs(organizations), nil
+}
+
+// Organizations of other users are not found, so their ids are not revealed
+func (s *orgService) Authorize(orgID string, userID string, role entity.Role) (*entity.OrganizationDTO, *errors.AppError) {
+
+	organization, err := s.repository.FindByID(orgID)
+	if err != nil || organization == nil {
+		return nil, errors.NotFound("Organization is not found")
+	}
+
+	memberRole := organization.RoleOf(userID)
+	if memberRole == "" {
+		return nil, errors.NotFound("Organization is not found")
+	}
+
+	if !memberRole.Includes(role) {
+		return nil, errors.Forbidden("You don't have access")
+	}
+
+	return entity.ToOrganizationDTO(organization), nil
+}
+
+func (s *orgService) Rename(orgID string, name string) (*entity.OrganizationDTO, *errors.AppError) {
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.BadRequest("Organization name is required")
+	}
+
+	organization, err := s.repository.UpdateName(orgID, name)
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+	if organization == nil {
+		return nil, errors.NotFound("Organization is not found")
+	}
+
+	return entity.ToOrganizationDTO(organization), nil
+}
+
+func (s *orgService) AddMember(orgID string, email string, role entity.Role) (*entity.OrganizationDTO, *errors.AppError) {
+
+	if !role.IsValid() {
+		return nil, errors.BadRequest("Role must be owner, maintainer or viewer")
+	}
+
+	user, err := s.users.FindByEmail(email)
+	if err != nil || user == nil {
+		return nil, errors.NotFound("User is not found")
+	}
+
+	organization, err := s.repository.AddMember(orgID, &entity.Member{UserID: user.ID, Role: role})
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+	if organization == nil {
+		return nil, errors.AlreadyExist("User is already member of organization")
+	}
+
+	return entity.ToOrganizationDTO(organization), nil
+}
+
+func (s *orgService) UpdateMember(orgID string, userID string, role entity.Role) (*entity.OrganizationDTO, *errors.AppError) {
+
+	if !role.IsValid() {
+		return nil, errors.BadRequest("Role must be owner, maintainer or viewer")
+	}
+
+	if role != entity.RoleOwner {
+		if appErr := s.checkLastOwner(orgID, userID); appErr != nil {
+			return nil, appErr
+		}
+	}
+
+	organization, err := s.repository.UpdateMemberRole(orgID, userID, role)
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+	if organization == nil {
+		return nil, errors.NotFound("Member is not found")
+	}
+
+	return entity.ToOrganizationDTO(organization), nil
+}
+
+func (s *orgService) RemoveMember(orgID string, userID string) (*entity.OrganizationDTO, *errors.AppError) {
+
+	if appErr := s.checkLastOwner(orgID, userID); appErr != nil {
+		return nil, appErr
+	}
+
+	organization, err := s.repository.RemoveMember(orgID, userID)
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+	if organization == nil {
+		return nil, errors.NotFound("Organization is not found")
+	}
+
+	return entity.ToOrganizationDTO(organization), nil
+}
+
+// Users that are last owner of an organization must transfer or delete it first
+func (s *orgService) RemoveUser(userID string) *errors.AppError {
+
+	organizations, err := s.repository.FindAllByUserID(userID)
+	if err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	for _, organization := range organizations {
+		if organization.RoleOf(userID) == entity.RoleOwner && organization.OwnerCount() == 1 {
+			return errors.BadRequest("You are last owner of " + organization.Name + ", add another owner or delete organization first")
+		}
+	}
+
+	if err := s.repository.RemoveMemberFromAll(userID); err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	return nil
+}
+
+func (s *orgService) Delete(orgID string) *errors.AppError {
+
+	repos, err := s.repos.FindAllByOrgID(orgID)
+	if err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	if len(repos) > 0 {
+		return errors.BadRequest("Organization has repositories, move or delete them first")
+	}
+
+	if err := s.repository.Delete(orgID); err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	return nil
+}
+
+// Organizations can not be left without an owner
+func (s *orgService) checkLastOwner(orgID string, userID string) *errors.AppError {
+
+	organization, err := s.repository.FindByID(orgID)
+	if err != nil || organization == nil {
+		return errors.NotFound("Organization is not found")
+	}
+
+	if organization.RoleOf(userID) == entity.RoleOwner && organization.OwnerCount() == 1 {
+		return errors.BadRequest("Organization must have at least one owner")
+	}
+
+	return nil
+}
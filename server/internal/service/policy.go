@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/nozgurozturk/marvin/pkg/advisories"
+	"github.com/nozgurozturk/marvin/pkg/errors"
+	"github.com/nozgurozturk/marvin/pkg/managers"
+	"github.com/nozgurozturk/marvin/pkg/utils"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Checks of policy violations
+const (
+	checkOutdatedMajors = "maxOutdatedMajors"
+	checkDeprecated     = "noDeprecated"
+	checkSeverity       = "maxSeverity"
+	checkLibyears       = "maxLibyears"
+)
+
+// Max severity that does not allow any advisory
+const severityNone = "none"
+
+const hoursInYear = 24 * 365.25
+
+// Registry details and advisories of package that policy needs
+type packageDetails struct {
+	release    *managers.ReleaseInfo
+	advisories []*advisories.Advisory
+}
+
+/*
+	1. Get Packages -> packages of repository or uploaded package files
+	2. Count Outdated Majors
+	3. Get Each Release Info -> only if deprecations or libyears are checked
+	4. Get Each Advisory -> only if severity is checked
+	5. Compare With Policy
+*/
+func (s *repoService) EvaluatePolicy(request *entity.PolicyRequest, repoDTO *entity.RepoDTO) (*entity.PolicyResult, *errors.AppError) {
+
+	policy := request.Policy
+	if appErr := validatePolicy(policy); appErr != nil {
+		return nil, appErr
+	}
+
+	failures := []*entity.ScanFailure{}
+
+	var packages []*entity.Package
+	if repoDTO != nil {
+		packages = repoDTO.PackageList
+	} else {
+		scanned, _, scanFailures, appErr := scanManifestFiles(request.Files)
+		if appErr != nil {
+			return nil, appErr
+		}
+		packages = scanned
+		failures = append(failures, scanFailures...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), scanTimeout)
+	defer cancel()
+
+	details, detailFailures := resolvePackageDetails(ctx, packages, policy)
+	failures = append(failures, detailFailures...)
+
+	if err := ctx.Err(); err != nil {
+		return nil, providerError(err)
+	}
+
+	summary := &entity.PolicySummary{Packages: len(packages)}
+	violations := []*entity.PolicyViolation{}
+	var majors []*entity.PolicyViolation
+
+	for _, pkg := range packages {
+		detail := details[pkg]
+
+		// Ignored packages are not outdated
+		if pkg.IsOutdated && utils.UpdateKind(pkg.Version.Current, pkg.Version.Last) == utils.Major {
+			summary.OutdatedMajors++
+			majors = append(majors, newViolation(checkOutdatedMajors, pkg,
+				fmt.Sprintf("Major update from %s to %s", pkg.Version.Current, pkg.Version.Last), ""))
+		}
+
+		if detail == nil {
+			continue
+		}
+
+		if detail.release != nil {
+			if detail.release.Deprecated != "" {
+				summary.Deprecated++
+				if policy.NoDeprecated {
+					violations = append(violations, newViolation(checkDeprecated, pkg, detail.release.Deprecated, ""))
+				}
+			}
+			summary.Libyears += libyears(pkg, detail.release)
+		}
+
+		for _, advisory := range detail.advisories {
+			summary.Vulnerabilities++
+			if policy.MaxSeverity == severityNone || advisories.IsHigher(advisory.Severity, policy.MaxSeverity) {
+				violations = append(violations, newViolation(checkSeverity, pkg,
+					fmt.Sprintf("%s severity advisory %s: %s", advisory.Severity, advisory.ID, advisory.Summary), advisory.Url))
+			}
+		}
+	}
+
+	if policy.MaxOutdatedMajors != nil && summary.OutdatedMajors > *policy.MaxOutdatedMajors {
+		violations = append(violations, majors...)
+	}
+
+	summary.Libyears = math.Round(summary.Libyears*100) / 100
+	if policy.MaxLibyears != nil && summary.Libyears > *policy.MaxLibyears {
+		violations = append(violations, &entity.PolicyViolation{
+			Check:   checkLibyears,
+			Message: fmt.Sprintf("Packages are %.2f libyears behind, policy allows %.2f", summary.Libyears, *policy.MaxLibyears),
+		})
+	}
+
+	sortViolations(violations)
+
+	return &entity.PolicyResult{
+		Passed:     len(violations) == 0,
+		Summary:    summary,
+		Violations: violations,
+		Failures:   failures,
+	}, nil
+}
+
+func validatePolicy(policy *entity.Policy) *errors.AppError {
+
+	if policy == nil {
+		return errors.BadRequest("Policy is required")
+	}
+
+	if policy.MaxOutdatedMajors != nil && *policy.MaxOutdatedMajors < 0 {
+		return errors.BadRequest("Max outdated majors must be positive")
+	}
+
+	if policy.MaxLibyears != nil && *policy.MaxLibyears < 0 {
+		return errors.BadRequest("Max libyears must be positive")
+	}
+
+	policy.MaxSeverity = strings.ToLower(policy.MaxSeverity)
+	if policy.MaxSeverity != "" && policy.MaxSeverity != severityNone && !advisories.IsSeverity(policy.MaxSeverity) {
+		return errors.BadRequest("Invalid severity, severities are none, low, moderate, high and critical")
+	}
+
+	return nil
+}
+
+// Release infos are only requested for checks that need them, advisories are requested for every package
+func resolvePackageDetails(ctx context.Context, packages []*entity.Package, policy *entity.Policy) (map[*entity.Package]*packageDetails, []*entity.ScanFailure) {
+
+	details := map[*entity.Package]*packageDetails{}
+	failures := []*entity.ScanFailure{}
+
+	checkAdvisories := policy.MaxSeverity != ""
+
+	var mutex sync.Mutex
+	fail := func(pkg *entity.Package, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		failures = append(failures, &entity.ScanFailure{Name: pkg.Name, Path: pkg.Path, Message: err.Error()})
+	}
+
+	var wg sync.WaitGroup
+	for _, pkg := range packages {
+		// Libyears of up to date packages are zero
+		checkRelease := policy.NoDeprecated || (policy.MaxLibyears != nil && pkg.IsOutdated)
+		if !checkRelease && !checkAdvisories {
+			continue
+		}
+
+		wg.Add(1)
+		go func(pkg *entity.Package) {
+			defer wg.Done()
+
+			detail := &packageDetails{}
+			version := installedVersion(pkg)
+
+			if checkRelease {
+				m, err := managers.NewManager(pkg.File)
+				if err != nil {
+					fail(pkg, err)
+					return
+				}
+				if detail.release, err = m.GetReleaseInfo(ctx, pkg.Name, version, pkg.Version.Last); err != nil {
+					fail(pkg, err)
+				}
+			}
+
+			if checkAdvisories {
+				var err error
+				if detail.advisories, err = advisories.Query(ctx, pkg.File, pkg.Name, version); err != nil {
+					fail(pkg, err)
+				}
+			}
+
+			mutex.Lock()
+			details[pkg] = detail
+			mutex.Unlock()
+		}(pkg)
+	}
+	wg.Wait()
+
+	return details, failures
+}
+
+// Lock file version is installed version, range operators of constraints are skipped otherwise
+func installedVersion(pkg *entity.Package) string {
+	if pkg.Version.Locked != "" {
+		return pkg.Version.Locked
+	}
+	return strings.TrimLeft(pkg.Version.Current, "^~>=<v ")
+}
+
+// Years between release of installed version and release of latest version
+func libyears(pkg *entity.Package, release *managers.ReleaseInfo) float64 {
+	if !pkg.IsOutdated || release.ReleasedAt.IsZero() || release.LatestReleasedAt.IsZero() {
+		return 0
+	}
+	behind := release.LatestReleasedAt.Sub(release.ReleasedAt)
+	if behind < 0 {
+		return 0
+	}
+	return behind.Hours() / hoursInYear
+}
+
+func newViolation(check string, pkg *entity.Package, message string, url string) *entity.PolicyViolation {
+	return &entity.PolicyViolation{
+		Check:   check,
+		Package: pkg.Name,
+		Path:    packagePath(pkg),
+		Message: message,
+		Url:     url,
+	}
+}
+
+// Packages that are scanned before path tracking only have file name
+func packagePath(pkg *entity.Package) string {
+	if pkg.Path == "" {
+		return pkg.File
+	}
+	return pkg.Path
+}
+
+func sortViolations(violations []*entity.PolicyViolation) {
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].Check != violations[j].Check {
+			return violations[i].Check < violations[j].Check
+		}
+		if violations[i].Path != violations[j].Path {
+			return violations[i].Path < violations[j].Path
+		}
+		return violations[i].Package < violations[j].Package
+	})
+}
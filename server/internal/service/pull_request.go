@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/nozgurozturk/marvin/pkg/errors"
+	"github.com/nozgurozturk/marvin/pkg/managers"
+	"github.com/nozgurozturk/marvin/pkg/parsers"
+	"github.com/nozgurozturk/marvin/pkg/providers"
+	"github.com/nozgurozturk/marvin/pkg/utils"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+func (s *repoService) UpdateToken(repoID string, token string, userID string) (*entity.RepoDTO, *errors.AppError) {
+
+	repo, err := s.repository.UpdateToken(repoID, token, userID)
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+
+	return entity.ToRepoDTO(repo), nil
+}
+
+/*
+	1. Get Provider -> with token of user
+	2. Select Updates -> outdated packages that can be bumped
+	3. Get Package Files -> raw files at tracked ref
+	4. Bump Versions -> only version strings are replaced
+	5. Create Pull Request -> branch, commit and pull request
+*/
+func (s *repoService) CreatePullRequest(repoDTO *entity.RepoDTO, selected []*entity.PackageSelector, userID string) (*entity.PullRequestDTO, *errors.AppError) {
+
+	// Parses rawUrl to url.URL
+	u, err := url.Parse(repoDTO.Path)
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+
+	// Pull requests are opened with token of user, tokens that other users saved are not used
+	token := s.providerToken(repoDTO, userID, u.Host)
+	if token == "" {
+		return nil, errors.BadRequest("You do not have provider token for this repository, save a token or login with repository access")
+	}
+
+	p, err := newProvider(u, token)
+	if err != nil {
+		return nil, providerError(err)
+	}
+
+	requester, ok := p.(providers.PullRequester)
+	if !ok {
+		return nil, errors.BadRequest("Provider of repository does not support pull requests")
+	}
+
+	owner, name, urlRef, err := p.UrlResolver()
+	if err != nil {
+		return nil, providerError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), scanTimeout)
+	defer cancel()
+
+	ref := repoDTO.Ref
+	if ref == "" {
+		if ref, err = resolveUrlRef(ctx, p, owner, name, urlRef); err != nil {
+			return nil, providerError(err)
+		}
+	}
+
+	updates := selectUpdates(ctx, repoDTO.PackageList, selected)
+	if len(updates) == 0 {
+		return nil, errors.BadRequest("There is no outdated package to update")
+	}
+
+	// package file path -> package name -> new version
+	versions := map[string]map[string]string{}
+	for _, update := range updates {
+		if versions[update.Path] == nil {
+			versions[update.Path] = map[string]string{}
+		}
+		versions[update.Path][update.Name] = update.To
+	}
+
+	files := map[string][]byte{}
+	for filePath, fileVersions := range versions {
+		data, err := requester.GetFile(ctx, owner, name, ref, filePath)
+		if err != nil {
+			return nil, providerError(err)
+		}
+
+		bumped, err := parsers.Bump(path.Base(filePath), data, fileVersions)
+		if err != nil {
+			return nil, errors.InternalServer(err.Error())
+		}
+
+		files[filePath] = bumped
+	}
+
+	title := pullRequestTitle(updates)
+	branch := fmt.Sprintf("marvin/update-%s", time.Now().UTC().Format("20060102150405"))
+
+	pullRequestUrl, err := requester.CreatePullRequest(ctx, owner, name, &providers.PullRequest{
+		Base:    ref,
+		Branch:  branch,
+		Title:   title,
+		Body:    pullRequestBody(updates),
+		Message: title,
+		Files:   files,
+	})
+	if err != nil {
+		return nil, providerError(err)
+	}
+
+	return &entity.PullRequestDTO{
+		Url:     pullRequestUrl,
+		Branch:  branch,
+		Updates: updates,
+	}, nil
+}
+
+// Finds outdated packages that are selected, versions that can not be bumped are skipped
+// All outdated packages are selected if selection is empty
+func selectUpdates(ctx context.Context, packages []*entity.Package, selected []*entity.PackageSelector) []*entity.PackageUpdate {
+
+	var updates []*entity.PackageUpdate
+
+	for _, pkg := range packages {
+		if !pkg.IsOutdated {
+			continue
+		}
+
+		// Packages that are scanned before path tracking are in root directory
+		filePath := pkg.Path
+		if filePath == "" {
+			filePath = pkg.File
+		}
+
+		if !isSelected(selected, pkg.Name, filePath) {
+			continue
+		}
+
+		to, ok := utils.BumpVersion(pkg.Version.Current, pkg.Version.Last)
+		if !ok {
+			continue
+		}
+
+		updates = append(updates, &entity.PackageUpdate{
+			Name:         pkg.Name,
+			Path:         filePath,
+			File:         pkg.File,
+			From:         pkg.Version.Current,
+			To:           to,
+			Kind:         utils.UpdateKind(pkg.Version.Current, pkg.Version.Last),
+			ChangelogUrl: findChangelogUrl(ctx, pkg),
+		})
+	}
+
+	sort.Slice(updates, func(i, j int) bool {
+		if updates[i].Path != updates[j].Path {
+			return updates[i].Path < updates[j].Path
+		}
+		return updates[i].Name < updates[j].Name
+	})
+
+	return updates
+}
+
+func isSelected(selected []*entity.PackageSelector, name string, filePath string) bool {
+	if len(selected) == 0 {
+		return true
+	}
+	for _, selector := range selected {
+		if selector.Name == name && (selector.Path == "" || selector.Path == filePath) {
+			return true
+		}
+	}
+	return false
+}
+
+// Tests replace it, so registries are not requested
+var findChangelogUrl = changelogUrl
+
+// Gets releases page of package, it is empty if registry can not be reached
+func changelogUrl(ctx context.Context, pkg *entity.Package) string {
+	m, err := managers.NewManager(pkg.File)
+	if err != nil {
+		return ""
+	}
+	changelog, _ := m.GetChangelogUrl(ctx, pkg.Name)
+	return changelog
+}
+
+func pullRequestTitle(updates []*entity.PackageUpdate) string {
+	if len(updates) == 1 {
+		return fmt.Sprintf("Update %s to %s", updates[0].Name, updates[0].To)
+	}
+	return fmt.Sprintf("Update %d packages", len(updates))
+}
+
+// Creates markdown table of version bumps
+func pullRequestBody(updates []*entity.PackageUpdate) string {
+
+	var body strings.Builder
+
+	body.WriteString("Marvin found outdated packages in this repository and updated them.\n\n")
+	body.WriteString("| Package | File | From | To | Update | Changelog |\n")
+	body.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+
+	for _, update := range updates {
+		changelog := "-"
+		if update.ChangelogUrl != "" {
+			changelog = fmt.Sprintf("[releases](%s)", update.ChangelogUrl)
+		}
+		fmt.Fprintf(&body, "| `%s` | `%s` | `%s` | `%s` | %s | %s |\n",
+			update.Name, update.Path, update.From, update.To, update.Kind, changelog)
+	}
+
+	return body.String()
+}
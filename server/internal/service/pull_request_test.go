@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"github.com/nozgurozturk/marvin/server/internal/config"
+	"github.com/nozgurozturk/marvin/server/internal/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Loads config from given variables, required expiration variables are set if they are not given
+func setTestConfig(t *testing.T, env map[string]string) {
+	t.Helper()
+
+	defaults := map[string]string{
+		"ACCESS_EXPIRE":  "60",
+		"REFRESH_EXPIRE": "720",
+		"SUB_EXPIRE":     "24",
+	}
+	for key, value := range env {
+		defaults[key] = value
+	}
+
+	for key, value := range defaults {
+		key := key
+		previous, exist := os.LookupEnv(key)
+		if err := os.Setenv(key, value); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			if exist {
+				os.Setenv(key, previous)
+			} else {
+				os.Unsetenv(key)
+			}
+		})
+	}
+
+	config.Set()
+}
+
+const testBaseSHA = "0123456789abcdef0123456789abcdef01234567"
+
+const testPackageFile = "{\n  \"name\": \"web\",\n  \"dependencies\": {\n    \"react\":   \"^16.8.0\",\n    \"lodash\": \"~4.17.0\"\n  },\n  \"devDependencies\": {\n    \"jest\": \">=25.1.0\"\n  }\n}\n"
+
+// User store with users of linked accounts
+type fakeUserRepository struct {
+	storage.UserRepository
+	users []*entity.User
+}
+
+func (r *fakeUserRepository) FindByID(userID string) (*entity.User, error) {
+	for _, u := range r.users {
+		if u.ID.Hex() == userID {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+
+// Fake GitHub api that serves package file of web directory and records created tree and used tokens
+func newFakeGithub(t *testing.T, tree *[]interface{}, pullRequest *map[string]interface{}, tokens *[]string) *httptest.Server {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		*tokens = append(*tokens, r.Header.Get("Authorization"))
+
+		var body map[string]interface{}
+		if data, _ := ioutil.ReadAll(r.Body); len(data) > 0 {
+			_ = json.Unmarshal(data, &body)
+		}
+
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/owner/name/contents/web/package.json":
+			if r.URL.Query().Get("ref") != "main" {
+				t.Errorf("package file is not read at tracked ref: %s", r.URL.RawQuery)
+			}
+			_, _ = w.Write([]byte(testPackageFile))
+		case "GET /repos/owner/name/commits/main":
+			_, _ = w.Write([]byte(testBaseSHA))
+		case "POST /repos/owner/name/git/trees":
+			*tree, _ = body["tree"].([]interface{})
+			_ = json.NewEncoder(w).Encode(map[string]string{"sha": "tree-sha"})
+		case "POST /repos/owner/name/git/commits":
+			_ = json.NewEncoder(w).Encode(map[string]string{"sha": "commit-sha"})
+		case "POST /repos/owner/name/git/refs":
+			_ = json.NewEncoder(w).Encode(map[string]string{"ref": "refs/heads/branch"})
+		case "POST /repos/owner/name/pulls":
+			*pullRequest = body
+			_ = json.NewEncoder(w).Encode(map[string]string{"html_url": "https://github.com/owner/name/pull/1"})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func testPackage(name string, current string, latest string, outdated bool) *entity.Package {
+	return &entity.Package{
+		Name:       name,
+		Version:    entity.PackageVersion{Current: current, Last: latest},
+		File:       "package.json",
+		Path:       "web/package.json",
+		IsOutdated: outdated,
+	}
+}
+
+func TestCreatePullRequest(t *testing.T) {
+
+	findChangelogUrl = func(ctx context.Context, pkg *entity.Package) string {
+		return "https://github.com/facebook/" + pkg.Name + "/releases"
+	}
+	defer func() { findChangelogUrl = changelogUrl }()
+
+	var tree []interface{}
+	var pullRequest map[string]interface{}
+	var tokens []string
+	server := newFakeGithub(t, &tree, &pullRequest, &tokens)
+	setTestConfig(t, map[string]string{"GITHUB_API_URL": server.URL})
+
+	ownerID := primitive.NewObjectID().Hex()
+	repoDTO := &entity.RepoDTO{
+		Path:        "https://github.com/owner/name",
+		Ref:         "main",
+		Token:       "secret",
+		TokenUserID: ownerID,
+		PackageList: []*entity.Package{
+			testPackage("react", "^16.8.0", "17.0.1", true),
+			testPackage("lodash", "~4.17.0", "4.17.20", true),
+			testPackage("jest", ">=25.1.0", "26.6.3", true),
+		},
+	}
+
+	s := &repoService{users: &fakeUserRepository{}}
+	result, appErr := s.CreatePullRequest(repoDTO, []*entity.PackageSelector{{Name: "react"}, {Name: "jest", Path: "web/package.json"}}, ownerID)
+	if appErr != nil {
+		t.Fatal(appErr.Message)
+	}
+
+	for _, token := range tokens {
+		if token != "token secret" {
+			t.Fatalf("expected saved token of user, got %q", token)
+		}
+	}
+
+	if result.Url != "https://github.com/owner/name/pull/1" || !strings.HasPrefix(result.Branch, "marvin/update-") {
+		t.Errorf("unexpected pull request %+v", result)
+	}
+	if len(result.Updates) != 2 {
+		t.Fatalf("expected only selected packages to be updated, got %d updates", len(result.Updates))
+	}
+
+	if len(tree) != 1 {
+		t.Fatalf("expected 1 updated file, got %v", tree)
+	}
+	entry := tree[0].(map[string]interface{})
+	expected := "{\n  \"name\": \"web\",\n  \"dependencies\": {\n    \"react\":   \"^17.0.1\",\n    \"lodash\": \"~4.17.0\"\n  },\n  \"devDependencies\": {\n    \"jest\": \">=26.6.3\"\n  }\n}\n"
+	if entry["path"] != "web/package.json" || entry["content"] != expected {
+		t.Errorf("unexpected updated file %s:\n%v", entry["path"], entry["content"])
+	}
+
+	if pullRequest["base"] != "main" || pullRequest["title"] != "Update 2 packages" {
+		t.Errorf("unexpected pull request %v", pullRequest)
+	}
+	body, _ := pullRequest["body"].(string)
+	if !strings.Contains(body, "| `jest` | `web/package.json` | `>=25.1.0` | `>=26.6.3` | major | [releases](https://github.com/facebook/jest/releases) |") {
+		t.Errorf("update is not in pull request body:\n%s", body)
+	}
+}
+
+func TestCreatePullRequestWithoutUpdates(t *testing.T) {
+
+	findChangelogUrl = func(ctx context.Context, pkg *entity.Package) string { return "" }
+	defer func() { findChangelogUrl = changelogUrl }()
+
+	setTestConfig(t, nil)
+
+	tests := []struct {
+		name    string
+		repoDTO *entity.RepoDTO
+	}{
+		{"without token", &entity.RepoDTO{Path: "https://github.com/owner/name", PackageList: []*entity.Package{testPackage("react", "^16.8.0", "17.0.1", true)}}},
+		{"with token of other user", &entity.RepoDTO{Path: "https://github.com/owner/name", Token: "secret", TokenUserID: primitive.NewObjectID().Hex(), PackageList: []*entity.Package{testPackage("react", "^16.8.0", "17.0.1", true)}}},
+		{"without outdated package", &entity.RepoDTO{Path: "https://github.com/owner/name", Token: "secret", TokenUserID: "user", PackageList: []*entity.Package{testPackage("react", "^17.0.1", "17.0.1", false)}}},
+		{"with unsupported constraint", &entity.RepoDTO{Path: "https://github.com/owner/name", Token: "secret", TokenUserID: "user", PackageList: []*entity.Package{testPackage("react", "16.x || 17.x", "17.0.1", true)}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &repoService{users: &fakeUserRepository{}}
+			if _, appErr := s.CreatePullRequest(tt.repoDTO, nil, "user"); appErr == nil || appErr.Status != http.StatusBadRequest {
+				t.Errorf("expected bad request, got %v", appErr)
+			}
+		})
+	}
+}
+
+func TestCreatePullRequestWithTokenOfMaintainer(t *testing.T) {
+
+	findChangelogUrl = func(ctx context.Context, pkg *entity.Package) string { return "" }
+	defer func() { findChangelogUrl = changelogUrl }()
+
+	var tree []interface{}
+	var pullRequest map[string]interface{}
+	var tokens []string
+	server := newFakeGithub(t, &tree, &pullRequest, &tokens)
+	setTestConfig(t, map[string]string{"GITHUB_API_URL": server.URL})
+
+	// Maintainer of organization has linked account with repository access
+	maintainer := &entity.User{
+		ID: primitive.NewObjectID(),
+		Identities: []*entity.Identity{
+			{Provider: "github", Host: "github.com", ID: "7", Token: "maintainer-token"},
+		},
+	}
+
+	repoDTO := &entity.RepoDTO{
+		Path:        "https://github.com/owner/name",
+		Ref:         "main",
+		Token:       "secret",
+		TokenUserID: primitive.NewObjectID().Hex(),
+		PackageList: []*entity.Package{testPackage("react", "^16.8.0", "17.0.1", true)},
+	}
+
+	s := &repoService{users: &fakeUserRepository{users: []*entity.User{maintainer}}}
+	if _, appErr := s.CreatePullRequest(repoDTO, nil, maintainer.ID.Hex()); appErr != nil {
+		t.Fatal(appErr.Message)
+	}
+
+	if len(tokens) == 0 {
+		t.Fatal("expected requests to provider")
+	}
+	for _, token := range tokens {
+		if token != "token maintainer-token" {
+			t.Fatalf("expected token of maintainer instead of saved token of other user, got %q", token)
+		}
+	}
+}
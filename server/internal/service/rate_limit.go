@@ -0,0 +1,148 @@
+package service
+
+import (
+	"fmt"
+	"github.com/nozgurozturk/marvin/pkg/errors"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"github.com/nozgurozturk/marvin/server/internal/storage"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type RateLimitService interface {
+	// Allow counts request of key, error is returned when limit is exceeded
+	Allow(limit entity.RateLimit, key string) (*entity.RateLimitUsage, *errors.AppError)
+	// AllowEmail counts emails that are sent to address
+	AllowEmail(to string) *errors.AppError
+	// CheckLogin returns error while account is locked after failed logins
+	CheckLogin(email string) *errors.AppError
+	// LoginFailed counts failed login, account is locked longer after every failure over threshold
+	LoginFailed(email string) *errors.AppError
+	// LoginSucceeded resets failed logins of account
+	LoginSucceeded(email string) *errors.AppError
+}
+
+var (
+	// Login, token refresh and password reset requests per IP
+	AuthLimit = entity.RateLimit{Name: "auth", Max: 20, Window: time.Minute}
+	// Sign up requests per IP
+	SignUpLimit = entity.RateLimit{Name: "signup", Max: 5, Window: time.Hour}
+	// Requests that send email per IP or user
+	EmailLimit = entity.RateLimit{Name: "email", Max: 10, Window: time.Hour}
+	// Emails that are sent to same address, it protects addresses that requester does not own
+	recipientLimit = entity.RateLimit{Name: "recipient", Max: 3, Window: time.Hour}
+)
+
+const (
+	// Failed logins are forgotten a day after first failure
+	loginFailureWindow = 24 * time.Hour
+	// Account is locked when failures reach threshold
+	loginFailureThreshold = 5
+	// Lock doubles with every failure after threshold
+	loginLockMin = time.Minute
+	loginLockMax = time.Hour
+)
+
+type rateLimitService struct {
+	repository storage.LimitRepository
+}
+
+func NewRateLimitService(r storage.LimitRepository) RateLimitService {
+	return &rateLimitService{
+		repository: r,
+	}
+}
+
+func (s *rateLimitService) Allow(limit entity.RateLimit, key string) (*entity.RateLimitUsage, *errors.AppError) {
+
+	count, resetIn, err := s.repository.Increment(limit.Name, key, limit.Window)
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+
+	usage := &entity.RateLimitUsage{Count: count, ResetIn: resetIn}
+	if count > limit.Max {
+		return usage, errors.TooManyRequests(fmt.Sprintf("Too many requests, please try again in %s", retryIn(resetIn)))
+	}
+
+	return usage, nil
+}
+
+func (s *rateLimitService) AllowEmail(to string) *errors.AppError {
+
+	_, err := s.Allow(recipientLimit, normalizeEmail(to))
+	if err != nil && err.Status == http.StatusTooManyRequests {
+		return errors.TooManyRequests("Too many emails are sent to this address, please try again later")
+	}
+
+	return err
+}
+
+func (s *rateLimitService) CheckLogin(email string) *errors.AppError {
+
+	locked, err := s.repository.FindLock("login", normalizeEmail(email))
+	if err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	if locked > 0 {
+		return errors.TooManyRequests(fmt.Sprintf("Account is locked after failed logins, please try again in %s", retryIn(locked)))
+	}
+
+	return nil
+}
+
+func (s *rateLimitService) LoginFailed(email string) *errors.AppError {
+
+	key := normalizeEmail(email)
+
+	failures, _, err := s.repository.Increment("login-failure", key, loginFailureWindow)
+	if err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	if failures < loginFailureThreshold {
+		return nil
+	}
+
+	if err := s.repository.CreateLock("login", key, loginLockDuration(failures)); err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	return nil
+}
+
+func (s *rateLimitService) LoginSucceeded(email string) *errors.AppError {
+
+	key := normalizeEmail(email)
+
+	if err := s.repository.Reset("login-failure", key); err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	return nil
+}
+
+// Lock of failures over threshold, 5th failure locks for a minute, 6th for two minutes...
+func loginLockDuration(failures int64) time.Duration {
+
+	exponent := float64(failures - loginFailureThreshold)
+	lock := time.Duration(float64(loginLockMin) * math.Pow(2, exponent))
+
+	if lock <= 0 || lock > loginLockMax {
+		return loginLockMax
+	}
+
+	return lock
+}
+
+// Same address can be written with different cases and spaces
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func retryIn(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
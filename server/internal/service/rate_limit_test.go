@@ -0,0 +1,133 @@
+package service
+
+import (
+	"github.com/nozgurozturk/marvin/server/internal/storage"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Limit store with counters and locks in memory, windows do not expire during tests
+type fakeLimitRepository struct {
+	storage.LimitRepository
+	counts map[string]int64
+	locks  map[string]time.Duration
+}
+
+func newFakeLimitRepository() *fakeLimitRepository {
+	return &fakeLimitRepository{counts: map[string]int64{}, locks: map[string]time.Duration{}}
+}
+
+func (r *fakeLimitRepository) Increment(name string, key string, window time.Duration) (int64, time.Duration, error) {
+	r.counts[name+":"+key]++
+	return r.counts[name+":"+key], window, nil
+}
+
+func (r *fakeLimitRepository) Reset(name string, key string) error {
+	delete(r.counts, name+":"+key)
+	return nil
+}
+
+func (r *fakeLimitRepository) CreateLock(name string, key string, duration time.Duration) error {
+	r.locks[name+":"+key] = duration
+	return nil
+}
+
+func (r *fakeLimitRepository) FindLock(name string, key string) (time.Duration, error) {
+	return r.locks[name+":"+key], nil
+}
+
+func TestLoginLockout(t *testing.T) {
+
+	repository := newFakeLimitRepository()
+	s := NewRateLimitService(repository)
+
+	// Failures below threshold do not lock account
+	for i := 1; i < loginFailureThreshold; i++ {
+		if appErr := s.LoginFailed("user@example.com"); appErr != nil {
+			t.Fatal(appErr.Message)
+		}
+		if appErr := s.CheckLogin("user@example.com"); appErr != nil {
+			t.Fatalf("expected account not to be locked after %d failures, got %s", i, appErr.Message)
+		}
+	}
+
+	// Lock doubles from a minute after threshold and it is capped at an hour
+	expected := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 16 * time.Minute, 32 * time.Minute, time.Hour, time.Hour}
+	for i, lock := range expected {
+		// Address is normalized, so other spellings count for same account
+		if appErr := s.LoginFailed(" User@Example.com "); appErr != nil {
+			t.Fatal(appErr.Message)
+		}
+		if found := repository.locks["login:user@example.com"]; found != lock {
+			t.Errorf("expected lock of %s after %d failures, got %s", lock, loginFailureThreshold+i, found)
+		}
+	}
+
+	if appErr := s.CheckLogin("user@example.com"); appErr == nil || appErr.Status != http.StatusTooManyRequests {
+		t.Errorf("expected locked account, got %v", appErr)
+	}
+}
+
+func TestLoginLockDuration(t *testing.T) {
+
+	tests := []struct {
+		failures int64
+		lock     time.Duration
+	}{
+		{5, time.Minute},
+		{6, 2 * time.Minute},
+		{10, 32 * time.Minute},
+		{11, time.Hour},
+		// Overflowed durations are capped too
+		{100, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if lock := loginLockDuration(tt.failures); lock != tt.lock {
+			t.Errorf("expected lock of %s after %d failures, got %s", tt.lock, tt.failures, lock)
+		}
+	}
+}
+
+func TestLoginSucceededResetsFailures(t *testing.T) {
+
+	repository := newFakeLimitRepository()
+	s := NewRateLimitService(repository)
+
+	for i := 1; i < loginFailureThreshold; i++ {
+		_ = s.LoginFailed("user@example.com")
+	}
+	if appErr := s.LoginSucceeded("USER@example.com"); appErr != nil {
+		t.Fatal(appErr.Message)
+	}
+
+	// Counting starts again, next failure does not lock account
+	if appErr := s.LoginFailed("user@example.com"); appErr != nil {
+		t.Fatal(appErr.Message)
+	}
+	if appErr := s.CheckLogin("user@example.com"); appErr != nil {
+		t.Errorf("expected failures to be reset after successful login, got %s", appErr.Message)
+	}
+	if count := repository.counts["login-failure:user@example.com"]; count != 1 {
+		t.Errorf("expected 1 failure after reset, got %d", count)
+	}
+}
+
+func TestAllowEmailPerRecipient(t *testing.T) {
+
+	s := NewRateLimitService(newFakeLimitRepository())
+
+	for i := int64(1); i <= recipientLimit.Max; i++ {
+		if appErr := s.AllowEmail("user@example.com"); appErr != nil {
+			t.Fatalf("expected email %d to be allowed, got %s", i, appErr.Message)
+		}
+	}
+
+	if appErr := s.AllowEmail(" USER@example.com"); appErr == nil || appErr.Status != http.StatusTooManyRequests {
+		t.Errorf("expected emails to same address to be limited, got %v", appErr)
+	}
+	if appErr := s.AllowEmail("other@example.com"); appErr != nil {
+		t.Errorf("expected emails to other address to be allowed, got %s", appErr.Message)
+	}
+}
@@ -1,29 +1,81 @@
 package service
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"github.com/nozgurozturk/marvin/pkg/advisories"
 	"github.com/nozgurozturk/marvin/pkg/errors"
 	"github.com/nozgurozturk/marvin/pkg/managers"
 	"github.com/nozgurozturk/marvin/pkg/parsers"
 	"github.com/nozgurozturk/marvin/pkg/providers"
 	"github.com/nozgurozturk/marvin/pkg/utils"
 	"github.com/nozgurozturk/marvin/server/entity"
+	"github.com/nozgurozturk/marvin/server/internal/config"
 	"github.com/nozgurozturk/marvin/server/internal/storage"
+	"log"
 	"net/url"
+	"path"
 	"sync"
+	"time"
 )
 
 // RepoService interface
 type RepoService interface {
-	// Create creates new git repository and saves into store
-	Create(rawUrl string, userID string) (*entity.RepoDTO, *errors.AppError)
+	// Create starts background scan of new git repository at ref, repository is saved when scan is completed
+	// Repository belongs to organization if organization id is given, OAuth token of user is saved if saveToken is true
+	Create(rawUrl string, ref string, orgID string, userID string, saveToken bool) (*entity.ScanJobDTO, *errors.AppError)
+	// FindScanJob returns scan job with matching id
+	FindScanJob(jobID string) (*entity.ScanJob, *errors.AppError)
+	// SubscribeScanJob returns scan job with its events, close must be called when events are not read anymore
+	SubscribeScanJob(jobID string) (*entity.ScanJob, <-chan *entity.ScanEvent, func(), *errors.AppError)
+	// CancelScanJob stops scan job, instance that runs it cancels requests
+	CancelScanJob(jobID string) (*entity.ScanJob, *errors.AppError)
 	// FindByID returns git repository with matching id
 	FindByID(repoID string) (*entity.RepoDTO, *errors.AppError)
+	// FindByBadgeToken returns git repository if badge token matches, it is not found otherwise
+	FindByBadgeToken(repoID string, token string) (*entity.RepoDTO, *errors.AppError)
 	// FindByUrlAndUserID returns git repository with matching url and user id
 	FindByUrlAndUserID(url string, userID string) (*entity.RepoDTO, *errors.AppError)
+	// Authorize checks user is owner of git repository or has given role in its organization
+	Authorize(userID string, repoDTO *entity.RepoDTO, role entity.Role) *errors.AppError
 	// FindAll returns git repository belongs to user
 	FindAll(userID string) ([]*entity.RepoDTO, *errors.AppError)
-	// UpdatePackages insert updated packages
-	UpdatePackages(repoDTO *entity.RepoDTO) (*entity.RepoDTO, *errors.AppError)
+	// FindAllByOrgID returns git repositories belongs to organization, secrets are omitted for roles below maintainer
+	FindAllByOrgID(orgID string, role entity.Role) ([]*entity.RepoDTO, *errors.AppError)
+	// Transfer moves git repository to organization, or to user if organization id is empty
+	Transfer(repoID string, userID string, orgID string) (*entity.RepoDTO, *errors.AppError)
+	// UpdatePackages insert updated packages, scans without user id are background scans
+	UpdatePackages(repoDTO *entity.RepoDTO, userID string) (*entity.RepoDTO, *errors.AppError)
+	// HandlePush rescans git repository in background when pushed commits change package files
+	HandlePush(repoDTO *entity.RepoDTO, event *entity.PushEvent) bool
+	// RotateWebhookSecret creates new webhook secret for git repository
+	RotateWebhookSecret(repoID string) (*entity.RepoDTO, *errors.AppError)
+	// RotateBadgeToken creates new badge token for git repository
+	RotateBadgeToken(repoID string) (*entity.RepoDTO, *errors.AppError)
+	// AddIgnoreRule adds ignore rule to git repository and applies rules to its packages
+	AddIgnoreRule(repoDTO *entity.RepoDTO, request *entity.IgnoreRuleRequest) (*entity.RepoDTO, *errors.AppError)
+	// DeleteIgnoreRule removes ignore rule of git repository and applies remaining rules to its packages
+	DeleteIgnoreRule(repoDTO *entity.RepoDTO, ruleID string) (*entity.RepoDTO, *errors.AppError)
+	// UpdateToken saves access token of provider for git repository, token is only used for actions of user
+	UpdateToken(repoID string, token string, userID string) (*entity.RepoDTO, *errors.AppError)
+	// CreatePullRequest bumps selected outdated packages and opens pull request in provider with token of user
+	CreatePullRequest(repoDTO *entity.RepoDTO, selected []*entity.PackageSelector, userID string) (*entity.PullRequestDTO, *errors.AppError)
+	// FindSnapshots returns scan snapshots of git repository without packages, newest snapshot is first
+	FindSnapshots(repoID string) ([]*entity.SnapshotDTO, *errors.AppError)
+	// FindSnapshot returns scan snapshot of git repository with packages
+	FindSnapshot(repoID string, snapshotID string) (*entity.SnapshotDTO, *errors.AppError)
+	// DiffSnapshots compares two scan snapshots of git repository, latest two snapshots are used if ids are empty
+	DiffSnapshots(repoID string, fromID string, toID string) (*entity.SnapshotDiff, *errors.AppError)
+	// ScanSBOM resolves registry versions of packages in CycloneDX or SPDX document
+	ScanSBOM(file map[string]interface{}) (*entity.SBOMScanDTO, *errors.AppError)
+	// ScanManifests resolves registry versions of uploaded package files, packages are saved as snapshot if repository is given
+	ScanManifests(request *entity.ManifestScanRequest, repoDTO *entity.RepoDTO) (*entity.ManifestScanDTO, *errors.AppError)
+	// EvaluatePolicy checks packages of git repository or uploaded package files with policy of CI gate
+	EvaluatePolicy(request *entity.PolicyRequest, repoDTO *entity.RepoDTO) (*entity.PolicyResult, *errors.AppError)
 	// Delete removes git repository
 	Delete(repoID string) *errors.AppError
 	// DeleteMany removes all git repositories belongs to user
@@ -32,25 +84,29 @@ type RepoService interface {
 
 type repoService struct {
 	repository storage.RepoRepository
+	users      storage.UserRepository
+	jobs       storage.JobRepository
+	snapshots  storage.SnapshotRepository
+	orgs       storage.OrganizationRepository
 }
 
-func NewRepoService(r storage.RepoRepository) RepoService {
+func NewRepoService(r storage.RepoRepository, u storage.UserRepository, j storage.JobRepository, sn storage.SnapshotRepository, o storage.OrganizationRepository) RepoService {
 	return &repoService{
 		repository: r,
+		users:      u,
+		jobs:       j,
+		snapshots:  sn,
+		orgs:       o,
 	}
 }
 
 /*
-	1. Resolve Url -> owner, name
+	1. Resolve Url -> owner, name, ref
 	2. Get Provider -> github
-	3. Get ManagerType
-	3. Get PackageManager -> npm
-	4. Get Packages -> packages
-	5. Get Each Package Version
-	6. Compare Versions
-	7. Create repo
+	3. Scan Packages
+	4. Create repo -> unless same repository is created during scan
 */
-func (s *repoService) Create(rawUrl string, userID string) (*entity.RepoDTO, *errors.AppError) {
+func (s *repoService) create(ctx context.Context, rawUrl string, ref string, orgID string, userID string, saveToken bool, observer scanObserver) (*entity.RepoDTO, *errors.AppError) {
 
 	// Parses rawUrl to url.URL
 	u, err := url.Parse(rawUrl)
@@ -58,37 +114,227 @@ func (s *repoService) Create(rawUrl string, userID string) (*entity.RepoDTO, *er
 		return nil, errors.InternalServer(err.Error())
 	}
 
+	// Token that user granted with OAuth login is used for private repositories
+	token := s.identityToken(userID, u.Host)
+
 	// Gets git provider with matching host name
-	p, err := providers.GetProvider(u)
+	p, err := newProvider(u, token)
+	if err != nil {
+		return nil, providerError(err)
+	}
+
+	// Resolves git repository's owner, name and ref from url
+	owner, name, urlRef, err := p.UrlResolver()
+	if err != nil {
+		return nil, providerError(err)
+	}
+
+	// Explicit ref overrides ref in url
+	if ref == "" {
+		if ref, err = resolveUrlRef(ctx, p, owner, name, urlRef); err != nil {
+			return nil, providerError(err)
+		}
+	}
+
+	scan, appErr := scanRepository(ctx, p, owner, name, ref, nil, observer)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	if exist, _ := s.repository.FindByUrlAndUserID(rawUrl, userID); exist != nil {
+		return nil, errors.AlreadyExist("Repository is already exist")
+	}
+
+	secret, err := newSecret()
 	if err != nil {
 		return nil, errors.InternalServer(err.Error())
 	}
 
-	// Resolves git repository's owner and name from url
-	owner, name := p.UrlResolver()
+	badgeToken, err := newSecret()
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
 
-	// Gets git repository file tree from root directory
-	tree, err := p.GetRepositoryTree(owner, name)
+	repo := &entity.RepoDTO{
+		Name:          name,
+		Owner:         owner,
+		Path:          rawUrl,
+		Ref:           ref,
+		Provider:      providerName(u),
+		PackageList:   scan.packages,
+		UserID:        userID,
+		OrgID:         orgID,
+		WebhookSecret: secret,
+		BadgeToken:    badgeToken,
+		CommitSHA:     scan.commitSHA,
+		ManifestBlobs: scan.blobs,
+		LastScan:      scan.result,
+	}
+
+	// Token is saved only if user allows it, other users of organization can not use it
+	if saveToken && token != "" {
+		repo.Token = token
+		repo.TokenUserID = userID
+	}
+
+	createdRepo, err := s.repository.Create(entity.ToRepo(repo))
 	if err != nil {
 		return nil, errors.InternalServer(err.Error())
 	}
 
-	// Find package file info from provider's API
-	packagesInfo := p.FindPackagesInfo(tree)
-	if packagesInfo == nil {
-		return nil, errors.InternalServer("Package file is not found")
+	createdRepoDTO := entity.ToRepoDTO(createdRepo)
+
+	s.saveSnapshot(createdRepoDTO)
+
+	return createdRepoDTO, nil
+
+}
+
+// Scans are cancelled when provider or registries do not respond in time
+const scanTimeout = 2 * time.Minute
+
+// Packages of git repository at scanned commit
+type scanOutput struct {
+	packages  []*entity.Package
+	commitSHA string
+	blobs     []*entity.ManifestBlob
+	result    *entity.ScanResult
+}
+
+/*
+	1. Get Head -> commit sha of ref
+	2. Get Tree -> files at commit, skipped when commit is not changed
+	3. Find Package Files -> root and workspace members
+	4. Compare Blobs -> package files are downloaded only when they are changed
+	5. Get Packages -> packages of changed package files or previous scan
+	6. Get Each Package Version
+	7. Compare Versions
+*/
+func scanRepository(ctx context.Context, p providers.Provider, owner string, name string, ref string, previous *entity.RepoDTO, observer scanObserver) (*scanOutput, *errors.AppError) {
+
+	// Gets commit that ref points to
+	commitSHA, err := p.GetHeadSHA(ctx, owner, name, ref)
+	if err != nil {
+		return nil, providerError(err)
+	}
+
+	output := &scanOutput{
+		commitSHA: commitSHA,
 	}
 
+	manifestChanged := previous == nil || previous.CommitSHA != commitSHA
+
+	var packagesInfo []map[string]interface{}
+
+	if manifestChanged {
+		// Tree is read at resolved commit, pushes during scan can not mix two commits
+		tree, err := p.GetRepositoryTree(ctx, owner, name, commitSHA)
+		if err != nil {
+			return nil, providerError(err)
+		}
+
+		// Find package file info from provider's API
+		packagesInfo = p.FindPackagesInfo(tree)
+		if packagesInfo == nil {
+			return nil, errors.InternalServer("Package file is not found")
+		}
+
+		output.blobs = toManifestBlobs(packagesInfo)
+
+		// Commits that do not touch package files keep their blobs
+		manifestChanged = previous == nil || !sameManifestBlobs(previous.ManifestBlobs, output.blobs)
+	} else {
+		output.blobs = previous.ManifestBlobs
+	}
+
+	observer.manifestsFound(manifestPaths(output.blobs))
+
+	if manifestChanged {
+		packages, appErr := parsePackageFiles(ctx, p, packagesInfo, observer)
+		if appErr != nil {
+			return nil, appErr
+		}
+		output.packages = packages
+	} else {
+		output.packages = copyPackages(previous.PackageList)
+	}
+
+	observer.packagesFound(output.packages)
+
+	resolveRegistryVersions(ctx, output.packages, observer)
+
+	// Registry errors are not fatal, but cancelled scans must not save partial versions
+	if err := ctx.Err(); err != nil {
+		return nil, providerError(err)
+	}
+
+	output.result = &entity.ScanResult{
+		ScannedAt:       time.Now().UTC(),
+		ManifestChanged: manifestChanged,
+		RegistryChanged: previous != nil && registryChanged(previous.PackageList, output.packages),
+	}
+
+	return output, nil
+}
+
+// Maps provider errors to app errors
+func providerError(err error) *errors.AppError {
+	if err == providers.ErrRepositoryNotFound {
+		return errors.NotFound("Repository is not found in provider")
+	}
+	if err == providers.ErrRefNotFound {
+		return errors.NotFound("Branch, tag or commit is not found in provider")
+	}
+	if err == providers.ErrFileNotFound {
+		return errors.NotFound("File is not found in provider")
+	}
+	if stderrors.Is(err, providers.ErrGitNotAllowed) || err == providers.ErrInvalidUrl || err == providers.ErrCloneTooLarge || err == providers.ErrTreeTooLarge {
+		return errors.BadRequest(err.Error())
+	}
+	if rateLimitErr, ok := err.(*providers.RateLimitError); ok {
+		return errors.ServiceUnavailable(fmt.Sprintf("Rate limit of %s is exhausted, try again after %s", rateLimitErr.Host, rateLimitErr.Reset.Format(time.RFC3339)))
+	}
+	if err == context.DeadlineExceeded {
+		return errors.ServiceUnavailable("Provider did not respond in time")
+	}
+	return errors.InternalServer(err.Error())
+}
+
+// Ref of url can contain slashes, providers that can resolve it find the branch or tag that it starts with
+func resolveUrlRef(ctx context.Context, p providers.Provider, owner string, name string, urlRef string) (string, error) {
+	if resolver, ok := p.(providers.RefResolver); ok && urlRef != "" {
+		return resolver.ResolveRef(ctx, owner, name, urlRef)
+	}
+	return urlRef, nil
+}
+
+// Downloads and parses package files
+func parsePackageFiles(ctx context.Context, p providers.Provider, packagesInfo []map[string]interface{}, observer scanObserver) ([]*entity.Package, *errors.AppError) {
+
 	// Gets packages from package file
-	packageFiles, err := p.GetPackageFiles(packagesInfo)
+	packageFiles, err := p.GetPackageFiles(ctx, packagesInfo)
 	if err != nil {
-		return nil, errors.InternalServer(err.Error())
+		return nil, providerError(err)
 	}
 
+	return parseManifests(packageFiles, observer)
+}
+
+// Parses package files that are keyed by their paths, lock files and workspace descriptors are included
+// Files that are not JSON objects are skipped and reported as failures
+func parseManifests(packageFiles map[string]interface{}, observer scanObserver) ([]*entity.Package, *errors.AppError) {
+
+	locks := lockedPackages(packageFiles)
+
+	// Keeps root package files and members of declared workspaces
+	packageFiles = parsers.FilterWorkspaces(packageFiles)
+
 	var packages []*entity.Package
 
-	// If git repository more than one package file with matching file names
-	for pkgName, file := range packageFiles {
+	// Package files are keyed by their paths, monorepos have more than one package file with matching file names
+	for filePath, file := range packageFiles {
+
+		pkgName := path.Base(filePath)
 
 		// Creates new parser with matching package file name
 		parser, err := parsers.NewParser(pkgName)
@@ -96,15 +342,78 @@ func (s *repoService) Create(rawUrl string, userID string) (*entity.RepoDTO, *er
 			return nil, errors.InternalServer(err.Error())
 		}
 
+		content, ok := file.(map[string]interface{})
+		if !ok {
+			observer.packageResolved(&entity.Package{Name: pkgName, Path: filePath}, stderrors.New("package file is not a JSON object"))
+			continue
+		}
+
 		// Parses registries with name and versions
-		rawPackages := parser.Parse(file.(map[string]interface{}))
+		rawPackages := parser.Parse(content)
 
 		// Maps raw package array to entity.Package array
-		pkgs := entity.ToPackageDTOs(rawPackages, pkgName)
+		pkgs := entity.ToPackageDTOs(rawPackages, pkgName, filePath)
 
 		packages = append(packages, pkgs...)
 	}
 
+	applyLockedPackages(packages, locks)
+
+	return packages, nil
+}
+
+// Removes lock files from package files and parses them, locked packages are keyed by path of package file they pin
+func lockedPackages(packageFiles map[string]interface{}) map[string]map[string]*parsers.LockedPackage {
+
+	locks := map[string]map[string]*parsers.LockedPackage{}
+
+	for filePath, file := range packageFiles {
+		fileName := path.Base(filePath)
+		if !parsers.IsLockFile(fileName) {
+			continue
+		}
+		delete(packageFiles, filePath)
+
+		if content, ok := file.(map[string]interface{}); ok {
+			manifestPath := path.Join(path.Dir(filePath), parsers.LockedPackageFile(fileName))
+			locks[manifestPath] = parsers.ParseLockFile(fileName, content)
+		}
+	}
+
+	return locks
+}
+
+// Sets installed versions and licenses of packages
+// Workspace members do not have their own lock files, so nearest lock file in parent directories is used
+func applyLockedPackages(packages []*entity.Package, locks map[string]map[string]*parsers.LockedPackage) {
+
+	if len(locks) == 0 {
+		return
+	}
+
+	for _, pkg := range packages {
+		for dir := path.Dir(pkg.Path); ; dir = path.Dir(dir) {
+			if locked, ok := locks[path.Join(dir, pkg.File)][pkg.Name]; ok {
+				pkg.Version.Locked = locked.Version
+				pkg.License = locked.License
+				break
+			}
+			if dir == "." || dir == "/" {
+				break
+			}
+		}
+	}
+}
+
+// Tests replace them, so registries and OSV are not requested
+var (
+	newManager      = managers.NewManager
+	queryAdvisories = advisories.Query
+)
+
+// Gets latest registry versions of packages and marks outdated ones
+func resolveRegistryVersions(ctx context.Context, packages []*entity.Package, observer scanObserver) {
+
 	var wg sync.WaitGroup
 	for _, pkg := range packages {
 		wg.Add(1)
@@ -112,14 +421,16 @@ func (s *repoService) Create(rawUrl string, userID string) (*entity.RepoDTO, *er
 			defer wg.Done()
 
 			// Creates new package manager that is consume api
-			m, err := managers.NewManager(pkg.File)
+			m, err := newManager(pkg.File)
 			if err != nil {
+				observer.packageResolved(pkg, err)
 				return
 			}
 
 			// Gets latest registry version
-			registryVersion, err := m.GetRegistryVersion(pkg.Name)
+			registryVersion, err := m.GetRegistryVersion(ctx, pkg.Name)
 			if err != nil {
+				observer.packageResolved(pkg, err)
 				return
 			}
 
@@ -130,28 +441,179 @@ func (s *repoService) Create(rawUrl string, userID string) (*entity.RepoDTO, *er
 				pkg.Version.Last = registryVersion
 				pkg.IsOutdated = isOutdated
 			}
+
+			resolvePackageRisks(ctx, m, pkg)
+
+			observer.packageResolved(pkg, nil)
 		}(pkg)
 	}
 	wg.Wait()
+}
 
-	repo := &entity.RepoDTO{
-		Name:        name,
-		Owner:       owner,
-		Path:        rawUrl,
-		Provider:    u.Host,
-		PackageList: packages,
-		UserID:      userID,
+// Finds deprecation and advisories of installed version, they are reported with outdated packages
+// Registry and OSV errors do not fail scans, package is saved without them
+func resolvePackageRisks(ctx context.Context, m managers.Manager, pkg *entity.Package) {
+
+	version := installedVersion(pkg)
+
+	if release, err := m.GetReleaseInfo(ctx, pkg.Name, version, pkg.Version.Last); err == nil {
+		pkg.Deprecated = release.Deprecated
 	}
 
-	createdRepo, err := s.repository.Create(entity.ToRepo(repo))
+	found, err := queryAdvisories(ctx, pkg.File, pkg.Name, version)
 	if err != nil {
-		return nil, errors.InternalServer(err.Error())
+		return
 	}
 
-	createdRepoDTO := entity.ToRepoDTO(createdRepo)
+	for _, advisory := range found {
+		pkg.Advisories = append(pkg.Advisories, &entity.Advisory{
+			ID:       advisory.ID,
+			Severity: advisory.Severity,
+			Summary:  advisory.Summary,
+			Url:      advisory.Url,
+		})
+	}
+}
 
-	return createdRepoDTO, nil
+// Gets blob sha of every package file, workspace descriptors are included because they change members
+func toManifestBlobs(packagesInfo []map[string]interface{}) []*entity.ManifestBlob {
 
+	blobs := make([]*entity.ManifestBlob, 0, len(packagesInfo))
+
+	for _, file := range packagesInfo {
+		filePath, _ := file["path"].(string)
+		sha, _ := file["sha"].(string)
+		blobs = append(blobs, &entity.ManifestBlob{
+			Path: filePath,
+			SHA:  sha,
+		})
+	}
+
+	return blobs
+}
+
+// Checks package files are not added, removed or modified
+func sameManifestBlobs(previous []*entity.ManifestBlob, current []*entity.ManifestBlob) bool {
+
+	if len(previous) != len(current) {
+		return false
+	}
+
+	shas := make(map[string]string, len(previous))
+	for _, blob := range previous {
+		shas[blob.Path] = blob.SHA
+	}
+
+	for _, blob := range current {
+		sha, ok := shas[blob.Path]
+		if !ok || blob.SHA == "" || sha != blob.SHA {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Copies packages of previous scan with their current versions, registry versions are resolved again
+func copyPackages(packages []*entity.Package) []*entity.Package {
+
+	copied := make([]*entity.Package, len(packages))
+
+	for i, pkg := range packages {
+		copied[i] = &entity.Package{
+			Name: pkg.Name,
+			Version: entity.PackageVersion{
+				Current: pkg.Version.Current,
+				Last:    pkg.Version.Current,
+				Locked:  pkg.Version.Locked,
+			},
+			File:       pkg.File,
+			Path:       pkg.Path,
+			License:    pkg.License,
+			IsOutdated: false,
+		}
+	}
+
+	return copied
+}
+
+// Checks latest version of any package is changed since previous scan
+func registryChanged(previous []*entity.Package, current []*entity.Package) bool {
+
+	key := func(pkg *entity.Package) string {
+		return pkg.Path + ":" + pkg.File + ":" + pkg.Name
+	}
+
+	versions := make(map[string]string, len(previous))
+	for _, pkg := range previous {
+		versions[key(pkg)] = pkg.Version.Last
+	}
+
+	for _, pkg := range current {
+		if last, ok := versions[key(pkg)]; ok && last != pkg.Version.Last {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Saved token of repository is used only by user that saved it, other users use their linked accounts
+func (s *repoService) providerToken(repoDTO *entity.RepoDTO, userID string, host string) string {
+	if repoDTO.Token != "" && repoDTO.TokenUserID == userID {
+		return repoDTO.Token
+	}
+	return s.identityToken(userID, host)
+}
+
+// Gets granted token of user's linked account in host
+func (s *repoService) identityToken(userID string, host string) string {
+
+	user, err := s.users.FindByID(userID)
+	if err != nil || user == nil {
+		return ""
+	}
+
+	for _, identity := range user.Identities {
+		if identity.Host == host && identity.Token != "" {
+			return identity.Token
+		}
+	}
+
+	return ""
+}
+
+// Creates git provider with token, api urls of providers can be overridden by config
+func newProvider(u *url.URL, token string) (providers.Provider, error) {
+
+	options := providers.Options{
+		Token: token,
+	}
+
+	if cnf := config.Get(); cnf != nil && cnf.Provider != nil {
+		switch u.Host {
+		case "github.com":
+			options.ApiUrl = cnf.Provider.GithubApiUrl
+		case "gitlab.com":
+			options.ApiUrl = cnf.Provider.GitlabApiUrl
+		}
+		options.Git = providers.GitOptions{
+			Enabled:    cnf.Provider.GitEnabled,
+			LocalRoots: cnf.Provider.GitLocalRoots,
+			Hosts:      cnf.Provider.GitHosts,
+			MaxSize:    cnf.Provider.GitMaxCloneSize,
+		}
+	}
+
+	return providers.NewProvider(u, options)
+}
+
+// Returns host of repository url, local repositories do not have host
+func providerName(u *url.URL) string {
+	if u.Host == "" {
+		return u.Scheme
+	}
+	return u.Host
 }
 
 func (s *repoService) FindByID(repoID string) (*entity.RepoDTO, *errors.AppError) {
@@ -165,7 +627,7 @@ func (s *repoService) FindByID(repoID string) (*entity.RepoDTO, *errors.AppError
 		return nil, errors.NotFound("Repository is not found")
 	}
 
-	return entity.ToRepoDTO(repo), nil
+	return withIgnoreRules(entity.ToRepoDTO(repo)), nil
 }
 
 func (s *repoService) FindByUrlAndUserID(url string, userID string) (*entity.RepoDTO, *errors.AppError) {
@@ -179,7 +641,7 @@ func (s *repoService) FindByUrlAndUserID(url string, userID string) (*entity.Rep
 		return nil, errors.NotFound("Repository is not found")
 	}
 
-	return entity.ToRepoDTO(repo), nil
+	return withIgnoreRules(entity.ToRepoDTO(repo)), nil
 }
 
 func (s *repoService) FindAll(userID string) ([]*entity.RepoDTO, *errors.AppError) {
@@ -190,100 +652,245 @@ func (s *repoService) FindAll(userID string) ([]*entity.RepoDTO, *errors.AppErro
 		return nil, errors.InternalServer(err.Error())
 	}
 
-	return entity.ToRepoDTOs(repos), nil
+	return withIgnoreRulesAll(entity.ToRepoDTOs(repos)), nil
 }
 
-func (s *repoService) UpdatePackages(repoDTO *entity.RepoDTO) (*entity.RepoDTO, *errors.AppError) {
+func (s *repoService) Authorize(userID string, repoDTO *entity.RepoDTO, role entity.Role) *errors.AppError {
 
-	// Parses rawUrl to url.URL
-	u, err := url.Parse(repoDTO.Path)
+	// Users have every role in their own repositories
+	if repoDTO.OrgID == "" {
+		if repoDTO.UserID != userID {
+			return errors.Forbidden("You don't have access")
+		}
+		return nil
+	}
+
+	organization, err := s.orgs.FindByID(repoDTO.OrgID)
+	if err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	if organization == nil || !organization.RoleOf(userID).Includes(role) {
+		return errors.Forbidden("You don't have access")
+	}
+
+	return nil
+}
+
+func (s *repoService) FindAllByOrgID(orgID string, role entity.Role) ([]*entity.RepoDTO, *errors.AppError) {
+
+	repos, err := s.repository.FindAllByOrgID(orgID)
 	if err != nil {
 		return nil, errors.InternalServer(err.Error())
 	}
 
-	// Gets git provider with matching host name
-	p, err := providers.GetProvider(u)
+	repoDTOs := withIgnoreRulesAll(entity.ToRepoDTOs(repos))
+	for i, repoDTO := range repoDTOs {
+		repoDTOs[i] = repoDTO.ForRole(role)
+	}
+
+	return repoDTOs, nil
+}
+
+// Repositories that are moved to user belong to user who moves them
+func (s *repoService) Transfer(repoID string, userID string, orgID string) (*entity.RepoDTO, *errors.AppError) {
+
+	repo, err := s.repository.UpdateOwner(repoID, userID, orgID)
 	if err != nil {
 		return nil, errors.InternalServer(err.Error())
 	}
 
-	// Resolves git repository's owner and name from url
-	owner, name := p.UrlResolver()
+	return entity.ToRepoDTO(repo), nil
+}
 
-	// Gets git repository file tree from root directory
-	tree, err := p.GetRepositoryTree(owner, name)
+func (s *repoService) UpdatePackages(repoDTO *entity.RepoDTO, userID string) (*entity.RepoDTO, *errors.AppError) {
+
+	// Parses rawUrl to url.URL
+	u, err := url.Parse(repoDTO.Path)
 	if err != nil {
 		return nil, errors.InternalServer(err.Error())
 	}
 
-	// Find package file info from provider's API
-	packagesInfo := p.FindPackagesInfo(tree)
-	if packagesInfo == nil {
-		return nil, errors.InternalServer("Package file is not found")
+	// Background scans use saved token, scans of users use their own token
+	token := repoDTO.Token
+	if userID != "" {
+		token = s.providerToken(repoDTO, userID, u.Host)
 	}
 
-	// Gets packages from package file
-	packageFiles, err := p.GetPackageFiles(packagesInfo)
+	// Gets git provider with matching host name, token is used for private repositories
+	p, err := newProvider(u, token)
+	if err != nil {
+		return nil, providerError(err)
+	}
+
+	// Resolves git repository's owner, name and ref from url
+	owner, name, urlRef, err := p.UrlResolver()
+	if err != nil {
+		return nil, providerError(err)
+	}
+
+	// Previous scan is compared with current commit and package files
+	ctx, cancel := context.WithTimeout(context.Background(), scanTimeout)
+	defer cancel()
+
+	// Stored ref is used, repositories that are created before ref tracking fall back to url
+	ref := repoDTO.Ref
+	if ref == "" {
+		if ref, err = resolveUrlRef(ctx, p, owner, name, urlRef); err != nil {
+			return nil, providerError(err)
+		}
+	}
+
+	scan, appErr := scanRepository(ctx, p, owner, name, ref, repoDTO, noopObserver{})
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	// Packages of new scan are not ignored yet
+	applyIgnoreRules(scan.packages, repoDTO.IgnoreRules, time.Now().UTC())
+
+	repoDTO.PackageList = scan.packages
+	repoDTO.CommitSHA = scan.commitSHA
+	repoDTO.ManifestBlobs = scan.blobs
+	repoDTO.LastScan = scan.result
+	updated, err := s.repository.UpdatePackages(entity.ToRepo(repoDTO))
 	if err != nil {
 		return nil, errors.InternalServer(err.Error())
 	}
 
-	var packages []*entity.Package
+	updatedDTO := entity.ToRepoDTO(updated)
 
-	// If git repository more than one package file with matching file names
-	for pkgName, file := range packageFiles {
+	s.saveSnapshot(updatedDTO)
 
-		// Creates new parser with matching package file name
-		parser, err := parsers.NewParser(pkgName)
-		if err != nil {
-			return nil, errors.InternalServer(err.Error())
+	return updatedDTO, nil
+}
+
+func (s *repoService) HandlePush(repoDTO *entity.RepoDTO, event *entity.PushEvent) bool {
+
+	// Pushes to other branches or tags do not change tracked packages
+	trackedRef := repoDTO.Ref
+	if trackedRef == "" {
+		trackedRef = event.DefaultBranch
+	}
+	if event.Ref == "" || event.Ref != trackedRef {
+		return false
+	}
+
+	// If payload does not contain all changed paths, repository is rescanned anyway
+	changed := !event.Complete
+	for _, changedPath := range event.ChangedPaths {
+		if parsers.IsManifestPath(changedPath) {
+			changed = true
+			break
 		}
+	}
+	if !changed {
+		return false
+	}
 
-		// Parses registries with name and versions
-		rawPackages := parser.Parse(file.(map[string]interface{}))
+	repoID := *repoDTO.ID
 
-		// Maps raw package array to entity.Package array
-		pkgs := entity.ToPackageDTOs(rawPackages, pkgName)
+	// Push during running scan is rescanned after it, pushes of a burst share one scan
+	pushScans.Lock()
+	if _, running := pushScans.pending[repoID]; running {
+		pushScans.pending[repoID] = true
+		pushScans.Unlock()
+		return true
+	}
+	pushScans.pending[repoID] = false
+	pushScans.Unlock()
 
-		packages = append(packages, pkgs...)
+	go s.rescanPushed(repoDTO)
+
+	return true
+}
+
+// Webhook rescans that run in this instance, repository id -> another push is received during scan
+// One scan runs for a repository, so concurrent scans do not overwrite packages of each other
+var pushScans = struct {
+	sync.Mutex
+	pending map[string]bool
+}{pending: map[string]bool{}}
+
+// Rescans repository until there is no pending push, repository is read again before next scan
+func (s *repoService) rescanPushed(repoDTO *entity.RepoDTO) {
+
+	repoID := *repoDTO.ID
+
+	for {
+		if _, err := s.UpdatePackages(repoDTO, ""); err != nil {
+			log.Printf("Webhook rescan of %s is failed: %s", repoDTO.Path, err.Message)
+		}
+
+		pushScans.Lock()
+		if !pushScans.pending[repoID] {
+			delete(pushScans.pending, repoID)
+			pushScans.Unlock()
+			return
+		}
+		pushScans.pending[repoID] = false
+		pushScans.Unlock()
+
+		latest, appErr := s.FindByID(repoID)
+		if appErr != nil {
+			log.Printf("Webhook rescan of %s is failed: %s", repoDTO.Path, appErr.Message)
+			pushScans.Lock()
+			delete(pushScans.pending, repoID)
+			pushScans.Unlock()
+			return
+		}
+		repoDTO = latest
 	}
+}
 
-	var wg sync.WaitGroup
-	for _, pkg := range packages {
-		wg.Add(1)
-		go func(pkg *entity.Package) {
-			defer wg.Done()
+func (s *repoService) RotateWebhookSecret(repoID string) (*entity.RepoDTO, *errors.AppError) {
 
-			// Creates new package manager that is consume api
-			m, err := managers.NewManager(pkg.File)
-			if err != nil {
-				return
-			}
+	secret, err := newSecret()
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
 
-			// Gets latest registry version
-			registryVersion, err := m.GetRegistryVersion(pkg.Name)
-			if err != nil {
-				return
-			}
+	repo, err := s.repository.UpdateWebhookSecret(repoID, secret)
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
 
-			// Compares semantic version of latest and current version
-			isOutdated := utils.CompareVersions(registryVersion, pkg.Version.Current)
+	return entity.ToRepoDTO(repo), nil
+}
 
-			if isOutdated {
-				pkg.Version.Last = registryVersion
-				pkg.IsOutdated = isOutdated
-			}
-		}(pkg)
+// Public badges must not reveal which repositories exist, so wrong tokens are not found too
+func (s *repoService) FindByBadgeToken(repoID string, token string) (*entity.RepoDTO, *errors.AppError) {
+
+	repo, err := s.repository.FindByID(repoID)
+	if err != nil || repo == nil || repo.BadgeToken == "" || subtle.ConstantTimeCompare([]byte(repo.BadgeToken), []byte(token)) != 1 {
+		return nil, errors.NotFound("Badge is not found")
 	}
-	wg.Wait()
 
-	repoDTO.PackageList = packages
-	updated, err := s.repository.UpdatePackages(entity.ToRepo(repoDTO))
+	return withIgnoreRules(entity.ToRepoDTO(repo)), nil
+}
+
+func (s *repoService) RotateBadgeToken(repoID string) (*entity.RepoDTO, *errors.AppError) {
+
+	token, err := newSecret()
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+
+	repo, err := s.repository.UpdateBadgeToken(repoID, token)
 	if err != nil {
 		return nil, errors.InternalServer(err.Error())
 	}
 
-	return entity.ToRepoDTO(updated), nil
+	return entity.ToRepoDTO(repo), nil
+}
+
+// Creates random hex secret for push webhooks and badge urls
+func newSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
 }
 
 func (s *repoService) Delete(repoID string) *errors.AppError {
@@ -294,16 +901,30 @@ func (s *repoService) Delete(repoID string) *errors.AppError {
 		return errors.InternalServer(err.Error())
 	}
 
+	if err := s.snapshots.DeleteMany(repoID); err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
 	return nil
 }
 
+// Repositories of organizations are kept with their snapshots
 func (s *repoService) DeleteMany(userID string) *errors.AppError {
 
-	err := s.repository.DeleteMany(userID)
-
+	repos, err := s.repository.FindAll(userID)
 	if err != nil {
 		return errors.InternalServer(err.Error())
 	}
 
+	for _, repo := range repos {
+		if err := s.snapshots.DeleteMany(repo.ID.Hex()); err != nil {
+			return errors.InternalServer(err.Error())
+		}
+	}
+
+	if err := s.repository.DeleteMany(userID); err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
 	return nil
 }
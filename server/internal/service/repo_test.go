@@ -0,0 +1,335 @@
+package service
+
+import (
+	"context"
+	"github.com/nozgurozturk/marvin/pkg/providers"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"github.com/nozgurozturk/marvin/server/internal/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Repository store that keeps repositories in memory
+type fakeRepoRepository struct {
+	storage.RepoRepository
+	sync.Mutex
+	repos   []*entity.Repo
+	updates int
+}
+
+func (r *fakeRepoRepository) FindByID(repoID string) (*entity.Repo, error) {
+	r.Lock()
+	defer r.Unlock()
+	for _, repo := range r.repos {
+		if repo.ID.Hex() == repoID {
+			found := *repo
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepoRepository) UpdatePackages(repo *entity.Repo) (*entity.Repo, error) {
+	r.Lock()
+	defer r.Unlock()
+	r.updates++
+	for i, stored := range r.repos {
+		if stored.ID == repo.ID {
+			r.repos[i] = repo
+		}
+	}
+	return repo, nil
+}
+
+func (r *fakeRepoRepository) FindAllByOrgID(orgID string) ([]*entity.Repo, error) {
+	var found []*entity.Repo
+	for _, repo := range r.repos {
+		if repo.OrgID != nil && repo.OrgID.Hex() == orgID {
+			found = append(found, repo)
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeRepoRepository) FindByUrlAndUserID(url string, userID string) (*entity.Repo, error) {
+	for _, repo := range r.repos {
+		if repo.Path == url && repo.UserID.Hex() == userID {
+			return repo, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepoRepository) Create(repo *entity.Repo) (*entity.Repo, error) {
+	repo.ID = primitive.NewObjectID()
+	r.repos = append(r.repos, repo)
+	return repo, nil
+}
+
+func TestFindAllByOrgIDOmitsSecretsForViewers(t *testing.T) {
+
+	orgID := primitive.NewObjectID()
+	repos := &fakeRepoRepository{repos: []*entity.Repo{
+		{ID: primitive.NewObjectID(), OrgID: &orgID, Name: "web", WebhookSecret: "webhook-secret", BadgeToken: "badge-token", Token: "token"},
+	}}
+
+	tests := []struct {
+		role        entity.Role
+		showSecrets bool
+	}{
+		{entity.RoleViewer, false},
+		{entity.RoleMaintainer, true},
+		{entity.RoleOwner, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role), func(t *testing.T) {
+			s := &repoService{repository: repos}
+
+			found, appErr := s.FindAllByOrgID(orgID.Hex(), tt.role)
+			if appErr != nil {
+				t.Fatal(appErr.Message)
+			}
+			if len(found) != 1 || found[0].Name != "web" {
+				t.Fatalf("expected repository of organization, got %v", found)
+			}
+
+			repo := found[0]
+			if shown := repo.WebhookSecret == "webhook-secret" && repo.BadgeToken == "badge-token"; shown != tt.showSecrets {
+				t.Errorf("expected secrets to be shown: %v, got webhook secret %q and badge token %q", tt.showSecrets, repo.WebhookSecret, repo.BadgeToken)
+			}
+			if !tt.showSecrets && repo.Token != "" {
+				t.Error("expected token to be omitted")
+			}
+		})
+	}
+
+	// Stored repositories keep their secrets
+	if repos.repos[0].WebhookSecret != "webhook-secret" {
+		t.Error("expected secrets of stored repository not to be changed")
+	}
+}
+
+// Waits until webhook rescans of repository are finished
+func waitPushScans(t *testing.T, repoID string) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		pushScans.Lock()
+		_, running := pushScans.pending[repoID]
+		pushScans.Unlock()
+		if !running {
+			return
+		}
+	}
+	t.Fatal("expected webhook rescans to finish")
+}
+
+func TestHandlePushCoalescesScansOfRepository(t *testing.T) {
+
+	setFakeRegistry(t, &fakeManager{}, nil)
+
+	started, release := make(chan bool, 1), make(chan bool)
+	var commits int
+	var commitsLock sync.Mutex
+	server := newFakeGithubRepository(t, func(w http.ResponseWriter, r *http.Request) {
+		commitsLock.Lock()
+		commits++
+		first := commits == 1
+		commitsLock.Unlock()
+		// First scan is running until pushes are received
+		if first {
+			started <- true
+			<-release
+		}
+		_, _ = w.Write([]byte(testBaseSHA))
+	})
+	setTestConfig(t, map[string]string{"GITHUB_API_URL": server.URL})
+
+	repo := &entity.Repo{ID: primitive.NewObjectID(), Path: "https://github.com/owner/name"}
+	repos := &fakeRepoRepository{repos: []*entity.Repo{repo}}
+	s := newTestScanService(newFakeJobRepository(), repos)
+	repoDTO := entity.ToRepoDTO(repo)
+	push := &entity.PushEvent{Ref: "main", DefaultBranch: "main", ChangedPaths: []string{"package.json"}, Complete: true}
+
+	if !s.HandlePush(repoDTO, push) {
+		t.Fatal("expected push to rescan repository")
+	}
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected first scan to request head commit")
+	}
+	for i := 0; i < 3; i++ {
+		if !s.HandlePush(repoDTO, push) {
+			t.Fatal("expected push during scan to be accepted")
+		}
+	}
+	close(release)
+
+	waitPushScans(t, repo.ID.Hex())
+
+	// Pushes during first scan share one more scan
+	repos.Lock()
+	defer repos.Unlock()
+	if repos.updates != 2 {
+		t.Errorf("expected 2 scans, got %d", repos.updates)
+	}
+}
+
+func TestHandlePushFiltersRefsAndPaths(t *testing.T) {
+
+	setFakeRegistry(t, &fakeManager{}, nil)
+
+	server := newFakeGithubRepository(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testBaseSHA))
+	})
+	setTestConfig(t, map[string]string{"GITHUB_API_URL": server.URL})
+
+	tests := []struct {
+		name    string
+		ref     string
+		event   *entity.PushEvent
+		rescans bool
+	}{
+		{"manifest on default branch", "", &entity.PushEvent{Ref: "main", DefaultBranch: "main", ChangedPaths: []string{"README.md", "web/package.json"}, Complete: true}, true},
+		{"lock file on tracked branch", "release", &entity.PushEvent{Ref: "release", DefaultBranch: "main", ChangedPaths: []string{"composer.lock"}, Complete: true}, true},
+		{"other branch", "", &entity.PushEvent{Ref: "feature", DefaultBranch: "main", ChangedPaths: []string{"package.json"}, Complete: true}, false},
+		{"default branch of repository that tracks other branch", "release", &entity.PushEvent{Ref: "main", DefaultBranch: "main", ChangedPaths: []string{"package.json"}, Complete: true}, false},
+		{"deleted branch without ref", "", &entity.PushEvent{DefaultBranch: "main", Complete: false}, false},
+		{"non manifest paths", "", &entity.PushEvent{Ref: "main", DefaultBranch: "main", ChangedPaths: []string{"README.md", "src/package.js"}, Complete: true}, false},
+		{"workspace descriptor", "", &entity.PushEvent{Ref: "main", DefaultBranch: "main", ChangedPaths: []string{"lerna.json"}, Complete: true}, true},
+		{"incomplete payload", "", &entity.PushEvent{Ref: "main", DefaultBranch: "main", ChangedPaths: []string{"README.md"}, Complete: false}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &entity.Repo{ID: primitive.NewObjectID(), Path: "https://github.com/owner/name", Ref: tt.ref}
+			repos := &fakeRepoRepository{repos: []*entity.Repo{repo}}
+			s := newTestScanService(newFakeJobRepository(), repos)
+
+			if rescans := s.HandlePush(entity.ToRepoDTO(repo), tt.event); rescans != tt.rescans {
+				t.Errorf("expected rescan: %v, got %v", tt.rescans, rescans)
+			}
+			waitPushScans(t, repo.ID.Hex())
+		})
+	}
+}
+
+func TestParseManifestsSkipsInvalidFiles(t *testing.T) {
+
+	packageFiles := map[string]interface{}{
+		"package.json": map[string]interface{}{
+			"dependencies": map[string]interface{}{"react": "^17.0.1"},
+		},
+		"web/package.json": []interface{}{"react"},
+	}
+
+	failures := &failureCollector{failures: []*entity.ScanFailure{}}
+	packages, err := parseManifests(packageFiles, failures)
+	if err != nil {
+		t.Fatal(err.Message)
+	}
+
+	if len(packages) != 1 || packages[0].Name != "react" || packages[0].Path != "package.json" {
+		t.Errorf("expected packages of valid file, got %v", packages)
+	}
+	if len(failures.failures) != 1 || failures.failures[0].Path != "web/package.json" || failures.failures[0].Name != "package.json" {
+		t.Errorf("expected failure of invalid file, got %v", failures.failures)
+	}
+}
+
+// Provider of repository with a package file at root, requests of tree and files are recorded
+type fakeProvider struct {
+	providers.Provider
+	headSHA  string
+	blobSHA  string
+	requests []string
+}
+
+func (p *fakeProvider) GetHeadSHA(ctx context.Context, owner string, name string, ref string) (string, error) {
+	return p.headSHA, nil
+}
+
+func (p *fakeProvider) GetRepositoryTree(ctx context.Context, owner string, name string, ref string) ([]map[string]interface{}, error) {
+	p.requests = append(p.requests, "tree "+ref)
+	return []map[string]interface{}{{"name": "package.json", "path": "package.json", "sha": p.blobSHA}}, nil
+}
+
+func (p *fakeProvider) FindPackagesInfo(tree []map[string]interface{}) []map[string]interface{} {
+	return tree
+}
+
+func (p *fakeProvider) GetPackageFiles(ctx context.Context, files []map[string]interface{}) (map[string]interface{}, error) {
+	p.requests = append(p.requests, "files")
+	return map[string]interface{}{
+		"package.json": map[string]interface{}{"dependencies": map[string]interface{}{"vue": "^3.0.0"}},
+	}, nil
+}
+
+func TestScanRepositorySkipsUnchangedCommit(t *testing.T) {
+
+	setFakeRegistry(t, &fakeManager{versions: map[string]string{"react": "17.0.1", "vue": "3.0.0"}}, nil)
+
+	const changedSHA = "1123456789abcdef0123456789abcdef01234567"
+
+	previous := func() *entity.RepoDTO {
+		return &entity.RepoDTO{
+			CommitSHA:     testBaseSHA,
+			ManifestBlobs: []*entity.ManifestBlob{{Path: "package.json", SHA: "a"}},
+			PackageList:   []*entity.Package{{Name: "react", Path: "package.json", File: "package.json", Version: entity.PackageVersion{Current: "^16.8.0"}}},
+		}
+	}
+
+	tests := []struct {
+		name            string
+		previous        *entity.RepoDTO
+		headSHA         string
+		blobSHA         string
+		requests        []string
+		packageName     string
+		manifestChanged bool
+	}{
+		{"unchanged commit", previous(), testBaseSHA, "a", nil, "react", false},
+		{"commit does not change package file", previous(), changedSHA, "a", []string{"tree " + changedSHA}, "react", false},
+		{"commit changes package file", previous(), changedSHA, "b", []string{"tree " + changedSHA, "files"}, "vue", true},
+		{"first scan", nil, testBaseSHA, "a", []string{"tree " + testBaseSHA, "files"}, "vue", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &fakeProvider{headSHA: tt.headSHA, blobSHA: tt.blobSHA}
+
+			output, err := scanRepository(context.Background(), p, "owner", "name", "", tt.previous, noopObserver{})
+			if err != nil {
+				t.Fatal(err.Message)
+			}
+
+			if !reflect.DeepEqual(p.requests, tt.requests) {
+				t.Errorf("expected requests %v, got %v", tt.requests, p.requests)
+			}
+			if output.commitSHA != tt.headSHA {
+				t.Errorf("expected commit %s, got %s", tt.headSHA, output.commitSHA)
+			}
+			if len(output.blobs) != 1 || output.blobs[0].SHA != tt.blobSHA {
+				t.Errorf("expected blob %s, got %v", tt.blobSHA, output.blobs)
+			}
+			if len(output.packages) != 1 || output.packages[0].Name != tt.packageName {
+				t.Fatalf("expected %s package, got %v", tt.packageName, output.packages)
+			}
+			if output.result.ManifestChanged != tt.manifestChanged {
+				t.Errorf("expected manifest changed %v, got %v", tt.manifestChanged, output.result.ManifestChanged)
+			}
+			// Versions of previous packages are resolved again
+			if pkg := output.packages[0]; pkg.Name == "react" && (!pkg.IsOutdated || pkg.Version.Last != "17.0.1") {
+				t.Errorf("expected registry version of unchanged package, got %+v", pkg)
+			}
+			if tt.previous != nil && tt.previous.PackageList[0].Version.Last != "" {
+				t.Error("expected packages of previous scan not to be changed")
+			}
+		})
+	}
+}
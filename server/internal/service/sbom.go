@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"github.com/nozgurozturk/marvin/pkg/errors"
+	"github.com/nozgurozturk/marvin/pkg/parsers"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"sync"
+)
+
+/*
+	1. Parse SBOM -> packages of supported ecosystems
+	2. Get Each Package Version
+	3. Compare Versions
+*/
+func (s *repoService) ScanSBOM(file map[string]interface{}) (*entity.SBOMScanDTO, *errors.AppError) {
+
+	sbomPackages, unsupported, err := parsers.ParseSBOM(file)
+	if err != nil {
+		return nil, errors.BadRequest(err.Error())
+	}
+
+	packages := []*entity.Package{}
+	for fileName, rawPackages := range sbomPackages {
+		packages = append(packages, entity.ToPackageDTOs(rawPackages, fileName, "")...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), scanTimeout)
+	defer cancel()
+
+	failures := &failureCollector{failures: []*entity.ScanFailure{}}
+	resolveRegistryVersions(ctx, packages, failures)
+
+	if err := ctx.Err(); err != nil {
+		return nil, providerError(err)
+	}
+
+	if unsupported == nil {
+		unsupported = []string{}
+	}
+
+	return &entity.SBOMScanDTO{
+		Packages:    packages,
+		Unsupported: unsupported,
+		Failures:    failures.failures,
+	}, nil
+}
+
+// Keeps packages whose registry versions can not be resolved
+type failureCollector struct {
+	noopObserver
+	sync.Mutex
+	failures []*entity.ScanFailure
+}
+
+func (c *failureCollector) packageResolved(pkg *entity.Package, err error) {
+	if err == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.failures = append(c.failures, &entity.ScanFailure{
+		Name:    pkg.Name,
+		Path:    pkg.Path,
+		Message: err.Error(),
+	})
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"encoding/json"
+	"github.com/nozgurozturk/marvin/pkg/advisories"
+	"sort"
+	"testing"
+)
+
+const testCycloneDX = `{
+	"bomFormat": "CycloneDX",
+	"specVersion": "1.5",
+	"metadata": {"component": {"type": "application", "name": "owner/name"}},
+	"components": [
+		{"type": "library", "name": "react", "version": "16.8.0", "purl": "pkg:npm/react@16.8.0"},
+		{"type": "library", "name": "vue", "version": "3.0.0", "purl": "pkg:npm/vue@3.0.0"},
+		{"type": "library", "name": "left-pad", "version": "1.0.0", "purl": "pkg:npm/left-pad@1.0.0"},
+		{"type": "library", "name": "gin", "version": "v1.6.3", "purl": "pkg:golang/github.com/gin-gonic/gin@v1.6.3"}
+	]
+}`
+
+func TestScanSBOM(t *testing.T) {
+
+	setFakeRegistry(t, &fakeManager{versions: map[string]string{"react": "17.0.1", "vue": "3.0.0"}}, map[string][]*advisories.Advisory{
+		"react": {{ID: "GHSA-1", Severity: advisories.High, Summary: "XSS"}},
+	})
+
+	var file map[string]interface{}
+	if err := json.Unmarshal([]byte(testCycloneDX), &file); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &repoService{}
+	scan, appErr := s.ScanSBOM(file)
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+
+	sort.Slice(scan.Packages, func(i, j int) bool {
+		return scan.Packages[i].Name < scan.Packages[j].Name
+	})
+	var results []string
+	for _, pkg := range scan.Packages {
+		result := pkg.Name + " " + pkg.Version.Current
+		if pkg.IsOutdated {
+			result += " -> " + pkg.Version.Last
+		}
+		for _, advisory := range pkg.Advisories {
+			result += " " + advisory.ID
+		}
+		results = append(results, result)
+	}
+	expected := []string{"left-pad 1.0.0", "react 16.8.0 -> 17.0.1 GHSA-1", "vue 3.0.0"}
+	if len(results) != len(expected) {
+		t.Fatalf("expected packages %v, got %v", expected, results)
+	}
+	for i := range expected {
+		if results[i] != expected[i] {
+			t.Errorf("expected package %q, got %q", expected[i], results[i])
+		}
+	}
+
+	if len(scan.Unsupported) != 1 || scan.Unsupported[0] != "pkg:golang/github.com/gin-gonic/gin@v1.6.3" {
+		t.Errorf("expected golang component to be unsupported, got %v", scan.Unsupported)
+	}
+	// Packages that are not found in registry are reported, they do not fail scan
+	if len(scan.Failures) != 1 || scan.Failures[0].Name != "left-pad" {
+		t.Errorf("expected failure of left-pad, got %v", scan.Failures)
+	}
+}
+
+func TestScanSBOMOfUnknownFormat(t *testing.T) {
+
+	s := &repoService{}
+	if _, appErr := s.ScanSBOM(map[string]interface{}{"dependencies": map[string]interface{}{}}); appErr == nil {
+		t.Error("expected error of file that is not an SBOM")
+	}
+}
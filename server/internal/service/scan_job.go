@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/nozgurozturk/marvin/pkg/errors"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"github.com/nozgurozturk/marvin/server/internal/storage"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// Finished jobs can be read for a day
+	scanJobExpire = 24 * time.Hour
+	// Progress is saved at most once in interval, status changes are saved immediately
+	progressSaveInterval = 500 * time.Millisecond
+	// Cancellations of other instances are checked in interval
+	cancelPollInterval = time.Second
+)
+
+// Cancel functions of jobs that run in this instance
+var runningJobs sync.Map
+
+// Receives events of running scan
+type scanObserver interface {
+	manifestsFound(paths []string)
+	packagesFound(packages []*entity.Package)
+	packageResolved(pkg *entity.Package, err error)
+}
+
+// Synchronous scans do not report progress
+type noopObserver struct{}
+
+func (noopObserver) manifestsFound(paths []string)                  {}
+func (noopObserver) packagesFound(packages []*entity.Package)       {}
+func (noopObserver) packageResolved(pkg *entity.Package, err error) {}
+
+/*
+	1. Validate Url -> provider, owner and name are resolved before job is queued
+	2. Save Job -> queued
+	3. Run Job -> in background, request is not blocked
+*/
+func (s *repoService) Create(rawUrl string, ref string, orgID string, userID string, saveToken bool) (*entity.ScanJobDTO, *errors.AppError) {
+
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, errors.BadRequest("Invalid repository url")
+	}
+
+	// Token is not needed to resolve url, scan gets token of user
+	p, err := newProvider(u, "")
+	if err != nil {
+		return nil, errors.BadRequest(err.Error())
+	}
+	if _, _, _, err := p.UrlResolver(); err != nil {
+		return nil, errors.BadRequest("Invalid repository url, " + err.Error())
+	}
+
+	now := time.Now().UTC()
+	job := &entity.ScanJob{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		OrgID:     orgID,
+		Url:       rawUrl,
+		Ref:       ref,
+		SaveToken: saveToken,
+		Status:    entity.JobQueued,
+		Progress: &entity.ScanProgress{
+			Failures: []*entity.ScanFailure{},
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := s.jobs.Save(job, scanJobExpire); err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+
+	go s.runScanJob(job)
+
+	return entity.ToScanJobDTO(job), nil
+}
+
+func (s *repoService) FindScanJob(jobID string) (*entity.ScanJob, *errors.AppError) {
+	job, err := s.jobs.FindByID(jobID)
+	if err != nil {
+		return nil, errors.NotFound("Scan job is not found")
+	}
+	return job, nil
+}
+
+// Subscribes events of scan job, current job is returned for events that are published before subscription
+func (s *repoService) SubscribeScanJob(jobID string) (*entity.ScanJob, <-chan *entity.ScanEvent, func(), *errors.AppError) {
+
+	events, closeEvents, err := s.jobs.Subscribe(jobID)
+	if err != nil {
+		return nil, nil, nil, errors.InternalServer(err.Error())
+	}
+
+	job, appErr := s.FindScanJob(jobID)
+	if appErr != nil {
+		closeEvents()
+		return nil, nil, nil, appErr
+	}
+
+	return job, events, closeEvents, nil
+}
+
+func (s *repoService) CancelScanJob(jobID string) (*entity.ScanJob, *errors.AppError) {
+
+	job, appErr := s.FindScanJob(jobID)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	if job.IsFinished() {
+		return nil, errors.BadRequest("Scan job is already finished")
+	}
+
+	if err := s.jobs.RequestCancel(jobID, scanJobExpire); err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+
+	// Job of this instance is stopped without waiting for poll
+	if cancel, ok := runningJobs.Load(jobID); ok {
+		cancel.(context.CancelFunc)()
+	}
+
+	return job, nil
+}
+
+func (s *repoService) runScanJob(job *entity.ScanJob) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), scanTimeout)
+	defer cancel()
+
+	runningJobs.Store(job.ID, cancel)
+	defer runningJobs.Delete(job.ID)
+
+	go s.watchCancel(ctx, job.ID, cancel)
+
+	tracker := &jobTracker{jobs: s.jobs, job: job}
+
+	// Nothing recovers panics of background jobs, job is failed instead of crashing server
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Scan job %s panicked: %v", job.ID, r)
+			tracker.update(true, entity.ScanEventStatus, func(job *entity.ScanJob, event *entity.ScanEvent) {
+				job.Status = entity.JobFailed
+				job.Error = "Scan is failed unexpectedly"
+			})
+		}
+	}()
+	tracker.update(true, entity.ScanEventStatus, func(job *entity.ScanJob, event *entity.ScanEvent) {
+		job.Status = entity.JobRunning
+	})
+
+	repo, appErr := s.create(ctx, job.Url, job.Ref, job.OrgID, job.UserID, job.SaveToken, tracker)
+
+	tracker.update(true, entity.ScanEventStatus, func(job *entity.ScanJob, event *entity.ScanEvent) {
+		switch {
+		case repo != nil:
+			job.Status = entity.JobCompleted
+			job.RepoID = *repo.ID
+		case ctx.Err() == context.Canceled:
+			job.Status = entity.JobCancelled
+		default:
+			job.Status = entity.JobFailed
+			job.Error = appErr.Message
+		}
+	})
+}
+
+// Cancels job when another instance requests cancellation
+func (s *repoService) watchCancel(ctx context.Context, jobID string, cancel context.CancelFunc) {
+
+	ticker := time.NewTicker(cancelPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if requested, _ := s.jobs.IsCancelRequested(jobID); requested {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// Saves scan events as job progress and publishes them to subscribers
+// Registry versions are resolved concurrently, updates are serialized
+type jobTracker struct {
+	sync.Mutex
+	jobs    storage.JobRepository
+	job     *entity.ScanJob
+	savedAt time.Time
+}
+
+func (t *jobTracker) manifestsFound(paths []string) {
+	t.update(false, entity.ScanEventManifests, func(job *entity.ScanJob, event *entity.ScanEvent) {
+		job.Progress.ManifestsFound = len(paths)
+		event.Manifests = paths
+	})
+}
+
+func (t *jobTracker) packagesFound(packages []*entity.Package) {
+	t.update(false, entity.ScanEventStatus, func(job *entity.ScanJob, event *entity.ScanEvent) {
+		job.Progress.PackagesFound = len(packages)
+	})
+}
+
+func (t *jobTracker) packageResolved(pkg *entity.Package, err error) {
+	eventType := entity.ScanEventPackage
+	if err != nil {
+		eventType = entity.ScanEventFailure
+	}
+
+	t.update(false, eventType, func(job *entity.ScanJob, event *entity.ScanEvent) {
+		if err != nil {
+			event.Failure = &entity.ScanFailure{
+				Name:    pkg.Name,
+				Path:    pkg.Path,
+				Message: err.Error(),
+			}
+			job.Progress.Failures = append(job.Progress.Failures, event.Failure)
+			return
+		}
+		event.Package = pkg
+		job.Progress.PackagesResolved++
+	})
+}
+
+func (t *jobTracker) update(force bool, eventType string, change func(job *entity.ScanJob, event *entity.ScanEvent)) {
+	t.Lock()
+	defer t.Unlock()
+
+	event := &entity.ScanEvent{Type: eventType}
+	change(t.job, event)
+	t.job.UpdatedAt = time.Now().UTC()
+
+	// Job is saved before event, so subscribers that read job after subscription do not miss finished status
+	if force || time.Since(t.savedAt) >= progressSaveInterval {
+		if err := t.jobs.Save(t.job, scanJobExpire); err != nil {
+			log.Printf("Scan job %s is not saved: %s", t.job.ID, err)
+		} else {
+			t.savedAt = time.Now()
+		}
+	}
+
+	// Event is encoded before next change, progress of job is shared
+	event.Job = entity.ToScanJobDTO(t.job)
+	if err := t.jobs.Publish(t.job.ID, event); err != nil {
+		log.Printf("Event of scan job %s is not published: %s", t.job.ID, err)
+	}
+}
+
+// Gets paths of package files and workspace descriptors
+func manifestPaths(blobs []*entity.ManifestBlob) []string {
+	paths := make([]string, 0, len(blobs))
+	for _, blob := range blobs {
+		paths = append(paths, blob.Path)
+	}
+	return paths
+}
@@ -0,0 +1,365 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/nozgurozturk/marvin/pkg/advisories"
+	"github.com/nozgurozturk/marvin/pkg/managers"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"github.com/nozgurozturk/marvin/server/internal/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Job store that keeps copies of saved jobs, saves and publishes are recorded in order
+type fakeJobRepository struct {
+	storage.JobRepository
+	sync.Mutex
+	saved  map[string]*entity.ScanJob
+	log    []string
+	events []*entity.ScanEvent
+	// Status of saved job when event is published, subscribers read it after subscription
+	savedOnPublish []string
+	cancelled      map[string]bool
+}
+
+func newFakeJobRepository() *fakeJobRepository {
+	return &fakeJobRepository{saved: map[string]*entity.ScanJob{}, cancelled: map[string]bool{}}
+}
+
+func (r *fakeJobRepository) Save(job *entity.ScanJob, expires time.Duration) error {
+	r.Lock()
+	defer r.Unlock()
+
+	saved := *job
+	progress := *job.Progress
+	saved.Progress = &progress
+	r.saved[job.ID] = &saved
+	r.log = append(r.log, "save "+job.Status)
+	return nil
+}
+
+func (r *fakeJobRepository) FindByID(jobID string) (*entity.ScanJob, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	job, ok := r.saved[jobID]
+	if !ok {
+		return nil, fmt.Errorf("job %s is not found", jobID)
+	}
+	found := *job
+	return &found, nil
+}
+
+func (r *fakeJobRepository) Publish(jobID string, event *entity.ScanEvent) error {
+	r.Lock()
+	defer r.Unlock()
+
+	savedStatus := ""
+	if job, ok := r.saved[jobID]; ok {
+		savedStatus = job.Status
+	}
+	r.events = append(r.events, event)
+	r.savedOnPublish = append(r.savedOnPublish, savedStatus)
+	r.log = append(r.log, "publish "+event.Type+" "+event.Job.Status)
+	return nil
+}
+
+func (r *fakeJobRepository) RequestCancel(jobID string, expires time.Duration) error {
+	r.Lock()
+	defer r.Unlock()
+	r.cancelled[jobID] = true
+	return nil
+}
+
+func (r *fakeJobRepository) IsCancelRequested(jobID string) (bool, error) {
+	r.Lock()
+	defer r.Unlock()
+	return r.cancelled[jobID], nil
+}
+
+// Snapshot store that accepts every snapshot
+type fakeSnapshotRepository struct {
+	storage.SnapshotRepository
+}
+
+func (r *fakeSnapshotRepository) Create(snapshot *entity.Snapshot) (*entity.Snapshot, error) {
+	return snapshot, nil
+}
+
+func (r *fakeSnapshotRepository) DeleteExpired(repoID string, keep int64, before time.Time) error {
+	return nil
+}
+
+// Registry with latest versions, other packages are not found
+type fakeManager struct {
+	versions   map[string]string
+	deprecated map[string]string
+}
+
+func (m *fakeManager) GetRegistryVersion(ctx context.Context, registryName string) (string, error) {
+	version, ok := m.versions[registryName]
+	if !ok {
+		return "", fmt.Errorf("%s is not found in registry", registryName)
+	}
+	return version, nil
+}
+
+func (m *fakeManager) GetChangelogUrl(ctx context.Context, registryName string) (string, error) {
+	return "", nil
+}
+
+func (m *fakeManager) GetReleaseInfo(ctx context.Context, registryName string, version string, latest string) (*managers.ReleaseInfo, error) {
+	return &managers.ReleaseInfo{Deprecated: m.deprecated[registryName]}, nil
+}
+
+// Replaces registries and OSV until test is finished
+func setFakeRegistry(t *testing.T, m managers.Manager, found map[string][]*advisories.Advisory) {
+	newManager = func(fileName string) (managers.Manager, error) { return m, nil }
+	queryAdvisories = func(ctx context.Context, fileName string, name string, version string) ([]*advisories.Advisory, error) {
+		return found[name], nil
+	}
+	t.Cleanup(func() {
+		newManager = managers.NewManager
+		queryAdvisories = advisories.Query
+	})
+}
+
+// Fake GitHub api of repository with a package file at root, commit handler responds for every ref
+func newFakeGithubRepository(t *testing.T, commit http.HandlerFunc) *httptest.Server {
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/name/commits/HEAD", "/repos/owner/name/commits/release":
+			commit(w, r)
+		case "/repos/owner/name/git/trees/" + testBaseSHA:
+			fmt.Fprintf(w, `{"sha": %q, "tree": [{"path": "package.json", "type": "blob", "sha": "a", "url": "%s/repos/owner/name/git/blobs/a"}]}`, testBaseSHA, server.URL)
+		case "/repos/owner/name/git/blobs/a":
+			_, _ = w.Write([]byte(`{"dependencies": {"react": "^16.8.0", "left-pad": "1.0.0"}}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func newTestScanJob() *entity.ScanJob {
+	return &entity.ScanJob{
+		ID:       "job",
+		UserID:   primitive.NewObjectID().Hex(),
+		Url:      "https://github.com/owner/name",
+		Status:   entity.JobQueued,
+		Progress: &entity.ScanProgress{Failures: []*entity.ScanFailure{}},
+	}
+}
+
+func newTestScanService(jobs *fakeJobRepository, repos *fakeRepoRepository) *repoService {
+	return &repoService{repository: repos, users: &fakeUserRepository{}, jobs: jobs, snapshots: &fakeSnapshotRepository{}}
+}
+
+// Status events are published after their job is saved, so subscribers that read job after subscription see the status
+func checkSavedBeforePublish(t *testing.T, jobs *fakeJobRepository) {
+	t.Helper()
+	for i, event := range jobs.events {
+		if event.Type == entity.ScanEventStatus && jobs.savedOnPublish[i] != event.Job.Status {
+			t.Errorf("event %d with %s status is published before job is saved, saved status is %q", i, event.Job.Status, jobs.savedOnPublish[i])
+		}
+	}
+}
+
+func TestRunScanJobCompleted(t *testing.T) {
+
+	setFakeRegistry(t, &fakeManager{
+		versions:   map[string]string{"react": "17.0.1"},
+		deprecated: map[string]string{"react": "use react 17"},
+	}, map[string][]*advisories.Advisory{
+		"react": {{ID: "GHSA-1", Severity: advisories.High, Summary: "XSS"}},
+	})
+
+	server := newFakeGithubRepository(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testBaseSHA))
+	})
+	setTestConfig(t, map[string]string{"GITHUB_API_URL": server.URL})
+
+	jobs := newFakeJobRepository()
+	repos := &fakeRepoRepository{}
+	job := newTestScanJob()
+
+	newTestScanService(jobs, repos).runScanJob(job)
+
+	saved, _ := jobs.FindByID(job.ID)
+	if saved.Status != entity.JobCompleted || saved.Error != "" {
+		t.Fatalf("expected completed job, got %s: %s", saved.Status, saved.Error)
+	}
+	if len(repos.repos) != 1 || saved.RepoID != repos.repos[0].ID.Hex() {
+		t.Fatalf("expected job to point created repository, got %s", saved.RepoID)
+	}
+
+	progress := saved.Progress
+	if progress.ManifestsFound != 1 || progress.PackagesFound != 2 || progress.PackagesResolved != 1 || len(progress.Failures) != 1 {
+		t.Fatalf("unexpected progress %+v", progress)
+	}
+	if progress.Failures[0].Name != "left-pad" {
+		t.Errorf("unexpected failure %+v", progress.Failures[0])
+	}
+
+	// Running is the first and completed is the last saved status
+	first, last := jobs.log[:2], jobs.log[len(jobs.log)-2:]
+	if !reflect.DeepEqual(first, []string{"save running", "publish status running"}) {
+		t.Errorf("unexpected first changes %v", first)
+	}
+	if !reflect.DeepEqual(last, []string{"save completed", "publish status completed"}) {
+		t.Errorf("unexpected last changes %v", last)
+	}
+	checkSavedBeforePublish(t, jobs)
+
+	types := map[string]bool{}
+	for _, event := range jobs.events {
+		types[event.Type] = true
+	}
+	for _, eventType := range []string{entity.ScanEventManifests, entity.ScanEventPackage, entity.ScanEventFailure} {
+		if !types[eventType] {
+			t.Errorf("expected %s event, got %v", eventType, jobs.log)
+		}
+	}
+
+	// Progress events are not saved one by one
+	saves := 0
+	for _, change := range jobs.log {
+		if strings.HasPrefix(change, "save ") {
+			saves++
+		}
+	}
+	if saves >= len(jobs.events) {
+		t.Errorf("expected progress saves to be throttled, got %d saves of %d events", saves, len(jobs.events))
+	}
+
+	var react *entity.Package
+	for _, pkg := range repos.repos[0].PackageList {
+		if pkg.Name == "react" {
+			react = pkg
+		}
+	}
+	if react == nil || !react.IsOutdated || react.Deprecated != "use react 17" || len(react.Advisories) != 1 {
+		t.Errorf("unexpected resolved package %+v", react)
+	}
+}
+
+func TestRunScanJobFailed(t *testing.T) {
+
+	setFakeRegistry(t, &fakeManager{}, nil)
+
+	server := newFakeGithubRepository(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message": "Not Found"}`))
+	})
+	setTestConfig(t, map[string]string{"GITHUB_API_URL": server.URL})
+
+	jobs := newFakeJobRepository()
+	repos := &fakeRepoRepository{}
+	job := newTestScanJob()
+
+	newTestScanService(jobs, repos).runScanJob(job)
+
+	expected := []string{"save running", "publish status running", "save failed", "publish status failed"}
+	if !reflect.DeepEqual(jobs.log, expected) {
+		t.Errorf("expected %v, got %v", expected, jobs.log)
+	}
+	checkSavedBeforePublish(t, jobs)
+
+	saved, _ := jobs.FindByID(job.ID)
+	if saved.Error != "Repository is not found in provider" || saved.RepoID != "" {
+		t.Errorf("unexpected failed job %+v", saved)
+	}
+	if len(repos.repos) != 0 {
+		t.Error("expected repository not to be created")
+	}
+}
+
+func TestRunScanJobCancelled(t *testing.T) {
+
+	setFakeRegistry(t, &fakeManager{}, nil)
+
+	jobs := newFakeJobRepository()
+	repos := &fakeRepoRepository{}
+	job := newTestScanJob()
+	s := newTestScanService(jobs, repos)
+
+	// Job is cancelled while provider responds
+	server := newFakeGithubRepository(t, func(w http.ResponseWriter, r *http.Request) {
+		if _, appErr := s.CancelScanJob(job.ID); appErr != nil {
+			t.Errorf("expected running job to be cancelled, got %s", appErr.Message)
+		}
+		<-r.Context().Done()
+	})
+	setTestConfig(t, map[string]string{"GITHUB_API_URL": server.URL})
+
+	s.runScanJob(job)
+
+	expected := []string{"save running", "publish status running", "save cancelled", "publish status cancelled"}
+	if !reflect.DeepEqual(jobs.log, expected) {
+		t.Errorf("expected %v, got %v", expected, jobs.log)
+	}
+	checkSavedBeforePublish(t, jobs)
+
+	if _, running := runningJobs.Load(job.ID); running {
+		t.Error("expected finished job to be removed from running jobs")
+	}
+	if _, appErr := s.CancelScanJob(job.ID); appErr == nil || appErr.Status != http.StatusBadRequest {
+		t.Errorf("expected finished job not to be cancelled, got %v", appErr)
+	}
+}
+
+func TestCreateRejectsInvalidUrls(t *testing.T) {
+
+	jobs := newFakeJobRepository()
+	s := newTestScanService(jobs, &fakeRepoRepository{})
+
+	for _, rawUrl := range []string{"https://github.com/owner", "https://gitlab.com/-/tree/main", "https://example.com/owner/name"} {
+		t.Run(rawUrl, func(t *testing.T) {
+			if _, appErr := s.Create(rawUrl, "", "", primitive.NewObjectID().Hex(), false); appErr == nil || appErr.Status != http.StatusBadRequest {
+				t.Errorf("expected bad request, got %v", appErr)
+			}
+		})
+	}
+
+	if len(jobs.log) != 0 {
+		t.Errorf("expected invalid urls not to be queued, got %v", jobs.log)
+	}
+}
+
+func TestRunScanJobRecoversPanic(t *testing.T) {
+
+	setFakeRegistry(t, &fakeManager{}, nil)
+
+	server := newFakeGithubRepository(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testBaseSHA))
+	})
+	setTestConfig(t, map[string]string{"GITHUB_API_URL": server.URL})
+
+	jobs := newFakeJobRepository()
+	job := newTestScanJob()
+
+	// Store without methods panics after scan, panic is recovered as failed job
+	s := newTestScanService(jobs, nil)
+	s.repository = struct{ storage.RepoRepository }{}
+	s.runScanJob(job)
+
+	saved, _ := jobs.FindByID(job.ID)
+	if saved.Status != entity.JobFailed || saved.Error == "" {
+		t.Errorf("expected failed job, got %s: %s", saved.Status, saved.Error)
+	}
+	if _, running := runningJobs.Load(job.ID); running {
+		t.Error("expected panicked job to be removed from running jobs")
+	}
+}
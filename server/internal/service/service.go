@@ -7,6 +7,9 @@ type Service interface {
 	User() UserService
 	Repo() RepoService
 	Subscriber() SubscriberService
+	Organization() OrganizationService
+	APIToken() APITokenService
+	RateLimit() RateLimitService
 }
 
 type service struct {
@@ -14,14 +17,20 @@ type service struct {
 	user       UserService
 	repo       RepoService
 	subscriber SubscriberService
+	org        OrganizationService
+	apiToken   APITokenService
+	rateLimit  RateLimitService
 }
 
 func New(s storage.Store) *service {
 	return &service{
 		auth:       NewAuthService(s.Auths()),
 		user:       NewUserService(s.Users()),
-		repo:       NewRepoService(s.Repos()),
+		repo:       NewRepoService(s.Repos(), s.Users(), s.Jobs(), s.Snapshots(), s.Organizations()),
 		subscriber: NewSubscriberService(s.Subscribers()),
+		org:        NewOrganizationService(s.Organizations(), s.Users(), s.Repos()),
+		apiToken:   NewAPITokenService(s.APITokens()),
+		rateLimit:  NewRateLimitService(s.Limits()),
 	}
 }
 
@@ -40,3 +49,15 @@ func (s *service) Repo() RepoService {
 func (s *service) Subscriber() SubscriberService {
 	return s.subscriber
 }
+
+func (s *service) Organization() OrganizationService {
+	return s.org
+}
+
+func (s *service) APIToken() APITokenService {
+	return s.apiToken
+}
+
+func (s *service) RateLimit() RateLimitService {
+	return s.rateLimit
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"github.com/nozgurozturk/marvin/pkg/errors"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"sort"
+	"time"
+)
+
+// Last activity of sessions is written at most once a minute
+const sessionActivityPeriod = time.Minute
+
+// Devices are shown with user agent, long agents are cut
+const maxDeviceLength = 256
+
+func (s *authService) CreateSession(tokens *entity.TokenDetails, client *entity.SessionClient) *errors.AppError {
+
+	refreshToken := tokens.RefreshToken
+	now := time.Now().UTC()
+
+	session := &entity.Session{
+		ID:         refreshToken.SessionID,
+		UserID:     refreshToken.UserID,
+		TokenID:    refreshToken.Uuid,
+		Device:     sessionDevice(client.Device),
+		IP:         client.IP,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  time.Unix(refreshToken.Expires, 0).UTC(),
+	}
+
+	if err := s.repository.CreateSession(session); err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	return nil
+}
+
+func (s *authService) FindSession(sessionID string) (*entity.Session, *errors.AppError) {
+
+	session, err := s.repository.FindSession(sessionID)
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+	if session == nil {
+		return nil, errors.Unauthorized("Session is expired or revoked, please login again")
+	}
+
+	return session, nil
+}
+
+/*
+	1. Refresh token must be latest token of session
+	2. Older tokens are already rotated, someone else could have them -> whole session is revoked
+	3. Session keeps its id, so client can refresh again with new token
+*/
+func (s *authService) RotateSession(session *entity.Session, tokenID string, tokens *entity.TokenDetails, client *entity.SessionClient) *errors.AppError {
+
+	reusedErr := errors.Unauthorized("Refresh token is already used, session is revoked. Please login again")
+
+	if session.TokenID != tokenID {
+		if err := s.repository.DeleteSession(session); err != nil {
+			return errors.InternalServer(err.Error())
+		}
+		return reusedErr
+	}
+
+	rotated := *session
+	rotated.TokenID = tokens.RefreshToken.Uuid
+	rotated.Device = sessionDevice(client.Device)
+	rotated.IP = client.IP
+	rotated.LastSeenAt = time.Now().UTC()
+	rotated.ExpiresAt = time.Unix(tokens.RefreshToken.Expires, 0).UTC()
+
+	ok, err := s.repository.RotateSession(&rotated, tokenID)
+	if err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	// Token is rotated by a concurrent refresh
+	if !ok {
+		current, err := s.repository.FindSession(session.ID)
+		if err != nil {
+			return errors.InternalServer(err.Error())
+		}
+		if current != nil {
+			if err := s.repository.DeleteSession(current); err != nil {
+				return errors.InternalServer(err.Error())
+			}
+		}
+		return reusedErr
+	}
+
+	return nil
+}
+
+func (s *authService) TouchSession(sessionID string, ip string) *errors.AppError {
+
+	session, err := s.repository.FindSession(sessionID)
+	if err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	now := time.Now().UTC()
+	if session == nil || (now.Sub(session.LastSeenAt) < sessionActivityPeriod && session.IP == ip) {
+		return nil
+	}
+
+	if err := s.repository.UpdateSessionActivity(sessionID, ip, now); err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	return nil
+}
+
+func (s *authService) FindSessions(userID string, currentID string) ([]*entity.SessionDTO, *errors.AppError) {
+
+	sessions, err := s.repository.FindSessions(userID)
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+
+	// Recently used sessions first
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+
+	return entity.ToSessionDTOs(sessions, currentID), nil
+}
+
+func (s *authService) RevokeSession(userID string, sessionID string) *errors.AppError {
+
+	session, err := s.repository.FindSession(sessionID)
+	if err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	// Sessions of other users are not revealed
+	if session == nil || session.UserID != userID {
+		return errors.NotFound("Session is not found")
+	}
+
+	if err := s.repository.DeleteSession(session); err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	return nil
+}
+
+func (s *authService) RevokeSessions(userID string, exceptID string) *errors.AppError {
+
+	sessions, err := s.repository.FindSessions(userID)
+	if err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	for _, session := range sessions {
+		if session.ID == exceptID {
+			continue
+		}
+		if err := s.repository.DeleteSession(session); err != nil {
+			return errors.InternalServer(err.Error())
+		}
+	}
+
+	return nil
+}
+
+func sessionDevice(userAgent string) string {
+	if len(userAgent) > maxDeviceLength {
+		return userAgent[:maxDeviceLength]
+	}
+	return userAgent
+}
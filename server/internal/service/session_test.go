@@ -0,0 +1,234 @@
+package service
+
+import (
+	"github.com/nozgurozturk/marvin/server/entity"
+	"github.com/nozgurozturk/marvin/server/internal/storage"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Session store that keeps sessions and uuids of their refresh tokens in memory like redis
+type fakeSessionRepository struct {
+	storage.AuthRepository
+	sessions map[string]*entity.Session
+	// Uuid of refresh token -> user id
+	tokens map[string]string
+	// Concurrent refresh rotates session between read and rotation of request
+	concurrent func()
+}
+
+func newFakeSessionRepository(sessions ...*entity.Session) *fakeSessionRepository {
+	r := &fakeSessionRepository{sessions: map[string]*entity.Session{}, tokens: map[string]string{}}
+	for _, session := range sessions {
+		_ = r.CreateSession(session)
+	}
+	return r
+}
+
+func (r *fakeSessionRepository) CreateSession(session *entity.Session) error {
+	stored := *session
+	r.sessions[session.ID] = &stored
+	r.tokens[session.TokenID] = session.UserID
+	return nil
+}
+
+func (r *fakeSessionRepository) FindSession(sessionID string) (*entity.Session, error) {
+	session, ok := r.sessions[sessionID]
+	if !ok {
+		return nil, nil
+	}
+	found := *session
+	return &found, nil
+}
+
+func (r *fakeSessionRepository) FindSessions(userID string) ([]*entity.Session, error) {
+	var sessions []*entity.Session
+	for _, session := range r.sessions {
+		if session.UserID == userID {
+			found := *session
+			sessions = append(sessions, &found)
+		}
+	}
+	return sessions, nil
+}
+
+// Rotates session only if token is still latest token, like WATCH transaction of redis
+func (r *fakeSessionRepository) RotateSession(session *entity.Session, tokenID string) (bool, error) {
+	if r.concurrent != nil {
+		r.concurrent()
+		r.concurrent = nil
+	}
+	current, ok := r.sessions[session.ID]
+	if !ok || current.TokenID != tokenID {
+		return false, nil
+	}
+	delete(r.tokens, tokenID)
+	return true, r.CreateSession(session)
+}
+
+func (r *fakeSessionRepository) DeleteSession(session *entity.Session) error {
+	delete(r.sessions, session.ID)
+	delete(r.tokens, session.TokenID)
+	return nil
+}
+
+func newTestSession(sessionID string, userID string, tokenID string) *entity.Session {
+	now := time.Now().UTC()
+	return &entity.Session{ID: sessionID, UserID: userID, TokenID: tokenID, CreatedAt: now, LastSeenAt: now, ExpiresAt: now.Add(time.Hour)}
+}
+
+// Token pair of refresh with given refresh token uuid
+func newTestTokens(sessionID string, userID string, tokenID string) *entity.TokenDetails {
+	expires := time.Now().Add(2 * time.Hour).Unix()
+	return &entity.TokenDetails{
+		AccessToken:  &entity.Token{Uuid: "access-" + tokenID, UserID: userID, SessionID: sessionID, Expires: expires},
+		RefreshToken: &entity.Token{Uuid: tokenID, UserID: userID, SessionID: sessionID, Expires: expires},
+	}
+}
+
+func TestRotateSession(t *testing.T) {
+
+	repository := newFakeSessionRepository(newTestSession("session", "user", "token-1"))
+	s := NewAuthService(repository)
+	client := &entity.SessionClient{Device: "Firefox", IP: "10.0.0.2"}
+
+	session, _ := s.FindSession("session")
+	if appErr := s.RotateSession(session, "token-1", newTestTokens("session", "user", "token-2"), client); appErr != nil {
+		t.Fatal(appErr.Message)
+	}
+
+	rotated, _ := repository.FindSession("session")
+	if rotated == nil || rotated.TokenID != "token-2" || rotated.Device != "Firefox" || rotated.IP != "10.0.0.2" {
+		t.Fatalf("unexpected rotated session %+v", rotated)
+	}
+	if !rotated.CreatedAt.Equal(session.CreatedAt) {
+		t.Error("expected rotated session to keep its creation time")
+	}
+	if _, ok := repository.tokens["token-1"]; ok {
+		t.Error("expected old refresh token to be removed")
+	}
+
+	// New token can be rotated again
+	if appErr := s.RotateSession(rotated, "token-2", newTestTokens("session", "user", "token-3"), client); appErr != nil {
+		t.Fatal(appErr.Message)
+	}
+}
+
+func TestRotateSessionRevokesSessionOfReusedToken(t *testing.T) {
+
+	repository := newFakeSessionRepository(newTestSession("session", "user", "token-1"), newTestSession("other", "user", "other-token"))
+	s := NewAuthService(repository)
+	client := &entity.SessionClient{}
+
+	session, _ := s.FindSession("session")
+	if appErr := s.RotateSession(session, "token-1", newTestTokens("session", "user", "token-2"), client); appErr != nil {
+		t.Fatal(appErr.Message)
+	}
+
+	// Old token is replayed, for exp. by someone who stole it
+	session, _ = s.FindSession("session")
+	appErr := s.RotateSession(session, "token-1", newTestTokens("session", "user", "token-3"), client)
+	if appErr == nil || appErr.Status != http.StatusUnauthorized {
+		t.Fatalf("expected reused token to be rejected, got %v", appErr)
+	}
+
+	if _, appErr := s.FindSession("session"); appErr == nil || appErr.Status != http.StatusUnauthorized {
+		t.Errorf("expected session of reused token to be revoked, got %v", appErr)
+	}
+	if _, ok := repository.tokens["token-2"]; ok {
+		t.Error("expected latest token of revoked session to be removed")
+	}
+	if _, appErr := s.FindSession("other"); appErr != nil {
+		t.Error("expected other sessions of user to be kept")
+	}
+}
+
+func TestRotateSessionRevokesSessionOfConcurrentRefresh(t *testing.T) {
+
+	repository := newFakeSessionRepository(newTestSession("session", "user", "token-1"))
+	s := NewAuthService(repository)
+	client := &entity.SessionClient{}
+
+	// Another refresh with same token rotates session after it is read
+	repository.concurrent = func() {
+		rotated := newTestSession("session", "user", "token-2")
+		delete(repository.tokens, "token-1")
+		_ = repository.CreateSession(rotated)
+	}
+
+	session, _ := s.FindSession("session")
+	appErr := s.RotateSession(session, "token-1", newTestTokens("session", "user", "token-3"), client)
+	if appErr == nil || appErr.Status != http.StatusUnauthorized {
+		t.Fatalf("expected second refresh with same token to be rejected, got %v", appErr)
+	}
+
+	if found, _ := repository.FindSession("session"); found != nil {
+		t.Errorf("expected session to be revoked, got %+v", found)
+	}
+	if len(repository.tokens) != 0 {
+		t.Errorf("expected tokens of revoked session to be removed, got %v", repository.tokens)
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+
+	repository := newFakeSessionRepository(newTestSession("session", "user", "token"), newTestSession("other", "other-user", "other-token"))
+	s := NewAuthService(repository)
+
+	// Logout revokes session of token
+	if appErr := s.RevokeSession("user", "session"); appErr != nil {
+		t.Fatal(appErr.Message)
+	}
+	if _, appErr := s.FindSession("session"); appErr == nil {
+		t.Error("expected session to be revoked")
+	}
+	if _, ok := repository.tokens["token"]; ok {
+		t.Error("expected refresh token of session to be removed")
+	}
+
+	// Sessions of other users are not found
+	if appErr := s.RevokeSession("user", "other"); appErr == nil || appErr.Status != http.StatusNotFound {
+		t.Errorf("expected session of other user not to be found, got %v", appErr)
+	}
+	if _, appErr := s.FindSession("other"); appErr != nil {
+		t.Error("expected session of other user to be kept")
+	}
+}
+
+func TestRevokeSessions(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		exceptID string
+		kept     []string
+	}{
+		// Password reset revokes every session, someone else could be logged in with old password
+		{"password reset", "", []string{"stranger"}},
+		{"logout from other devices", "laptop", []string{"laptop", "stranger"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newFakeSessionRepository(
+				newTestSession("laptop", "user", "laptop-token"),
+				newTestSession("phone", "user", "phone-token"),
+				newTestSession("stranger", "other-user", "stranger-token"),
+			)
+			s := NewAuthService(repository)
+
+			if appErr := s.RevokeSessions("user", tt.exceptID); appErr != nil {
+				t.Fatal(appErr.Message)
+			}
+
+			if len(repository.sessions) != len(tt.kept) || len(repository.tokens) != len(tt.kept) {
+				t.Fatalf("expected %d sessions, got %d sessions and %d tokens", len(tt.kept), len(repository.sessions), len(repository.tokens))
+			}
+			for _, sessionID := range tt.kept {
+				if _, appErr := s.FindSession(sessionID); appErr != nil {
+					t.Errorf("expected session %s to be kept", sessionID)
+				}
+			}
+		})
+	}
+}
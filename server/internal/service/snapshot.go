@@ -0,0 +1,218 @@
+package service
+
+import (
+	"github.com/nozgurozturk/marvin/pkg/errors"
+	"github.com/nozgurozturk/marvin/pkg/utils"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"github.com/nozgurozturk/marvin/server/internal/config"
+	"log"
+	"sort"
+	"time"
+)
+
+// Saves scan of repository as snapshot and deletes expired snapshots
+// Scan is already saved, so snapshot errors are only logged
+func (s *repoService) saveSnapshot(repoDTO *entity.RepoDTO) {
+	if _, err := s.createSnapshot(entity.NewSnapshot(repoDTO)); err != nil {
+		log.Printf("Snapshot of %s is not saved: %s", repoDTO.Path, err)
+	}
+}
+
+func (s *repoService) createSnapshot(snapshot *entity.Snapshot) (*entity.Snapshot, error) {
+
+	created, err := s.snapshots.Create(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	cnf := config.Get().Snapshot
+
+	var before time.Time
+	if cnf.RetentionDays > 0 {
+		before = time.Now().UTC().AddDate(0, 0, -int(cnf.RetentionDays))
+	}
+
+	// New snapshot is counted in retention, so older snapshots over limits are deleted with this scan
+	if err := s.snapshots.DeleteExpired(created.RepoID.Hex(), cnf.RetentionCount, before); err != nil {
+		log.Printf("Expired snapshots of %s are not deleted: %s", created.RepoID.Hex(), err)
+	}
+
+	return created, nil
+}
+
+func (s *repoService) FindSnapshots(repoID string) ([]*entity.SnapshotDTO, *errors.AppError) {
+
+	snapshots, err := s.snapshots.FindAll(repoID)
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+
+	return entity.ToSnapshotSummaryDTOs(snapshots), nil
+}
+
+func (s *repoService) FindSnapshot(repoID string, snapshotID string) (*entity.SnapshotDTO, *errors.AppError) {
+
+	snapshot, appErr := s.findSnapshot(repoID, snapshotID)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	return entity.ToSnapshotDTO(snapshot), nil
+}
+
+// Snapshots of other repositories are not found
+func (s *repoService) findSnapshot(repoID string, snapshotID string) (*entity.Snapshot, *errors.AppError) {
+
+	snapshot, err := s.snapshots.FindByID(snapshotID)
+	if err != nil || snapshot == nil || snapshot.RepoID.Hex() != repoID {
+		return nil, errors.NotFound("Snapshot is not found")
+	}
+
+	return snapshot, nil
+}
+
+/*
+	1. Get Newer Snapshot -> given id or latest snapshot
+	2. Get Older Snapshot -> given id or snapshot before newer one
+	3. Diff Packages
+*/
+func (s *repoService) DiffSnapshots(repoID string, fromID string, toID string) (*entity.SnapshotDiff, *errors.AppError) {
+
+	to, appErr := s.findSnapshotOrLatest(repoID, toID, time.Time{})
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	from, appErr := s.findSnapshotOrLatest(repoID, fromID, to.CreatedAt)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	if from.CreatedAt.After(to.CreatedAt) {
+		from, to = to, from
+	}
+
+	diff := diffPackages(from.Packages, to.Packages)
+
+	diff.From = entity.ToSnapshotSummaryDTOs([]*entity.Snapshot{from})[0]
+	diff.To = entity.ToSnapshotSummaryDTOs([]*entity.Snapshot{to})[0]
+
+	return diff, nil
+}
+
+// Finds snapshot with id, newest snapshot before given time is used if id is empty
+func (s *repoService) findSnapshotOrLatest(repoID string, snapshotID string, before time.Time) (*entity.Snapshot, *errors.AppError) {
+
+	if snapshotID != "" {
+		return s.findSnapshot(repoID, snapshotID)
+	}
+
+	snapshot, err := s.snapshots.FindLatest(repoID, before)
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+
+	if snapshot == nil {
+		return nil, errors.NotFound("Repository does not have enough snapshots to compare")
+	}
+
+	return snapshot, nil
+}
+
+// Compares packages with their manifest paths and names
+func diffPackages(previous []*entity.Package, current []*entity.Package) *entity.SnapshotDiff {
+
+	diff := &entity.SnapshotDiff{
+		Added:         []*entity.Package{},
+		Removed:       []*entity.Package{},
+		Upgraded:      []*entity.PackageChange{},
+		Downgraded:    []*entity.PackageChange{},
+		Changed:       []*entity.PackageChange{},
+		NewlyOutdated: []*entity.Package{},
+	}
+
+	previousPackages := map[string]*entity.Package{}
+	for _, pkg := range previous {
+		previousPackages[packageKey(pkg)] = pkg
+	}
+
+	currentPackages := map[string]*entity.Package{}
+	for _, pkg := range current {
+		currentPackages[packageKey(pkg)] = pkg
+	}
+
+	for key, pkg := range currentPackages {
+		old, ok := previousPackages[key]
+		if !ok {
+			diff.Added = append(diff.Added, pkg)
+			if pkg.IsOutdated {
+				diff.NewlyOutdated = append(diff.NewlyOutdated, pkg)
+			}
+			continue
+		}
+
+		if pkg.IsOutdated && !old.IsOutdated {
+			diff.NewlyOutdated = append(diff.NewlyOutdated, pkg)
+		}
+
+		if old.Version.Current == pkg.Version.Current {
+			continue
+		}
+
+		change := &entity.PackageChange{
+			Name: pkg.Name,
+			Path: pkg.Path,
+			File: pkg.File,
+			From: old.Version.Current,
+			To:   pkg.Version.Current,
+		}
+
+		switch utils.CompareVersionNumbers(pkg.Version.Current, old.Version.Current) {
+		case 1:
+			diff.Upgraded = append(diff.Upgraded, change)
+		case -1:
+			diff.Downgraded = append(diff.Downgraded, change)
+		default:
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+
+	for key, pkg := range previousPackages {
+		if _, ok := currentPackages[key]; !ok {
+			diff.Removed = append(diff.Removed, pkg)
+		}
+	}
+
+	sortPackages(diff.Added)
+	sortPackages(diff.Removed)
+	sortPackages(diff.NewlyOutdated)
+	sortPackageChanges(diff.Upgraded)
+	sortPackageChanges(diff.Downgraded)
+	sortPackageChanges(diff.Changed)
+
+	return diff
+}
+
+// Packages that are scanned before path tracking are keyed by file name
+func packageKey(pkg *entity.Package) string {
+	path := pkg.Path
+	if path == "" {
+		path = pkg.File
+	}
+	return path + ":" + pkg.Name
+}
+
+func sortPackages(packages []*entity.Package) {
+	sort.Slice(packages, func(i, j int) bool {
+		return packageKey(packages[i]) < packageKey(packages[j])
+	})
+}
+
+func sortPackageChanges(changes []*entity.PackageChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Path != changes[j].Path {
+			return changes[i].Path < changes[j].Path
+		}
+		return changes[i].Name < changes[j].Name
+	})
+}
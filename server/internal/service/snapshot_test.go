@@ -0,0 +1,212 @@
+package service
+
+import (
+	"github.com/nozgurozturk/marvin/server/entity"
+	"github.com/nozgurozturk/marvin/server/internal/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func snapshotPackage(path string, name string, version string, outdated bool) *entity.Package {
+	return &entity.Package{Name: name, Path: path, File: "package.json", Version: entity.PackageVersion{Current: version}, IsOutdated: outdated}
+}
+
+func packageKeys(packages []*entity.Package) []string {
+	keys := []string{}
+	for _, pkg := range packages {
+		keys = append(keys, packageKey(pkg))
+	}
+	return keys
+}
+
+func changeKeys(changes []*entity.PackageChange) []string {
+	keys := []string{}
+	for _, change := range changes {
+		keys = append(keys, change.Path+":"+change.Name+" "+change.From+" -> "+change.To)
+	}
+	return keys
+}
+
+func TestDiffPackages(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		previous      []*entity.Package
+		current       []*entity.Package
+		added         []string
+		removed       []string
+		upgraded      []string
+		downgraded    []string
+		changed       []string
+		newlyOutdated []string
+	}{
+		{
+			name:     "same packages",
+			previous: []*entity.Package{snapshotPackage("package.json", "react", "^17.0.1", false)},
+			current:  []*entity.Package{snapshotPackage("package.json", "react", "^17.0.1", false)},
+		},
+		{
+			name:          "added and removed",
+			previous:      []*entity.Package{snapshotPackage("package.json", "left-pad", "^1.0.0", false)},
+			current:       []*entity.Package{snapshotPackage("package.json", "react", "^17.0.1", true), snapshotPackage("package.json", "vue", "^3.0.0", false)},
+			added:         []string{"package.json:react", "package.json:vue"},
+			removed:       []string{"package.json:left-pad"},
+			newlyOutdated: []string{"package.json:react"},
+		},
+		{
+			name:       "upgraded and downgraded",
+			previous:   []*entity.Package{snapshotPackage("package.json", "react", "^16.0.0", false), snapshotPackage("package.json", "vue", "^3.0.0", false)},
+			current:    []*entity.Package{snapshotPackage("package.json", "react", "^17.0.1", false), snapshotPackage("package.json", "vue", "^2.6.0", false)},
+			upgraded:   []string{"package.json:react ^16.0.0 -> ^17.0.1"},
+			downgraded: []string{"package.json:vue ^3.0.0 -> ^2.6.0"},
+		},
+		{
+			name:     "range operator is changed",
+			previous: []*entity.Package{snapshotPackage("package.json", "react", "^17.0.1", false)},
+			current:  []*entity.Package{snapshotPackage("package.json", "react", "~17.0.1", false)},
+			changed:  []string{"package.json:react ^17.0.1 -> ~17.0.1"},
+		},
+		{
+			name:          "newly outdated",
+			previous:      []*entity.Package{snapshotPackage("package.json", "react", "^17.0.1", false), snapshotPackage("package.json", "vue", "^3.0.0", true)},
+			current:       []*entity.Package{snapshotPackage("package.json", "react", "^17.0.1", true), snapshotPackage("package.json", "vue", "^3.0.0", true)},
+			newlyOutdated: []string{"package.json:react"},
+		},
+		{
+			name:     "same package in other manifest",
+			previous: []*entity.Package{snapshotPackage("package.json", "react", "^17.0.1", false)},
+			current:  []*entity.Package{snapshotPackage("package.json", "react", "^17.0.1", false), snapshotPackage("web/package.json", "react", "^16.0.0", false)},
+			added:    []string{"web/package.json:react"},
+		},
+		{
+			name:     "packages scanned before path tracking",
+			previous: []*entity.Package{snapshotPackage("", "react", "^16.0.0", false)},
+			current:  []*entity.Package{{Name: "react", File: "package.json", Version: entity.PackageVersion{Current: "^17.0.1"}}},
+			upgraded: []string{":react ^16.0.0 -> ^17.0.1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := diffPackages(tt.previous, tt.current)
+
+			got := map[string][]string{
+				"added":         packageKeys(diff.Added),
+				"removed":       packageKeys(diff.Removed),
+				"upgraded":      changeKeys(diff.Upgraded),
+				"downgraded":    changeKeys(diff.Downgraded),
+				"changed":       changeKeys(diff.Changed),
+				"newlyOutdated": packageKeys(diff.NewlyOutdated),
+			}
+			expected := map[string][]string{
+				"added":         tt.added,
+				"removed":       tt.removed,
+				"upgraded":      tt.upgraded,
+				"downgraded":    tt.downgraded,
+				"changed":       tt.changed,
+				"newlyOutdated": tt.newlyOutdated,
+			}
+
+			for category, keys := range expected {
+				if keys == nil {
+					keys = []string{}
+				}
+				if !reflect.DeepEqual(got[category], keys) {
+					t.Errorf("expected %s %v, got %v", category, keys, got[category])
+				}
+			}
+		})
+	}
+}
+
+// Snapshot store that deletes expired snapshots like database does
+type memorySnapshotRepository struct {
+	storage.SnapshotRepository
+	snapshots []*entity.Snapshot
+}
+
+func (r *memorySnapshotRepository) Create(snapshot *entity.Snapshot) (*entity.Snapshot, error) {
+	snapshot.ID = primitive.NewObjectID()
+	if snapshot.CreatedAt.IsZero() {
+		snapshot.CreatedAt = time.Now().UTC()
+	}
+	r.snapshots = append(r.snapshots, snapshot)
+	return snapshot, nil
+}
+
+func (r *memorySnapshotRepository) DeleteExpired(repoID string, keep int64, before time.Time) error {
+	sort.Slice(r.snapshots, func(i, j int) bool {
+		return r.snapshots[i].CreatedAt.After(r.snapshots[j].CreatedAt)
+	})
+
+	var kept []*entity.Snapshot
+	for _, snapshot := range r.snapshots {
+		if snapshot.RepoID.Hex() == repoID {
+			if snapshot.CreatedAt.Before(before) {
+				continue
+			}
+			if keep > 0 && int64(len(kept)) >= keep {
+				continue
+			}
+		}
+		kept = append(kept, snapshot)
+	}
+	r.snapshots = kept
+	return nil
+}
+
+func TestCreateSnapshotDeletesExpiredSnapshots(t *testing.T) {
+
+	repoID := primitive.NewObjectID()
+	otherRepoID := primitive.NewObjectID()
+	now := time.Now().UTC()
+
+	tests := []struct {
+		name     string
+		count    string
+		days     string
+		expected []string
+	}{
+		{"count and age limits", "2", "365", []string{"new", "yesterday"}},
+		{"age limit", "0", "365", []string{"new", "yesterday", "last week"}},
+		{"count limit", "3", "0", []string{"new", "yesterday", "last week"}},
+		{"without limits", "0", "0", []string{"new", "yesterday", "last week", "two years ago"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestConfig(t, map[string]string{"SNAPSHOT_RETENTION_COUNT": tt.count, "SNAPSHOT_RETENTION_DAYS": tt.days})
+
+			snapshots := &memorySnapshotRepository{snapshots: []*entity.Snapshot{
+				{RepoID: repoID, Ref: "yesterday", CreatedAt: now.AddDate(0, 0, -1)},
+				{RepoID: repoID, Ref: "last week", CreatedAt: now.AddDate(0, 0, -7)},
+				{RepoID: repoID, Ref: "two years ago", CreatedAt: now.AddDate(-2, 0, 0)},
+				{RepoID: otherRepoID, Ref: "other repository", CreatedAt: now.AddDate(-2, 0, 0)},
+			}}
+			s := &repoService{snapshots: snapshots}
+
+			// Scan is saved now, so it is kept and counted at once, not in next scan
+			if _, err := s.createSnapshot(&entity.Snapshot{RepoID: repoID, Ref: "new"}); err != nil {
+				t.Fatal(err)
+			}
+
+			var refs []string
+			for _, snapshot := range snapshots.snapshots {
+				if snapshot.RepoID == repoID {
+					refs = append(refs, snapshot.Ref)
+				} else if snapshot.Ref != "other repository" {
+					t.Errorf("unexpected snapshot %s", snapshot.Ref)
+				}
+			}
+			if len(snapshots.snapshots)-len(refs) != 1 {
+				t.Error("expected snapshots of other repository to be kept")
+			}
+			if !reflect.DeepEqual(refs, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, refs)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/nozgurozturk/marvin/pkg/errors"
 	"github.com/nozgurozturk/marvin/server/entity"
 	"github.com/nozgurozturk/marvin/server/internal/storage"
+	"strings"
 )
 
 type UserService interface {
@@ -17,6 +18,12 @@ type UserService interface {
 	Update(userDTO *entity.UserDTO) (*entity.UserDTO, *errors.AppError)
 	// Confirm verify user's account
 	Confirm(userID string) *errors.AppError
+	// UpdatePassword replaces user's password, it is used by password resets
+	UpdatePassword(userID string, password string) *errors.AppError
+	// UpdateEmail replaces user's email with verified address
+	UpdateEmail(userID string, email string) (*entity.UserDTO, *errors.AppError)
+	// LoginWithIdentity returns user of OAuth account, account is linked to user with same email or new user
+	LoginWithIdentity(profile *entity.OAuthProfile, identity *entity.Identity) (*entity.UserDTO, *errors.AppError)
 	// Delete removes user entity from store
 	Delete(id string) *errors.AppError
 }
@@ -102,6 +109,70 @@ func (s *userService) Confirm(userID string) *errors.AppError {
 	return nil
 }
 
+func (s *userService) UpdatePassword(userID string, password string) *errors.AppError {
+
+	password = strings.TrimSpace(password)
+	if validationError := entity.ValidatePassword(password); validationError != "" {
+		return errors.BadRequest(validationError)
+	}
+
+	user, err := s.repository.FindByID(userID)
+	if err != nil {
+		return errors.InternalServer(err.Error())
+	}
+	if user == nil {
+		return errors.NotFound("User is not found")
+	}
+
+	// Password is hashed by repository
+	_, err = s.repository.Update(&entity.User{ID: user.ID, Password: password})
+	if err != nil {
+		return errors.InternalServer(err.Error())
+	}
+
+	return nil
+}
+
+func (s *userService) UpdateEmail(userID string, email string) (*entity.UserDTO, *errors.AppError) {
+
+	email = strings.TrimSpace(email)
+	if validationError := entity.ValidateEmail(email); validationError != "" {
+		return nil, errors.BadRequest(validationError)
+	}
+
+	user, err := s.repository.FindByID(userID)
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+	if user == nil {
+		return nil, errors.NotFound("User is not found")
+	}
+
+	// Address could be taken after verification email is sent
+	exist, err := s.repository.FindByEmail(email)
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+	if exist != nil && exist.ID != user.ID {
+		return nil, errors.AlreadyExist("This email is taken by an another user")
+	}
+
+	_, err = s.repository.Update(&entity.User{ID: user.ID, Email: email})
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+
+	// New address is verified with the link
+	if err := s.repository.Confirm(userID, true); err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+
+	user.Email = email
+	user.IsConfirmed = true
+
+	return entity.ToUserDTO(user), nil
+}
+
 func (s *userService) Delete(id string) *errors.AppError {
 
 	err := s.repository.Delete(id)
@@ -111,3 +182,75 @@ func (s *userService) Delete(id string) *errors.AppError {
 
 	return nil
 }
+
+/*
+	1. Find By Identity -> account is linked before
+	2. Find By Email -> account is linked to user with same verified email
+	3. Create User -> confirmed user without password
+*/
+func (s *userService) LoginWithIdentity(profile *entity.OAuthProfile, identity *entity.Identity) (*entity.UserDTO, *errors.AppError) {
+
+	found, err := s.repository.FindByIdentity(identity.Provider, identity.ID)
+	if err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+
+	if found == nil {
+		// Emails that are not verified by provider could belong to someone else
+		if profile.Email == "" || !profile.EmailVerified {
+			return nil, errors.BadRequest("Provider account does not have a verified email")
+		}
+
+		found, err = s.repository.FindByEmail(profile.Email)
+		if err != nil {
+			return nil, errors.InternalServer(err.Error())
+		}
+	}
+
+	if found == nil {
+		created, err := s.repository.Create(&entity.User{
+			Name:        profile.Name,
+			Email:       profile.Email,
+			IsConfirmed: true,
+			Identities:  []*entity.Identity{identity},
+		})
+		if err != nil {
+			return nil, errors.InternalServer(err.Error())
+		}
+		return entity.ToUserDTO(created), nil
+	}
+
+	identities := linkIdentity(found.Identities, identity)
+	if err := s.repository.UpdateIdentities(found.ID.Hex(), identities); err != nil {
+		return nil, errors.InternalServer(err.Error())
+	}
+	found.Identities = identities
+
+	// Provider verified the email
+	if !found.IsConfirmed {
+		if err := s.repository.Confirm(found.ID.Hex(), true); err != nil {
+			return nil, errors.InternalServer(err.Error())
+		}
+		found.IsConfirmed = true
+	}
+
+	return entity.ToUserDTO(found), nil
+}
+
+// Adds or replaces identity of provider, granted token is kept if new login does not grant repository access
+func linkIdentity(identities []*entity.Identity, identity *entity.Identity) []*entity.Identity {
+
+	var linked []*entity.Identity
+
+	for _, existing := range identities {
+		if existing.Provider != identity.Provider {
+			linked = append(linked, existing)
+			continue
+		}
+		if identity.Token == "" && existing.ID == identity.ID {
+			identity.Token = existing.Token
+		}
+	}
+
+	return append(linked, identity)
+}
@@ -0,0 +1,143 @@
+package apitoken
+
+import (
+	"context"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+type Repository struct {
+	Collection *mongo.Collection
+}
+
+// Creates new mongo repository for personal API tokens
+func NewRepository(db *mongo.Database) *Repository {
+	collection := db.Collection("apiTokens")
+	return &Repository{
+		Collection: collection,
+	}
+}
+
+// Creates new API token
+func (r *Repository) Create(token *entity.APIToken) (*entity.APIToken, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	token.CreatedAt = time.Now().UTC()
+	result, err := r.Collection.InsertOne(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	token.ID = result.InsertedID.(primitive.ObjectID)
+
+	return token, nil
+}
+
+// Finds API token by id
+func (r *Repository) FindByID(tokenID string) (*entity.APIToken, error) {
+
+	id, err := primitive.ObjectIDFromHex(tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.findOne(bson.M{"_id": id})
+}
+
+// Finds API token by hash of token
+func (r *Repository) FindByHash(hash string) (*entity.APIToken, error) {
+	return r.findOne(bson.M{"hash": hash})
+}
+
+// Finds all API tokens of user, newest first
+func (r *Repository) FindAllByUserID(userID string) ([]*entity.APIToken, error) {
+
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.Collection.Find(ctx, bson.M{"userID": id},
+		options.Find().SetSort(bson.M{"createdAt": -1}))
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := []*entity.APIToken{}
+	if err = cursor.All(ctx, &tokens); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// Updates last usage time of API token
+func (r *Repository) UpdateLastUsed(tokenID primitive.ObjectID, usedAt time.Time) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.Collection.UpdateOne(ctx, bson.M{"_id": tokenID},
+		bson.M{"$set": bson.M{"lastUsedAt": usedAt}})
+
+	return err
+}
+
+// Deletes API token
+func (r *Repository) Delete(tokenID string) error {
+
+	id, err := primitive.ObjectIDFromHex(tokenID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = r.Collection.DeleteOne(ctx, bson.M{"_id": id})
+
+	return err
+}
+
+// Deletes all API tokens of user
+// Run after deleting user
+func (r *Repository) DeleteMany(userID string) error {
+
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = r.Collection.DeleteMany(ctx, bson.M{"userID": id})
+
+	return err
+}
+
+func (r *Repository) findOne(filter bson.M) (*entity.APIToken, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	token := new(entity.APIToken)
+	err := r.Collection.FindOne(ctx, filter).Decode(token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return token, nil
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/go-redis/redis/v8"
 	"github.com/nozgurozturk/marvin/server/entity"
 	"time"
@@ -56,3 +57,310 @@ func (r *Repository) DeleteAuth(uuid string) error {
 
 	return nil
 }
+
+// Creates state of OAuth authorization request into redis db
+func (r *Repository) CreateOAuthState(state string, oauthState *entity.OAuthState, expires time.Duration) error {
+
+	value, err := json.Marshal(oauthState)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return r.Client.Set(ctx, oauthStateKey(state), value, expires).Err()
+}
+
+// Gets and deletes state of OAuth authorization request from redis db
+func (r *Repository) FindOAuthState(state string) (*entity.OAuthState, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	value, err := r.Client.Get(ctx, oauthStateKey(state)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Only one request can delete state, so concurrent callbacks can not use same state
+	deleted, err := r.Client.Del(ctx, oauthStateKey(state)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if deleted == 0 {
+		return nil, redis.Nil
+	}
+
+	oauthState := new(entity.OAuthState)
+	if err := json.Unmarshal(value, oauthState); err != nil {
+		return nil, err
+	}
+
+	return oauthState, nil
+}
+
+func oauthStateKey(state string) string {
+	return "oauth-state:" + state
+}
+
+// Creates single use token of email link into redis db
+func (r *Repository) CreateActionToken(action entity.TokenAction, token string, actionToken *entity.ActionToken, expires time.Duration) error {
+
+	value, err := json.Marshal(actionToken)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return r.Client.Set(ctx, actionTokenKey(action, token), value, expires).Err()
+}
+
+// Gets and deletes token of email link from redis db
+func (r *Repository) FindActionToken(action entity.TokenAction, token string) (*entity.ActionToken, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	value, err := r.Client.Get(ctx, actionTokenKey(action, token)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Links can be used once, even if they are opened concurrently
+	deleted, err := r.Client.Del(ctx, actionTokenKey(action, token)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if deleted == 0 {
+		return nil, redis.Nil
+	}
+
+	actionToken := new(entity.ActionToken)
+	if err := json.Unmarshal(value, actionToken); err != nil {
+		return nil, err
+	}
+
+	return actionToken, nil
+}
+
+func actionTokenKey(action entity.TokenAction, token string) string {
+	return "action-token:" + string(action) + ":" + token
+}
+
+// Creates session and uuid of its refresh token into redis db
+func (r *Repository) CreateSession(session *entity.Session) error {
+
+	value, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	expires := time.Until(session.ExpiresAt)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = r.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, sessionKey(session.ID), value, expires)
+		pipe.Set(ctx, session.TokenID, session.UserID, expires)
+		pipe.SAdd(ctx, userSessionsKey(session.UserID), session.ID)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return r.extendUserSessions(ctx, session.UserID, expires)
+}
+
+// Gets session from redis db, nil is returned if session is expired or revoked
+func (r *Repository) FindSession(sessionID string) (*entity.Session, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return r.findSession(ctx, r.Client, sessionID)
+}
+
+// Gets sessions of user from redis db, expired sessions are removed from user's set
+func (r *Repository) FindSessions(userID string) ([]*entity.Session, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ids, err := r.Client.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []*entity.Session
+	var expired []interface{}
+
+	for _, id := range ids {
+		session, err := r.findSession(ctx, r.Client, id)
+		if err != nil {
+			return nil, err
+		}
+		if session == nil {
+			expired = append(expired, id)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+
+	if len(expired) > 0 {
+		if err := r.Client.SRem(ctx, userSessionsKey(userID), expired...).Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return sessions, nil
+}
+
+// Replaces refresh token of session if token is still latest token of session
+// Returns false if token is rotated before, so it is reused
+func (r *Repository) RotateSession(session *entity.Session, tokenID string) (bool, error) {
+
+	value, err := json.Marshal(session)
+	if err != nil {
+		return false, err
+	}
+
+	expires := time.Until(session.ExpiresAt)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rotated := false
+
+	// Concurrent refreshes with same token can not both rotate session
+	err = r.Client.Watch(ctx, func(tx *redis.Tx) error {
+		current, err := r.findSession(ctx, tx, session.ID)
+		if err != nil {
+			return err
+		}
+		if current == nil || current.TokenID != tokenID {
+			return nil
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, sessionKey(session.ID), value, expires)
+			pipe.Set(ctx, session.TokenID, session.UserID, expires)
+			pipe.Del(ctx, tokenID)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		rotated = true
+		return nil
+	}, sessionKey(session.ID))
+	if err == redis.TxFailedErr {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if rotated {
+		if err := r.extendUserSessions(ctx, session.UserID, expires); err != nil {
+			return false, err
+		}
+	}
+
+	return rotated, nil
+}
+
+// Updates last activity of session, session is not changed if it is rotated or deleted meanwhile
+func (r *Repository) UpdateSessionActivity(sessionID string, ip string, seenAt time.Time) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := r.Client.Watch(ctx, func(tx *redis.Tx) error {
+		session, err := r.findSession(ctx, tx, sessionID)
+		if err != nil || session == nil {
+			return err
+		}
+
+		session.IP = ip
+		session.LastSeenAt = seenAt
+
+		value, err := json.Marshal(session)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, sessionKey(sessionID), value, time.Until(session.ExpiresAt))
+			return nil
+		})
+		return err
+	}, sessionKey(sessionID))
+
+	// Activity is updated by next request
+	if err == redis.TxFailedErr {
+		return nil
+	}
+
+	return err
+}
+
+// Deletes session and uuid of its refresh token from redis db
+func (r *Repository) DeleteSession(session *entity.Session) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, sessionKey(session.ID), session.TokenID)
+		pipe.SRem(ctx, userSessionsKey(session.UserID), session.ID)
+		return nil
+	})
+
+	return err
+}
+
+func (r *Repository) findSession(ctx context.Context, client redis.Cmdable, sessionID string) (*entity.Session, error) {
+
+	value, err := client.Get(ctx, sessionKey(sessionID)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	session := new(entity.Session)
+	if err := json.Unmarshal(value, session); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// Set of user's sessions lives as long as newest session
+func (r *Repository) extendUserSessions(ctx context.Context, userID string, expires time.Duration) error {
+
+	ttl, err := r.Client.PTTL(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+	if ttl >= expires {
+		return nil
+	}
+
+	return r.Client.PExpire(ctx, userSessionsKey(userID), expires).Err()
+}
+
+func sessionKey(sessionID string) string {
+	return "session:" + sessionID
+}
+
+func userSessionsKey(userID string) string {
+	return "user-sessions:" + userID
+}
@@ -5,8 +5,13 @@ import (
 	"fmt"
 	"github.com/go-redis/redis/v8"
 	"github.com/nozgurozturk/marvin/server/internal/config"
+	"github.com/nozgurozturk/marvin/server/internal/storage/apitoken"
 	"github.com/nozgurozturk/marvin/server/internal/storage/auth"
+	"github.com/nozgurozturk/marvin/server/internal/storage/job"
+	"github.com/nozgurozturk/marvin/server/internal/storage/limit"
+	"github.com/nozgurozturk/marvin/server/internal/storage/organization"
 	"github.com/nozgurozturk/marvin/server/internal/storage/repo"
+	"github.com/nozgurozturk/marvin/server/internal/storage/snapshot"
 	"github.com/nozgurozturk/marvin/server/internal/storage/subscriber"
 	"github.com/nozgurozturk/marvin/server/internal/storage/user"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -22,6 +27,11 @@ type DB struct {
 	repos       RepoRepository
 	users       UserRepository
 	subscribers SubscriberRepository
+	jobs        JobRepository
+	snapshots   SnapshotRepository
+	orgs        OrganizationRepository
+	apiTokens   APITokenRepository
+	limits      LimitRepository
 }
 // Connects MongoDB and returns mongo.Database struct
 func MongoConnect() (*mongo.Database, error) {
@@ -79,6 +89,11 @@ func New(mongo *mongo.Database, redis *redis.Client) *DB {
 		users:       user.NewRepository(mongo),
 		subscribers: subscriber.NewRepository(mongo),
 		auths:       auth.NewRepository(redis),
+		jobs:        job.NewRepository(redis),
+		snapshots:   snapshot.NewRepository(mongo),
+		orgs:        organization.NewRepository(mongo),
+		apiTokens:   apitoken.NewRepository(mongo),
+		limits:      limit.NewRepository(redis),
 	}
 }
 
@@ -101,3 +116,28 @@ func (db *DB) Subscribers() SubscriberRepository {
 func (db *DB) Auths() AuthRepository {
 	return db.auths
 }
+
+// Returns scan job redis repository
+func (db *DB) Jobs() JobRepository {
+	return db.jobs
+}
+
+// Returns snapshot mongo repository
+func (db *DB) Snapshots() SnapshotRepository {
+	return db.snapshots
+}
+
+// Returns organization mongo repository
+func (db *DB) Organizations() OrganizationRepository {
+	return db.orgs
+}
+
+// Returns API token mongo repository
+func (db *DB) APITokens() APITokenRepository {
+	return db.apiTokens
+}
+
+// Returns rate limit redis repository
+func (db *DB) Limits() LimitRepository {
+	return db.limits
+}
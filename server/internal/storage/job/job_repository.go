@@ -0,0 +1,153 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-redis/redis/v8"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"sync"
+	"time"
+)
+
+type Repository struct {
+	Client *redis.Client
+}
+
+// Creates new redis repository for scan jobs
+func NewRepository(client *redis.Client) *Repository {
+	return &Repository{Client: client}
+}
+
+// Saves scan job into redis db, same key is overwritten by updates
+func (r *Repository) Save(job *entity.ScanJob, expires time.Duration) error {
+
+	value, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return r.Client.Set(ctx, jobKey(job.ID), value, expires).Err()
+}
+
+// Gets scan job from redis db
+func (r *Repository) FindByID(jobID string) (*entity.ScanJob, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	value, err := r.Client.Get(ctx, jobKey(jobID)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	job := new(entity.ScanJob)
+	if err := json.Unmarshal(value, job); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// Marks scan job as cancelled, instance that runs job stops it
+func (r *Repository) RequestCancel(jobID string, expires time.Duration) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return r.Client.Set(ctx, cancelKey(jobID), true, expires).Err()
+}
+
+// Checks cancellation of scan job is requested
+func (r *Repository) IsCancelRequested(jobID string) (bool, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := r.Client.Exists(ctx, cancelKey(jobID)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Publishes scan event into redis channel of job
+func (r *Repository) Publish(jobID string, event *entity.ScanEvent) error {
+
+	value, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return r.Client.Publish(ctx, eventsKey(jobID), value).Err()
+}
+
+// Subscribes redis channel of job, events of every instance are received
+func (r *Repository) Subscribe(jobID string) (<-chan *entity.ScanEvent, func(), error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pubSub := r.Client.Subscribe(ctx, eventsKey(jobID))
+
+	// Waits for confirmation, events that are published after it are not missed
+	if _, err := pubSub.Receive(ctx); err != nil {
+		pubSub.Close()
+		return nil, nil, err
+	}
+
+	events := make(chan *entity.ScanEvent)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(events)
+		messages := pubSub.Channel()
+		for {
+			select {
+			case <-done:
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				event := new(entity.ScanEvent)
+				if err := json.Unmarshal([]byte(message.Payload), event); err != nil {
+					continue
+				}
+				select {
+				case events <- event:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	closeFunc := func() {
+		once.Do(func() {
+			close(done)
+			pubSub.Close()
+		})
+	}
+
+	return events, closeFunc, nil
+}
+
+func jobKey(jobID string) string {
+	return "scan-job:" + jobID
+}
+
+func cancelKey(jobID string) string {
+	return "scan-job-cancel:" + jobID
+}
+
+func eventsKey(jobID string) string {
+	return "scan-job-events:" + jobID
+}
@@ -0,0 +1,94 @@
+package limit
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"time"
+)
+
+type Repository struct {
+	Client *redis.Client
+}
+
+// Creates new redis repository for rate limits and lockouts
+func NewRepository(client *redis.Client) *Repository {
+	return &Repository{Client: client}
+}
+
+// Increments counter of key, window starts with first request
+// Returns count in window and remaining time of window
+func (r *Repository) Increment(name string, key string, window time.Duration) (int64, time.Duration, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pipe := r.Client.TxPipeline()
+	count := pipe.Incr(ctx, counterKey(name, key))
+	ttl := pipe.PTTL(ctx, counterKey(name, key))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, 0, err
+	}
+
+	// Counter does not have expiry if it is created now or expire call is failed before
+	remaining := ttl.Val()
+	if remaining < 0 {
+		if err := r.Client.PExpire(ctx, counterKey(name, key), window).Err(); err != nil {
+			return 0, 0, err
+		}
+		remaining = window
+	}
+
+	return count.Val(), remaining, nil
+}
+
+// Deletes counter of key
+func (r *Repository) Reset(name string, key string) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return r.Client.Del(ctx, counterKey(name, key)).Err()
+}
+
+// Locks key for duration, existing lock is replaced
+func (r *Repository) CreateLock(name string, key string, duration time.Duration) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return r.Client.Set(ctx, lockKey(name, key), true, duration).Err()
+}
+
+// Returns remaining time of lock, zero is returned if key is not locked
+func (r *Repository) FindLock(name string, key string) (time.Duration, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ttl, err := r.Client.PTTL(ctx, lockKey(name, key)).Result()
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+// Deletes lock of key
+func (r *Repository) DeleteLock(name string, key string) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return r.Client.Del(ctx, lockKey(name, key)).Err()
+}
+
+func counterKey(name string, key string) string {
+	return "rate-limit:" + name + ":" + key
+}
+
+func lockKey(name string, key string) string {
+	return "lock:" + name + ":" + key
+}
@@ -0,0 +1,187 @@
+package organization
+
+import (
+	"context"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+type Repository struct {
+	Collection *mongo.Collection
+}
+
+// Creates new mongo repository for organizations
+func NewRepository(db *mongo.Database) *Repository {
+	collection := db.Collection("organizations")
+	return &Repository{
+		Collection: collection,
+	}
+}
+
+// Creates new organization
+func (r *Repository) Create(organization *entity.Organization) (*entity.Organization, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	organization.CreatedAt = time.Now().UTC()
+	result, err := r.Collection.InsertOne(ctx, organization)
+	if err != nil {
+		return nil, err
+	}
+
+	organization.ID = result.InsertedID.(primitive.ObjectID)
+
+	return organization, nil
+}
+
+// Finds organization by id
+func (r *Repository) FindByID(orgID string) (*entity.Organization, error) {
+
+	id, err := primitive.ObjectIDFromHex(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	organization := new(entity.Organization)
+	err = r.Collection.FindOne(ctx, bson.M{"_id": id}).Decode(organization)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return organization, nil
+}
+
+// Finds organizations that user is member of
+func (r *Repository) FindAllByUserID(userID string) ([]*entity.Organization, error) {
+
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.Collection.Find(ctx, bson.M{"members.userID": id})
+	if err != nil {
+		return nil, err
+	}
+
+	organizations := []*entity.Organization{}
+	if err = cursor.All(ctx, &organizations); err != nil {
+		return nil, err
+	}
+
+	return organizations, nil
+}
+
+// Updates organization's name
+func (r *Repository) UpdateName(orgID string, name string) (*entity.Organization, error) {
+	return r.update(orgID, bson.M{}, bson.M{"$set": bson.M{"name": name}})
+}
+
+// Adds member if user is not a member yet
+func (r *Repository) AddMember(orgID string, member *entity.Member) (*entity.Organization, error) {
+	return r.update(orgID,
+		bson.M{"members.userID": bson.M{"$ne": member.UserID}},
+		bson.M{"$push": bson.M{"members": member}},
+	)
+}
+
+// Updates role of member
+func (r *Repository) UpdateMemberRole(orgID string, userID string, role entity.Role) (*entity.Organization, error) {
+
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.update(orgID,
+		bson.M{"members.userID": id},
+		bson.M{"$set": bson.M{"members.$.role": role}},
+	)
+}
+
+// Removes member from organization
+func (r *Repository) RemoveMember(orgID string, userID string) (*entity.Organization, error) {
+
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.update(orgID, bson.M{}, bson.M{"$pull": bson.M{"members": bson.M{"userID": id}}})
+}
+
+// Removes user from all organizations
+// Run after deleting user
+func (r *Repository) RemoveMemberFromAll(userID string) error {
+
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = r.Collection.UpdateMany(ctx,
+		bson.M{"members.userID": id},
+		bson.M{"$pull": bson.M{"members": bson.M{"userID": id}}},
+	)
+
+	return err
+}
+
+// Deletes organization
+func (r *Repository) Delete(orgID string) error {
+
+	id, err := primitive.ObjectIDFromHex(orgID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = r.Collection.DeleteOne(ctx, bson.M{"_id": id})
+
+	return err
+}
+
+// Updates organization that matches filter, nil is returned if it does not match
+func (r *Repository) update(orgID string, filter bson.M, update bson.M) (*entity.Organization, error) {
+
+	id, err := primitive.ObjectIDFromHex(orgID)
+	if err != nil {
+		return nil, err
+	}
+	filter["_id"] = id
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	organization := new(entity.Organization)
+	after := options.After
+	err = r.Collection.FindOneAndUpdate(ctx, filter, update,
+		&options.FindOneAndUpdateOptions{ReturnDocument: &after}).Decode(organization)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return organization, nil
+}
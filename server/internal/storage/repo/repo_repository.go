@@ -6,6 +6,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"time"
 )
 
@@ -83,7 +84,7 @@ func (r *Repository) FindByUrlAndUserID(url string, userID string) (*entity.Repo
 	return repo, nil
 }
 
-// Finds git all repositories belongs to user
+// Finds git all repositories belongs to user, repositories of organizations are not included
 func (r *Repository) FindAll(userID string) ([]*entity.Repo, error) {
 
 	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second)
@@ -93,7 +94,7 @@ func (r *Repository) FindAll(userID string) ([]*entity.Repo, error) {
 		return nil, err
 	}
 
-	findFilter := bson.D{{"userID", bson.D{{"$in", bson.A{id}}}}}
+	findFilter := bson.D{{"userID", bson.D{{"$in", bson.A{id}}}}, {"orgID", bson.D{{"$exists", false}}}}
 
 	var repos []*entity.Repo
 
@@ -107,15 +108,82 @@ func (r *Repository) FindAll(userID string) ([]*entity.Repo, error) {
 	return repos, nil
 }
 
-// Updates git repository's packages
+// Finds all git repositories belongs to organization
+func (r *Repository) FindAllByOrgID(orgID string) ([]*entity.Repo, error) {
+
+	id, err := primitive.ObjectIDFromHex(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	repos := []*entity.Repo{}
+
+	cursor, err := r.Collection.Find(ctx, bson.D{{"orgID", id}})
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &repos); err != nil {
+		return nil, err
+	}
+
+	return repos, nil
+}
+
+// Moves git repository to organization or to user if organization id is empty
+func (r *Repository) UpdateOwner(repoID string, userID string, orgID string) (*entity.Repo, error) {
+
+	repo := new(entity.Repo)
+
+	id, err := primitive.ObjectIDFromHex(repoID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.D{{"$set", bson.D{{"userID", user}}}, {"$unset", bson.D{{"orgID", ""}}}}
+	if orgID != "" {
+		org, err := primitive.ObjectIDFromHex(orgID)
+		if err != nil {
+			return nil, err
+		}
+		update = bson.D{{"$set", bson.D{{"userID", user}, {"orgID", org}}}}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	after := options.After
+	err = r.Collection.FindOneAndUpdate(ctx, bson.D{{"_id", id}}, update,
+		&options.FindOneAndUpdateOptions{ReturnDocument: &after}).Decode(&repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// Updates git repository's packages with scanned commit and scan result
 func (r *Repository) UpdatePackages(repo *entity.Repo) (*entity.Repo, error) {
 
 	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second)
 
+	after := options.After
 	err := r.Collection.FindOneAndUpdate(ctx, bson.D{{"_id", repo.ID}},
 		bson.D{{"$set",
-			bson.D{{"packageList", repo.PackageList}},
-		}}).Decode(&repo)
+			bson.D{
+				{"packageList", repo.PackageList},
+				{"commitSHA", repo.CommitSHA},
+				{"manifestBlobs", repo.ManifestBlobs},
+				{"lastScan", repo.LastScan},
+			},
+		}}, &options.FindOneAndUpdateOptions{ReturnDocument: &after}).Decode(&repo)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, err
@@ -126,6 +194,113 @@ func (r *Repository) UpdatePackages(repo *entity.Repo) (*entity.Repo, error) {
 	return repo, nil
 }
 
+// Updates git repository's webhook secret
+func (r *Repository) UpdateWebhookSecret(repoID string, secret string) (*entity.Repo, error) {
+
+	repo := new(entity.Repo)
+
+	id, err := primitive.ObjectIDFromHex(repoID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	after := options.After
+	err = r.Collection.FindOneAndUpdate(ctx, bson.D{{"_id", id}},
+		bson.D{{"$set",
+			bson.D{{"webhookSecret", secret}},
+		}}, &options.FindOneAndUpdateOptions{ReturnDocument: &after}).Decode(&repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// Updates git repository's badge token
+func (r *Repository) UpdateBadgeToken(repoID string, token string) (*entity.Repo, error) {
+
+	repo := new(entity.Repo)
+
+	id, err := primitive.ObjectIDFromHex(repoID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	after := options.After
+	err = r.Collection.FindOneAndUpdate(ctx, bson.D{{"_id", id}},
+		bson.D{{"$set",
+			bson.D{{"badgeToken", token}},
+		}}, &options.FindOneAndUpdateOptions{ReturnDocument: &after}).Decode(&repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// Updates git repository's ignore rules with packages that rules are applied to
+func (r *Repository) UpdateIgnoreRules(repoID string, rules []*entity.IgnoreRule, packages []*entity.Package) (*entity.Repo, error) {
+
+	repo := new(entity.Repo)
+
+	id, err := primitive.ObjectIDFromHex(repoID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	after := options.After
+	err = r.Collection.FindOneAndUpdate(ctx, bson.D{{"_id", id}},
+		bson.D{{"$set",
+			bson.D{{"ignoreRules", rules}, {"packageList", packages}},
+		}}, &options.FindOneAndUpdateOptions{ReturnDocument: &after}).Decode(&repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// Updates git repository's provider token and user that saved it, empty token removes both
+func (r *Repository) UpdateToken(repoID string, token string, userID string) (*entity.Repo, error) {
+
+	repo := new(entity.Repo)
+
+	id, err := primitive.ObjectIDFromHex(repoID)
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.D{{"$unset", bson.D{{"token", ""}, {"tokenUserID", ""}}}}
+	if token != "" {
+		tokenUserID, err := primitive.ObjectIDFromHex(userID)
+		if err != nil {
+			return nil, err
+		}
+		update = bson.D{{"$set", bson.D{{"token", token}, {"tokenUserID", tokenUserID}}}}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	after := options.After
+	err = r.Collection.FindOneAndUpdate(ctx, bson.D{{"_id", id}}, update,
+		&options.FindOneAndUpdateOptions{ReturnDocument: &after}).Decode(&repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
 // Deletes git repository
 func (r *Repository) Delete(repoID string) error {
 
@@ -144,7 +319,7 @@ func (r *Repository) Delete(repoID string) error {
 	return nil
 }
 
-// Deletes all git repositories belongs to user
+// Deletes all git repositories belongs to user, repositories of organizations are kept
 // Run after deleting user
 func (r *Repository) DeleteMany(userID string) error {
 
@@ -155,7 +330,7 @@ func (r *Repository) DeleteMany(userID string) error {
 		return err
 	}
 
-	_, err = r.Collection.DeleteMany(ctx, bson.D{{"userID", id}})
+	_, err = r.Collection.DeleteMany(ctx, bson.D{{"userID", id}, {"orgID", bson.D{{"$exists", false}}}})
 	if err != nil {
 		return err
 	}
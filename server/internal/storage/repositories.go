@@ -1,6 +1,10 @@
 package storage
 
-import "github.com/nozgurozturk/marvin/server/entity"
+import (
+	"github.com/nozgurozturk/marvin/server/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
 
 // UserRepository interface
 type UserRepository interface {
@@ -10,6 +14,10 @@ type UserRepository interface {
 	FindByID(userID string) (*entity.User, error)
 	// FindByEmail returns entity with matching email
 	FindByEmail(email string) (*entity.User, error)
+	// FindByIdentity returns entity with linked OAuth account
+	FindByIdentity(provider string, id string) (*entity.User, error)
+	// UpdateIdentities replaces linked OAuth accounts of entity
+	UpdateIdentities(userID string, identities []*entity.Identity) error
 	// Update insert updated entity values in collection
 	Update(user *entity.User) (*entity.User, error)
 	// Confirm updates entity when user verify email
@@ -26,10 +34,22 @@ type RepoRepository interface {
 	FindByID(repoID string) (*entity.Repo, error)
 	// FindByEmail returns entity with matching url and user id
 	FindByUrlAndUserID(url string, userID string) (*entity.Repo, error)
-	// FindAll returns entities belongs to user
+	// FindAll returns entities belongs to user, repositories of organizations are not included
 	FindAll(userID string) ([]*entity.Repo, error)
+	// FindAllByOrgID returns entities belongs to organization
+	FindAllByOrgID(orgID string) ([]*entity.Repo, error)
+	// UpdateOwner moves entity to organization, entity belongs to user if organization id is empty
+	UpdateOwner(repoID string, userID string, orgID string) (*entity.Repo, error)
 	// UpdatePackages insert updated packages into entity
 	UpdatePackages(repo *entity.Repo) (*entity.Repo, error)
+	// UpdateWebhookSecret replaces secret of push webhooks
+	UpdateWebhookSecret(repoID string, secret string) (*entity.Repo, error)
+	// UpdateBadgeToken replaces token of public badge url
+	UpdateBadgeToken(repoID string, token string) (*entity.Repo, error)
+	// UpdateIgnoreRules replaces ignore rules and packages that rules are applied to
+	UpdateIgnoreRules(repoID string, rules []*entity.IgnoreRule, packages []*entity.Package) (*entity.Repo, error)
+	// UpdateToken replaces access token of provider and user that saved it
+	UpdateToken(repoID string, token string, userID string) (*entity.Repo, error)
 	// Delete removes entity from collection
 	Delete(repoID string) error
 	// Delete removes all entities belongs to user
@@ -58,6 +78,22 @@ type SubscriberRepository interface {
 	DeleteMany(repoID string) error
 }
 
+// SnapshotRepository interface
+type SnapshotRepository interface {
+	// Create insert entity to collection
+	Create(snapshot *entity.Snapshot) (*entity.Snapshot, error)
+	// FindByID returns entity with matching id
+	FindByID(snapshotID string) (*entity.Snapshot, error)
+	// FindAll returns entities belongs to git repository without packages
+	FindAll(repoID string) ([]*entity.Snapshot, error)
+	// FindLatest returns newest entity belongs to git repository that is created before given time
+	FindLatest(repoID string, before time.Time) (*entity.Snapshot, error)
+	// DeleteExpired removes entities that are older than given time or exceed count limit
+	DeleteExpired(repoID string, keep int64, before time.Time) error
+	// DeleteMany removes all entities belongs to git repository
+	DeleteMany(repoID string) error
+}
+
 // AuthRepository interface
 type AuthRepository interface {
 	// CreateAuth insert entity to store
@@ -66,4 +102,94 @@ type AuthRepository interface {
 	FindAuth(uuid string) (string, error)
 	// Delete removes entity from store
 	DeleteAuth(uuid string) error
+	// CreateOAuthState insert state of authorization request to store
+	CreateOAuthState(state string, oauthState *entity.OAuthState, expires time.Duration) error
+	// FindOAuthState returns and removes state, states can be used once
+	FindOAuthState(state string) (*entity.OAuthState, error)
+	// CreateActionToken insert token of email link to store
+	CreateActionToken(action entity.TokenAction, token string, actionToken *entity.ActionToken, expires time.Duration) error
+	// FindActionToken returns and removes token, tokens can be used once
+	FindActionToken(action entity.TokenAction, token string) (*entity.ActionToken, error)
+	// CreateSession insert session and uuid of its refresh token to store
+	CreateSession(session *entity.Session) error
+	// FindSession returns session with matching id, nil if it is expired or revoked
+	FindSession(sessionID string) (*entity.Session, error)
+	// FindSessions returns sessions of user
+	FindSessions(userID string) ([]*entity.Session, error)
+	// RotateSession replaces refresh token of session, false is returned if token is not latest token of session
+	RotateSession(session *entity.Session, tokenID string) (bool, error)
+	// UpdateSessionActivity sets last usage of session
+	UpdateSessionActivity(sessionID string, ip string, seenAt time.Time) error
+	// DeleteSession removes session and uuid of its refresh token from store
+	DeleteSession(session *entity.Session) error
+}
+
+// LimitRepository interface
+type LimitRepository interface {
+	// Increment counts request of key, returns count and remaining time of window
+	Increment(name string, key string, window time.Duration) (int64, time.Duration, error)
+	// Reset removes counter of key
+	Reset(name string, key string) error
+	// CreateLock locks key for duration
+	CreateLock(name string, key string, duration time.Duration) error
+	// FindLock returns remaining time of lock, zero if key is not locked
+	FindLock(name string, key string) (time.Duration, error)
+	// DeleteLock removes lock of key
+	DeleteLock(name string, key string) error
+}
+
+// JobRepository interface
+type JobRepository interface {
+	// Save insert or replaces entity in store
+	Save(job *entity.ScanJob, expires time.Duration) error
+	// FindByID returns entity with matching id
+	FindByID(jobID string) (*entity.ScanJob, error)
+	// RequestCancel marks entity as cancelled for instance that runs it
+	RequestCancel(jobID string, expires time.Duration) error
+	// IsCancelRequested checks entity is marked as cancelled
+	IsCancelRequested(jobID string) (bool, error)
+	// Publish sends event of entity to subscribers
+	Publish(jobID string, event *entity.ScanEvent) error
+	// Subscribe receives events of entity until close is called
+	Subscribe(jobID string) (events <-chan *entity.ScanEvent, close func(), err error)
+}
+
+// OrganizationRepository interface
+type OrganizationRepository interface {
+	// Create insert entity to collection
+	Create(organization *entity.Organization) (*entity.Organization, error)
+	// FindByID returns entity with matching id
+	FindByID(orgID string) (*entity.Organization, error)
+	// FindAllByUserID returns entities that user is member of
+	FindAllByUserID(userID string) ([]*entity.Organization, error)
+	// UpdateName replaces name of entity
+	UpdateName(orgID string, name string) (*entity.Organization, error)
+	// AddMember inserts member unless user is already member, nil is returned if user is member
+	AddMember(orgID string, member *entity.Member) (*entity.Organization, error)
+	// UpdateMemberRole replaces role of member, nil is returned if user is not member
+	UpdateMemberRole(orgID string, userID string, role entity.Role) (*entity.Organization, error)
+	// RemoveMember removes member from entity
+	RemoveMember(orgID string, userID string) (*entity.Organization, error)
+	// RemoveMemberFromAll removes user from all entities
+	RemoveMemberFromAll(userID string) error
+	// Delete removes entity from collection
+	Delete(orgID string) error
+}
+
+// APITokenRepository interface
+type APITokenRepository interface {
+	// Create insert entity to collection
+	Create(token *entity.APIToken) (*entity.APIToken, error)
+	// FindByID returns entity with matching id
+	FindByID(tokenID string) (*entity.APIToken, error)
+	// FindByHash returns entity with matching token hash
+	FindByHash(hash string) (*entity.APIToken, error)
+	// FindAllByUserID returns entities belongs to user
+	FindAllByUserID(userID string) ([]*entity.APIToken, error)
+	// UpdateLastUsed sets last usage time of entity
+	UpdateLastUsed(tokenID primitive.ObjectID, usedAt time.Time) error
+	// Delete removes entity from collection
+	Delete(tokenID string) error
+	// DeleteMany removes all entities belongs to user
+	DeleteMany(userID string) error
 }
@@ -0,0 +1,188 @@
+package snapshot
+
+import (
+	"context"
+	"github.com/nozgurozturk/marvin/server/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+type Repository struct {
+	Collection *mongo.Collection
+}
+
+// Creates new mongo repository for scan snapshots
+func NewRepository(db *mongo.Database) *Repository {
+	collection := db.Collection("snapshots")
+	return &Repository{
+		Collection: collection,
+	}
+}
+
+// Creates new snapshot
+func (r *Repository) Create(snapshot *entity.Snapshot) (*entity.Snapshot, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.Collection.InsertOne(ctx, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot.ID = result.InsertedID.(primitive.ObjectID)
+
+	return snapshot, nil
+}
+
+// Finds snapshot by id
+func (r *Repository) FindByID(snapshotID string) (*entity.Snapshot, error) {
+
+	id, err := primitive.ObjectIDFromHex(snapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	snapshot := new(entity.Snapshot)
+	err = r.Collection.FindOne(ctx, bson.M{"_id": id}).Decode(snapshot)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// Finds snapshots of git repository without packages, newest snapshot is first
+func (r *Repository) FindAll(repoID string) ([]*entity.Snapshot, error) {
+
+	id, err := primitive.ObjectIDFromHex(repoID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	findOptions := options.Find().
+		SetSort(bson.M{"createdAt": -1}).
+		SetProjection(bson.M{"packages": 0})
+
+	cursor, err := r.Collection.Find(ctx, bson.M{"repoID": id}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []*entity.Snapshot{}
+	if err := cursor.All(ctx, &snapshots); err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+// Finds newest snapshot of git repository that is created before given time
+// Zero time finds latest snapshot
+func (r *Repository) FindLatest(repoID string, before time.Time) (*entity.Snapshot, error) {
+
+	id, err := primitive.ObjectIDFromHex(repoID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"repoID": id}
+	if !before.IsZero() {
+		filter["createdAt"] = bson.M{"$lt": before}
+	}
+
+	findOptions := options.FindOne().SetSort(bson.M{"createdAt": -1})
+
+	snapshot := new(entity.Snapshot)
+	err = r.Collection.FindOne(ctx, filter, findOptions).Decode(snapshot)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// Deletes snapshots of git repository that are older than given time or exceed count limit
+// Zero limits are ignored
+func (r *Repository) DeleteExpired(repoID string, keep int64, before time.Time) error {
+
+	id, err := primitive.ObjectIDFromHex(repoID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if !before.IsZero() {
+		_, err = r.Collection.DeleteMany(ctx, bson.M{"repoID": id, "createdAt": bson.M{"$lt": before}})
+		if err != nil {
+			return err
+		}
+	}
+
+	if keep <= 0 {
+		return nil
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.M{"createdAt": -1}).
+		SetSkip(keep).
+		SetProjection(bson.M{"_id": 1})
+
+	cursor, err := r.Collection.Find(ctx, bson.M{"repoID": id}, findOptions)
+	if err != nil {
+		return err
+	}
+
+	var expired []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &expired); err != nil {
+		return err
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	ids := bson.A{}
+	for _, snapshot := range expired {
+		ids = append(ids, snapshot.ID)
+	}
+
+	_, err = r.Collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	return err
+}
+
+// Deletes all snapshots of git repository
+func (r *Repository) DeleteMany(repoID string) error {
+
+	id, err := primitive.ObjectIDFromHex(repoID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = r.Collection.DeleteMany(ctx, bson.M{"repoID": id})
+	return err
+}
@@ -5,4 +5,9 @@ type Store interface {
 	Subscribers() SubscriberRepository
 	Users() UserRepository
 	Auths() AuthRepository
+	Jobs() JobRepository
+	Snapshots() SnapshotRepository
+	Organizations() OrganizationRepository
+	APITokens() APITokenRepository
+	Limits() LimitRepository
 }
@@ -151,6 +151,49 @@ func (r *Repository) Confirm(userID string, confirmed bool) error {
 	return nil
 }
 
+// Finds user by linked OAuth account
+func (r *Repository) FindByIdentity(provider string, id string) (*entity.User, error) {
+
+	user := new(entity.User)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"identities": bson.M{"$elemMatch": bson.M{"provider": provider, "id": id}}}
+
+	err := r.Collection.FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Replaces user's linked OAuth accounts
+func (r *Repository) UpdateIdentities(userID string, identities []*entity.Identity) error {
+
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.Collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"identities": identities}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("User is not found")
+	}
+
+	return nil
+}
+
 // Deletes user from collection
 func (r *Repository) Delete(userID string) error {
 